@@ -0,0 +1,280 @@
+package adminserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/application"
+	"github.com/242617/core/buildinfo"
+	"github.com/242617/core/healthcheck"
+	"github.com/242617/core/httpserver"
+	"github.com/242617/core/protocol"
+)
+
+// Config is an admin server configuration suitable for config.Scan.
+// If Token is set, endpoints except probes require it as bearer token.
+type Config struct {
+	Addr  string `yaml:"addr" env:"ADMIN_ADDR" default:":9090"`
+	Token string `yaml:"token" env:"ADMIN_TOKEN"`
+}
+
+type option = func(s *Server) error
+
+func withDefaults() option {
+	return func(s *Server) error {
+		s.name, s.addr = "adminserver", ":9090"
+		s.pprof = true
+		s.checkTimeout = time.Second
+		s.log = l.With().Str("component", "adminserver").Logger()
+		return nil
+	}
+}
+
+func WithConfig(cfg Config) option {
+	return func(s *Server) error {
+		if cfg.Addr != "" {
+			s.addr = cfg.Addr
+		}
+		s.token = cfg.Token
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(s *Server) error {
+		s.name = name
+		return nil
+	}
+}
+
+func WithAddr(addr string) option {
+	return func(s *Server) error {
+		s.addr = addr
+		return nil
+	}
+}
+
+// WithToken requires bearer token for every endpoint except probes
+func WithToken(token string) option {
+	return func(s *Server) error {
+		s.token = token
+		return nil
+	}
+}
+
+// WithHealth serves liveness and readiness of health component on /healthz and /readyz
+func WithHealth(health *healthcheck.Health) option {
+	return func(s *Server) error {
+		s.health = health
+		return nil
+	}
+}
+
+// WithMetrics serves handler on /metrics, e.g. one returned by metrics.Handler
+func WithMetrics(handler http.Handler) option {
+	return func(s *Server) error {
+		s.metrics = handler
+		return nil
+	}
+}
+
+// WithPprof enables or disables /debug/pprof endpoints, they are enabled by default
+func WithPprof(enabled bool) option {
+	return func(s *Server) error {
+		s.pprof = enabled
+		return nil
+	}
+}
+
+// WithComponents lists components on /components, ones implementing protocol.HealthChecker are checked on request
+func WithComponents(components ...application.Component) option {
+	return func(s *Server) error {
+		s.components = append(s.components, components...)
+		return nil
+	}
+}
+
+// WithHandler mounts additional handler, it is protected by token as well
+func WithHandler(pattern string, handler http.Handler) option {
+	return func(s *Server) error {
+		if pattern == "" || handler == nil {
+			return errors.New("empty pattern or handler")
+		}
+		s.handlers = append(s.handlers, route{pattern, handler})
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(s *Server) error {
+		s.log = log
+		return nil
+	}
+}
+
+// New creates admin server component serving operational endpoints on a single internal port:
+// probes, metrics, pprof, build info, log level control and component introspection.
+func New(options ...option) (*Server, error) {
+	var s Server
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&s); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+
+	server, err := httpserver.New(
+		httpserver.WithName(s.name),
+		httpserver.WithAddr(s.addr),
+		httpserver.WithHandler(s.Handler()),
+		httpserver.WithMiddlewares(),
+		httpserver.WithLogger(s.log),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "new server")
+	}
+	s.server = server
+	return &s, nil
+}
+
+type route struct {
+	pattern string
+	handler http.Handler
+}
+
+type Server struct {
+	name, addr   string
+	token        string
+	health       *healthcheck.Health
+	metrics      http.Handler
+	pprof        bool
+	components   []application.Component
+	handlers     []route
+	checkTimeout time.Duration
+	log          zerolog.Logger
+
+	server *httpserver.Server
+}
+
+// Handler returns handler serving all endpoints
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	protected := http.NewServeMux()
+
+	if s.health != nil {
+		mux.Handle("/healthz", s.health.LivenessHandler())
+		mux.Handle("/readyz", s.health.ReadinessHandler())
+	} else {
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			respond(w, http.StatusOK, application.Healthz())
+		})
+	}
+
+	if s.metrics != nil {
+		protected.Handle("/metrics", s.metrics)
+	}
+	if s.pprof {
+		protected.HandleFunc("/debug/pprof/", pprof.Index)
+		protected.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		protected.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		protected.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		protected.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	protected.HandleFunc("/buildinfo", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, buildinfo.Get())
+	})
+	protected.HandleFunc("/loglevel", s.logLevel)
+	protected.HandleFunc("/components", s.introspect)
+	for _, route := range s.handlers {
+		protected.Handle(route.pattern, route.handler)
+	}
+
+	mux.Handle("/", s.auth(protected))
+	return mux
+}
+
+func (s *Server) auth(next http.Handler) http.Handler {
+	if s.token == "" {
+		return next
+	}
+	expected := []byte("Bearer " + s.token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			respond(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// logLevel returns global log level on GET and sets it from level parameter on PUT or POST
+func (s *Server) logLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		level, err := zerolog.ParseLevel(strings.ToLower(r.FormValue("level")))
+		if err != nil || r.FormValue("level") == "" {
+			respond(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid level %q", r.FormValue("level"))})
+			return
+		}
+		if previous := zerolog.GlobalLevel(); previous != level {
+			zerolog.SetGlobalLevel(level)
+			s.log.WithLevel(zerolog.NoLevel).Str("from", previous.String()).Str("to", level.String()).Msg("log level changed")
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		respond(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	respond(w, http.StatusOK, map[string]string{"level": zerolog.GlobalLevel().String()})
+}
+
+// ComponentInfo describes component on /components, Status is set for health checkers only
+type ComponentInfo struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (s *Server) introspect(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), s.checkTimeout)
+	defer cancel()
+
+	infos := make([]ComponentInfo, len(s.components))
+	for i, component := range s.components {
+		infos[i] = ComponentInfo{Name: component.String(), Type: fmt.Sprintf("%T", component)}
+		if checker, ok := component.(protocol.HealthChecker); ok {
+			infos[i].Status = healthcheck.StatusOK
+			if err := checker.HealthCheck(ctx); err != nil {
+				infos[i].Status, infos[i].Error = healthcheck.StatusFail, err.Error()
+			}
+		}
+	}
+	respond(w, http.StatusOK, infos)
+}
+
+func (s *Server) Start(ctx context.Context) error { return s.server.Start(ctx) }
+
+func (s *Server) Stop(ctx context.Context) error { return s.server.Stop(ctx) }
+
+// Addr returns address server is listening on
+func (s *Server) Addr() string { return s.server.Addr() }
+
+func (s *Server) String() string { return s.name }
+
+func respond(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,98 @@
+package adminserver_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/adminserver"
+	"github.com/242617/core/application"
+	"github.com/242617/core/healthcheck"
+)
+
+type checker struct{ application.MethodsComponent }
+
+func (checker) HealthCheck(context.Context) error { return errors.New("sample error") }
+
+func serve(h http.Handler, method, target, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	health, err := healthcheck.New()
+	require.NoError(t, err, "new health")
+
+	s, err := adminserver.New(
+		adminserver.WithToken("secret"),
+		adminserver.WithHealth(health),
+		adminserver.WithMetrics(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("metrics")) })),
+		adminserver.WithComponents(
+			application.NewMethodsComponent("plain", nil, nil),
+			checker{application.NewMethodsComponent("db", nil, nil)},
+		),
+	)
+	require.NoError(t, err, "new")
+	h := s.Handler()
+
+	assert.Equal(t, http.StatusOK, serve(h, "GET", "/healthz", "").Code, "liveness without token")
+	assert.Equal(t, http.StatusServiceUnavailable, serve(h, "GET", "/readyz", "").Code, "readiness without token")
+	assert.Equal(t, http.StatusUnauthorized, serve(h, "GET", "/metrics", "").Code, "metrics without token")
+	assert.Equal(t, http.StatusUnauthorized, serve(h, "GET", "/metrics", "wrong").Code, "wrong token")
+
+	w := serve(h, "GET", "/metrics", "secret")
+	assert.Equal(t, "metrics", w.Body.String(), "metrics")
+	assert.Equal(t, http.StatusOK, serve(h, "GET", "/debug/pprof/", "secret").Code, "pprof")
+	assert.Contains(t, serve(h, "GET", "/buildinfo", "secret").Body.String(), "go_version", "build info")
+
+	var components []adminserver.ComponentInfo
+	require.NoError(t, json.Unmarshal(serve(h, "GET", "/components", "secret").Body.Bytes(), &components), "components")
+	assert.Equal(t, []adminserver.ComponentInfo{
+		{Name: "plain", Type: "application.MethodsComponent"},
+		{Name: "db", Type: "adminserver_test.checker", Status: healthcheck.StatusFail, Error: "sample error"},
+	}, components, "introspection")
+}
+
+func TestLogLevel(t *testing.T) {
+	defer zerolog.SetGlobalLevel(zerolog.GlobalLevel())
+
+	s, err := adminserver.New()
+	require.NoError(t, err, "new")
+	h := s.Handler()
+
+	r := httptest.NewRequest("PUT", "/loglevel", strings.NewReader("level=warn"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, "set")
+	assert.Equal(t, zerolog.WarnLevel, zerolog.GlobalLevel(), "level")
+	assert.JSONEq(t, `{"level":"warn"}`, serve(h, "GET", "/loglevel", "").Body.String(), "get")
+
+	assert.Equal(t, http.StatusBadRequest, serve(h, "POST", "/loglevel?level=loud", "").Code, "invalid level")
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(h, "DELETE", "/loglevel", "").Code, "method")
+}
+
+func TestStartStop(t *testing.T) {
+	s, err := adminserver.New(adminserver.WithAddr("127.0.0.1:0"))
+	require.NoError(t, err, "new")
+	require.NoError(t, s.Start(context.Background()), "start")
+
+	res, err := http.Get("http://" + s.Addr() + "/healthz")
+	require.NoError(t, err, "get")
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode, "served")
+	assert.NoError(t, s.Stop(context.Background()), "stop")
+}
@@ -0,0 +1,127 @@
+package amqp
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	amqp091 "github.com/rabbitmq/amqp091-go"
+)
+
+// ConsumerConfig is a consumer configuration suitable for config.Scan
+type ConsumerConfig struct {
+	Queue       string   `yaml:"queue"`
+	Exchange    string   `yaml:"exchange"`
+	RoutingKeys []string `yaml:"routing_keys"`
+	Prefetch    int      `yaml:"prefetch" default:"10"`
+	// Requeue returns failed messages to queue, otherwise they are dead-lettered or dropped
+	Requeue bool `yaml:"requeue"`
+	// DeadLetterExchange receives rejected messages, it is set on declared queue
+	DeadLetterExchange string `yaml:"dead_letter_exchange"`
+}
+
+// NewConsumer creates component declaring durable queue bound to exchange
+// and consuming it with manual acks between Start and Stop
+func NewConsumer(conn *Conn, cfg ConsumerConfig, handler Handler) (*Consumer, error) {
+	if cfg.Queue == "" {
+		return nil, errors.New("empty queue")
+	}
+	if cfg.Prefetch < 0 {
+		return nil, errors.New("prefetch must not be negative")
+	}
+	return &Consumer{conn: conn, cfg: cfg, handler: handler, tag: conn.name + ":" + cfg.Queue}, nil
+}
+
+type Consumer struct {
+	conn    *Conn
+	cfg     ConsumerConfig
+	handler Handler
+	tag     string
+
+	ch channel
+	wg sync.WaitGroup
+}
+
+func (c *Consumer) Start(context.Context) error {
+	ch, err := c.conn.open()
+	if err != nil {
+		return err
+	}
+	if err := c.declare(ch); err != nil {
+		ch.Close()
+		return err
+	}
+
+	deliveries, err := ch.Consume(c.cfg.Queue, c.tag, false, false, false, false, nil)
+	if err != nil {
+		ch.Close()
+		return errors.Wrap(err, "consume")
+	}
+	c.ch = ch
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for d := range deliveries {
+			c.process(d)
+		}
+	}()
+	return nil
+}
+
+func (c *Consumer) declare(ch channel) error {
+	if err := ch.Qos(c.cfg.Prefetch, 0, false); err != nil {
+		return errors.Wrap(err, "qos")
+	}
+	var args amqp091.Table
+	if c.cfg.DeadLetterExchange != "" {
+		args = amqp091.Table{"x-dead-letter-exchange": c.cfg.DeadLetterExchange}
+	}
+	if _, err := ch.QueueDeclare(c.cfg.Queue, true, false, false, false, args); err != nil {
+		return errors.Wrap(err, "declare queue")
+	}
+	for _, key := range c.cfg.RoutingKeys {
+		if err := ch.QueueBind(c.cfg.Queue, key, c.cfg.Exchange, false, nil); err != nil {
+			return errors.Wrapf(err, "bind %q", key)
+		}
+	}
+	return nil
+}
+
+// Stop cancels consumption and waits for in-flight deliveries to be handled
+func (c *Consumer) Stop(ctx context.Context) error {
+	if c.ch == nil {
+		return nil
+	}
+	if err := c.ch.Cancel(c.tag, false); err != nil {
+		c.conn.log.Warn().Err(err).Str("queue", c.cfg.Queue).Msg("cancel")
+	}
+
+	doneCh := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(doneCh)
+	}()
+	select {
+	case <-ctx.Done():
+		c.ch.Close()
+		return errors.Wrap(ctx.Err(), "drain")
+	case <-doneCh:
+	}
+	return errors.Wrap(c.ch.Close(), "close channel")
+}
+
+func (c *Consumer) String() string { return c.tag }
+
+func (c *Consumer) process(d amqp091.Delivery) {
+	if err := c.conn.handle(c.handler, d); err != nil {
+		c.conn.log.Error().Err(err).Str("queue", c.cfg.Queue).Str("routing_key", d.RoutingKey).Msg("handle")
+		if err := d.Nack(false, c.cfg.Requeue); err != nil {
+			c.conn.log.Error().Err(err).Msg("nack")
+		}
+		return
+	}
+	if err := d.Ack(false); err != nil {
+		c.conn.log.Error().Err(err).Msg("ack")
+	}
+}
@@ -0,0 +1,157 @@
+package amqp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+
+	"github.com/pkg/errors"
+	amqp091 "github.com/rabbitmq/amqp091-go"
+
+	"github.com/242617/core/requestid"
+)
+
+var (
+	// ErrReturned is returned for mandatory message not routed to any queue
+	ErrReturned = errors.New("message returned")
+	// ErrNacked is returned when broker failed to take responsibility for message
+	ErrNacked = errors.New("message nacked")
+)
+
+type PublisherConfig struct {
+	Exchange string `yaml:"exchange"`
+	// Mandatory makes unroutable messages fail with ErrReturned
+	Mandatory bool `yaml:"mandatory"`
+}
+
+// NewPublisher creates component publishing persistent messages to exchange and waiting for confirms
+func NewPublisher(conn *Conn, cfg PublisherConfig) *Publisher {
+	return &Publisher{conn: conn, cfg: cfg}
+}
+
+type Publisher struct {
+	conn *Conn
+	cfg  PublisherConfig
+
+	mu       sync.Mutex
+	ch       channel
+	returns  chan amqp091.Return
+	confirms chan amqp091.Confirmation
+	// tag is a delivery tag of the last message published on channel
+	tag uint64
+}
+
+func (p *Publisher) Start(context.Context) error {
+	ch, err := p.conn.open()
+	if err != nil {
+		return err
+	}
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		return errors.Wrap(err, "confirm mode")
+	}
+	returns := ch.NotifyReturn(make(chan amqp091.Return, 16))
+	confirms := ch.NotifyPublish(make(chan amqp091.Confirmation, 16))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ch, p.returns, p.confirms, p.tag = ch, returns, confirms, 0
+	return nil
+}
+
+func (p *Publisher) Stop(context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ch == nil || p.ch.IsClosed() {
+		return nil
+	}
+	return errors.Wrap(p.ch.Close(), "close channel")
+}
+
+func (p *Publisher) String() string { return p.conn.name + ":" + p.cfg.Exchange }
+
+// Publish sends message and waits for broker confirmation
+func (p *Publisher) Publish(ctx context.Context, msg Message) error {
+	id, err := messageID()
+	if err != nil {
+		return err
+	}
+	headers := amqp091.Table{}
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+	if rid := requestid.FromContext(ctx); rid != "" {
+		if _, ok := headers[requestid.Header]; !ok {
+			headers[requestid.Header] = rid
+		}
+	}
+
+	// broker sends return before ack, so serialized publishing lets returns be matched
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ch == nil {
+		return errors.New("publisher is not started")
+	}
+
+	err = p.ch.PublishWithContext(ctx, p.cfg.Exchange, msg.RoutingKey, p.cfg.Mandatory, false, amqp091.Publishing{
+		MessageId:    id,
+		ContentType:  msg.ContentType,
+		DeliveryMode: amqp091.Persistent,
+		Headers:      headers,
+		Body:         msg.Body,
+	})
+	if err != nil {
+		return errors.Wrap(err, "publish")
+	}
+	p.tag++
+	acked, err := p.confirmed(ctx, p.tag)
+	if err != nil {
+		return errors.Wrap(err, "wait confirm")
+	}
+	var returned *amqp091.Return
+drain:
+	for {
+		select {
+		case r := <-p.returns:
+			if r.MessageId == id {
+				returned = &r
+			}
+		default:
+			break drain
+		}
+	}
+	if returned != nil {
+		return errors.Wrapf(ErrReturned, "%d %s", returned.ReplyCode, returned.ReplyText)
+	}
+	if !acked {
+		return ErrNacked
+	}
+	return nil
+}
+
+// confirmed waits for confirmation of tag, confirmations of messages whose publishing
+// was abandoned by context are skipped
+func (p *Publisher) confirmed(ctx context.Context, tag uint64) (bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case c, ok := <-p.confirms:
+			if !ok {
+				return false, errors.New("channel closed")
+			}
+			if c.DeliveryTag == tag {
+				return c.Ack, nil
+			}
+		}
+	}
+}
+
+func messageID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "generate message id")
+	}
+	return hex.EncodeToString(b), nil
+}
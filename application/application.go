@@ -34,6 +34,23 @@ func withDefaultLogger() option {
 	}
 }
 
+// WithOnError sets callback called when components fail to start or stop, e.g. to page on-call
+func WithOnError(f func(error)) option {
+	return func(a *Application) error {
+		a.onError = f
+		return nil
+	}
+}
+
+// WithVersionFlag makes Run print build info and return without starting components
+// if command line contains --version
+func WithVersionFlag() option {
+	return func(a *Application) error {
+		a.versionFlag = true
+		return nil
+	}
+}
+
 func WithComponents(components ...Component) option {
 	return func(a *Application) error {
 		a.components = components
@@ -59,6 +76,8 @@ type Application struct {
 	startTimeout, stopTimeout time.Duration
 	log                       zerolog.Logger
 	components                []Component
+	onError                   func(error)
+	versionFlag               bool
 }
 
 type Component interface {
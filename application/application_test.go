@@ -2,6 +2,7 @@ package application_test
 
 import (
 	"context"
+	"os"
 	"syscall"
 	"testing"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/242617/core/application"
+	"github.com/242617/core/leaktest"
 )
 
 func TestBasic(t *testing.T) {
@@ -23,7 +25,35 @@ func TestBasic(t *testing.T) {
 	assert.NoError(t, a.Run(), "run application")
 }
 
+func TestVersionFlag(t *testing.T) {
+	period := 10 * time.Millisecond
+	args := os.Args
+	defer func() { os.Args = args }()
+	os.Args = []string{"app", "--version"}
+
+	var started bool
+	component := application.NewMethodsComponent("test",
+		func(context.Context) error { started = true; return nil },
+		nil,
+	)
+
+	a, err := application.New(application.WithVersionFlag(), application.WithComponents(component))
+	assert.NoError(t, err, "new application")
+	assert.NoError(t, a.Run(), "run application")
+	assert.False(t, started, "not started with version flag")
+
+	a, err = application.New(application.WithComponents(component))
+	assert.NoError(t, err, "new application")
+	go func() {
+		time.Sleep(period)
+		syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+	}()
+	assert.NoError(t, a.Run(), "run application")
+	assert.True(t, started, "version flag is opt-in")
+}
+
 func TestWithComponent(t *testing.T) {
+	defer leaktest.Check(t)()
 	period := 10 * time.Millisecond
 	a, err := application.New(
 		application.WithComponents(
@@ -48,9 +78,14 @@ func TestStartError(t *testing.T) {
 		func(context.Context) error { return nil },
 	)
 
-	a, err := application.New(application.WithComponents(cmp))
+	var reported error
+	a, err := application.New(
+		application.WithComponents(cmp),
+		application.WithOnError(func(err error) { reported = err }),
+	)
 	assert.NoError(t, err, "new application")
 	assert.ErrorIs(t, a.Run(), startErr, "start error")
+	assert.ErrorIs(t, reported, startErr, "error reported")
 }
 
 func TestStopError(t *testing.T) {
@@ -7,14 +7,22 @@ import (
 	"syscall"
 
 	"github.com/pkg/errors"
+
+	"github.com/242617/core/buildinfo"
 )
 
+// Run starts components and stops them on termination signal.
+// With WithVersionFlag, build info is printed instead if command line contains --version.
 func (a *Application) Run() error {
+	if a.versionFlag && buildinfo.VersionFlag(os.Args[1:], os.Stdout) {
+		return nil
+	}
+
 	startCtx, startCancel := context.WithTimeout(context.Background(), a.startTimeout)
 	defer startCancel()
 
 	if err := a.start(startCtx); err != nil {
-		return errors.Wrap(err, "start application")
+		return a.fail(errors.Wrap(err, "start application"))
 	}
 
 	quitCh := make(chan os.Signal, 1)
@@ -25,8 +33,15 @@ func (a *Application) Run() error {
 	defer stopCancel()
 
 	if err := a.stop(stopCtx); err != nil {
-		return errors.Wrap(err, "stop application")
+		return a.fail(errors.Wrap(err, "stop application"))
 	}
 
 	return nil
 }
+
+func (a *Application) fail(err error) error {
+	if a.onError != nil {
+		a.onError(err)
+	}
+	return err
+}
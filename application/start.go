@@ -4,10 +4,12 @@ import (
 	"context"
 
 	"github.com/pkg/errors"
+
+	"github.com/242617/core/buildinfo"
 )
 
 func (a *Application) start(ctx context.Context) error {
-	a.log.Info().Msgf("starting %s (%s)", Name, Hostname)
+	a.log.Info().EmbedObject(buildinfo.Get()).Msgf("starting %s (%s)", Name, Hostname)
 
 	okCh, errCh := make(chan struct{}), make(chan error)
 	go func() {
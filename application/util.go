@@ -4,6 +4,8 @@ import (
 	"log"
 	"os"
 	"time"
+
+	"github.com/242617/core/buildinfo"
 )
 
 var (
@@ -25,13 +27,18 @@ type HealthzRespone struct {
 	Time     string `json:"time"`
 	Hostname string `json:"hostname"`
 	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Commit   string `json:"commit"`
 }
 
 func Healthz() *HealthzRespone {
+	info := buildinfo.Get()
 	return &HealthzRespone{
 		Uptime:   time.Since(start).String(),
 		Time:     time.Now().Format(time.RFC3339Nano),
 		Hostname: Hostname,
 		Name:     Name,
+		Version:  info.Version,
+		Commit:   info.Commit,
 	}
 }
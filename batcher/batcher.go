@@ -0,0 +1,310 @@
+package batcher
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/protocol"
+)
+
+const (
+	MetricItemsTotal    = "batcher_items_total"
+	MetricErrorsTotal   = "batcher_errors_total"
+	MetricFlushDuration = "batcher_flush_duration_seconds"
+)
+
+var ErrStopped = errors.New("batcher is stopped")
+
+// FlushFunc handles batch of items
+type FlushFunc[T any] func(ctx context.Context, items []T) error
+
+type Option[T any] func(b *Batcher[T]) error
+
+func withDefaults[T any]() Option[T] {
+	return func(b *Batcher[T]) error {
+		b.name = "batcher"
+		b.size, b.interval, b.workers = 100, time.Second, 1
+		b.log = l.With().Str("component", "batcher").Logger()
+		return nil
+	}
+}
+
+func WithName[T any](name string) Option[T] {
+	return func(b *Batcher[T]) error {
+		b.name = name
+		return nil
+	}
+}
+
+// WithSize sets number of items triggering flush
+func WithSize[T any](size int) Option[T] {
+	return func(b *Batcher[T]) error {
+		if size < 1 {
+			return errors.New("size must be positive")
+		}
+		b.size = size
+		return nil
+	}
+}
+
+// WithInterval sets max time items are buffered
+func WithInterval[T any](interval time.Duration) Option[T] {
+	return func(b *Batcher[T]) error {
+		if interval <= 0 {
+			return errors.New("interval must be positive")
+		}
+		b.interval = interval
+		return nil
+	}
+}
+
+// WithWorkers sets number of concurrent flushes
+func WithWorkers[T any](workers int) Option[T] {
+	return func(b *Batcher[T]) error {
+		if workers < 1 {
+			return errors.New("workers must be positive")
+		}
+		b.workers = workers
+		return nil
+	}
+}
+
+// WithOnError sets callback receiving failed batch, errors are logged by default
+func WithOnError[T any](f func(items []T, err error)) Option[T] {
+	return func(b *Batcher[T]) error {
+		b.onError = f
+		return nil
+	}
+}
+
+func WithMetrics[T any](recorder protocol.MetricsRecorder) Option[T] {
+	return func(b *Batcher[T]) error {
+		b.metrics = recorder
+		return nil
+	}
+}
+
+func WithLogger[T any](log zerolog.Logger) Option[T] {
+	return func(b *Batcher[T]) error {
+		b.log = log
+		return nil
+	}
+}
+
+// New creates batcher buffering items and passing them to flush in batches.
+// Batches are flushed by workers between Start and Stop, buffered items are flushed on Stop.
+func New[T any](flush FlushFunc[T], options ...Option[T]) (*Batcher[T], error) {
+	b := Batcher[T]{flush: flush}
+	options = append([]Option[T]{withDefaults[T]()}, options...)
+	for _, option := range options {
+		if err := option(&b); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	return &b, nil
+}
+
+type Batcher[T any] struct {
+	name          string
+	flush         FlushFunc[T]
+	size, workers int
+	interval      time.Duration
+	onError       func([]T, error)
+	metrics       protocol.MetricsRecorder
+	log           zerolog.Logger
+
+	// send guards queue from being closed while batches are sent
+	send     sync.RWMutex
+	mu       sync.Mutex
+	items    []T
+	started  bool
+	stopped  bool
+	queue    chan []T
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stopTick context.CancelFunc
+	tickDone chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Add buffers item, it blocks while all workers are busy and batch is full
+func (b *Batcher[T]) Add(item T) error {
+	b.send.RLock()
+	defer b.send.RUnlock()
+
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		return ErrStopped
+	}
+	b.items = append(b.items, item)
+	var batch []T
+	if b.started && len(b.items) >= b.size {
+		batch = b.cut()
+	}
+	b.mu.Unlock()
+
+	if batch != nil {
+		b.queue <- batch
+	}
+	return nil
+}
+
+// Flush passes buffered items to flush in caller goroutine
+func (b *Batcher[T]) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	batch := b.cut()
+	b.mu.Unlock()
+
+	if batch == nil {
+		return nil
+	}
+	return b.process(ctx, batch)
+}
+
+// Len returns number of buffered items
+func (b *Batcher[T]) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.items)
+}
+
+func (b *Batcher[T]) Start(context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.started || b.stopped {
+		return errors.New("already started")
+	}
+	b.started = true
+	b.queue = make(chan []T, b.workers)
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+	for i := 0; i < b.workers; i++ {
+		b.wg.Add(1)
+		go b.work()
+	}
+	var tickCtx context.Context
+	tickCtx, b.stopTick = context.WithCancel(context.Background())
+	b.tickDone = make(chan struct{})
+	go b.tick(tickCtx)
+	if len(b.items) >= b.size {
+		b.queue <- b.cut()
+	}
+	return nil
+}
+
+// Stop flushes buffered items and waits for running flushes.
+// Context passed to flush is cancelled when ctx is done.
+func (b *Batcher[T]) Stop(ctx context.Context) error {
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		return nil
+	}
+	b.stopped = true
+	started := b.started
+	b.mu.Unlock()
+
+	if !started {
+		return b.Flush(ctx)
+	}
+
+	b.stopTick()
+	<-b.tickDone
+
+	b.send.Lock()
+	b.mu.Lock()
+	batch := b.cut()
+	b.mu.Unlock()
+	if batch != nil {
+		b.queue <- batch
+	}
+	close(b.queue)
+	b.send.Unlock()
+
+	doneCh := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(doneCh)
+	}()
+	select {
+	case <-ctx.Done():
+		b.cancel()
+		<-doneCh
+		return errors.Wrap(ctx.Err(), "drain")
+	case <-doneCh:
+		b.cancel()
+	}
+	return nil
+}
+
+func (b *Batcher[T]) String() string { return b.name }
+
+func (b *Batcher[T]) cut() []T {
+	if len(b.items) == 0 {
+		return nil
+	}
+	batch := b.items
+	b.items = nil
+	return batch
+}
+
+func (b *Batcher[T]) tick(ctx context.Context) {
+	defer close(b.tickDone)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		b.send.RLock()
+		b.mu.Lock()
+		batch := b.cut()
+		b.mu.Unlock()
+		if batch != nil {
+			b.queue <- batch
+		}
+		b.send.RUnlock()
+	}
+}
+
+func (b *Batcher[T]) work() {
+	defer b.wg.Done()
+	for batch := range b.queue {
+		_ = b.process(b.ctx, batch)
+	}
+}
+func (b *Batcher[T]) process(ctx context.Context, batch []T) (err error) {
+	start := time.Now()
+	defer func() {
+		if v := recover(); v != nil {
+			b.log.Error().Interface("panic", v).Bytes("stack", debug.Stack()).Msg("recovered")
+			err = errors.Errorf("panic: %v", v)
+		}
+		if err != nil {
+			if b.onError != nil {
+				b.onError(batch, err)
+			} else {
+				b.log.Error().Err(err).Int("items", len(batch)).Msg("flush")
+			}
+		}
+		if b.metrics != nil {
+			status := "ok"
+			if err != nil {
+				status = "error"
+				b.metrics.Add(MetricErrorsTotal, 1, "batcher", b.name)
+			}
+			b.metrics.Add(MetricItemsTotal, float64(len(batch)), "batcher", b.name, "status", status)
+			b.metrics.Observe(MetricFlushDuration, time.Since(start).Seconds(), "batcher", b.name)
+		}
+	}()
+	return b.flush(ctx, batch)
+}
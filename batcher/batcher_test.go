@@ -0,0 +1,128 @@
+package batcher_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/batcher"
+	"github.com/242617/core/leaktest"
+)
+
+var period = 10 * time.Millisecond
+
+type sink struct {
+	mu      sync.Mutex
+	batches [][]int
+}
+
+func (s *sink) flush(_ context.Context, items []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, items)
+	return nil
+}
+
+func (s *sink) get() [][]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]int(nil), s.batches...)
+}
+
+func TestSize(t *testing.T) {
+	defer leaktest.Check(t)()
+	var s sink
+	b, err := batcher.New(s.flush, batcher.WithSize[int](2), batcher.WithInterval[int](time.Minute))
+	require.NoError(t, err, "new batcher")
+
+	require.NoError(t, b.Add(1), "add before start")
+	require.NoError(t, b.Start(context.Background()), "start")
+	require.NoError(t, b.Add(2), "add")
+	assert.Eventually(t, func() bool { return len(s.get()) == 1 }, 10*period, period, "full batch flushed")
+
+	require.NoError(t, b.Add(3), "add")
+	require.NoError(t, b.Stop(context.Background()), "stop")
+	assert.Equal(t, [][]int{{1, 2}, {3}}, s.get(), "rest flushed on stop")
+	assert.ErrorIs(t, b.Add(4), batcher.ErrStopped, "add after stop")
+}
+
+func TestInterval(t *testing.T) {
+	var s sink
+	b, err := batcher.New(s.flush, batcher.WithSize[int](100), batcher.WithInterval[int](period))
+	require.NoError(t, err, "new batcher")
+	require.NoError(t, b.Start(context.Background()), "start")
+	defer b.Stop(context.Background())
+
+	require.NoError(t, b.Add(1), "add")
+	assert.Eventually(t, func() bool { return len(s.get()) == 1 }, 10*period, period, "flushed by interval")
+	assert.Zero(t, b.Len(), "buffer empty")
+}
+
+func TestConcurrent(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		total int
+		fails [][]string
+	)
+	flushErr := errors.New("flush error")
+	b, err := batcher.New(func(_ context.Context, items []string) error {
+		time.Sleep(period / 10)
+		if items[0] == "fail" {
+			return flushErr
+		}
+		mu.Lock()
+		total += len(items)
+		mu.Unlock()
+		return nil
+	},
+		batcher.WithSize[string](5),
+		batcher.WithWorkers[string](4),
+		batcher.WithOnError(func(items []string, err error) {
+			assert.ErrorIs(t, err, flushErr, "error passed")
+			mu.Lock()
+			fails = append(fails, items)
+			mu.Unlock()
+		}),
+	)
+	require.NoError(t, err, "new batcher")
+	require.NoError(t, b.Start(context.Background()), "start")
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				assert.NoError(t, b.Add("item"), "add")
+			}
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, b.Stop(context.Background()), "stop")
+	assert.Equal(t, 200, total, "all items flushed")
+
+	b, err = batcher.New(func(context.Context, []string) error { return flushErr },
+		batcher.WithOnError(func(items []string, err error) {
+			fails = append(fails, items)
+		}),
+	)
+	require.NoError(t, err, "new batcher")
+	require.NoError(t, b.Add("fail"), "add")
+	assert.ErrorIs(t, b.Stop(context.Background()), flushErr, "stop without start flushes")
+	assert.Equal(t, [][]string{{"fail"}}, fails, "error callback")
+}
+
+func TestOptions(t *testing.T) {
+	flush := func(context.Context, []int) error { return nil }
+	_, err := batcher.New(flush, batcher.WithSize[int](0))
+	assert.Error(t, err, "zero size")
+	_, err = batcher.New(flush, batcher.WithWorkers[int](0))
+	assert.Error(t, err, "zero workers")
+	_, err = batcher.New(flush, batcher.WithInterval[int](0))
+	assert.Error(t, err, "zero interval")
+}
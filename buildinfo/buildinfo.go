@@ -0,0 +1,132 @@
+package buildinfo
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+
+	"github.com/242617/core/protocol"
+)
+
+const MetricBuildInfo = "build_info"
+
+// Values are set at build time:
+//
+//	go build -ldflags "-X github.com/242617/core/buildinfo.Version=v1.2.3 -X github.com/242617/core/buildinfo.Commit=$(git rev-parse HEAD)"
+//
+// Empty values are taken from module and vcs info embedded by go build.
+var (
+	Version   = ""
+	Commit    = ""
+	BuildTime = ""
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	Modified  bool   `json:"modified"`
+	GoVersion string `json:"go_version"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns build info of running binary
+func Get() Info {
+	once.Do(func() {
+		info = Info{Version: Version, Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			if info.Version == "" && bi.Main.Version != "(devel)" {
+				info.Version = bi.Main.Version
+			}
+			for _, s := range bi.Settings {
+				switch s.Key {
+				case "vcs.revision":
+					if info.Commit == "" {
+						info.Commit = s.Value
+					}
+				case "vcs.time":
+					if info.BuildTime == "" {
+						info.BuildTime = s.Value
+					}
+				case "vcs.modified":
+					info.Modified = s.Value == "true"
+				}
+			}
+		}
+		if info.Version == "" {
+			info.Version = "dev"
+		}
+	})
+	return info
+}
+
+// ShortCommit returns first 12 characters of commit
+func (i Info) ShortCommit() string {
+	if len(i.Commit) > 12 {
+		return i.Commit[:12]
+	}
+	return i.Commit
+}
+
+func (i Info) String() string {
+	parts := []string{i.Version}
+	if i.Commit != "" {
+		commit := i.ShortCommit()
+		if i.Modified {
+			commit += "-dirty"
+		}
+		parts = append(parts, commit)
+	}
+	if i.BuildTime != "" {
+		parts = append(parts, i.BuildTime)
+	}
+	parts = append(parts, i.GoVersion)
+	return parts[0] + " (" + strings.Join(parts[1:], ", ") + ")"
+}
+
+// MarshalZerologObject adds build info fields to log event, use with Dict or EmbedObject
+func (i Info) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("version", i.Version).Str("commit", i.ShortCommit())
+	if i.BuildTime != "" {
+		e.Str("build_time", i.BuildTime)
+	}
+}
+
+var publish sync.Once
+
+// Publish exposes build info as "buildinfo" expvar
+func Publish() {
+	publish.Do(func() {
+		expvar.Publish("buildinfo", expvar.Func(func() any { return Get() }))
+	})
+}
+
+// Record sets constant build_info gauge labeled with build info
+func Record(recorder protocol.MetricsRecorder) {
+	i := Get()
+	recorder.Set(MetricBuildInfo, 1, "version", i.Version, "commit", i.ShortCommit(), "go_version", i.GoVersion)
+}
+
+// VersionFlag prints build info to w if args contain -version or --version
+func VersionFlag(args []string, w io.Writer) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "-version" || arg == "--version" {
+			fmt.Fprintln(w, Get())
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package buildinfo_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/buildinfo"
+)
+
+type recorder struct {
+	name   string
+	value  float64
+	labels []string
+}
+
+func (r *recorder) Add(string, float64, ...string)     {}
+func (r *recorder) Observe(string, float64, ...string) {}
+func (r *recorder) Set(name string, value float64, labels ...string) {
+	r.name, r.value, r.labels = name, value, labels
+}
+
+func TestBuildInfo(t *testing.T) {
+	buildinfo.Version, buildinfo.Commit = "v1.2.3", "0123456789abcdef"
+	info := buildinfo.Get()
+	assert.Equal(t, "v1.2.3", info.Version, "version from ldflags")
+	assert.Equal(t, "0123456789ab", info.ShortCommit(), "short commit")
+	assert.True(t, strings.HasPrefix(info.String(), "v1.2.3 (0123456789ab"), "string %q", info)
+
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	log.Info().EmbedObject(info).Msg("")
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &fields), "decode log")
+	assert.Equal(t, "v1.2.3", fields["version"], "version field")
+	assert.Equal(t, "0123456789ab", fields["commit"], "commit field")
+
+	var r recorder
+	buildinfo.Record(&r)
+	assert.Equal(t, buildinfo.MetricBuildInfo, r.name, "metric")
+	assert.Equal(t, 1.0, r.value, "value")
+	assert.Contains(t, r.labels, "v1.2.3", "labels")
+
+	buildinfo.Publish()
+	buildinfo.Publish()
+	assert.Contains(t, expvar.Get("buildinfo").String(), `"version":"v1.2.3"`, "expvar")
+
+	buf.Reset()
+	assert.True(t, buildinfo.VersionFlag([]string{"-config", "x", "--version"}, &buf), "flag")
+	assert.Equal(t, info.String()+"\n", buf.String(), "printed")
+	assert.False(t, buildinfo.VersionFlag([]string{"--", "--version"}, &buf), "after terminator")
+}
@@ -0,0 +1,296 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/242617/core/protocol"
+)
+
+const (
+	MetricHitsTotal      = "cache_hits_total"
+	MetricMissesTotal    = "cache_misses_total"
+	MetricEvictionsTotal = "cache_evictions_total"
+)
+
+type Reason string
+
+const (
+	ReasonExpired  Reason = "expired"
+	ReasonCapacity Reason = "capacity"
+	ReasonDeleted  Reason = "deleted"
+)
+
+type Option[K comparable, V any] func(c *Cache[K, V]) error
+
+func withDefaults[K comparable, V any]() Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		c.name = "cache"
+		c.loadTimeout = 30 * time.Second
+		return nil
+	}
+}
+
+func WithName[K comparable, V any](name string) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		c.name = name
+		return nil
+	}
+}
+
+// WithTTL sets entries lifetime, zero means entries never expire
+func WithTTL[K comparable, V any](ttl time.Duration) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		if ttl < 0 {
+			return errors.New("ttl must not be negative")
+		}
+		c.ttl = ttl
+		return nil
+	}
+}
+
+// WithMaxEntries limits number of entries evicting least recently used ones, zero means no limit
+func WithMaxEntries[K comparable, V any](n int) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		if n < 0 {
+			return errors.New("max entries must not be negative")
+		}
+		c.maxEntries = n
+		return nil
+	}
+}
+
+// WithLoadTimeout limits duration of load of GetOrLoad, zero means no limit
+func WithLoadTimeout[K comparable, V any](timeout time.Duration) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		if timeout < 0 {
+			return errors.New("load timeout must not be negative")
+		}
+		c.loadTimeout = timeout
+		return nil
+	}
+}
+
+// WithOnEvict sets callback called outside of lock for every removed entry
+func WithOnEvict[K comparable, V any](f func(key K, value V, reason Reason)) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		c.onEvict = f
+		return nil
+	}
+}
+
+func WithMetrics[K comparable, V any](recorder protocol.MetricsRecorder) Option[K, V] {
+	return func(c *Cache[K, V]) error {
+		c.metrics = recorder
+		return nil
+	}
+}
+
+func New[K comparable, V any](options ...Option[K, V]) (*Cache[K, V], error) {
+	c := Cache[K, V]{
+		items: map[K]*list.Element{},
+		lru:   list.New(),
+		calls: map[K]*call[V]{},
+	}
+	options = append([]Option[K, V]{withDefaults[K, V]()}, options...)
+	for _, option := range options {
+		if err := option(&c); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	return &c, nil
+}
+
+type Cache[K comparable, V any] struct {
+	name        string
+	ttl         time.Duration
+	maxEntries  int
+	loadTimeout time.Duration
+	onEvict     func(K, V, Reason)
+	metrics     protocol.MetricsRecorder
+
+	mu    sync.Mutex
+	items map[K]*list.Element
+	lru   *list.List
+	calls map[K]*call[V]
+}
+
+type item[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+type eviction[K comparable, V any] struct {
+	item   *item[K, V]
+	reason Reason
+}
+
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	value, ok, evicted := c.get(key)
+	c.mu.Unlock()
+
+	c.evicted(evicted)
+	c.record(ok)
+	return value, ok
+}
+
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	evicted := c.set(key, value)
+	c.mu.Unlock()
+
+	c.evicted(evicted)
+}
+
+func (c *Cache[K, V]) Delete(key K) {
+	var evicted []eviction[K, V]
+	c.mu.Lock()
+	if e, ok := c.items[key]; ok {
+		evicted = append(evicted, c.remove(e, ReasonDeleted))
+	}
+	c.mu.Unlock()
+
+	c.evicted(evicted)
+}
+
+// Len returns number of entries including expired but not yet removed ones
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// GetOrLoad returns cached value or stores one returned by load.
+// Concurrent calls for the same key share single load, it is not canceled with ctx of the caller
+// which started it, so other callers still get its result, and is limited by WithLoadTimeout instead.
+// Every caller stops waiting once its ctx is done.
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, load func(context.Context) (V, error)) (V, error) {
+	c.mu.Lock()
+	value, ok, evicted := c.get(key)
+	if ok {
+		c.mu.Unlock()
+		c.evicted(evicted)
+		c.record(true)
+		return value, nil
+	}
+	cl, loading := c.calls[key]
+	if !loading {
+		cl = &call[V]{done: make(chan struct{})}
+		c.calls[key] = cl
+	}
+	c.mu.Unlock()
+	c.evicted(evicted)
+	c.record(false)
+
+	if !loading {
+		go c.load(ctx, key, cl, load)
+	}
+	select {
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	case <-cl.done:
+		return cl.value, cl.err
+	}
+}
+
+func (c *Cache[K, V]) load(ctx context.Context, key K, cl *call[V], load func(context.Context) (V, error)) {
+	var evicted []eviction[K, V]
+	defer func() {
+		c.evicted(evicted)
+	}()
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		if cl.err == nil {
+			evicted = c.set(key, cl.value)
+		}
+		c.mu.Unlock()
+		close(cl.done)
+	}()
+
+	defer func() {
+		if v := recover(); v != nil {
+			cl.err = errors.Errorf("load panicked: %v", v)
+		}
+	}()
+
+	ctx = context.WithoutCancel(ctx)
+	if c.loadTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.loadTimeout)
+		defer cancel()
+	}
+	cl.value, cl.err = load(ctx)
+}
+
+func (c *Cache[K, V]) get(key K) (value V, ok bool, evicted []eviction[K, V]) {
+	e, ok := c.items[key]
+	if !ok {
+		return value, false, nil
+	}
+	it := e.Value.(*item[K, V])
+	if !it.expires.IsZero() && time.Now().After(it.expires) {
+		return value, false, []eviction[K, V]{c.remove(e, ReasonExpired)}
+	}
+	c.lru.MoveToFront(e)
+	return it.value, true, nil
+}
+
+func (c *Cache[K, V]) set(key K, value V) (evicted []eviction[K, V]) {
+	var expires time.Time
+	if c.ttl > 0 {
+		expires = time.Now().Add(c.ttl)
+	}
+	if e, ok := c.items[key]; ok {
+		it := e.Value.(*item[K, V])
+		it.value, it.expires = value, expires
+		c.lru.MoveToFront(e)
+		return nil
+	}
+	c.items[key] = c.lru.PushFront(&item[K, V]{key: key, value: value, expires: expires})
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		evicted = append(evicted, c.remove(c.lru.Back(), ReasonCapacity))
+	}
+	return evicted
+}
+
+func (c *Cache[K, V]) remove(e *list.Element, reason Reason) eviction[K, V] {
+	it := c.lru.Remove(e).(*item[K, V])
+	delete(c.items, it.key)
+	return eviction[K, V]{item: it, reason: reason}
+}
+
+func (c *Cache[K, V]) evicted(evicted []eviction[K, V]) {
+	for _, e := range evicted {
+		if c.metrics != nil {
+			c.metrics.Add(MetricEvictionsTotal, 1, "cache", c.name, "reason", string(e.reason))
+		}
+		if c.onEvict != nil {
+			c.onEvict(e.item.key, e.item.value, e.reason)
+		}
+	}
+}
+
+func (c *Cache[K, V]) record(hit bool) {
+	if c.metrics == nil {
+		return
+	}
+	if hit {
+		c.metrics.Add(MetricHitsTotal, 1, "cache", c.name)
+	} else {
+		c.metrics.Add(MetricMissesTotal, 1, "cache", c.name)
+	}
+}
@@ -0,0 +1,156 @@
+package cache_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/cache"
+)
+
+var period = 10 * time.Millisecond
+
+func TestCache(t *testing.T) {
+	var evicted []string
+	c, err := cache.New(
+		cache.WithMaxEntries[string, int](2),
+		cache.WithOnEvict(func(key string, _ int, reason cache.Reason) {
+			evicted = append(evicted, key+" "+string(reason))
+		}),
+	)
+	require.NoError(t, err, "new")
+
+	c.Set("one", 1)
+	c.Set("two", 2)
+	_, ok := c.Get("one")
+	assert.True(t, ok, "one cached")
+	c.Set("three", 3)
+
+	_, ok = c.Get("two")
+	assert.False(t, ok, "least recently used evicted")
+	value, ok := c.Get("one")
+	assert.True(t, ok, "recently used kept")
+	assert.Equal(t, 1, value, "value")
+
+	c.Delete("one")
+	assert.Equal(t, 1, c.Len(), "one entry left")
+	assert.Equal(t, []string{"two capacity", "one deleted"}, evicted, "evictions")
+}
+
+func TestTTL(t *testing.T) {
+	var recorder withRecorder
+	c, err := cache.New(
+		cache.WithName[string, int]("sample"),
+		cache.WithTTL[string, int](2*period),
+		cache.WithMetrics[string, int](&recorder),
+	)
+	require.NoError(t, err, "new")
+
+	c.Set("key", 1)
+	_, ok := c.Get("key")
+	assert.True(t, ok, "not expired")
+
+	time.Sleep(3 * period)
+	_, ok = c.Get("key")
+	assert.False(t, ok, "expired")
+	assert.Zero(t, c.Len(), "expired removed")
+	assert.Equal(t, []string{
+		"cache_hits_total cache=sample",
+		"cache_evictions_total cache=sample reason=expired",
+		"cache_misses_total cache=sample",
+	}, recorder.calls, "metrics")
+}
+
+func TestGetOrLoad(t *testing.T) {
+	c, err := cache.New[string, int]()
+	require.NoError(t, err, "new")
+
+	var loads atomic.Int32
+	load := func(context.Context) (int, error) {
+		loads.Add(1)
+		time.Sleep(2 * period)
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := c.GetOrLoad(context.Background(), "key", load)
+			assert.NoError(t, err, "load")
+			assert.Equal(t, 42, value, "loaded value")
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 1, loads.Load(), "single load")
+
+	errSample := errors.New("sample")
+	_, err = c.GetOrLoad(context.Background(), "failed", func(context.Context) (int, error) { return 0, errSample })
+	assert.ErrorIs(t, err, errSample, "load error")
+	_, ok := c.Get("failed")
+	assert.False(t, ok, "error not cached")
+}
+
+func TestGetOrLoadCanceled(t *testing.T) {
+	c, err := cache.New(cache.WithLoadTimeout[string, int](5 * period))
+	require.NoError(t, err, "new")
+
+	started := make(chan struct{})
+	load := func(ctx context.Context) (int, error) {
+		close(started)
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(2 * period):
+			return 42, nil
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(ctx, "key", load)
+		errCh <- err
+	}()
+	<-started
+	cancel()
+	assert.ErrorIs(t, <-errCh, context.Canceled, "first caller canceled")
+
+	value, err := c.GetOrLoad(context.Background(), "key", load)
+	assert.NoError(t, err, "shared load not canceled")
+	assert.Equal(t, 42, value, "loaded value")
+
+	_, err = c.GetOrLoad(context.Background(), "slow", func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "load timeout")
+
+	_, err = c.GetOrLoad(context.Background(), "panic", func(context.Context) (int, error) { panic("sample") })
+	assert.Error(t, err, "load panicked")
+}
+
+type withRecorder struct {
+	sync.Mutex
+	calls []string
+}
+
+func (r *withRecorder) Add(name string, _ float64, labels ...string)     { r.record(name, labels) }
+func (r *withRecorder) Set(name string, _ float64, labels ...string)     { r.record(name, labels) }
+func (r *withRecorder) Observe(name string, _ float64, labels ...string) { r.record(name, labels) }
+
+func (r *withRecorder) record(name string, labels []string) {
+	r.Lock()
+	defer r.Unlock()
+	for i := 0; i+1 < len(labels); i += 2 {
+		name += " " + labels[i] + "=" + labels[i+1]
+	}
+	r.calls = append(r.calls, name)
+}
@@ -0,0 +1,177 @@
+package cdc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/242617/core/streams"
+)
+
+// Op is a kind of change
+type Op string
+
+const (
+	OpCreate   Op = "c"
+	OpUpdate   Op = "u"
+	OpDelete   Op = "d"
+	OpRead     Op = "r"
+	OpTruncate Op = "t"
+	OpMessage  Op = "m"
+)
+
+func (o Op) String() string {
+	switch o {
+	case OpCreate:
+		return "create"
+	case OpUpdate:
+		return "update"
+	case OpDelete:
+		return "delete"
+	case OpRead:
+		return "read"
+	case OpTruncate:
+		return "truncate"
+	case OpMessage:
+		return "message"
+	default:
+		return string(o)
+	}
+}
+
+// Source is a metadata of change origin, fields not applicable to connector are empty
+type Source struct {
+	Version   string `json:"version"`
+	Connector string `json:"connector"`
+	Name      string `json:"name"`
+	TsMs      int64  `json:"ts_ms"`
+	// Snapshot is true, last or incremental for snapshot events
+	Snapshot   Snapshot `json:"snapshot"`
+	DB         string   `json:"db"`
+	Schema     string   `json:"schema"`
+	Table      string   `json:"table"`
+	Collection string   `json:"collection"`
+	TxID       int64    `json:"txId"`
+	LSN        int64    `json:"lsn"`
+	File       string   `json:"file"`
+	Pos        int64    `json:"pos"`
+}
+
+// Time returns time change was made in database
+func (s Source) Time() time.Time { return time.UnixMilli(s.TsMs) }
+
+// Snapshot is a snapshot marker being either string or bool depending on connector version
+type Snapshot string
+
+func (s *Snapshot) UnmarshalJSON(b []byte) error {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case bool:
+		*s = "false"
+		if v {
+			*s = "true"
+		}
+	case string:
+		*s = Snapshot(v)
+	case nil:
+		*s = ""
+	default:
+		return errors.Errorf("unexpected snapshot %s", b)
+	}
+	return nil
+}
+
+// Event is a decoded change event. Tombstones following deletes carry key only.
+type Event[T any] struct {
+	Op        Op
+	Before    *T
+	After     *T
+	Source    Source
+	Time      time.Time
+	Key       []byte
+	Tombstone bool
+	// Schema is a raw schema of envelope, set only if converter embeds schemas
+	Schema json.RawMessage
+}
+
+// Value returns state after change or state before deletion
+func (e Event[T]) Value() *T {
+	if e.Op == OpDelete {
+		return e.Before
+	}
+	return e.After
+}
+
+// Snapshot reports whether event is read during snapshot rather than captured from log
+func (e Event[T]) Snapshot() bool {
+	return e.Op == OpRead || (e.Source.Snapshot != "" && e.Source.Snapshot != "false")
+}
+
+type payload[T any] struct {
+	Op     Op     `json:"op"`
+	Before *T     `json:"before"`
+	After  *T     `json:"after"`
+	Source Source `json:"source"`
+	TsMs   int64  `json:"ts_ms"`
+}
+
+// Decode decodes change event in Debezium envelope produced by json converter
+// with or without embedded schema, empty or null value is decoded as tombstone
+func Decode[T any](value []byte) (Event[T], error) {
+	var event Event[T]
+	value = bytes.TrimSpace(value)
+	if len(value) == 0 || bytes.Equal(value, []byte("null")) {
+		event.Tombstone, event.Op = true, OpDelete
+		return event, nil
+	}
+
+	var envelope struct {
+		Schema  json.RawMessage `json:"schema"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(value, &envelope); err != nil {
+		return event, errors.Wrap(err, "decode envelope")
+	}
+	if len(envelope.Payload) > 0 && !bytes.Equal(envelope.Payload, []byte("null")) {
+		value, event.Schema = envelope.Payload, envelope.Schema
+	} else if envelope.Schema != nil {
+		// envelope with schema and null payload is a tombstone as well
+		event.Tombstone, event.Op = true, OpDelete
+		return event, nil
+	}
+
+	var p payload[T]
+	if err := json.Unmarshal(value, &p); err != nil {
+		return event, errors.Wrap(err, "decode payload")
+	}
+	if p.Op == "" {
+		return event, errors.New("missing op")
+	}
+	event.Op, event.Before, event.After, event.Source = p.Op, p.Before, p.After, p.Source
+	if p.TsMs > 0 {
+		event.Time = time.UnixMilli(p.TsMs)
+	}
+	return event, nil
+}
+
+// Decoder decodes stream messages as change events keeping message key
+func Decoder[T any]() streams.DecodeFunc[Event[T]] {
+	return func(msg streams.Message) (Event[T], error) {
+		event, err := Decode[T](msg.Value)
+		event.Key = []byte(msg.Key)
+		return event, err
+	}
+}
+
+// SkipTombstones is a stream stage dropping tombstones
+func SkipTombstones[T any]() streams.Option[Event[T]] {
+	return streams.Filter("skip_tombstones", func(_ context.Context, event Event[T]) (bool, error) {
+		return !event.Tombstone, nil
+	})
+}
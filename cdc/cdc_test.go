@@ -0,0 +1,75 @@
+package cdc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/cdc"
+	"github.com/242617/core/streams"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+const update = `{"before":{"id":1,"name":"ivan"},"after":{"id":1,"name":"vasily"},
+"source":{"version":"2.5.0.Final","connector":"postgresql","name":"app","ts_ms":1700000000000,"snapshot":"false","db":"app","schema":"public","table":"users","txId":42,"lsn":100},
+"op":"u","ts_ms":1700000000100}`
+
+func TestDecode(t *testing.T) {
+	event, err := cdc.Decode[user]([]byte(update))
+	require.NoError(t, err, "decode")
+	assert.Equal(t, cdc.OpUpdate, event.Op, "op")
+	assert.Equal(t, &user{1, "ivan"}, event.Before, "before")
+	assert.Equal(t, &user{1, "vasily"}, event.After, "after")
+	assert.Equal(t, event.After, event.Value(), "value")
+	assert.Equal(t, "users", event.Source.Table, "table")
+	assert.Equal(t, int64(42), event.Source.TxID, "tx")
+	assert.Equal(t, time.UnixMilli(1700000000100), event.Time, "time")
+	assert.False(t, event.Snapshot(), "not snapshot")
+	assert.Nil(t, event.Schema, "no schema")
+}
+
+func TestDecodeSchema(t *testing.T) {
+	value := `{"schema":{"type":"struct","name":"app.public.users.Envelope"},"payload":{"before":{"id":1,"name":"ivan"},"after":null,"source":{"snapshot":true,"table":"users"},"op":"d"}}`
+	event, err := cdc.Decode[user]([]byte(value))
+	require.NoError(t, err, "decode")
+	assert.Equal(t, cdc.OpDelete, event.Op, "op")
+	assert.Equal(t, &user{1, "ivan"}, event.Value(), "deleted value")
+	assert.Nil(t, event.After, "after")
+	assert.True(t, event.Snapshot(), "snapshot as bool")
+	assert.JSONEq(t, `{"type":"struct","name":"app.public.users.Envelope"}`, string(event.Schema), "schema")
+
+	_, err = cdc.Decode[user]([]byte(`{"after":{"id":1}}`))
+	assert.Error(t, err, "missing op")
+}
+
+func TestTombstone(t *testing.T) {
+	for _, value := range []string{"", "null", `{"schema":{"type":"struct"},"payload":null}`} {
+		event, err := cdc.Decode[user]([]byte(value))
+		require.NoError(t, err, "decode %q", value)
+		assert.True(t, event.Tombstone, "tombstone %q", value)
+	}
+}
+
+func TestStream(t *testing.T) {
+	var names []string
+	s, err := streams.New("users", cdc.Decoder[user](),
+		cdc.SkipTombstones[user](),
+		streams.Tap("collect", func(_ context.Context, event cdc.Event[user]) error {
+			assert.Equal(t, `{"id":1}`, string(event.Key), "key")
+			names = append(names, event.Value().Name)
+			return nil
+		}),
+	)
+	require.NoError(t, err, "new stream")
+
+	require.NoError(t, s.Process(context.Background(), streams.Message{Key: `{"id":1}`, Value: []byte(update)}), "update")
+	require.NoError(t, s.Process(context.Background(), streams.Message{Key: `{"id":1}`}), "tombstone")
+	assert.Equal(t, []string{"vasily"}, names, "tombstone skipped")
+}
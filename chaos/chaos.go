@@ -0,0 +1,193 @@
+package chaos
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/protocol"
+)
+
+const MetricFaultsTotal = "chaos_faults_total"
+
+// ErrInjected is returned by injected failures
+var ErrInjected = errors.New("chaos: injected failure")
+
+// Config is a fault injection configuration suitable for config.Scan, injection is disabled by default.
+// It is not excluded from any build, so production configs are expected to keep it disabled.
+type Config struct {
+	Enabled bool          `yaml:"enabled" env:"CHAOS_ENABLED"`
+	Latency time.Duration `yaml:"latency" env:"CHAOS_LATENCY"`
+	// Jitter adds random duration up to jitter to latency
+	Jitter time.Duration `yaml:"jitter"`
+	// ErrorRate is a fraction of calls failed with ErrInjected
+	ErrorRate float64 `yaml:"error_rate" env:"CHAOS_ERROR_RATE"`
+	// TimeoutRate is a fraction of calls blocked until context is done
+	TimeoutRate float64 `yaml:"timeout_rate" env:"CHAOS_TIMEOUT_RATE"`
+}
+
+// Fault describes faults injected into a call
+type Fault struct {
+	Latency     time.Duration
+	Jitter      time.Duration
+	ErrorRate   float64
+	TimeoutRate float64
+	// Err replaces ErrInjected
+	Err error
+}
+
+type option = func(i *Injector) error
+
+func withDefaults() option {
+	return func(i *Injector) error {
+		i.name = "chaos"
+		i.log = l.With().Str("component", "chaos").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(i *Injector) error {
+		i.name = name
+		return nil
+	}
+}
+
+func WithMetrics(recorder protocol.MetricsRecorder) option {
+	return func(i *Injector) error {
+		i.metrics = recorder
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(i *Injector) error {
+		i.log = log
+		return nil
+	}
+}
+
+// New creates injector applying faults of config while enabled.
+// Faults attached to context by WithFault are applied regardless of config.
+func New(cfg Config, options ...option) (*Injector, error) {
+	for _, rate := range []float64{cfg.ErrorRate, cfg.TimeoutRate} {
+		if rate < 0 || rate > 1 {
+			return nil, errors.New("rate must be within [0, 1]")
+		}
+	}
+	i := Injector{
+		fault: Fault{Latency: cfg.Latency, Jitter: cfg.Jitter, ErrorRate: cfg.ErrorRate, TimeoutRate: cfg.TimeoutRate},
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	i.enabled.Store(cfg.Enabled)
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&i); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	if cfg.Enabled {
+		i.log.Warn().Msg("fault injection enabled")
+	}
+	return &i, nil
+}
+
+type Injector struct {
+	name    string
+	enabled atomic.Bool
+	metrics protocol.MetricsRecorder
+	log     zerolog.Logger
+
+	mu    sync.Mutex
+	fault Fault
+	rand  *rand.Rand
+}
+
+// SetEnabled turns configured faults on and off at runtime
+func (i *Injector) SetEnabled(enabled bool) {
+	if i.enabled.Swap(enabled) != enabled {
+		i.log.Warn().Bool("enabled", enabled).Msg("fault injection toggled")
+	}
+}
+
+// Enabled reports if configured faults are applied, nil injector is disabled
+func (i *Injector) Enabled() bool { return i != nil && i.enabled.Load() }
+
+// SetFault replaces configured faults
+func (i *Injector) SetFault(fault Fault) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.fault = fault
+}
+
+func (i *Injector) String() string { return i.name }
+
+type faultKey struct{}
+
+// WithFault attaches fault to context, it is applied by any injector even if one is disabled
+func WithFault(ctx context.Context, fault Fault) context.Context {
+	return context.WithValue(ctx, faultKey{}, fault)
+}
+
+// Inject applies fault of context or configured one if injector is enabled.
+// It returns error to be returned by wrapped call, nil injector never fails.
+func (i *Injector) Inject(ctx context.Context, target string) error {
+	if i == nil {
+		return nil
+	}
+	fault, ok := ctx.Value(faultKey{}).(Fault)
+	if !ok {
+		if !i.enabled.Load() {
+			return nil
+		}
+		i.mu.Lock()
+		fault = i.fault
+		i.mu.Unlock()
+	}
+
+	i.mu.Lock()
+	var jitter time.Duration
+	if fault.Jitter > 0 {
+		jitter = time.Duration(i.rand.Int63n(int64(fault.Jitter)))
+	}
+	timeout := i.rand.Float64() < fault.TimeoutRate
+	fail := i.rand.Float64() < fault.ErrorRate
+	i.mu.Unlock()
+
+	if delay := fault.Latency + jitter; delay > 0 {
+		i.record("latency", target)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if timeout {
+		i.record("timeout", target)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if fail {
+		i.record("error", target)
+		if fault.Err != nil {
+			return fault.Err
+		}
+		return ErrInjected
+	}
+	return nil
+}
+
+func (i *Injector) record(kind, target string) {
+	i.log.Debug().Str("kind", kind).Str("target", target).Msg("fault injected")
+	if i.metrics != nil {
+		i.metrics.Add(MetricFaultsTotal, 1, "kind", kind, "target", target)
+	}
+}
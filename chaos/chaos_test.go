@@ -0,0 +1,156 @@
+package chaos_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/application"
+	"github.com/242617/core/chaos"
+)
+
+var period = 10 * time.Millisecond
+
+func TestInject(t *testing.T) {
+	_, err := chaos.New(chaos.Config{ErrorRate: 2})
+	assert.Error(t, err, "invalid rate")
+
+	i, err := chaos.New(chaos.Config{ErrorRate: 1})
+	require.NoError(t, err, "new")
+	ctx := context.Background()
+	assert.NoError(t, i.Inject(ctx, "sample"), "disabled by default")
+
+	i.SetEnabled(true)
+	assert.ErrorIs(t, i.Inject(ctx, "sample"), chaos.ErrInjected, "enabled")
+
+	sampleErr := errors.New("sample error")
+	i.SetFault(chaos.Fault{ErrorRate: 1, Err: sampleErr})
+	assert.ErrorIs(t, i.Inject(ctx, "sample"), sampleErr, "custom error")
+
+	i.SetFault(chaos.Fault{Latency: 2 * period})
+	start := time.Now()
+	assert.NoError(t, i.Inject(ctx, "sample"), "latency")
+	assert.GreaterOrEqual(t, time.Since(start), 2*period, "delayed")
+
+	i.SetFault(chaos.Fault{TimeoutRate: 1})
+	timeoutCtx, cancel := context.WithTimeout(ctx, period)
+	defer cancel()
+	assert.ErrorIs(t, i.Inject(timeoutCtx, "sample"), context.DeadlineExceeded, "timeout")
+
+	var disabled *chaos.Injector
+	assert.NoError(t, disabled.Inject(ctx, "sample"), "nil injector")
+}
+
+func TestWithFault(t *testing.T) {
+	i, err := chaos.New(chaos.Config{})
+	require.NoError(t, err, "new")
+	ctx := chaos.WithFault(context.Background(), chaos.Fault{ErrorRate: 1})
+	assert.ErrorIs(t, i.Inject(ctx, "sample"), chaos.ErrInjected, "context fault applied while disabled")
+}
+
+func TestWrappers(t *testing.T) {
+	i, err := chaos.New(chaos.Config{Enabled: true, ErrorRate: 1})
+	require.NoError(t, err, "new")
+	ctx := context.Background()
+
+	var started bool
+	c := chaos.Lifecycle(i, application.NewMethodsComponent("sample", func(context.Context) error {
+		started = true
+		return nil
+	}, nil))
+	assert.ErrorIs(t, c.Start(ctx), chaos.ErrInjected, "start")
+	assert.False(t, started, "not started")
+	assert.Equal(t, "sample", c.String(), "name")
+
+	h := chaos.Handler(i, "orders", func(context.Context, string) error { return nil })
+	assert.ErrorIs(t, h(ctx, "message"), chaos.ErrInjected, "handler")
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+	client := http.Client{Transport: chaos.Transport(i, nil)}
+	_, err = client.Get(s.URL)
+	assert.ErrorIs(t, err, chaos.ErrInjected, "transport")
+}
+
+func TestMiddleware(t *testing.T) {
+	i, err := chaos.New(chaos.Config{Enabled: true})
+	require.NoError(t, err, "new")
+
+	var injected error
+	h := chaos.Middleware(i)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		injected = i.Inject(r.Context(), "dependency")
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(chaos.HeaderErrorRate, "1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.ErrorIs(t, injected, chaos.ErrInjected, "fault from header")
+
+	i.SetEnabled(false)
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.NoError(t, injected, "headers ignored while disabled")
+
+	var disabled *chaos.Injector
+	h = chaos.Middleware(disabled)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		injected = disabled.Inject(r.Context(), "dependency")
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.NoError(t, injected, "nil injector")
+}
+
+func TestConnector(t *testing.T) {
+	mockDB, mock, err := sqlmock.NewWithDSN("chaos")
+	require.NoError(t, err, "new mock")
+	defer mockDB.Close()
+
+	i, err := chaos.New(chaos.Config{ErrorRate: 1})
+	require.NoError(t, err, "new")
+	db := sql.OpenDB(chaos.Connector(i, mockDB.Driver(), "chaos"))
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+	_, err = db.Exec("UPDATE users SET name = $1", "ivan")
+	require.NoError(t, err, "disabled")
+
+	i.SetEnabled(true)
+	_, err = db.Exec("UPDATE users SET name = $1", "ivan")
+	assert.ErrorIs(t, err, chaos.ErrInjected, "exec")
+	_, err = db.Query("SELECT 1")
+	assert.ErrorIs(t, err, chaos.ErrInjected, "query")
+	assert.NoError(t, mock.ExpectationsWereMet(), "expectations")
+}
+
+type point struct{ x, y int }
+
+type pointConverter struct{}
+
+func (pointConverter) ConvertValue(v interface{}) (driver.Value, error) {
+	if p, ok := v.(point); ok {
+		return fmt.Sprintf("(%d,%d)", p.x, p.y), nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
+
+func TestConnectorNamedValueChecker(t *testing.T) {
+	mockDB, mock, err := sqlmock.NewWithDSN("chaos-checker", sqlmock.ValueConverterOption(pointConverter{}))
+	require.NoError(t, err, "new mock")
+	defer mockDB.Close()
+
+	db := sql.OpenDB(chaos.Connector(nil, mockDB.Driver(), "chaos-checker"))
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE users").WithArgs("(1,2)").WillReturnResult(sqlmock.NewResult(0, 1))
+	_, err = db.Exec("UPDATE users SET location = $1", point{1, 2})
+	require.NoError(t, err, "converted by wrapped driver")
+	assert.NoError(t, mock.ExpectationsWereMet(), "expectations")
+}
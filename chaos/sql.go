@@ -0,0 +1,110 @@
+package chaos
+
+import (
+	"context"
+	"database/sql/driver"
+)
+
+// Connector wraps database driver injecting faults into connecting, queries, execs and transactions:
+//
+//	db := sql.OpenDB(chaos.Connector(injector, pq.Driver{}, dsn))
+func Connector(injector *Injector, d driver.Driver, dsn string) driver.Connector {
+	return &connector{injector, d, dsn}
+}
+
+type connector struct {
+	injector *Injector
+	driver   driver.Driver
+	dsn      string
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err := c.injector.Inject(ctx, "sql:connect"); err != nil {
+		return nil, err
+	}
+	var (
+		conn driver.Conn
+		err  error
+	)
+	if dc, ok := c.driver.(driver.DriverContext); ok {
+		var base driver.Connector
+		if base, err = dc.OpenConnector(c.dsn); err != nil {
+			return nil, err
+		}
+		conn, err = base.Connect(ctx)
+	} else {
+		conn, err = c.driver.Open(c.dsn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedConn{conn, c.injector}, nil
+}
+
+func (c *connector) Driver() driver.Driver { return c.driver }
+
+type wrappedConn struct {
+	driver.Conn
+	injector *Injector
+}
+
+func (c *wrappedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.injector.Inject(ctx, "sql:query"); err != nil {
+		return nil, err
+	}
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return queryer.QueryContext(ctx, query, args)
+}
+
+func (c *wrappedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.injector.Inject(ctx, "sql:exec"); err != nil {
+		return nil, err
+	}
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (c *wrappedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *wrappedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.injector.Inject(ctx, "sql:begin"); err != nil {
+		return nil, err
+	}
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+// CheckNamedValue keeps argument conversions of wrapped driver
+func (c *wrappedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c *wrappedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *wrappedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
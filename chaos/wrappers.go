@@ -0,0 +1,95 @@
+package chaos
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/242617/core/application"
+	"github.com/242617/core/httpserver/middleware"
+)
+
+// Lifecycle wraps component injecting faults into Start and Stop
+func Lifecycle(injector *Injector, component application.Component) application.Component {
+	return &lifecycle{component, injector}
+}
+
+type lifecycle struct {
+	application.Component
+	injector *Injector
+}
+
+func (c *lifecycle) Start(ctx context.Context) error {
+	if err := c.injector.Inject(ctx, c.String()+":start"); err != nil {
+		return err
+	}
+	return c.Component.Start(ctx)
+}
+
+func (c *lifecycle) Stop(ctx context.Context) error {
+	if err := c.injector.Inject(ctx, c.String()+":stop"); err != nil {
+		return err
+	}
+	return c.Component.Stop(ctx)
+}
+
+// Handler wraps message handler, e.g. amqp.Handler or nats.Handler, injecting faults before handling
+func Handler[T any](injector *Injector, target string, handler func(ctx context.Context, msg T) error) func(ctx context.Context, msg T) error {
+	return func(ctx context.Context, msg T) error {
+		if err := injector.Inject(ctx, target); err != nil {
+			return err
+		}
+		return handler(ctx, msg)
+	}
+}
+
+// Transport wraps http client transport injecting faults before requests, nil base means default transport
+func Transport(injector *Injector, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripper(func(r *http.Request) (*http.Response, error) {
+		if err := injector.Inject(r.Context(), r.URL.Host); err != nil {
+			return nil, err
+		}
+		return base.RoundTrip(r)
+	})
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// Headers set faults of single request handled by Middleware
+const (
+	HeaderLatency   = "X-Chaos-Latency"
+	HeaderErrorRate = "X-Chaos-Error-Rate"
+)
+
+// Middleware attaches fault described by request headers to its context, so faults propagate
+// to wrapped dependencies of handler. Headers are honored only while injector is enabled, nil injector is disabled.
+func Middleware(injector *Injector) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !injector.Enabled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+			var (
+				fault Fault
+				set   bool
+			)
+			if d, err := time.ParseDuration(r.Header.Get(HeaderLatency)); err == nil && d > 0 {
+				fault.Latency, set = d, true
+			}
+			if rate, err := strconv.ParseFloat(r.Header.Get(HeaderErrorRate), 64); err == nil && rate > 0 && rate <= 1 {
+				fault.ErrorRate, set = rate, true
+			}
+			if set {
+				r = r.WithContext(WithFault(r.Context(), fault))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
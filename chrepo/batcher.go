@@ -0,0 +1,84 @@
+package chrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/242617/core/batcher"
+)
+
+type batcherOption = func(b *Batcher) error
+
+// WithBatchSize sets number of rows flushed at once
+func WithBatchSize(size int) batcherOption {
+	return func(b *Batcher) error {
+		if size < 1 {
+			return errors.New("batch size must be positive")
+		}
+		b.size = size
+		return nil
+	}
+}
+
+// WithFlushInterval sets max time rows are buffered
+func WithFlushInterval(interval time.Duration) batcherOption {
+	return func(b *Batcher) error {
+		if interval <= 0 {
+			return errors.New("interval must be positive")
+		}
+		b.interval = interval
+		return nil
+	}
+}
+
+// NewBatcher creates helper buffering rows and inserting them into table in batches,
+// buffered rows are flushed on Stop
+func NewBatcher(repo *Repo, table string, options ...batcherOption) (*Batcher, error) {
+	b := Batcher{repo: repo, table: table, size: 1000, interval: time.Second}
+	for _, option := range options {
+		if err := option(&b); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+
+	var err error
+	b.batcher, err = batcher.New(b.insert,
+		batcher.WithName[any](b.String()),
+		batcher.WithSize[any](b.size),
+		batcher.WithInterval[any](b.interval),
+		batcher.WithLogger[any](repo.log.With().Str("table", table).Logger()),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "new batcher")
+	}
+	return &b, nil
+}
+
+type Batcher struct {
+	repo     *Repo
+	table    string
+	size     int
+	interval time.Duration
+	batcher  *batcher.Batcher[any]
+}
+
+// Add buffers row triggering flush once batch is full
+func (b *Batcher) Add(row any) { _ = b.batcher.Add(row) }
+
+// Flush inserts buffered rows, they are dropped on error
+func (b *Batcher) Flush(ctx context.Context) error { return b.batcher.Flush(ctx) }
+
+func (b *Batcher) Start(ctx context.Context) error { return b.batcher.Start(ctx) }
+
+func (b *Batcher) Stop(ctx context.Context) error { return b.batcher.Stop(ctx) }
+
+func (b *Batcher) String() string { return b.repo.name + ":" + b.table }
+
+func (b *Batcher) insert(ctx context.Context, rows []any) error {
+	if err := b.repo.Insert(ctx, b.table, rows...); err != nil {
+		return errors.Wrapf(err, "insert %d rows", len(rows))
+	}
+	return nil
+}
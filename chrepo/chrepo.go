@@ -0,0 +1,223 @@
+package chrepo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+)
+
+// Config is a clickhouse configuration suitable for config.Scan
+type Config struct {
+	// Addr is an address of HTTP interface, e.g. http://localhost:8123
+	Addr     string        `yaml:"addr" env:"CLICKHOUSE_ADDR" default:"http://localhost:8123"`
+	Database string        `yaml:"database" env:"CLICKHOUSE_DATABASE" default:"default"`
+	Username string        `yaml:"username" env:"CLICKHOUSE_USERNAME" default:"default"`
+	Password string        `yaml:"password" env:"CLICKHOUSE_PASSWORD"`
+	Timeout  time.Duration `yaml:"timeout" default:"30s"`
+}
+
+// Params are query parameters referenced in query as {name:Type}
+type Params map[string]any
+
+type option = func(r *Repo) error
+
+func withDefaults() option {
+	return func(r *Repo) error {
+		r.name = "chrepo"
+		r.log = l.With().Str("component", "chrepo").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(r *Repo) error {
+		r.name = name
+		return nil
+	}
+}
+
+func WithClient(client *http.Client) option {
+	return func(r *Repo) error {
+		r.client = client
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(r *Repo) error {
+		r.log = log
+		return nil
+	}
+}
+
+// New creates clickhouse component talking to HTTP interface, connection is checked on Start
+func New(cfg Config, options ...option) (*Repo, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("empty addr")
+	}
+	if _, err := url.Parse(cfg.Addr); err != nil {
+		return nil, errors.Wrap(err, "parse addr")
+	}
+
+	r := Repo{cfg: cfg}
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&r); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	if r.client == nil {
+		r.client = &http.Client{Timeout: cfg.Timeout}
+	}
+	return &r, nil
+}
+
+type Repo struct {
+	name   string
+	cfg    Config
+	client *http.Client
+	log    zerolog.Logger
+}
+
+func (r *Repo) Start(ctx context.Context) error {
+	if err := r.HealthCheck(ctx); err != nil {
+		return err
+	}
+	r.log.Info().Msgf("connected to clickhouse %s", r.cfg.Addr)
+	return nil
+}
+
+func (r *Repo) Stop(context.Context) error {
+	r.client.CloseIdleConnections()
+	return nil
+}
+
+func (r *Repo) HealthCheck(ctx context.Context) error {
+	if err := r.Exec(ctx, "SELECT 1", nil); err != nil {
+		return errors.Wrap(err, "ping")
+	}
+	return nil
+}
+
+func (r *Repo) String() string { return r.name }
+
+// Exec executes query discarding result
+func (r *Repo) Exec(ctx context.Context, query string, params Params) error {
+	body, err := r.do(ctx, query, params, nil)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, body)
+	return body.Close()
+}
+
+// Insert inserts rows marshalled to JSON into table
+func (r *Repo) Insert(ctx context.Context, table string, rows ...any) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			return errors.Wrap(err, "encode row")
+		}
+	}
+
+	body, err := r.do(ctx, fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", table), nil, &buf)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, body)
+	return body.Close()
+}
+
+// Select executes query and unmarshals every returned row into T
+func Select[T any](ctx context.Context, r *Repo, query string, params Params) ([]T, error) {
+	body, err := r.do(ctx, query+" FORMAT JSONEachRow", params, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var rows []T
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		var row T
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			return nil, errors.Wrap(err, "decode row")
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read rows")
+	}
+	return rows, nil
+}
+
+func (r *Repo) do(ctx context.Context, query string, params Params, data io.Reader) (io.ReadCloser, error) {
+	start := time.Now()
+	body, err := r.request(ctx, query, params, data)
+
+	event := r.log.Debug()
+	if err != nil {
+		event = r.log.Error().Err(err)
+	}
+	event.Str("query", query).Dur("duration", time.Since(start)).Msg("query")
+	return body, err
+}
+
+func (r *Repo) request(ctx context.Context, query string, params Params, data io.Reader) (io.ReadCloser, error) {
+	values := url.Values{"database": {r.cfg.Database}}
+	for name, value := range params {
+		values.Set("param_"+name, formatParam(value))
+	}
+
+	// query goes to url when body carries inserted data
+	body := data
+	if data == nil {
+		body = strings.NewReader(query)
+	} else {
+		values.Set("query", query)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(r.cfg.Addr, "/")+"/?"+values.Encode(), body)
+	if err != nil {
+		return nil, errors.Wrap(err, "new request")
+	}
+	req.Header.Set("X-ClickHouse-User", r.cfg.Username)
+	if r.cfg.Password != "" {
+		req.Header.Set("X-ClickHouse-Key", r.cfg.Password)
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "do request")
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 4<<10))
+		return nil, errors.Errorf("clickhouse: %s", strings.TrimSpace(string(msg)))
+	}
+	return res.Body, nil
+}
+
+func formatParam(value any) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format("2006-01-02 15:04:05")
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
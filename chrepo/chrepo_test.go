@@ -0,0 +1,105 @@
+package chrepo_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/chrepo"
+)
+
+var period = 10 * time.Millisecond
+
+type server struct {
+	*httptest.Server
+	mu      sync.Mutex
+	queries []string
+	inserts []string
+}
+
+func newServer(t *testing.T) *server {
+	s := server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "analytics", r.URL.Query().Get("database"), "database")
+		body, _ := io.ReadAll(r.Body)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if query := r.URL.Query().Get("query"); query != "" {
+			s.queries = append(s.queries, query)
+			s.inserts = append(s.inserts, string(body))
+			return
+		}
+		query := string(body)
+		s.queries = append(s.queries, query)
+		switch {
+		case strings.HasPrefix(query, "SELECT 1"):
+			w.Write([]byte("1\n"))
+		case strings.HasPrefix(query, "SELECT id, name"):
+			assert.Equal(t, "7", r.URL.Query().Get("param_id"), "param")
+			w.Write([]byte(`{"id":7,"name":"sample"}` + "\n"))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Code: 62. DB::Exception: Syntax error"))
+		}
+	}))
+	t.Cleanup(s.Close)
+	return &s
+}
+
+type row struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestRepo(t *testing.T) {
+	s := newServer(t)
+	r, err := chrepo.New(chrepo.Config{Addr: s.URL, Database: "analytics"})
+	require.NoError(t, err, "new")
+	require.NoError(t, r.Start(context.Background()), "start")
+	defer r.Stop(context.Background())
+
+	rows, err := chrepo.Select[row](context.Background(), r, "SELECT id, name FROM events WHERE id = {id:UInt64}", chrepo.Params{"id": 7})
+	require.NoError(t, err, "select")
+	assert.Equal(t, []row{{7, "sample"}}, rows, "rows")
+
+	require.NoError(t, r.Insert(context.Background(), "events", row{1, "one"}, row{2, "two"}), "insert")
+	assert.Equal(t, []string{`{"id":1,"name":"one"}` + "\n" + `{"id":2,"name":"two"}` + "\n"}, s.inserts, "inserted rows")
+
+	err = r.Exec(context.Background(), "INVALID", nil)
+	assert.ErrorContains(t, err, "Syntax error", "server error")
+}
+
+func TestBatcher(t *testing.T) {
+	s := newServer(t)
+	r, err := chrepo.New(chrepo.Config{Addr: s.URL, Database: "analytics"})
+	require.NoError(t, err, "new")
+
+	b, err := chrepo.NewBatcher(r, "events", chrepo.WithBatchSize(2), chrepo.WithFlushInterval(time.Minute))
+	require.NoError(t, err, "new batcher")
+	require.NoError(t, b.Start(context.Background()), "start")
+
+	b.Add(row{1, "one"})
+	b.Add(row{2, "two"})
+	assert.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.inserts) == 1
+	}, 10*period, period, "full batch flushed")
+
+	b.Add(row{3, "three"})
+	require.NoError(t, b.Stop(context.Background()), "stop")
+	assert.Equal(t, []string{
+		"INSERT INTO events FORMAT JSONEachRow",
+		"INSERT INTO events FORMAT JSONEachRow",
+	}, s.queries, "queries")
+	assert.Equal(t, `{"id":3,"name":"three"}`+"\n", s.inserts[1], "rest flushed on stop")
+}
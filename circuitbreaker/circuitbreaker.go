@@ -0,0 +1,328 @@
+package circuitbreaker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/protocol"
+)
+
+const (
+	MetricState         = "circuitbreaker_state"
+	MetricRejectedTotal = "circuitbreaker_rejected_total"
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// Counts are collected in closed state within window
+type Counts struct {
+	Requests, Successes, Failures, ConsecutiveFailures uint32
+}
+
+// Policy decides whether breaker should open
+type Policy interface {
+	Trip(Counts) bool
+}
+
+type PolicyFunc func(Counts) bool
+
+func (f PolicyFunc) Trip(counts Counts) bool { return f(counts) }
+
+// ConsecutiveFailures opens breaker after n failures in a row
+func ConsecutiveFailures(n uint32) Policy {
+	return PolicyFunc(func(counts Counts) bool { return counts.ConsecutiveFailures >= n })
+}
+
+// FailureRate opens breaker when share of failures reaches rate, once there were at least minRequests
+func FailureRate(rate float64, minRequests uint32) Policy {
+	return PolicyFunc(func(counts Counts) bool {
+		return counts.Requests >= minRequests && float64(counts.Failures)/float64(counts.Requests) >= rate
+	})
+}
+
+type StateChangeFunc = func(name string, from, to State)
+
+type option = func(b *Breaker) error
+
+func withDefaults() option {
+	return func(b *Breaker) error {
+		b.name = "circuitbreaker"
+		b.policy = ConsecutiveFailures(5)
+		b.openTimeout, b.window = 30*time.Second, time.Minute
+		b.halfOpenRequests = 1
+		b.log = l.With().Str("component", "circuitbreaker").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(b *Breaker) error {
+		b.name = name
+		return nil
+	}
+}
+
+func WithPolicy(policy Policy) option {
+	return func(b *Breaker) error {
+		b.policy = policy
+		return nil
+	}
+}
+
+// WithOpenTimeout sets duration of open state before trial requests are allowed
+func WithOpenTimeout(timeout time.Duration) option {
+	return func(b *Breaker) error {
+		b.openTimeout = timeout
+		return nil
+	}
+}
+
+// WithWindow sets period counts are reset with in closed state, zero disables resetting
+func WithWindow(window time.Duration) option {
+	return func(b *Breaker) error {
+		b.window = window
+		return nil
+	}
+}
+
+// WithHalfOpenRequests sets number of successful trial requests needed to close breaker
+func WithHalfOpenRequests(n uint32) option {
+	return func(b *Breaker) error {
+		if n == 0 {
+			return errors.New("half-open requests must be positive")
+		}
+		b.halfOpenRequests = n
+		return nil
+	}
+}
+
+// WithOnStateChange adds callback called on every transition, it's called under breaker lock
+// and must not call breaker methods
+func WithOnStateChange(f StateChangeFunc) option {
+	return func(b *Breaker) error {
+		b.onStateChange = append(b.onStateChange, f)
+		return nil
+	}
+}
+
+func WithMetrics(recorder protocol.MetricsRecorder) option {
+	return func(b *Breaker) error {
+		b.metrics = recorder
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(b *Breaker) error {
+		b.log = log
+		return nil
+	}
+}
+
+func New(options ...option) (*Breaker, error) {
+	var b Breaker
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&b); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	b.expiry = b.nextExpiry(time.Now())
+	return &b, nil
+}
+
+type Breaker struct {
+	name             string
+	policy           Policy
+	openTimeout      time.Duration
+	window           time.Duration
+	halfOpenRequests uint32
+	onStateChange    []StateChangeFunc
+	metrics          protocol.MetricsRecorder
+	log              zerolog.Logger
+
+	mu         sync.Mutex
+	state      State
+	counts     Counts
+	expiry     time.Time
+	inflight   uint32
+	generation uint64
+}
+
+// Allow returns ErrOpen if request should not be made, otherwise generation of breaker.
+// Every allowed request must be followed by either Success or Failure call with that generation,
+// results of requests allowed before breaker changed state or reset counts are ignored.
+func (b *Breaker) Allow() (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.refresh(now)
+
+	switch b.state {
+	case StateOpen:
+		b.reject()
+		return 0, ErrOpen
+	case StateHalfOpen:
+		if b.inflight+b.counts.Successes >= b.halfOpenRequests {
+			b.reject()
+			return 0, ErrOpen
+		}
+	}
+	b.inflight++
+	b.counts.Requests++
+	return b.generation, nil
+}
+
+func (b *Breaker) Success(generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stale(generation) {
+		return
+	}
+	b.release()
+	b.counts.Successes++
+	b.counts.ConsecutiveFailures = 0
+	if b.state == StateHalfOpen && b.counts.Successes >= b.halfOpenRequests {
+		b.setState(StateClosed, time.Now())
+	}
+}
+
+func (b *Breaker) Failure(generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stale(generation) {
+		return
+	}
+	b.release()
+	b.counts.Failures++
+	b.counts.ConsecutiveFailures++
+	switch b.state {
+	case StateClosed:
+		if b.policy.Trip(b.counts) {
+			b.setState(StateOpen, time.Now())
+		}
+	case StateHalfOpen:
+		b.setState(StateOpen, time.Now())
+	}
+}
+
+// Execute calls f if breaker allows, error returned by f is counted as failure
+func (b *Breaker) Execute(f func() error) error {
+	generation, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	if err := f(); err != nil {
+		b.Failure(generation)
+		return err
+	}
+	b.Success(generation)
+	return nil
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh(time.Now())
+	return b.state
+}
+
+func (b *Breaker) Counts() Counts {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh(time.Now())
+	return b.counts
+}
+
+func (b *Breaker) String() string { return b.name }
+
+func (b *Breaker) refresh(now time.Time) {
+	if b.expiry.IsZero() || now.Before(b.expiry) {
+		return
+	}
+	switch b.state {
+	case StateClosed:
+		b.counts, b.inflight, b.expiry = Counts{}, 0, b.nextExpiry(now)
+		b.generation++
+	case StateOpen:
+		b.setState(StateHalfOpen, now)
+	}
+}
+
+func (b *Breaker) setState(state State, now time.Time) {
+	if b.state == state {
+		return
+	}
+	from := b.state
+	b.state, b.counts, b.inflight = state, Counts{}, 0
+	b.expiry = b.nextExpiry(now)
+	b.generation++
+
+	b.log.Warn().Str("name", b.name).Str("from", from.String()).Str("to", state.String()).Msg("state changed")
+	if b.metrics != nil {
+		b.metrics.Set(MetricState, float64(state), "name", b.name)
+	}
+	for _, f := range b.onStateChange {
+		f(b.name, from, state)
+	}
+}
+
+func (b *Breaker) nextExpiry(now time.Time) time.Time {
+	switch b.state {
+	case StateClosed:
+		if b.window > 0 {
+			return now.Add(b.window)
+		}
+	case StateOpen:
+		return now.Add(b.openTimeout)
+	}
+	return time.Time{}
+}
+
+// stale tells result of request allowed in previous generation, state may be refreshed first
+func (b *Breaker) stale(generation uint64) bool {
+	b.refresh(time.Now())
+	return generation != b.generation
+}
+
+func (b *Breaker) release() {
+	if b.inflight > 0 {
+		b.inflight--
+	}
+}
+
+func (b *Breaker) reject() {
+	if b.metrics != nil {
+		b.metrics.Add(MetricRejectedTotal, 1, "name", b.name)
+	}
+}
@@ -0,0 +1,147 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/circuitbreaker"
+	"github.com/242617/core/httpclient"
+)
+
+var (
+	_ httpclient.Breaker = (*circuitbreaker.Breaker)(nil)
+
+	period    = 10 * time.Millisecond
+	sampleErr = errors.New("sample error")
+)
+
+func TestConsecutiveFailures(t *testing.T) {
+	var transitions []string
+	b, err := circuitbreaker.New(
+		circuitbreaker.WithPolicy(circuitbreaker.ConsecutiveFailures(3)),
+		circuitbreaker.WithOpenTimeout(period),
+		circuitbreaker.WithHalfOpenRequests(2),
+		circuitbreaker.WithOnStateChange(func(_ string, from, to circuitbreaker.State) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		}),
+	)
+	require.NoError(t, err, "new breaker")
+
+	fail := func() error { return sampleErr }
+	ok := func() error { return nil }
+
+	for i := 0; i < 2; i++ {
+		assert.ErrorIs(t, b.Execute(fail), sampleErr, "failure")
+	}
+	assert.NoError(t, b.Execute(ok), "success resets consecutive failures")
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(t, b.Execute(fail), sampleErr, "failure")
+	}
+	assert.Equal(t, circuitbreaker.StateOpen, b.State(), "open")
+	assert.ErrorIs(t, b.Execute(ok), circuitbreaker.ErrOpen, "rejected")
+
+	time.Sleep(2 * period)
+	assert.Equal(t, circuitbreaker.StateHalfOpen, b.State(), "half-open after timeout")
+	assert.ErrorIs(t, b.Execute(fail), sampleErr, "trial failure")
+	assert.Equal(t, circuitbreaker.StateOpen, b.State(), "open again")
+
+	time.Sleep(2 * period)
+	first, err := b.Allow()
+	require.NoError(t, err, "first trial")
+	second, err := b.Allow()
+	require.NoError(t, err, "second trial")
+	_, err = b.Allow()
+	assert.ErrorIs(t, err, circuitbreaker.ErrOpen, "trials limited")
+	b.Success(first)
+	b.Success(second)
+	assert.Equal(t, circuitbreaker.StateClosed, b.State(), "closed after trials")
+
+	assert.Equal(t, strings.Join([]string{
+		"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed",
+	}, ","), strings.Join(transitions, ","), "unexpected transitions")
+}
+
+func TestFailureRate(t *testing.T) {
+	b, err := circuitbreaker.New(
+		circuitbreaker.WithPolicy(circuitbreaker.FailureRate(0.5, 4)),
+		circuitbreaker.WithWindow(5*period),
+	)
+	require.NoError(t, err, "new breaker")
+
+	for _, success := range []bool{false, true, false} {
+		generation, err := b.Allow()
+		require.NoError(t, err, "allow")
+		if success {
+			b.Success(generation)
+		} else {
+			b.Failure(generation)
+		}
+	}
+	assert.Equal(t, circuitbreaker.StateClosed, b.State(), "not enough requests")
+
+	time.Sleep(6 * period)
+	assert.Equal(t, circuitbreaker.Counts{}, b.Counts(), "counts reset after window")
+
+	for _, success := range []bool{true, false, true, false} {
+		generation, err := b.Allow()
+		require.NoError(t, err, "allow")
+		if success {
+			b.Success(generation)
+		} else {
+			b.Failure(generation)
+		}
+	}
+	assert.Equal(t, circuitbreaker.StateOpen, b.State(), "failure rate reached")
+}
+
+func TestStaleResults(t *testing.T) {
+	b, err := circuitbreaker.New(
+		circuitbreaker.WithPolicy(circuitbreaker.ConsecutiveFailures(1)),
+		circuitbreaker.WithOpenTimeout(period),
+	)
+	require.NoError(t, err, "new breaker")
+
+	slowSuccess, err := b.Allow()
+	require.NoError(t, err, "allow slow success")
+	slowFailure, err := b.Allow()
+	require.NoError(t, err, "allow slow failure")
+	assert.ErrorIs(t, b.Execute(func() error { return sampleErr }), sampleErr, "failure")
+	assert.Equal(t, circuitbreaker.StateOpen, b.State(), "open")
+
+	time.Sleep(2 * period)
+	assert.Equal(t, circuitbreaker.StateHalfOpen, b.State(), "half-open after timeout")
+	b.Success(slowSuccess)
+	assert.Equal(t, circuitbreaker.StateHalfOpen, b.State(), "stale success ignored")
+	b.Failure(slowFailure)
+	assert.Equal(t, circuitbreaker.StateHalfOpen, b.State(), "stale failure ignored")
+	assert.Equal(t, circuitbreaker.Counts{}, b.Counts(), "stale results not counted")
+
+	assert.NoError(t, b.Execute(func() error { return nil }), "trial")
+	assert.Equal(t, circuitbreaker.StateClosed, b.State(), "closed after trial")
+}
+
+func TestHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	b, err := circuitbreaker.New(circuitbreaker.WithPolicy(circuitbreaker.ConsecutiveFailures(2)))
+	require.NoError(t, err, "new breaker")
+	c, err := httpclient.New(
+		httpclient.WithBreaker(b),
+		httpclient.WithBackoff(time.Millisecond, period),
+	)
+	require.NoError(t, err, "new client")
+
+	_, err = c.Get(context.Background(), srv.URL)
+	assert.ErrorIs(t, err, circuitbreaker.ErrOpen, "breaker opened by client")
+}
@@ -0,0 +1,182 @@
+package conc
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+)
+
+// Semaphore limits total weight of concurrent holders
+type Semaphore = semaphore.Weighted
+
+func NewSemaphore(n int64) *Semaphore { return semaphore.NewWeighted(n) }
+
+// PanicError is returned by Group when function panics
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string { return fmt.Sprintf("panic: %v", e.Value) }
+
+// Group runs functions with limited concurrency, cancels context on first error and converts panics to errors
+type Group struct {
+	group *errgroup.Group
+}
+
+// NewGroup creates group, limit below one means no limit
+func NewGroup(ctx context.Context, limit int) (*Group, context.Context) {
+	group, ctx := errgroup.WithContext(ctx)
+	if limit > 0 {
+		group.SetLimit(limit)
+	}
+	return &Group{group}, ctx
+}
+
+// Go runs f blocking while limit is reached
+func (g *Group) Go(f func() error) { g.group.Go(recovered(f)) }
+
+// TryGo runs f only if limit is not reached
+func (g *Group) TryGo(f func() error) bool { return g.group.TryGo(recovered(f)) }
+
+// Wait returns first error
+func (g *Group) Wait() error { return g.group.Wait() }
+
+func recovered(f func() error) func() error {
+	return func() (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				err = &PanicError{Value: v, Stack: debug.Stack()}
+			}
+		}()
+		return f()
+	}
+}
+
+// ForEach calls fn for every item using at most limit goroutines
+func ForEach[T any](ctx context.Context, items []T, limit int, fn func(context.Context, T) error) error {
+	g, gctx := NewGroup(ctx, limit)
+	for _, item := range items {
+		if gctx.Err() != nil {
+			break
+		}
+		g.Go(func() error { return fn(gctx, item) })
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// Map calls fn for every item using at most limit goroutines keeping order of results
+func Map[T, R any](ctx context.Context, items []T, limit int, fn func(context.Context, T) (R, error)) ([]R, error) {
+	results := make([]R, len(items))
+	g, gctx := NewGroup(ctx, limit)
+	for i, item := range items {
+		if gctx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			res, err := fn(gctx, item)
+			results[i] = res
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Merge forwards values from all channels to returned one, which is closed once all inputs are closed or ctx is done
+func Merge[T any](ctx context.Context, chans ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	for _, ch := range chans {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range ch {
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// FanOut processes values from in by workers goroutines, results are unordered.
+// Returned channel is closed once in is closed and drained or ctx is done.
+func FanOut[T, R any](ctx context.Context, in <-chan T, workers int, fn func(context.Context, T) R) <-chan R {
+	out := make(chan R)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case v, ok := <-in:
+					if !ok {
+						return
+					}
+					select {
+					case out <- fn(ctx, v):
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+var ErrTimeout = errors.New("wait timeout")
+
+// WaitWithTimeout waits for wg returning ErrTimeout if it takes longer than timeout
+func WaitWithTimeout(wg *sync.WaitGroup, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := WaitContext(ctx, wg); err != nil {
+		return ErrTimeout
+	}
+	return nil
+}
+
+// WaitContext waits for wg returning ctx error if it is done first
+func WaitContext(ctx context.Context, wg *sync.WaitGroup) error {
+	doneCh := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(doneCh)
+	}()
+	select {
+	case <-doneCh:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,176 @@
+package conc_test
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/conc"
+)
+
+var period = 10 * time.Millisecond
+
+func TestSemaphore(t *testing.T) {
+	s := conc.NewSemaphore(3)
+	require.NoError(t, s.Acquire(context.Background(), 2), "acquire")
+	assert.False(t, s.TryAcquire(2), "over capacity")
+	assert.True(t, s.TryAcquire(1), "within capacity")
+	s.Release(3)
+}
+
+func TestGroup(t *testing.T) {
+	var running, peak int32
+	items := make([]int, 20)
+	err := conc.ForEach(context.Background(), items, 3, func(context.Context, int) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(period / 5)
+		atomic.AddInt32(&running, -1)
+		return nil
+	})
+	require.NoError(t, err, "for each")
+	assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(3), "limit")
+
+	res, err := conc.Map(context.Background(), []int{1, 2, 3, 4}, 2, func(_ context.Context, v int) (int, error) {
+		return v * v, nil
+	})
+	require.NoError(t, err, "map")
+	assert.Equal(t, []int{1, 4, 9, 16}, res, "ordered results")
+
+	fail := errors.New("fail")
+	_, err = conc.Map(context.Background(), []int{1, 2, 3}, 0, func(_ context.Context, v int) (int, error) {
+		if v == 2 {
+			return 0, fail
+		}
+		return v, nil
+	})
+	assert.ErrorIs(t, err, fail, "first error")
+
+	g, _ := conc.NewGroup(context.Background(), 1)
+	g.Go(func() error { panic("boom") })
+	var perr *conc.PanicError
+	require.ErrorAs(t, g.Wait(), &perr, "panic converted")
+	assert.Equal(t, "boom", perr.Value, "panic value")
+}
+
+func TestChannels(t *testing.T) {
+	ctx := context.Background()
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := 1; i <= 10; i++ {
+			in <- i
+		}
+	}()
+	squares := conc.FanOut(ctx, in, 3, func(_ context.Context, v int) int { return v * v })
+
+	other := make(chan int, 1)
+	other <- 0
+	close(other)
+
+	var got []int
+	for v := range conc.Merge(ctx, squares, other) {
+		got = append(got, v)
+	}
+	sort.Ints(got)
+	assert.Equal(t, []int{0, 1, 4, 9, 16, 25, 36, 49, 64, 81, 100}, got, "all values")
+
+	ctx, cancel := context.WithCancel(ctx)
+	out := conc.Merge(ctx, make(chan int))
+	cancel()
+	select {
+	case _, ok := <-out:
+		assert.True(t, ok, "no values")
+	case <-time.After(period):
+	}
+}
+
+func TestWait(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		time.Sleep(2 * period)
+		wg.Done()
+	}()
+	assert.ErrorIs(t, conc.WaitWithTimeout(&wg, period), conc.ErrTimeout, "timeout")
+	assert.NoError(t, conc.WaitWithTimeout(&wg, 5*period), "done")
+
+	var pending sync.WaitGroup
+	pending.Add(1)
+	defer pending.Done()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, conc.WaitContext(ctx, &pending), context.Canceled, "context done")
+}
+
+func TestDebounce(t *testing.T) {
+	for _, tc := range []struct {
+		mode conc.Mode
+		want int32
+	}{
+		{conc.Trailing, 1},
+		{conc.Leading, 1},
+		{conc.Leading | conc.Trailing, 2},
+	} {
+		var calls int32
+		call := conc.Debounce(context.Background(), 2*period, tc.mode, func() { atomic.AddInt32(&calls, 1) })
+		for i := 0; i < 5; i++ {
+			call()
+			time.Sleep(period / 2)
+		}
+		time.Sleep(4 * period)
+		assert.Equal(t, tc.want, atomic.LoadInt32(&calls), "mode %d", tc.mode)
+	}
+
+	var calls int32
+	ctx, cancel := context.WithCancel(context.Background())
+	call := conc.Debounce(ctx, period, conc.Trailing, func() { atomic.AddInt32(&calls, 1) })
+	call()
+	cancel()
+	call()
+	time.Sleep(3 * period)
+	assert.Zero(t, atomic.LoadInt32(&calls), "cancelled")
+}
+
+func TestThrottle(t *testing.T) {
+	for _, tc := range []struct {
+		mode     conc.Mode
+		min, max int32
+	}{
+		{conc.Leading, 3, 4},
+		{conc.Trailing, 3, 4},
+		{conc.Leading | conc.Trailing, 4, 5},
+	} {
+		var calls int32
+		call := conc.Throttle(context.Background(), 3*period, tc.mode, func() { atomic.AddInt32(&calls, 1) })
+		// 10 periods of calls produce window boundaries at 3, 6 and 9 periods
+		for i := 0; i < 10; i++ {
+			call()
+			time.Sleep(period)
+		}
+		time.Sleep(7 * period)
+		n := atomic.LoadInt32(&calls)
+		assert.GreaterOrEqual(t, n, tc.min, "mode %d", tc.mode)
+		assert.LessOrEqual(t, n, tc.max, "mode %d", tc.mode)
+	}
+
+	var calls int32
+	ctx, cancel := context.WithCancel(context.Background())
+	call := conc.Throttle(ctx, period, conc.Trailing, func() { atomic.AddInt32(&calls, 1) })
+	call()
+	cancel()
+	time.Sleep(3 * period)
+	assert.Zero(t, atomic.LoadInt32(&calls), "cancelled")
+}
@@ -0,0 +1,120 @@
+package conc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Mode selects edges of burst on which debounced or throttled function is called
+type Mode int
+
+const (
+	Trailing Mode = 1 << iota
+	Leading
+)
+
+// Debounce returns function calling f once calls stop for wait.
+// Leading call happens in caller goroutine, trailing one in separate goroutine.
+// Pending call is dropped and further calls are ignored once ctx is done.
+func Debounce(ctx context.Context, wait time.Duration, mode Mode, f func()) func() {
+	d := limiter{ctx: ctx, wait: wait, mode: mode, f: f}
+	context.AfterFunc(ctx, d.cancel)
+	return d.debounce
+}
+
+// Throttle returns function calling f at most once per interval.
+// Leading call happens in caller goroutine, trailing one in separate goroutine.
+// Pending call is dropped and further calls are ignored once ctx is done.
+func Throttle(ctx context.Context, interval time.Duration, mode Mode, f func()) func() {
+	d := limiter{ctx: ctx, wait: interval, mode: mode, f: f}
+	context.AfterFunc(ctx, d.cancel)
+	return d.throttle
+}
+
+type limiter struct {
+	ctx  context.Context
+	wait time.Duration
+	mode Mode
+	f    func()
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	gen     int
+	pending bool
+}
+
+func (d *limiter) debounce() {
+	if d.ctx.Err() != nil {
+		return
+	}
+	d.mu.Lock()
+	idle := d.timer == nil
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.gen++
+	gen := d.gen
+	d.timer = time.AfterFunc(d.wait, func() { d.fire(gen, false) })
+	leading := idle && d.mode&Leading != 0
+	d.pending = !leading && d.mode&Trailing != 0
+	d.mu.Unlock()
+
+	if leading {
+		d.f()
+	}
+}
+
+func (d *limiter) throttle() {
+	if d.ctx.Err() != nil {
+		return
+	}
+	d.mu.Lock()
+	if d.timer != nil {
+		d.pending = d.mode&Trailing != 0
+		d.mu.Unlock()
+		return
+	}
+	d.gen++
+	gen := d.gen
+	d.timer = time.AfterFunc(d.wait, func() { d.fire(gen, true) })
+	leading := d.mode&Leading != 0
+	d.pending = !leading && d.mode&Trailing != 0
+	d.mu.Unlock()
+
+	if leading {
+		d.f()
+	}
+}
+
+// fire ends window, throttled function starts next window if call was made
+func (d *limiter) fire(gen int, restart bool) {
+	d.mu.Lock()
+	if gen != d.gen || d.ctx.Err() != nil {
+		d.mu.Unlock()
+		return
+	}
+	call := d.pending
+	d.pending = false
+	d.timer = nil
+	if call && restart {
+		d.gen++
+		gen := d.gen
+		d.timer = time.AfterFunc(d.wait, func() { d.fire(gen, true) })
+	}
+	d.mu.Unlock()
+
+	if call {
+		d.f()
+	}
+}
+
+func (d *limiter) cancel() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.gen++
+	d.timer, d.pending = nil, false
+}
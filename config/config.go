@@ -1,19 +1,64 @@
 package config
 
-import "github.com/242617/core/config/source"
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/242617/core/config/source"
+	"github.com/242617/core/config/source/file"
+	"github.com/242617/core/validate"
+)
 
 // ConfigEngine is an interface for config scanner
 type ConfigEngine interface {
 	With(...source.ConfigSource) ConfigEngine
 	Scan(interface{}) error
+	Watch(ctx context.Context, target interface{}, onChange func(old, next interface{}) error) error
+	// Profile returns active profile set by WithProfile
+	Profile() string
+	// Explain returns origins of fields of config pointer scanned by engine
+	Explain(cfg interface{}, options ...dumpOption) Report
+}
+
+type option = func(c *config)
+
+// WithProfile loads config.yaml and then config.<profile>.yaml of config directory before sources
+// added by With, both files are optional. Empty profile loads config.yaml only.
+func WithProfile(profile string) option {
+	return func(c *config) {
+		c.profile, c.profiled = profile, true
+	}
+}
+
+// WithDir sets directory of profile files, it is working directory by default
+func WithDir(dir string) option {
+	return func(c *config) {
+		c.dir = dir
+	}
 }
 
 // New creates a new config engine with default scanner
-func New() ConfigEngine {
-	return &config{[]source.ConfigSource{source.Default()}}
+func New(options ...option) ConfigEngine {
+	c := config{sources: []source.ConfigSource{source.Default()}}
+	for _, option := range options {
+		option(&c)
+	}
+	if c.profiled {
+		c.sources = append(c.sources, file.Optional(file.YAML(filepath.Join(c.dir, "config.yaml"))))
+		if c.profile != "" {
+			c.sources = append(c.sources, file.Optional(file.YAML(filepath.Join(c.dir, "config."+c.profile+".yaml"))))
+		}
+	}
+	return &c
 }
 
-type config struct{ sources []source.ConfigSource }
+type config struct {
+	sources  []source.ConfigSource
+	dir      string
+	profile  string
+	profiled bool
+	origins  origins
+}
 
 // With adds source(s) for engine. Make sure you are adding sources in desired order.
 func (c *config) With(sources ...source.ConfigSource) ConfigEngine {
@@ -21,12 +66,29 @@ func (c *config) With(sources ...source.ConfigSource) ConfigEngine {
 	return c
 }
 
-// Scan returns error of scanning sources into config
+func (c *config) Profile() string { return c.profile }
+
+// Scan returns error of scanning sources into config.
+// Fields tagged `required:"true"` which are still zero fail scan with MissingFields,
+// then scanned config is checked with validate.Struct. Origins of fields are recorded for Explain.
 func (c *config) Scan(p interface{}) error {
+	t := newTracker(p)
 	for _, source := range c.sources {
+		if t != nil {
+			t.snapshot()
+		}
 		if err := source.Scan(p); err != nil {
 			return err
 		}
+		if t != nil {
+			t.update(source)
+		}
+	}
+	if t != nil {
+		c.origins.store(p, t.sources)
+	}
+	if err := required(p); err != nil {
+		return err
 	}
-	return nil
+	return validate.Struct(p)
 }
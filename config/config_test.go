@@ -1,11 +1,20 @@
 package config
 
 import (
+	"context"
+	"encoding/json"
 	"io/ioutil"
 	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -54,6 +63,36 @@ func TestDefault(t *testing.T) {
 	}
 }
 
+func TestDefaultCollections(t *testing.T) {
+	var cfg struct {
+		Brokers  []string                 `default:"kafka-1:9092,kafka-2:9092,kafka-3:9092"`
+		Weights  []float64                `default:"0.5, 0.25"`
+		Headers  map[string]string        `default:"Accept=text/html,application/json;X-Team=core"`
+		Timeouts map[string]time.Duration `default:"read=1s,write=2s"`
+	}
+
+	config := New()
+	if err := config.Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config"))
+	}
+
+	if strings.Join(cfg.Brokers, ";") != "kafka-1:9092;kafka-2:9092;kafka-3:9092" {
+		t.Fatalf("unexpected brokers: %q", cfg.Brokers)
+	}
+
+	if len(cfg.Weights) != 2 || cfg.Weights[0] != 0.5 || cfg.Weights[1] != 0.25 {
+		t.Fatalf("unexpected weights: %v", cfg.Weights)
+	}
+
+	if len(cfg.Headers) != 2 || cfg.Headers["Accept"] != "text/html,application/json" || cfg.Headers["X-Team"] != "core" {
+		t.Fatalf("unexpected headers: %v", cfg.Headers)
+	}
+
+	if len(cfg.Timeouts) != 2 || cfg.Timeouts["read"] != time.Second || cfg.Timeouts["write"] != 2*time.Second {
+		t.Fatalf("unexpected timeouts: %v", cfg.Timeouts)
+	}
+}
+
 func TestEnvBasic(t *testing.T) {
 	for k, v := range map[string]string{
 		"USER_FIRST_NAME": "Vasily",
@@ -95,6 +134,137 @@ func TestEnvBasic(t *testing.T) {
 	}
 }
 
+func TestEnvCollections(t *testing.T) {
+	var cfg struct {
+		Brokers []string          `env:"KAFKA_BROKERS"`
+		Ports   []int             `default:"80,443"`
+		Labels  map[string]string `env:"LABELS"`
+		Log     struct {
+			MaxSize int `yaml:"max_size"`
+			Level   string
+		} `yaml:"log"`
+		Secret string `env:"-"`
+	}
+
+	for k, v := range map[string]string{
+		"MYAPP_KAFKA_BROKERS":    "kafka-1:9092, kafka-2:9092",
+		"MYAPP_LABELS":           "team=core,env=prod",
+		"MYAPP_LOG_MAX_SIZE":     "100",
+		"MYAPP_LOG_LEVEL":        "debug",
+		"MYAPP_SECRET":           "leaked",
+		"KAFKA_BROKERS":          "ignored:9092",
+		"MYAPP_UNKNOWN_VARIABLE": "ignored",
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	config := New().With(source.Env(source.WithPrefix("MYAPP_")))
+	if err := config.Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config"))
+	}
+
+	if strings.Join(cfg.Brokers, ";") != "kafka-1:9092;kafka-2:9092" {
+		t.Fatalf("unexpected brokers: %q", cfg.Brokers)
+	}
+
+	if len(cfg.Ports) != 2 || cfg.Ports[0] != 80 || cfg.Ports[1] != 443 {
+		t.Fatalf("unexpected ports: %v", cfg.Ports)
+	}
+
+	if len(cfg.Labels) != 2 || cfg.Labels["team"] != "core" || cfg.Labels["env"] != "prod" {
+		t.Fatalf("unexpected labels: %v", cfg.Labels)
+	}
+
+	if cfg.Log.MaxSize != 100 || cfg.Log.Level != "debug" {
+		t.Fatalf("unexpected log: %+v", cfg.Log)
+	}
+
+	if cfg.Secret != "" {
+		t.Fatalf("unexpected secret: %q", cfg.Secret)
+	}
+
+	os.Setenv("MYAPP_LABELS", "team")
+	if err := config.Scan(&cfg); err == nil {
+		t.Fatal("invalid map is expected to fail")
+	}
+}
+
+type level int
+
+func (l *level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "debug":
+		*l = 1
+	case "info":
+		*l = 2
+	default:
+		return errors.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+func TestTextUnmarshaler(t *testing.T) {
+	var cfg struct {
+		Level     level     `default:"info" env:"TU_LEVEL"`
+		Bind      net.IP    `default:"127.0.0.1"`
+		Allowlist []net.IP  `env:"TU_ALLOWLIST"`
+		Since     time.Time `env:"TU_SINCE"`
+		Endpoint  url.URL   `default:"https://example.com/api?v=1"`
+		Proxy     *url.URL  `env:"TU_PROXY"`
+		Key       []byte    `env:"TU_KEY"`
+	}
+
+	for k, v := range map[string]string{
+		"TU_LEVEL":     "debug",
+		"TU_ALLOWLIST": "10.0.0.1, ::1",
+		"TU_SINCE":     "2024-03-01T12:00:00Z",
+		"TU_PROXY":     "http://proxy:3128",
+		"TU_KEY":       "c2VjcmV0",
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	config := New().With(source.Env())
+	if err := config.Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config"))
+	}
+
+	if cfg.Level != 1 {
+		t.Fatalf("unexpected level: want %d, got %d", 1, cfg.Level)
+	}
+
+	if !cfg.Bind.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Fatalf("unexpected bind: want %s, got %s", "127.0.0.1", cfg.Bind)
+	}
+
+	if len(cfg.Allowlist) != 2 || !cfg.Allowlist[0].Equal(net.IPv4(10, 0, 0, 1)) || !cfg.Allowlist[1].Equal(net.IPv6loopback) {
+		t.Fatalf("unexpected allowlist: %v", cfg.Allowlist)
+	}
+
+	if !cfg.Since.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected since: %s", cfg.Since)
+	}
+
+	if cfg.Endpoint.Host != "example.com" || cfg.Endpoint.Query().Get("v") != "1" {
+		t.Fatalf("unexpected endpoint: %s", cfg.Endpoint.String())
+	}
+
+	if cfg.Proxy == nil || cfg.Proxy.Host != "proxy:3128" {
+		t.Fatalf("unexpected proxy: %v", cfg.Proxy)
+	}
+
+	if string(cfg.Key) != "secret" {
+		t.Fatalf("unexpected key: want %q, got %q", "secret", cfg.Key)
+	}
+
+	os.Setenv("TU_LEVEL", "verbose")
+	if err := config.Scan(&cfg); err == nil || !strings.Contains(err.Error(), "TU_LEVEL") {
+		t.Fatalf("unexpected error: want invalid TU_LEVEL, got %v", err)
+	}
+}
+
 func TestYAMLBasic(t *testing.T) {
 	content := []byte(strings.Join([]string{
 		"user:",
@@ -138,3 +308,863 @@ func TestYAMLBasic(t *testing.T) {
 		log.Fatalf("unexpected status: want %q, got %q", "idle", cfg.Status)
 	}
 }
+
+func TestLayered(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "config")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "cannot create temp directory"))
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "conf.d"), 0777); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot create conf.d directory"))
+	}
+	for name, content := range map[string]string{
+		"conf.d/00-base.yaml":       "status_string: idle\ntimeout: 5s\nuser:\n  age: 30",
+		"conf.d/10-production.yaml": "status_string: busy",
+		"conf.d/README.md":          "not a config",
+		"local.yaml":                "user:\n  age: 40",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(errors.Wrap(err, "cannot write file"))
+		}
+	}
+
+	var cfg Item
+
+	config := New().With(
+		file.YAMLGlob(filepath.Join(dir, "conf.d", "*.yaml")),
+		file.Optional(file.YAML(filepath.Join(dir, "missing.yaml"))),
+		file.Optional(file.YAML(filepath.Join(dir, "local.yaml"))),
+	)
+	if err := config.Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config"))
+	}
+
+	if cfg.Status != "busy" {
+		t.Fatalf("unexpected status: want %q, got %q", "busy", cfg.Status)
+	}
+
+	if cfg.Timeout != 5*time.Second {
+		t.Fatalf("unexpected timeout: want %s, got %s", 5*time.Second, cfg.Timeout)
+	}
+
+	if cfg.User.Age != 40 {
+		t.Fatalf("unexpected user age: want %d, got %d", 40, cfg.User.Age)
+	}
+
+	if _, ok := file.Optional(file.YAML("local.yaml")).(source.FileSource); !ok {
+		t.Fatal("optional file source is expected to be watched")
+	}
+
+	if err := New().With(file.YAML(filepath.Join(dir, "missing.yaml"))).Scan(&cfg); err == nil {
+		t.Fatal("scanning missing file is expected to fail")
+	}
+}
+
+func TestDotEnv(t *testing.T) {
+	content := []byte(`# local development
+USER_FIRST_NAME="Ivan \"the\" Dev"
+export USER_AGE=30 # inline comment
+USER_ACTIVE='false # kept'
+
+TIMEOUT = 5s
+`)
+
+	dir, err := ioutil.TempDir(os.TempDir(), "config")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "cannot create temp directory"))
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, ".env")
+	if err := ioutil.WriteFile(filename, content, 0666); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot write file"))
+	}
+
+	var cfg Item
+
+	config := New().With(source.Default(), file.DotEnv(filename))
+	if err := config.Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config"))
+	}
+
+	if cfg.User.Name.First != `Ivan "the" Dev` {
+		t.Fatalf("unexpected user first name: want %q, got %q", `Ivan "the" Dev`, cfg.User.Name.First)
+	}
+
+	if cfg.User.Age != 30 {
+		t.Fatalf("unexpected user age: want %d, got %d", 30, cfg.User.Age)
+	}
+
+	if cfg.Timeout != 5*time.Second {
+		t.Fatalf("unexpected timeout: want %v, got %v", 5*time.Second, cfg.Timeout)
+	}
+
+	if cfg.User.Active {
+		t.Fatal("unexpected user active: want false")
+	}
+
+	for _, k := range []string{"USER_FIRST_NAME", "USER_AGE", "USER_ACTIVE", "TIMEOUT"} {
+		os.Unsetenv(k)
+		defer os.Unsetenv(k)
+	}
+	os.Setenv("USER_AGE", "40")
+
+	cfg = Item{}
+	config = New().With(file.DotEnv(filename, file.WithProcessEnv()), source.Env())
+	if err := config.Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config"))
+	}
+
+	if cfg.User.Age != 40 {
+		t.Fatalf("unexpected user age: want %d, got %d", 40, cfg.User.Age)
+	}
+
+	if cfg.User.Name.First != `Ivan "the" Dev` {
+		t.Fatalf("unexpected user first name: want %q, got %q", `Ivan "the" Dev`, cfg.User.Name.First)
+	}
+
+	if err := New().With(file.DotEnv(filepath.Join(dir, "missing.env"))).Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config without file"))
+	}
+
+	if err := ioutil.WriteFile(filename, []byte("USER_AGE\n"), 0666); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot write file"))
+	}
+	if err := New().With(file.DotEnv(filename)).Scan(&cfg); err == nil {
+		t.Fatal("expected error for line without value")
+	}
+}
+
+func TestJSONBasic(t *testing.T) {
+	content := []byte(`{
+	"user": {"name": {"first": "Ivan"}, "age": 30, "active": true},
+	"status_string": "idle",
+	"timeout": "5s"
+}`)
+
+	dir, err := ioutil.TempDir(os.TempDir(), "config")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "cannot create temp directory"))
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(filename, content, 0666); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot write file"))
+	}
+
+	var cfg Item
+
+	config := New().With(file.JSON(filename))
+	if err := config.Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config"))
+	}
+
+	if cfg.User.Name.First != "Ivan" {
+		t.Fatalf("unexpected user first name: want %q, got %q", "Ivan", cfg.User.Name.First)
+	}
+
+	if cfg.User.Age != 30 {
+		t.Fatalf("unexpected user age: want %d, got %d", 30, cfg.User.Age)
+	}
+
+	if cfg.User.Balance != 10.25 {
+		t.Fatalf("unexpected user balance: want %f, got %f", 10.25, cfg.User.Balance)
+	}
+
+	if cfg.Status != "idle" {
+		t.Fatalf("unexpected status: want %q, got %q", "idle", cfg.Status)
+	}
+
+	if cfg.Timeout != 5*time.Second {
+		t.Fatalf("unexpected timeout: want %s, got %s", 5*time.Second, cfg.Timeout)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	content := []byte(`user:
+  name:
+    first: ${EXPAND_FIRST_NAME}
+    second: "${EXPAND_SECOND_NAME:-Petrov}"
+  age: ${EXPAND_AGE}
+  active: ${EXPAND_ACTIVE:-false}
+status_string: "${EXPAND_STATUS}-${EXPAND_MISSING}"
+timeout: ${EXPAND_TIMEOUT:-5s}
+`)
+
+	dir, err := ioutil.TempDir(os.TempDir(), "config")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "cannot create temp directory"))
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(filename, content, 0666); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot write file"))
+	}
+
+	for k, v := range map[string]string{
+		"EXPAND_FIRST_NAME": "0x1F: #secret",
+		"EXPAND_AGE":        "30",
+		"EXPAND_STATUS":     "idle",
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	var cfg Item
+
+	config := New().With(file.YAML(filename))
+	if err := config.Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config"))
+	}
+
+	if cfg.User.Name.First != "0x1F: #secret" {
+		t.Fatalf("unexpected user first name: want %q, got %q", "0x1F: #secret", cfg.User.Name.First)
+	}
+
+	if cfg.User.Name.Second != "Petrov" {
+		t.Fatalf("unexpected user second name: want %q, got %q", "Petrov", cfg.User.Name.Second)
+	}
+
+	if cfg.User.Age != 30 {
+		t.Fatalf("unexpected user age: want %d, got %d", 30, cfg.User.Age)
+	}
+
+	if cfg.User.Active {
+		t.Fatal("unexpected user active: want false")
+	}
+
+	if cfg.Status != "idle-" {
+		t.Fatalf("unexpected status: want %q, got %q", "idle-", cfg.Status)
+	}
+
+	if cfg.Timeout != 5*time.Second {
+		t.Fatalf("unexpected timeout: want %v, got %v", 5*time.Second, cfg.Timeout)
+	}
+
+	err = New().With(file.YAML(filename, file.WithStrictExpansion())).Scan(&cfg)
+	if err == nil || !strings.Contains(err.Error(), "EXPAND_MISSING") {
+		t.Fatalf("unexpected error: want missing EXPAND_MISSING, got %v", err)
+	}
+
+	filename = filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(filename, []byte(`{"user": {"age": "${EXPAND_AGE}"}, "status_string": "${EXPAND_STATUS}"}`), 0666); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot write file"))
+	}
+
+	cfg = Item{}
+	if err := New().With(file.JSON(filename)).Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config"))
+	}
+
+	if cfg.User.Age != 30 || cfg.Status != "idle" {
+		t.Fatalf("unexpected user age and status: want %d and %q, got %d and %q", 30, "idle", cfg.User.Age, cfg.Status)
+	}
+}
+
+func TestFlags(t *testing.T) {
+	var cfg struct {
+		Listen  string        `default:":8080" env:"LISTEN" flag:"listen"`
+		Debug   bool          `flag:"debug"`
+		Timeout time.Duration `default:"10s" flag:"timeout"`
+		Server  struct {
+			Workers int `default:"4" flag:"workers"`
+		}
+	}
+
+	os.Setenv("LISTEN", ":9090")
+	defer os.Unsetenv("LISTEN")
+
+	config := New().With(source.Env(), source.Flags("-listen=:7070", "-debug", "-timeout", "5s"))
+	if err := config.Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config"))
+	}
+
+	if cfg.Listen != ":7070" {
+		t.Fatalf("unexpected listen: want %q, got %q", ":7070", cfg.Listen)
+	}
+
+	if !cfg.Debug {
+		t.Fatalf("unexpected debug: want %t, got %t", true, cfg.Debug)
+	}
+
+	if cfg.Timeout != 5*time.Second {
+		t.Fatalf("unexpected timeout: want %s, got %s", 5*time.Second, cfg.Timeout)
+	}
+
+	if cfg.Server.Workers != 4 {
+		t.Fatalf("unexpected workers: want %d, got %d", 4, cfg.Server.Workers)
+	}
+
+	if err := New().With(source.Flags("-workers=many")).Scan(&cfg); err == nil {
+		t.Fatal("invalid flag value is expected to fail")
+	}
+
+	// flags of go test are not in config
+	if err := New().With(source.Flags()).Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config with test flags"))
+	}
+
+	config = New().With(source.Flags("-test.v", "-test.run", "TestFlags", "--workers=8", "-test.count=1", "-timeout", "7s"))
+	if err := config.Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config with undefined flags"))
+	}
+
+	if cfg.Server.Workers != 8 || cfg.Timeout != 7*time.Second {
+		t.Fatalf("unexpected workers and timeout: want %d and %s, got %d and %s", 8, 7*time.Second, cfg.Server.Workers, cfg.Timeout)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "config")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "cannot create temp directory"))
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(filename, []byte("status_string: idle"), 0666); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot write file"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var cfg Item
+	var current atomic.Pointer[Item]
+	current.Store(&cfg)
+	if err := New().With(file.YAML(filename)).Watch(ctx, &cfg, Swap(&current)); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot watch config"))
+	}
+
+	if cfg.Status != "idle" {
+		t.Fatalf("unexpected status: want %q, got %q", "idle", cfg.Status)
+	}
+
+	if err := ioutil.WriteFile(filename, []byte("status_string: busy"), 0666); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot write file"))
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for current.Load().Status != "busy" {
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected status: want %q, got %q", "busy", current.Load().Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if current.Load().Timeout != 10*time.Second {
+		t.Fatalf("unexpected timeout: want %s, got %s", 10*time.Second, current.Load().Timeout)
+	}
+
+	if err := New().Watch(ctx, &cfg, Swap(&current)); err == nil {
+		t.Fatal("watching without file sources is expected to fail")
+	}
+}
+
+func TestWatchGlob(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "config")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "cannot create temp directory"))
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"base", "production"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0777); err != nil {
+			t.Fatal(errors.Wrap(err, "cannot create directory"))
+		}
+	}
+	filename := filepath.Join(dir, "production", "config.yaml")
+	if err := ioutil.WriteFile(filename, []byte("status_string: idle"), 0666); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot write file"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var cfg Item
+	var current atomic.Pointer[Item]
+	current.Store(&cfg)
+	config := New().With(file.Optional(file.YAMLGlob(filepath.Join(dir, "*", "config.yaml"))))
+	if err := config.Watch(ctx, &cfg, Swap(&current)); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot watch config"))
+	}
+
+	if err := ioutil.WriteFile(filename, []byte("status_string: busy"), 0666); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot write file"))
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for current.Load().Status != "busy" {
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected status: want %q, got %q", "busy", current.Load().Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// consulKV serves keys like Consul KV, blocking queries wait for next put
+type consulKV struct {
+	mu      sync.Mutex
+	index   uint64
+	keys    map[string]string
+	changed chan struct{}
+}
+
+func (c *consulKV) put(key, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keys[key] = value
+	c.index++
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (c *consulKV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Consul-Token") != "secret" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	prefix := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	index, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+
+	c.mu.Lock()
+	if index > 0 && index == c.index {
+		changed := c.changed
+		c.mu.Unlock()
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+		c.mu.Lock()
+	}
+	defer c.mu.Unlock()
+
+	type entry struct {
+		Key   string
+		Value []byte
+	}
+	var entries []entry
+	for key, value := range c.keys {
+		if strings.HasPrefix(key, prefix) {
+			entries = append(entries, entry{key, []byte(value)})
+		}
+	}
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(c.index, 10))
+	if len(entries) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(entries)
+}
+
+func TestConsul(t *testing.T) {
+	kv := &consulKV{index: 1, changed: make(chan struct{}), keys: map[string]string{
+		"services/app":                 "status_string: idle\ntimeout: 5s",
+		"services/app/user/age":        "30",
+		"services/app/user/name/first": "0x1F",
+		"services/application/user":    "ignored",
+	}}
+	srv := httptest.NewServer(kv)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var cfg Item
+	var current atomic.Pointer[Item]
+	current.Store(&cfg)
+	config := New().With(source.Consul(srv.URL, "services/app/", source.WithToken("secret")))
+	if err := config.Watch(ctx, &cfg, Swap(&current)); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot watch config"))
+	}
+
+	if cfg.Status != "idle" {
+		t.Fatalf("unexpected status: want %q, got %q", "idle", cfg.Status)
+	}
+
+	if cfg.Timeout != 5*time.Second {
+		t.Fatalf("unexpected timeout: want %s, got %s", 5*time.Second, cfg.Timeout)
+	}
+
+	if cfg.User.Age != 30 {
+		t.Fatalf("unexpected user age: want %d, got %d", 30, cfg.User.Age)
+	}
+
+	if cfg.User.Name.First != "0x1F" {
+		t.Fatalf("unexpected user first name: want %q, got %q", "0x1F", cfg.User.Name.First)
+	}
+
+	kv.put("services/app/status_string", "busy")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for current.Load().Status != "busy" {
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected status: want %q, got %q", "busy", current.Load().Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := New().With(source.Consul(srv.URL, "services/missing", source.WithToken("secret"))).Scan(&cfg); err == nil {
+		t.Fatal("scanning missing prefix is expected to fail")
+	}
+}
+
+func TestEtcd(t *testing.T) {
+	keys := map[string]string{
+		"/app":                `{"status_string": "idle", "user": {"age": 30}}`,
+		"/app/user/active":    "false",
+		"/application/status": "ignored",
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v3/kv/range", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Key      []byte `json:"key"`
+			RangeEnd []byte `json:"range_end"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		type kv struct {
+			Key   []byte `json:"key"`
+			Value []byte `json:"value"`
+		}
+		var kvs []kv
+		for key, value := range keys {
+			if key >= string(body.Key) && key < string(body.RangeEnd) {
+				kvs = append(kvs, kv{[]byte(key), []byte(value)})
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"kvs": kvs})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var cfg Item
+	config := New().With(source.Etcd([]string{"127.0.0.1:1", srv.URL}, "/app"))
+	if err := config.Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config"))
+	}
+
+	if cfg.Status != "idle" {
+		t.Fatalf("unexpected status: want %q, got %q", "idle", cfg.Status)
+	}
+
+	if cfg.User.Age != 30 {
+		t.Fatalf("unexpected user age: want %d, got %d", 30, cfg.User.Age)
+	}
+
+	if cfg.User.Active {
+		t.Fatal("unexpected user active: want false")
+	}
+}
+
+func TestVault(t *testing.T) {
+	var logins, renewals int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/auth/approle/login", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["role_id"] != "app" || req["secret_id"] != "s3cr3t" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		atomic.AddInt32(&logins, 1)
+		w.Write([]byte(`{"auth": {"client_token": "token", "lease_duration": 1, "renewable": true}}`))
+	})
+	mux.HandleFunc("/v1/auth/token/renew-self", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&renewals, 1)
+		w.Write([]byte(`{"auth": {"client_token": "token", "lease_duration": 60, "renewable": true}}`))
+	})
+	mux.HandleFunc("/v1/secret/data/db", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"data": {"data": {"user": "app", "password": "p@ss", "port": 5432}, "metadata": {"version": 3}}}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var cfg struct {
+		DB struct {
+			User     string `vault:"db#user"`
+			Password string `vault:"db#password"`
+			Port     int    `vault:"db#port"`
+		}
+	}
+
+	config := New().With(source.Vault(srv.URL, "secret/data", source.VaultAppRoleAuth("app", "s3cr3t")))
+	if err := config.Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config"))
+	}
+
+	if cfg.DB.User != "app" || cfg.DB.Password != "p@ss" || cfg.DB.Port != 5432 {
+		t.Fatalf("unexpected db: %+v", cfg.DB)
+	}
+
+	time.Sleep(700 * time.Millisecond)
+	if err := config.Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config"))
+	}
+
+	if atomic.LoadInt32(&logins) != 1 || atomic.LoadInt32(&renewals) != 1 {
+		t.Fatalf("unexpected logins and renewals: want 1 and 1, got %d and %d", logins, renewals)
+	}
+
+	var missing struct {
+		Secret string `vault:"db#secret"`
+	}
+	if err := New().With(source.Vault(srv.URL, "secret/data", source.VaultAppRoleAuth("app", "s3cr3t"))).Scan(&missing); err == nil {
+		t.Fatal("scanning missing key is expected to fail")
+	}
+
+	if err := New().With(source.Vault(srv.URL, "", source.VaultAppRoleAuth("app", "wrong"))).Scan(&cfg); err == nil {
+		t.Fatal("scanning with wrong secret id is expected to fail")
+	}
+}
+
+type listener struct {
+	Host string `default:"localhost" flag:"host"`
+	Port int    `validate:"min=1,max=65535"`
+}
+
+func (l listener) Validate() error {
+	if l.Host == "0.0.0.0" {
+		return errors.New("must not listen on all interfaces")
+	}
+	return nil
+}
+
+func TestValidateNested(t *testing.T) {
+	var cfg struct {
+		Listener listener
+		Workers  int `validate:"min=1"`
+	}
+
+	err := New().With(source.Flags("-host=0.0.0.0")).Scan(&cfg)
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, msg := range []string{
+		"Listener: must not listen on all interfaces",
+		"Listener.Port: must be at least 1",
+		"Workers: must be at least 1",
+	} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var cfg struct {
+		Name  string `env:"SERVICE_NAME" validate:"required"`
+		Level string `default:"trace" validate:"oneof=debug info warn"`
+	}
+
+	config := New().With(source.Env())
+	err := config.Scan(&cfg)
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	if !strings.Contains(err.Error(), "Name: is required") || !strings.Contains(err.Error(), "Level: must be one of") {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestRequired(t *testing.T) {
+	var cfg struct {
+		DB struct {
+			Host     string `default:"localhost" required:"true"`
+			Password string `env:"DB_PASSWORD" required:"true"`
+		} `yaml:"db"`
+		Token string `yaml:"api_token" required:"true"`
+		Debug bool
+	}
+
+	config := New().With(source.Env())
+	err := config.Scan(&cfg)
+	if err == nil {
+		t.Fatal("expected missing fields error")
+	}
+	var missing MissingFields
+	if !errors.As(err, &missing) || len(missing) != 2 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err.Error() != "missing required fields: db.password (env DB_PASSWORD), api_token" {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	os.Setenv("DB_PASSWORD", "secret")
+	defer os.Unsetenv("DB_PASSWORD")
+	cfg.Token = "token"
+	if err := config.Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config"))
+	}
+}
+
+func TestDump(t *testing.T) {
+	var cfg struct {
+		DB struct {
+			Host     string `default:"localhost"`
+			Password string `default:"p@ss"`
+			DSN      string `yaml:"dsn" default:"postgres://app:p@ss@localhost/app" secret:"true"`
+		} `yaml:"db"`
+		APIToken string        `yaml:"api_token"`
+		Signing  string        `yaml:"signing" default:"k3y" vault:"app#signing"`
+		Timeout  time.Duration `default:"5s"`
+		Brokers  []string      `default:"kafka-1:9092,kafka-2:9092"`
+		Internal string        `yaml:"-" default:"hidden"`
+	}
+
+	if err := New().Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config"))
+	}
+
+	var b strings.Builder
+	if err := Dump(&cfg, &b, MaskTagged("secret")); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot dump config"))
+	}
+	want := `db:
+  host: localhost
+  password: '******'
+  dsn: '******'
+api_token: ""
+signing: '******'
+timeout: 5s
+brokers:
+- kafka-1:9092
+- kafka-2:9092
+`
+	if b.String() != want {
+		t.Fatalf("unexpected dump: want %q, got %q", want, b.String())
+	}
+
+	b.Reset()
+	if err := Dump(cfg, &b, AsJSON()); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot dump config"))
+	}
+	if !strings.Contains(b.String(), `"password": "******"`) || !strings.Contains(b.String(), `"dsn": "postgres://app:p@ss@localhost/app"`) ||
+		strings.Contains(b.String(), "k3y") {
+		t.Fatalf("unexpected dump: %s", b.String())
+	}
+
+	rec := httptest.NewRecorder()
+	Handler(&cfg, MaskTagged("secret")).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "p@ss") {
+		t.Fatalf("unexpected response: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestProfile(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "config")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "cannot create temp directory"))
+	}
+	defer os.RemoveAll(dir)
+
+	for name, content := range map[string]string{
+		"config.yaml":      "status_string: idle\ntimeout: 5s",
+		"config.prod.yaml": "status_string: busy",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(errors.Wrap(err, "cannot write file"))
+		}
+	}
+
+	os.Setenv("USER_AGE", "30")
+	defer os.Unsetenv("USER_AGE")
+
+	var cfg Item
+
+	config := New(WithProfile("prod"), WithDir(dir)).With(source.Env())
+	if err := config.Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config"))
+	}
+
+	if config.Profile() != "prod" {
+		t.Fatalf("unexpected profile: want %q, got %q", "prod", config.Profile())
+	}
+
+	if cfg.Status != "busy" || cfg.Timeout != 5*time.Second || cfg.User.Age != 30 {
+		t.Fatalf("unexpected status, timeout and user age: %q, %s, %d", cfg.Status, cfg.Timeout, cfg.User.Age)
+	}
+
+	cfg = Item{}
+	if err := New(WithProfile("dev"), WithDir(dir)).Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config"))
+	}
+
+	if cfg.Status != "idle" {
+		t.Fatalf("unexpected status: want %q, got %q", "idle", cfg.Status)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "config")
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "cannot create temp directory"))
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(filename, []byte("user:\n  age: 30\n  balance: 10.25\nstatus_string: idle\n"), 0666); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot write file"))
+	}
+
+	for _, k := range []string{"USER_FIRST_NAME", "USER_AGE", "USER_BALANCE", "USER_ACTIVE"} {
+		os.Unsetenv(k)
+	}
+	os.Setenv("TIMEOUT", "1m")
+	defer os.Unsetenv("TIMEOUT")
+
+	var cfg Item
+
+	config := New().With(file.YAML(filename), source.Env())
+	if err := config.Scan(&cfg); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan config"))
+	}
+
+	origins := map[string]string{}
+	for _, f := range config.Explain(&cfg) {
+		origins[f.Path] = f.Source
+	}
+	for path, want := range map[string]string{
+		"user.name.first": "",
+		"user.age":        "file " + filename + ":2",
+		"user.balance":    "file " + filename + ":3",
+		"user.active":     "default",
+		"status_string":   "file " + filename + ":4",
+		"timeout":         "env TIMEOUT",
+	} {
+		if origins[path] != want {
+			t.Fatalf("unexpected origin of %s: want %q, got %q", path, want, origins[path])
+		}
+	}
+
+	if report := config.Explain(&cfg).String(); !strings.Contains(report, "timeout = 1m0s (env TIMEOUT)\n") {
+		t.Fatalf("unexpected report: %s", report)
+	}
+
+	var other Item
+	envConfig := New().With(source.Env())
+	if err := envConfig.Scan(&other); err != nil {
+		t.Fatal(errors.Wrap(err, "cannot scan other config"))
+	}
+	for _, f := range config.Explain(&cfg) {
+		if f.Path == "user.age" && f.Source != "file "+filename+":2" {
+			t.Fatalf("unexpected origin of user.age after other scan: want %q, got %q", "file "+filename+":2", f.Source)
+		}
+	}
+	for _, f := range envConfig.Explain(&other) {
+		if f.Path == "user.age" && f.Source != "" {
+			t.Fatalf("unexpected origin of other user.age: want %q, got %q", "", f.Source)
+		}
+	}
+}
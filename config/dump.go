@@ -0,0 +1,207 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	yaml2 "gopkg.in/yaml.v2"
+)
+
+// Masked replaces values of secret fields in Dump
+const Masked = "******"
+
+// secretNames are suffixes of lowercased field names masked by Dump
+var secretNames = []string{"password", "token", "secret"}
+
+type dumpOption = func(d *dumper)
+
+// MaskTagged masks fields tagged with tag set to true, e.g. MaskTagged("secret") masks `secret:"true"`
+func MaskTagged(tag string) dumpOption {
+	return func(d *dumper) {
+		d.tags = append(d.tags, tag)
+	}
+}
+
+// AsJSON makes Dump write JSON instead of YAML
+func AsJSON() dumpOption {
+	return func(d *dumper) {
+		d.json = true
+	}
+}
+
+// Dump writes scanned config as YAML with keys of yaml tags, so it is safe to log on startup
+// or serve by debug endpoint. Fields named like password, token or secret, fields loaded from Vault
+// and fields tagged for MaskTagged are replaced with Masked unless they are empty.
+func Dump(cfg interface{}, w io.Writer, options ...dumpOption) error {
+	var d dumper
+	for _, option := range options {
+		option(&d)
+	}
+
+	v := d.value(reflect.ValueOf(cfg))
+	if d.json {
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(v)
+	}
+	barr, err := yaml2.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(barr)
+	return err
+}
+
+// Handler serves Dump of cfg as JSON, e.g. on /config of adminserver with WithHandler.
+// Pointer to config is dumped as it is at the moment of request.
+func Handler(cfg interface{}, options ...dumpOption) http.Handler {
+	options = append(options, AsJSON())
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var b bytes.Buffer
+		if err := Dump(cfg, &b, options...); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b.Bytes())
+	})
+}
+
+type dumper struct {
+	tags []string
+	json bool
+}
+
+// value converts v to tree of dumpMap, slices and scalars
+func (d *dumper) value(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Ptr && v.Type().Elem() == reflect.TypeOf(url.URL{}) {
+			return v.Interface().(*url.URL).String()
+		}
+		v = v.Elem()
+	}
+
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		return v.Interface().(time.Duration).String()
+	}
+	if v.Type() == reflect.TypeOf(url.URL{}) {
+		u := v.Interface().(url.URL)
+		return u.String()
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		if text, err := m.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		var m dumpMap
+		for i := 0; i < v.NumField(); i++ {
+			tf := v.Type().Field(i)
+			if !tf.IsExported() || tf.Tag.Get("yaml") == "-" {
+				continue
+			}
+			vf := v.Field(i)
+			value := d.value(vf)
+			if d.secret(tf) && !vf.IsZero() {
+				value = Masked
+			}
+			m = append(m, dumpField{yamlName(tf), value})
+		}
+		return m
+	case reflect.Map:
+		keys := v.MapKeys()
+		m := make(dumpMap, 0, len(keys))
+		for _, key := range keys {
+			m = append(m, dumpField{fmt.Sprint(key.Interface()), d.value(v.MapIndex(key))})
+		}
+		return m.sorted()
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return base64.StdEncoding.EncodeToString(v.Bytes())
+		}
+		s := make([]interface{}, v.Len())
+		for i := range s {
+			s[i] = d.value(v.Index(i))
+		}
+		return s
+	default:
+		return v.Interface()
+	}
+}
+
+func (d *dumper) secret(tf reflect.StructField) bool {
+	if tf.Tag.Get("vault") != "" {
+		return true
+	}
+	for _, tag := range d.tags {
+		if tf.Tag.Get(tag) == "true" {
+			return true
+		}
+	}
+	name := strings.ToLower(tf.Name)
+	for _, secret := range secretNames {
+		if strings.HasSuffix(name, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// dumpMap keeps order of fields in both YAML and JSON
+type dumpMap []dumpField
+
+type dumpField struct {
+	key   string
+	value interface{}
+}
+
+func (m dumpMap) sorted() dumpMap {
+	sort.Slice(m, func(i, j int) bool { return m[i].key < m[j].key })
+	return m
+}
+
+func (m dumpMap) MarshalYAML() (interface{}, error) {
+	s := make(yaml2.MapSlice, len(m))
+	for i, f := range m {
+		s[i] = yaml2.MapItem{Key: f.key, Value: f.value}
+	}
+	return s, nil
+}
+
+func (m dumpMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, f := range m {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
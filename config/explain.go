@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/242617/core/config/source"
+)
+
+// FieldOrigin tells which source supplied value of field
+type FieldOrigin struct {
+	// Path is a yaml path like "db.timeout"
+	Path  string
+	Value string
+	// Source is like "default", "env DB_TIMEOUT" or "file config.yaml:12", empty if field is not set
+	Source string
+}
+
+// Report lists origins of all fields of config
+type Report []FieldOrigin
+
+func (r Report) String() string {
+	var b strings.Builder
+	for _, f := range r {
+		source := f.Source
+		if source == "" {
+			source = "not set"
+		}
+		fmt.Fprintf(&b, "%s = %s (%s)\n", f.Path, f.Value, source)
+	}
+	return b.String()
+}
+
+// origins keeps sources of fields by pointers to configs scanned by engine
+type origins struct {
+	mu      sync.Mutex
+	sources map[interface{}][]string
+}
+
+func (o *origins) store(p interface{}, sources []string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.sources == nil {
+		o.sources = map[interface{}][]string{}
+	}
+	o.sources[p] = sources
+}
+
+func (o *origins) load(p interface{}) []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.sources[p]
+}
+
+func (o *origins) forget(p interface{}) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.sources, p)
+}
+
+// Explain returns origins of fields recorded by the last Scan of cfg by this engine,
+// cfg must be the pointer passed to Scan. Values are masked like in Dump.
+func (c *config) Explain(cfg interface{}, options ...dumpOption) Report {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	v = v.Elem()
+	sources := c.origins.load(cfg)
+
+	var d dumper
+	for _, option := range options {
+		option(&d)
+	}
+	var report Report
+	for i, leaf := range source.Leaves(v) {
+		path := make([]string, len(leaf.Fields))
+		secret := false
+		for j, f := range leaf.Fields {
+			path[j] = yamlName(f)
+			secret = secret || d.secret(f)
+		}
+		value := fmt.Sprint(d.value(leaf.Value))
+		if secret && !leaf.Value.IsZero() {
+			value = Masked
+		}
+		f := FieldOrigin{Path: strings.Join(path, "."), Value: value}
+		if i < len(sources) {
+			f.Source = sources[i]
+		}
+		report = append(report, f)
+	}
+	return report
+}
+
+// tracker records which source sets every leaf of config during Scan
+type tracker struct {
+	leaves  []source.Leaf
+	sources []string
+	before  []interface{}
+}
+
+func newTracker(p interface{}) *tracker {
+	v := reflect.ValueOf(p)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	leaves := source.Leaves(v.Elem())
+	return &tracker{leaves: leaves, sources: make([]string, len(leaves)), before: make([]interface{}, len(leaves))}
+}
+
+func (t *tracker) snapshot() {
+	for i, leaf := range t.leaves {
+		t.before[i] = leaf.Value.Interface()
+	}
+}
+
+// update attributes leaves told by s or changed since snapshot to it
+func (t *tracker) update(s source.ConfigSource) {
+	o, _ := s.(source.Origin)
+	for i, leaf := range t.leaves {
+		if o != nil {
+			if origin := o.Origin(leaf.Fields); origin != "" {
+				t.sources[i] = origin
+				continue
+			}
+		}
+		if !reflect.DeepEqual(t.before[i], leaf.Value.Interface()) {
+			t.sources[i] = sourceName(s)
+		}
+	}
+}
+
+// sourceName describes source which does not tell origins of fields
+func sourceName(s source.ConfigSource) string {
+	if s, ok := s.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return strings.TrimPrefix(reflect.TypeOf(s).String(), "*")
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// MissingField is a field tagged `required:"true"` which is zero after scan
+type MissingField struct {
+	// Path is a yaml path like "db.password"
+	Path string
+	// Env is a name of environment variable of field if it has one
+	Env string
+}
+
+// MissingFields lists every missing required field of scanned config
+type MissingFields []MissingField
+
+func (m MissingFields) Error() string {
+	fields := make([]string, len(m))
+	for i, f := range m {
+		fields[i] = f.Path
+		if f.Env != "" {
+			fields[i] += " (env " + f.Env + ")"
+		}
+	}
+	return "missing required fields: " + strings.Join(fields, ", ")
+}
+
+// required returns MissingFields of p or nil if all required fields are set
+func required(p interface{}) error {
+	v := reflect.ValueOf(p)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var missing MissingFields
+	collectMissing(v, "", &missing)
+	if len(missing) > 0 {
+		return missing
+	}
+	return nil
+}
+
+func collectMissing(v reflect.Value, prefix string, missing *MissingFields) {
+	for i := 0; i < v.NumField(); i++ {
+		vf := v.Field(i)
+		tf := v.Type().Field(i)
+		if !tf.IsExported() {
+			continue
+		}
+
+		path := prefix + yamlName(tf)
+		if tf.Tag.Get("required") == "true" && vf.IsZero() {
+			*missing = append(*missing, MissingField{Path: path, Env: tf.Tag.Get("env")})
+			continue
+		}
+		if vf.Kind() == reflect.Struct {
+			collectMissing(vf, path+".", missing)
+		}
+	}
+}
+
+// yamlName is a key of field in yaml, lowercased name of field unless yaml tag sets it
+func yamlName(tf reflect.StructField) string {
+	if name, _, _ := strings.Cut(tf.Tag.Get("yaml"), ","); name != "" && name != "-" {
+		return name
+	}
+	return strings.ToLower(tf.Name)
+}
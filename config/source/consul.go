@@ -0,0 +1,89 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Consul creates config source that fills config with Consul KV keys of prefix, see remote for layout.
+// Addr is like "http://localhost:8500", changes are watched with blocking queries.
+func Consul(addr, keyPrefix string, options ...remoteOption) ConfigSource {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return &consul{remote: newRemote(keyPrefix, "X-Consul-Token", options), addr: strings.TrimSuffix(addr, "/")}
+}
+
+type consul struct {
+	remote
+	addr string
+}
+
+func (c *consul) String() string { return "consul " + c.addr + "/" + c.prefix }
+
+func (c *consul) Scan(p interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+	kvs, _, err := c.get(ctx, 0)
+	if err != nil {
+		return fmt.Errorf("consul: %w", err)
+	}
+	return c.scan(p, kvs)
+}
+
+func (c *consul) Watch(ctx context.Context, changed func()) {
+	var index uint64
+	for ctx.Err() == nil {
+		_, next, err := c.get(ctx, index)
+		if err != nil {
+			if !retry(ctx) {
+				return
+			}
+			continue
+		}
+		// first response catches up with changes made since Scan
+		if next != index {
+			changed()
+		}
+		// index going backwards means Consul state was reset
+		if next < index {
+			next = 0
+		}
+		index = next
+	}
+}
+
+// get reads keys of prefix, index other than zero makes request block until it changes
+func (c *consul) get(ctx context.Context, index uint64) (map[string][]byte, uint64, error) {
+	query := url.Values{"recurse": {"true"}}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+	}
+	resp, err := c.request(ctx, http.MethodGet, c.addr+"/v1/kv"+(&url.URL{Path: "/" + c.prefix}).EscapedPath()+"?"+query.Encode(), "")
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	next, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, next, nil
+	}
+
+	var entries []struct {
+		Key   string
+		Value []byte
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, 0, err
+	}
+	kvs := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		kvs[entry.Key] = entry.Value
+	}
+	return kvs, next, nil
+}
@@ -3,9 +3,6 @@ package source
 import (
 	"fmt"
 	"reflect"
-	"strconv"
-	"strings"
-	"time"
 )
 
 // Default creates config source that fills config with default values
@@ -23,6 +20,13 @@ func (d *def) Scan(p interface{}) error {
 	return d.describe(v.Elem())
 }
 
+func (d *def) Origin(fields []reflect.StructField) string {
+	if fields[len(fields)-1].Tag.Get("default") == "" {
+		return ""
+	}
+	return "default"
+}
+
 func (d *def) describe(v reflect.Value) error {
 	for i := 0; i < v.NumField(); i++ {
 
@@ -30,7 +34,7 @@ func (d *def) describe(v reflect.Value) error {
 		tf := v.Type().Field(i)
 		tag := tf.Tag.Get("default")
 
-		if vf.Kind() == reflect.Struct {
+		if nested(vf) {
 			err := d.describe(vf)
 			if err != nil {
 				return err
@@ -43,46 +47,8 @@ func (d *def) describe(v reflect.Value) error {
 			continue
 		}
 
-		switch vf.Kind() {
-
-		case reflect.String:
-			vf.SetString(val)
-
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			if vf.Kind() == reflect.Int64 && vf.Type() == reflect.TypeOf(time.Nanosecond) {
-				v, err := time.ParseDuration(val)
-				if err != nil {
-					return err
-				}
-				vf.Set(reflect.ValueOf(v))
-				continue
-			}
-
-			i, err := strconv.ParseInt(val, 10, 64)
-			if err != nil {
-				return err
-			}
-			vf.SetInt(i)
-
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			u, err := strconv.ParseUint(val, 10, 64)
-			if err != nil {
-				return err
-			}
-			vf.SetUint(u)
-
-		case reflect.Float32, reflect.Float64:
-			f, err := strconv.ParseFloat(val, 64)
-			if err != nil {
-				return err
-			}
-			vf.SetFloat(f)
-
-		case reflect.Bool:
-			vf.SetBool(strings.ToLower(val) == "true")
-
-		default:
-			return fmt.Errorf("unsupported type: %q", vf.Kind())
+		if err := set(vf, val); err != nil {
+			return err
 		}
 
 	}
@@ -4,89 +4,124 @@ import (
 	"fmt"
 	"os"
 	"reflect"
-	"strconv"
 	"strings"
-	"time"
+	"unicode"
 )
 
-// Env creates config source that fills config from environment variables
-func Env() ConfigSource {
-	return &env{}
+// Env creates config source that fills config from environment variables named by env tags.
+// Slices are set from comma-separated values and maps from k1=v1,k2=v2 pairs.
+func Env(options ...envOption) ConfigSource {
+	e := env{lookup: os.Getenv}
+	for _, option := range options {
+		option(&e)
+	}
+	return &e
+}
+
+// EnvMap creates config source that fills config from vars like Env does from environment
+func EnvMap(vars map[string]string, options ...envOption) ConfigSource {
+	e := env{lookup: func(name string) string { return vars[name] }}
+	for _, option := range options {
+		option(&e)
+	}
+	return &e
 }
 
-type env struct{}
+type envOption = func(e *env)
+
+// WithPrefix prepends prefix to names of variables, e.g. "MYAPP_". Fields without env tag are
+// filled too, their names are derived from path of yaml tags or field names, so Log.MaxSize
+// is set with MYAPP_LOG_MAX_SIZE. Use `env:"-"` to skip field.
+func WithPrefix(prefix string) envOption {
+	return func(e *env) {
+		e.prefix = prefix
+	}
+}
+
+type env struct {
+	lookup func(string) string
+	prefix string
+}
 
 func (e *env) Scan(p interface{}) error {
 	v := reflect.ValueOf(p)
 	if v.Kind() != reflect.Ptr || v.IsNil() {
 		return fmt.Errorf("unexpected kind: %q", v.Kind())
 	}
-	return e.describe(v.Elem())
+	return e.describe(v.Elem(), nil)
 }
 
-func (e *env) describe(v reflect.Value) error {
+func (e *env) describe(v reflect.Value, path []string) error {
 	for i := 0; i < v.NumField(); i++ {
 
 		vf := v.Field(i)
 		tf := v.Type().Field(i)
 		tag := tf.Tag.Get("env")
+		if tag == "-" || !tf.IsExported() {
+			continue
+		}
+		fieldPath := append(path[:len(path):len(path)], envName(tf))
 
-		if vf.Kind() == reflect.Struct {
-			err := e.describe(vf)
+		if nested(vf) {
+			err := e.describe(vf, fieldPath)
 			if err != nil {
 				return err
 			}
 			continue
 		}
 
-		val := os.Getenv(tag)
-		if val == "" {
+		name := tag
+		if name == "" && e.prefix != "" {
+			name = strings.Join(fieldPath, "_")
+		}
+		if name == "" {
 			continue
 		}
 
-		switch vf.Kind() {
-
-		case reflect.String:
-			vf.SetString(val)
-
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			if vf.Kind() == reflect.Int64 && vf.Type() == reflect.TypeOf(time.Nanosecond) {
-				v, err := time.ParseDuration(val)
-				if err != nil {
-					return err
-				}
-				vf.Set(reflect.ValueOf(v))
-				continue
-			}
-
-			i, err := strconv.ParseInt(val, 10, 64)
-			if err != nil {
-				return err
-			}
-			vf.SetInt(i)
+		val := e.lookup(e.prefix + name)
+		if val == "" {
+			continue
+		}
 
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			u, err := strconv.ParseUint(val, 10, 64)
-			if err != nil {
-				return err
-			}
-			vf.SetUint(u)
+		if err := set(vf, val); err != nil {
+			return fmt.Errorf("%s: %w", e.prefix+name, err)
+		}
 
-		case reflect.Float32, reflect.Float64:
-			f, err := strconv.ParseFloat(val, 64)
-			if err != nil {
-				return err
-			}
-			vf.SetFloat(f)
+	}
 
-		case reflect.Bool:
-			vf.SetBool(strings.ToLower(val) == "true")
+	return nil
+}
 
-		default:
-			return fmt.Errorf("unsupported type: %q", vf.Kind())
+func (e *env) Origin(fields []reflect.StructField) string {
+	path := make([]string, len(fields))
+	for i, f := range fields {
+		if f.Tag.Get("env") == "-" {
+			return ""
 		}
-
+		path[i] = envName(f)
+	}
+	name := fields[len(fields)-1].Tag.Get("env")
+	if name == "" && e.prefix != "" {
+		name = strings.Join(path, "_")
+	}
+	if name == "" || e.lookup(e.prefix+name) == "" {
+		return ""
 	}
+	return "env " + e.prefix + name
+}
 
-	return nil
+// envName returns upper snake case of yaml tag or field name, e.g. MAX_POOL_SIZE for MaxPoolSize
+func envName(f reflect.StructField) string {
+	if n, _, _ := strings.Cut(f.Tag.Get("yaml"), ","); n != "" && n != "-" {
+		return strings.ToUpper(n)
+	}
+	var b strings.Builder
+	runes := []rune(f.Name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
 }
@@ -0,0 +1,127 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Etcd creates config source that fills config with etcd keys of prefix, see remote for layout.
+// Endpoints are like "http://localhost:2379" and are tried in order, etcd is read by its JSON gateway
+// and changes are watched by its watch stream.
+func Etcd(endpoints []string, keyPrefix string, options ...remoteOption) ConfigSource {
+	e := etcd{remote: newRemote(keyPrefix, "Authorization", options)}
+	for _, endpoint := range endpoints {
+		if !strings.Contains(endpoint, "://") {
+			endpoint = "http://" + endpoint
+		}
+		e.endpoints = append(e.endpoints, strings.TrimSuffix(endpoint, "/"))
+	}
+	return &e
+}
+
+type etcd struct {
+	remote
+	endpoints []string
+}
+
+type etcdKV struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value,omitempty"`
+}
+
+func (e *etcd) String() string { return "etcd " + e.prefix }
+
+func (e *etcd) Scan(p interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+
+	var resp struct{ Kvs []etcdKV }
+	if err := e.call(ctx, "/v3/kv/range", e.rangeRequest(), &resp); err != nil {
+		return fmt.Errorf("etcd: %w", err)
+	}
+	kvs := make(map[string][]byte, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		kvs[string(kv.Key)] = kv.Value
+	}
+	return e.scan(p, kvs)
+}
+
+func (e *etcd) Watch(ctx context.Context, changed func()) {
+	body, _ := json.Marshal(map[string]interface{}{"create_request": e.rangeRequest()})
+	for {
+		for _, endpoint := range e.endpoints {
+			if err := e.watch(ctx, endpoint, string(body), changed); err == nil || ctx.Err() != nil {
+				break
+			}
+		}
+		if !retry(ctx) {
+			return
+		}
+	}
+}
+
+// watch reads watch stream of endpoint until it ends, error means endpoint is not reached
+func (e *etcd) watch(ctx context.Context, endpoint, body string, changed func()) error {
+	resp, err := e.request(ctx, http.MethodPost, endpoint+"/v3/watch", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// changes made before stream is created are picked by rescan
+	changed()
+
+	d := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Result struct {
+				Events   []json.RawMessage `json:"events"`
+				Canceled bool              `json:"canceled"`
+			} `json:"result"`
+		}
+		if err := d.Decode(&msg); err != nil || msg.Result.Canceled {
+			return nil
+		}
+		if len(msg.Result.Events) > 0 {
+			changed()
+		}
+	}
+}
+
+// rangeRequest covers document key of prefix and keys under it
+func (e *etcd) rangeRequest() map[string][]byte {
+	key := []byte(e.prefix)
+	end := append([]byte(nil), key...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			end = end[:i+1]
+			return map[string][]byte{"key": key, "range_end": end}
+		}
+	}
+	return map[string][]byte{"key": key, "range_end": {0}}
+}
+
+// call posts request to endpoints until one of them answers
+func (e *etcd) call(ctx context.Context, path string, req, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	err = errors.New("no endpoints")
+	for _, endpoint := range e.endpoints {
+		var r *http.Response
+		if r, err = e.request(ctx, http.MethodPost, endpoint+path, string(body)); err != nil {
+			continue
+		}
+		err = json.NewDecoder(r.Body).Decode(resp)
+		r.Body.Close()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/242617/core/config/source"
+)
+
+// DotEnv creates config source that fills env-tagged fields with KEY=VALUE pairs of file,
+// e.g. ".env" of local development. Values may be quoted, lines starting with # are comments.
+// Missing file is skipped, so the same setup works where variables are set by environment.
+func DotEnv(file string, options ...dotEnvOption) source.ConfigSource {
+	d := dotEnv{file: file}
+	for _, option := range options {
+		option(&d)
+	}
+	return &d
+}
+
+type dotEnvOption = func(d *dotEnv)
+
+// WithProcessEnv loads variables into process environment instead of filling fields,
+// variables already set are kept, so Env source added afterwards sees both
+func WithProcessEnv() dotEnvOption {
+	return func(d *dotEnv) {
+		d.setenv = true
+	}
+}
+
+type dotEnv struct {
+	file   string
+	setenv bool
+
+	mu   sync.Mutex
+	vars map[string]string
+}
+
+func (d *dotEnv) Scan(p interface{}) error {
+	barr, err := os.ReadFile(d.file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	vars, err := parseDotEnv(barr)
+	if err != nil {
+		return fmt.Errorf("%s: %w", d.file, err)
+	}
+
+	if !d.setenv {
+		d.mu.Lock()
+		d.vars = vars
+		d.mu.Unlock()
+		return source.EnvMap(vars).Scan(p)
+	}
+	for key, value := range vars {
+		if _, ok := os.LookupEnv(key); ok {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *dotEnv) Path() string { return d.file }
+
+// Origin names variable of file setting field, variables loaded into environment are told by Env
+func (d *dotEnv) Origin(fields []reflect.StructField) string {
+	d.mu.Lock()
+	vars := d.vars
+	d.mu.Unlock()
+	name, ok := strings.CutPrefix(origin(source.EnvMap(vars), fields), "env ")
+	if !ok {
+		return ""
+	}
+	return "file " + d.file + " " + name
+}
+
+func parseDotEnv(barr []byte) (map[string]string, error) {
+	vars := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(barr))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", n)
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case strings.HasPrefix(value, `"`):
+			end := closingQuote(value)
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quote", n)
+			}
+			unquoted, err := strconv.Unquote(value[:end+1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			value = unquoted
+		case strings.HasPrefix(value, "'"):
+			end := strings.IndexByte(value[1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quote", n)
+			}
+			value = value[1 : end+1]
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		vars[key] = value
+	}
+	return vars, scanner.Err()
+}
+
+// closingQuote returns index of double quote closing the one value starts with
+func closingQuote(value string) int {
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,98 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/242617/core/config/source"
+)
+
+type expandOption = func(e *expansion)
+
+// WithStrictExpansion makes Scan fail if ${VAR} without default refers to variable which is not set,
+// by default it is expanded to empty string
+func WithStrictExpansion() expandOption {
+	return func(e *expansion) {
+		e.strict = true
+	}
+}
+
+// expansion replaces ${VAR} and ${VAR:-default} in string values of config file with environment
+// variables, default is used if variable is not set or empty
+type expansion struct{ strict bool }
+
+func newExpansion(options []expandOption) expansion {
+	var e expansion
+	for _, option := range options {
+		option(&e)
+	}
+	return e
+}
+
+// tree expands string values of decoded file, expanded values looking like numbers or booleans
+// are converted, so they fill fields of those types as if they were written in file
+func (e expansion) tree(v interface{}) (interface{}, error) {
+	var err error
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		for key, value := range v {
+			if v[key], err = e.tree(value); err != nil {
+				return nil, fmt.Errorf("%v: %w", key, err)
+			}
+		}
+	case map[string]interface{}:
+		for key, value := range v {
+			if v[key], err = e.tree(value); err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			if v[i], err = e.tree(value); err != nil {
+				return nil, err
+			}
+		}
+	case string:
+		if !strings.Contains(v, "${") {
+			return v, nil
+		}
+		s, err := e.string(v)
+		if err != nil {
+			return nil, err
+		}
+		return source.Scalar(s), nil
+	}
+	return v, nil
+}
+
+func (e expansion) string(s string) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated variable in %q", s)
+		}
+		b.WriteString(s[:start])
+
+		name, def, hasDefault := strings.Cut(s[start+2:start+end], ":-")
+		if name == "" {
+			return "", fmt.Errorf("empty variable name in %q", s)
+		}
+		value, ok := os.LookupEnv(name)
+		switch {
+		case value != "":
+		case hasDefault:
+			value = def
+		case !ok && e.strict:
+			return "", fmt.Errorf("variable %s is not set", name)
+		}
+		b.WriteString(value)
+		s = s[start+end+1:]
+	}
+}
@@ -0,0 +1,76 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+
+	yaml2 "gopkg.in/yaml.v2"
+
+	"github.com/242617/core/config/source"
+)
+
+// JSON creates config source that fills config with values from json-file. Fields are matched
+// by yaml tags and durations like "5s" are parsed, so the same config struct serves both formats.
+// Variables in string values are expanded like in YAML.
+func JSON(file string, options ...expandOption) source.ConfigSource {
+	return &jsonFile{file: file, expand: newExpansion(options)}
+}
+
+type jsonFile struct {
+	file   string
+	expand expansion
+	lines  lines
+}
+
+func (j *jsonFile) Scan(p interface{}) error {
+	barr, err := os.ReadFile(j.file)
+	if err != nil {
+		return err
+	}
+
+	j.lines.parse(barr)
+
+	dec := json.NewDecoder(bytes.NewReader(barr))
+	dec.UseNumber()
+	var v interface{}
+	if err = dec.Decode(&v); err != nil {
+		return err
+	}
+
+	if v, err = j.expand.tree(v); err != nil {
+		return err
+	}
+
+	// values are passed through yaml to keep semantics of YAML source
+	if barr, err = yaml2.Marshal(numbers(v)); err != nil {
+		return err
+	}
+	return yaml2.Unmarshal(barr, p)
+}
+
+// numbers replaces json numbers with integers or floats, so they are not quoted by yaml
+func numbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = numbers(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = numbers(value)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
+
+func (j *jsonFile) Path() string { return j.file }
+
+func (j *jsonFile) Origin(fields []reflect.StructField) string { return j.lines.origin(j.file, fields) }
@@ -0,0 +1,119 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/242617/core/config/source"
+)
+
+// YAMLGlob creates config source that fills config with yaml-files matching pattern in lexical order,
+// e.g. "conf.d/*.yaml" with 00-base.yaml and 10-production.yaml, so later files override earlier ones.
+// Pattern matching no files is not an error. Directories matching directory part of pattern
+// are watched, directories created after Watch starts are not.
+func YAMLGlob(pattern string, options ...expandOption) source.ConfigSource {
+	return &yamlGlob{pattern: pattern, options: options}
+}
+
+type yamlGlob struct {
+	pattern string
+	options []expandOption
+
+	mu      sync.Mutex
+	scanned []source.ConfigSource
+}
+
+func (g *yamlGlob) Scan(p interface{}) error {
+	files, err := filepath.Glob(g.pattern)
+	if err != nil {
+		return err
+	}
+	scanned := make([]source.ConfigSource, 0, len(files))
+	for _, file := range files {
+		s := YAML(file, g.options...)
+		if err := s.Scan(p); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		scanned = append(scanned, s)
+	}
+	g.mu.Lock()
+	g.scanned = scanned
+	g.mu.Unlock()
+	return nil
+}
+
+// Origin returns origin of the last file setting field
+func (g *yamlGlob) Origin(fields []reflect.StructField) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i := len(g.scanned) - 1; i >= 0; i-- {
+		if o := origin(g.scanned[i], fields); o != "" {
+			return o
+		}
+	}
+	return ""
+}
+
+// Dirs returns directories matching directory part of pattern
+func (g *yamlGlob) Dirs() ([]string, error) {
+	dir := filepath.Dir(g.pattern)
+	if !strings.ContainsAny(dir, `*?[\`) {
+		return []string{dir}, nil
+	}
+	return filepath.Glob(dir)
+}
+
+// Optional makes source of file not failing if file does not exist, e.g. local override
+func Optional(s source.ConfigSource) source.ConfigSource {
+	switch s := s.(type) {
+	case source.FileSource:
+		return &optionalFile{s}
+	case source.DirSource:
+		return &optionalDir{s}
+	}
+	return &optional{s}
+}
+
+type optional struct{ source.ConfigSource }
+
+func (o *optional) Scan(p interface{}) error {
+	if err := o.ConfigSource.Scan(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (o *optional) Origin(fields []reflect.StructField) string { return origin(o.ConfigSource, fields) }
+
+type optionalFile struct{ source.FileSource }
+
+func (o *optionalFile) Scan(p interface{}) error {
+	return (&optional{o.FileSource}).Scan(p)
+}
+
+func (o *optionalFile) Origin(fields []reflect.StructField) string {
+	return origin(o.FileSource, fields)
+}
+
+type optionalDir struct{ source.DirSource }
+
+func (o *optionalDir) Scan(p interface{}) error {
+	return (&optional{o.DirSource}).Scan(p)
+}
+
+func (o *optionalDir) Origin(fields []reflect.StructField) string {
+	return origin(o.DirSource, fields)
+}
+
+// origin returns origin of field in s if s tells it
+func origin(s source.ConfigSource, fields []reflect.StructField) string {
+	if o, ok := s.(source.Origin); ok {
+		return o.Origin(fields)
+	}
+	return ""
+}
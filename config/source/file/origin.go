@@ -0,0 +1,66 @@
+package file
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// lines keeps positions of keys of last scanned yaml or json document for Origin
+type lines struct {
+	mu   sync.Mutex
+	root *yaml3.Node
+}
+
+func (l *lines) parse(barr []byte) {
+	var root yaml3.Node
+	if err := yaml3.Unmarshal(barr, &root); err != nil || len(root.Content) == 0 {
+		l.store(nil)
+		return
+	}
+	l.store(root.Content[0])
+}
+
+func (l *lines) store(root *yaml3.Node) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.root = root
+}
+
+// origin returns "file <name>:<line>" of key of field or empty string if document has no such key
+func (l *lines) origin(file string, fields []reflect.StructField) string {
+	l.mu.Lock()
+	node := l.root
+	l.mu.Unlock()
+
+	line := 0
+	for _, f := range fields {
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		for node != nil && node.Kind == yaml3.AliasNode {
+			node = node.Alias
+		}
+		if node == nil || node.Kind != yaml3.MappingNode {
+			return ""
+		}
+		var value *yaml3.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == name {
+				line, value = node.Content[i].Line, node.Content[i+1]
+			}
+		}
+		if value == nil {
+			return ""
+		}
+		node = value
+	}
+	return fmt.Sprintf("file %s:%d", file, line)
+}
@@ -1,19 +1,26 @@
 package file
 
 import (
+	"bytes"
 	"io/ioutil"
+	"reflect"
 
 	yaml2 "gopkg.in/yaml.v2"
 
 	"github.com/242617/core/config/source"
 )
 
-// YAML creates config source that fills config with values from yaml-file
-func YAML(file string) source.ConfigSource {
-	return &yaml{file}
+// YAML creates config source that fills config with values from yaml-file,
+// ${VAR} and ${VAR:-default} in string values are expanded with environment variables
+func YAML(file string, options ...expandOption) source.ConfigSource {
+	return &yaml{file: file, expand: newExpansion(options)}
 }
 
-type yaml struct{ file string }
+type yaml struct {
+	file   string
+	expand expansion
+	lines  lines
+}
 
 func (y *yaml) Scan(p interface{}) error {
 	barr, err := ioutil.ReadFile(y.file)
@@ -21,9 +28,28 @@ func (y *yaml) Scan(p interface{}) error {
 		return err
 	}
 
+	y.lines.parse(barr)
+
+	if bytes.Contains(barr, []byte("${")) {
+		var v interface{}
+		if err = yaml2.Unmarshal(barr, &v); err != nil {
+			return err
+		}
+		if v, err = y.expand.tree(v); err != nil {
+			return err
+		}
+		if barr, err = yaml2.Marshal(v); err != nil {
+			return err
+		}
+	}
+
 	if err = yaml2.Unmarshal(barr, p); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func (y *yaml) Path() string { return y.file }
+
+func (y *yaml) Origin(fields []reflect.StructField) string { return y.lines.origin(y.file, fields) }
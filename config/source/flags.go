@@ -0,0 +1,127 @@
+package source
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Flags creates config source that fills config from command-line flags named by flag tags,
+// e.g. `flag:"listen"` is set with -listen=:8080. Only flags passed in args are applied, so
+// adding source last makes flags override files and environment. Args are os.Args[1:] if none are passed.
+// Flags not tagged in config, like -test.v of go test, are skipped along with their values
+// unless set with "=", so values of skipped boolean flags must not be followed by other arguments.
+func Flags(args ...string) ConfigSource {
+	if len(args) == 0 {
+		args = os.Args[1:]
+	}
+	return &flags{args: args}
+}
+
+type flags struct {
+	args    []string
+	visited map[string]bool
+}
+
+func (f *flags) Scan(p interface{}) error {
+	v := reflect.ValueOf(p)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("unexpected kind: %q", v.Kind())
+	}
+
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	values := map[string]*flagValue{}
+	if err := f.describe(fs, values, v.Elem()); err != nil {
+		return err
+	}
+	if err := fs.Parse(defined(f.args, values)); err != nil {
+		return err
+	}
+
+	var err error
+	f.visited = map[string]bool{}
+	fs.Visit(func(fl *flag.Flag) {
+		f.visited[fl.Name] = true
+		if err == nil {
+			if err = set(values[fl.Name].field, values[fl.Name].value); err != nil {
+				err = fmt.Errorf("flag %q: %w", fl.Name, err)
+			}
+		}
+	})
+	return err
+}
+
+// defined drops flags of args which are not in values
+func defined(args []string, values map[string]*flagValue) []string {
+	var res []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || len(arg) < 2 || arg[0] != '-' {
+			return append(res, args[i:]...)
+		}
+		name, _, hasValue := strings.Cut(strings.TrimPrefix(arg[1:], "-"), "=")
+		if _, ok := values[name]; ok {
+			res = append(res, arg)
+			continue
+		}
+		if !hasValue && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			i++
+		}
+	}
+	return res
+}
+
+func (f *flags) Origin(fields []reflect.StructField) string {
+	name := fields[len(fields)-1].Tag.Get("flag")
+	if name == "" || !f.visited[name] {
+		return ""
+	}
+	return "flag -" + name
+}
+
+func (f *flags) describe(fs *flag.FlagSet, values map[string]*flagValue, v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+
+		vf := v.Field(i)
+		tf := v.Type().Field(i)
+		tag := tf.Tag.Get("flag")
+
+		if nested(vf) {
+			err := f.describe(fs, values, vf)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if tag == "" {
+			continue
+		}
+		if _, ok := values[tag]; ok {
+			return fmt.Errorf("duplicate flag: %q", tag)
+		}
+		value := &flagValue{field: vf, bool: vf.Kind() == reflect.Bool}
+		values[tag] = value
+		fs.Var(value, tag, tf.Name)
+	}
+
+	return nil
+}
+
+// flagValue keeps value of flag until parsing succeeds
+type flagValue struct {
+	field reflect.Value
+	value string
+	bool  bool
+}
+
+func (v *flagValue) String() string { return v.value }
+
+func (v *flagValue) Set(s string) error {
+	v.value = s
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool { return v.bool }
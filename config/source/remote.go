@@ -0,0 +1,129 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	yaml2 "gopkg.in/yaml.v2"
+)
+
+// WatchSource is a config source notifying of its changes, they are watched by config Watch
+type WatchSource interface {
+	ConfigSource
+	// Watch calls changed on every change of source until ctx is done
+	Watch(ctx context.Context, changed func())
+}
+
+const (
+	remoteTimeout = 10 * time.Second
+	remoteRetry   = time.Second
+)
+
+type remoteOption = func(r *remote)
+
+// WithToken sets ACL token of Consul or auth token of etcd
+func WithToken(token string) remoteOption {
+	return func(r *remote) {
+		r.token = token
+	}
+}
+
+// WithHTTPClient sets client of key-value store, its timeout should be longer than Consul blocking queries
+func WithHTTPClient(client *http.Client) remoteOption {
+	return func(r *remote) {
+		r.client = client
+	}
+}
+
+// remote is a key-value store source. Value of key equal to prefix is yaml or json document,
+// keys under prefix form a tree like "db/host" fills host of db, each of their values is yaml scalar.
+// Tree is applied after document, so single keys override it.
+type remote struct {
+	prefix      string
+	token       string
+	tokenHeader string
+	client      *http.Client
+}
+
+func newRemote(prefix, tokenHeader string, options []remoteOption) remote {
+	r := remote{prefix: strings.TrimSuffix(prefix, "/"), tokenHeader: tokenHeader, client: http.DefaultClient}
+	for _, option := range options {
+		option(&r)
+	}
+	return r
+}
+
+// scan fills p with keys of store read under prefix
+func (r *remote) scan(p interface{}, kvs map[string][]byte) error {
+	if len(kvs) == 0 {
+		return fmt.Errorf("no keys with prefix %q", r.prefix)
+	}
+
+	if doc, ok := kvs[r.prefix]; ok && len(doc) > 0 {
+		if err := yaml2.Unmarshal(doc, p); err != nil {
+			return fmt.Errorf("%s: %w", r.prefix, err)
+		}
+	}
+
+	tree := map[string]interface{}{}
+	for key, value := range kvs {
+		name, ok := strings.CutPrefix(key, r.prefix+"/")
+		if !ok || name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		node := tree
+		parts := strings.Split(name, "/")
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				node[part] = child
+			}
+			node = child
+		}
+		if _, ok := node[parts[len(parts)-1]].(map[string]interface{}); !ok {
+			node[parts[len(parts)-1]] = Scalar(string(value))
+		}
+	}
+	if len(tree) == 0 {
+		return nil
+	}
+
+	barr, err := yaml2.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return yaml2.Unmarshal(barr, p)
+}
+
+func (r *remote) request(ctx context.Context, method, url string, body string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if r.token != "" {
+		req.Header.Set(r.tokenHeader, r.token)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status: %q", resp.Status)
+	}
+	return resp, nil
+}
+
+// retry waits before next attempt to watch, false means ctx is done
+func retry(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(remoteRetry):
+		return true
+	}
+}
@@ -1,6 +1,204 @@
 package source
 
+import (
+	"encoding"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml2 "gopkg.in/yaml.v2"
+)
+
 // ConfigSource is an interface for config source
 type ConfigSource interface {
 	Scan(p interface{}) error
 }
+
+// Origin is implemented by sources telling where they take value of field from, e.g. "env TIMEOUT".
+// Fields chain from config struct to the field, empty origin means source does not set it.
+type Origin interface {
+	Origin(fields []reflect.StructField) string
+}
+
+// Leaf is a field of config filled by sources, Fields chain from config struct to it
+type Leaf struct {
+	Fields []reflect.StructField
+	Value  reflect.Value
+}
+
+// Leaves returns exported fields of struct v filled by sources, nested structs are walked
+func Leaves(v reflect.Value) []Leaf {
+	return leaves(v, nil, nil)
+}
+
+func leaves(v reflect.Value, path []reflect.StructField, result []Leaf) []Leaf {
+	for i := 0; i < v.NumField(); i++ {
+		vf := v.Field(i)
+		tf := v.Type().Field(i)
+		if !tf.IsExported() {
+			continue
+		}
+		fields := append(path[:len(path):len(path)], tf)
+		if nested(vf) {
+			result = leaves(vf, fields, result)
+			continue
+		}
+		result = append(result, Leaf{fields, vf})
+	}
+	return result
+}
+
+// FileSource is a config source read from file, its changes are watched by config Watch
+type FileSource interface {
+	ConfigSource
+	Path() string
+}
+
+// DirSource is a config source read from files of several directories, they are watched by config Watch
+type DirSource interface {
+	ConfigSource
+	Dirs() ([]string, error)
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	urlType             = reflect.TypeOf(url.URL{})
+)
+
+// nested reports whether field is a struct of config fields rather than value set from string
+func nested(vf reflect.Value) bool {
+	return vf.Kind() == reflect.Struct && vf.Type() != urlType && !reflect.PointerTo(vf.Type()).Implements(textUnmarshalerType)
+}
+
+// set parses val into field of kind supported by config sources, slices are comma-separated,
+// maps are k1=v1,k2=v2 or k1=v1;k2=v2 pairs and []byte is base64. Types implementing encoding.TextUnmarshaler
+// like time.Time (RFC 3339) and net.IP parse val themselves.
+func set(vf reflect.Value, val string) error {
+	if vf.CanAddr() {
+		if u, ok := vf.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(val))
+		}
+	}
+
+	switch vf.Kind() {
+
+	case reflect.Ptr:
+		v := reflect.New(vf.Type().Elem())
+		if err := set(v.Elem(), val); err != nil {
+			return err
+		}
+		vf.Set(v)
+
+	case reflect.Struct:
+		if vf.Type() != urlType {
+			return fmt.Errorf("unsupported type: %q", vf.Type())
+		}
+		u, err := url.Parse(val)
+		if err != nil {
+			return err
+		}
+		vf.Set(reflect.ValueOf(*u))
+
+	case reflect.String:
+		vf.SetString(val)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if vf.Kind() == reflect.Int64 && vf.Type() == reflect.TypeOf(time.Nanosecond) {
+			v, err := time.ParseDuration(val)
+			if err != nil {
+				return err
+			}
+			vf.Set(reflect.ValueOf(v))
+			return nil
+		}
+
+		i, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return err
+		}
+		vf.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			return err
+		}
+		vf.SetUint(u)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return err
+		}
+		vf.SetFloat(f)
+
+	case reflect.Bool:
+		vf.SetBool(strings.ToLower(val) == "true")
+
+	case reflect.Slice:
+		if vf.Type().Elem().Kind() == reflect.Uint8 {
+			b, err := base64.StdEncoding.DecodeString(val)
+			if err != nil {
+				return err
+			}
+			vf.SetBytes(b)
+			return nil
+		}
+		parts := strings.Split(val, ",")
+		s := reflect.MakeSlice(vf.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := set(s.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		vf.Set(s)
+
+	case reflect.Map:
+		sep := ","
+		if strings.Contains(val, ";") {
+			sep = ";"
+		}
+		parts := strings.Split(val, sep)
+		m := reflect.MakeMapWithSize(vf.Type(), len(parts))
+		for _, part := range parts {
+			k, v, ok := strings.Cut(part, "=")
+			if !ok {
+				return fmt.Errorf("invalid map entry: %q", part)
+			}
+			key, value := reflect.New(vf.Type().Key()).Elem(), reflect.New(vf.Type().Elem()).Elem()
+			if err := set(key, strings.TrimSpace(k)); err != nil {
+				return err
+			}
+			if err := set(value, strings.TrimSpace(v)); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, value)
+		}
+		vf.Set(m)
+
+	default:
+		return fmt.Errorf("unsupported type: %q", vf.Kind())
+	}
+
+	return nil
+}
+
+// Scalar converts string value to number or boolean if yaml writes it back unchanged,
+// so value which is not written in yaml itself fills fields of those types
+func Scalar(s string) interface{} {
+	var v interface{}
+	if err := yaml2.Unmarshal([]byte(s), &v); err != nil {
+		return s
+	}
+	switch v.(type) {
+	case int, int64, uint64, float64, bool:
+		if barr, err := yaml2.Marshal(v); err == nil && strings.TrimSpace(string(barr)) == s {
+			return v
+		}
+	}
+	return s
+}
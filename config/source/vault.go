@@ -0,0 +1,271 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// kubernetesTokenFile is a service account token of pod used by VaultKubernetesAuth
+const kubernetesTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// Vault creates config source that fills fields tagged like `vault:"secret/data/db#password"` with
+// keys of HashiCorp Vault KV secrets, version 2 and 1 are both read. Path is prepended to paths
+// of tags, e.g. Vault(addr, "secret/data", auth) reads `vault:"db#password"` from secret/data/db.
+// Token of auth is renewed on scans once two thirds of its ttl pass, or logged in again if it
+// cannot be renewed.
+func Vault(addr, path string, auth VaultAuth, options ...remoteOption) ConfigSource {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return &vault{
+		remote: newRemote("", "X-Vault-Token", options),
+		addr:   strings.TrimSuffix(addr, "/"),
+		path:   strings.Trim(path, "/"),
+		auth:   auth,
+	}
+}
+
+// VaultAuth is a method of Vault login
+type VaultAuth interface {
+	login(ctx context.Context, v *vault) (vaultToken, error)
+}
+
+type vaultToken struct {
+	token     string
+	ttl       time.Duration
+	renewable bool
+}
+
+type vaultAuthFunc func(ctx context.Context, v *vault) (vaultToken, error)
+
+func (f vaultAuthFunc) login(ctx context.Context, v *vault) (vaultToken, error) { return f(ctx, v) }
+
+// VaultTokenAuth uses token as is, it is renewed if it has ttl and may be looked up
+func VaultTokenAuth(token string) VaultAuth {
+	return vaultAuthFunc(func(ctx context.Context, v *vault) (vaultToken, error) {
+		v.token = token
+		var resp struct {
+			Data struct {
+				TTL       int  `json:"ttl"`
+				Renewable bool `json:"renewable"`
+			} `json:"data"`
+		}
+		if err := v.call(ctx, http.MethodGet, "auth/token/lookup-self", nil, &resp); err != nil {
+			return vaultToken{token: token}, nil
+		}
+		return vaultToken{token, time.Duration(resp.Data.TTL) * time.Second, resp.Data.Renewable}, nil
+	})
+}
+
+// VaultAppRoleAuth logs in with role and secret ids of AppRole auth mounted at approle
+func VaultAppRoleAuth(roleID, secretID string) VaultAuth {
+	return vaultAuthFunc(func(ctx context.Context, v *vault) (vaultToken, error) {
+		return v.login(ctx, "auth/approle/login", map[string]string{"role_id": roleID, "secret_id": secretID})
+	})
+}
+
+// VaultKubernetesAuth logs in as role of Kubernetes auth mounted at kubernetes
+// with service account token of pod
+func VaultKubernetesAuth(role string) VaultAuth {
+	return vaultAuthFunc(func(ctx context.Context, v *vault) (vaultToken, error) {
+		jwt, err := os.ReadFile(kubernetesTokenFile)
+		if err != nil {
+			return vaultToken{}, err
+		}
+		return v.login(ctx, "auth/kubernetes/login", map[string]string{"role": role, "jwt": strings.TrimSpace(string(jwt))})
+	})
+}
+
+type vault struct {
+	remote
+	addr string
+	path string
+	auth VaultAuth
+
+	mu      sync.Mutex
+	renewAt time.Time
+	expires time.Time
+	current vaultToken
+}
+
+func (v *vault) Scan(p interface{}) error {
+	rv := reflect.ValueOf(p)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("unexpected kind: %q", rv.Kind())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	secrets := map[string]map[string]interface{}{}
+	return v.describe(ctx, secrets, rv.Elem())
+}
+
+func (v *vault) describe(ctx context.Context, secrets map[string]map[string]interface{}, rv reflect.Value) error {
+	for i := 0; i < rv.NumField(); i++ {
+
+		vf := rv.Field(i)
+		tf := rv.Type().Field(i)
+		tag := tf.Tag.Get("vault")
+
+		if nested(vf) {
+			if err := v.describe(ctx, secrets, vf); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if tag == "" {
+			continue
+		}
+		path, key, ok := strings.Cut(tag, "#")
+		if !ok || path == "" || key == "" {
+			return fmt.Errorf("invalid vault tag: %q", tag)
+		}
+		if v.path != "" {
+			path = v.path + "/" + strings.TrimPrefix(path, "/")
+		}
+
+		secret, ok := secrets[path]
+		if !ok {
+			var err error
+			if secret, err = v.read(ctx, path); err != nil {
+				return fmt.Errorf("vault %s: %w", path, err)
+			}
+			secrets[path] = secret
+		}
+		value, ok := secret[key]
+		if !ok {
+			return fmt.Errorf("vault %s: no key %q", path, key)
+		}
+		s, ok := value.(string)
+		if !ok {
+			s = fmt.Sprint(value)
+		}
+		if err := set(vf, s); err != nil {
+			return fmt.Errorf("vault %s#%s: %w", path, key, err)
+		}
+	}
+
+	return nil
+}
+
+func (v *vault) Origin(fields []reflect.StructField) string {
+	path, key, ok := strings.Cut(fields[len(fields)-1].Tag.Get("vault"), "#")
+	if !ok {
+		return ""
+	}
+	if v.path != "" {
+		path = v.path + "/" + strings.TrimPrefix(path, "/")
+	}
+	return "vault " + path + "#" + key
+}
+
+// read returns data of secret, token is renewed or obtained first if needed
+func (v *vault) read(ctx context.Context, path string) (map[string]interface{}, error) {
+	if err := v.authenticate(ctx); err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := v.call(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		// token may be revoked, so next scan logs in again
+		v.current = vaultToken{}
+		return nil, err
+	}
+	if resp.Data == nil {
+		return nil, errors.New("not found")
+	}
+	// KV version 2 keeps secret in data with metadata next to it
+	if data, ok := resp.Data["data"].(map[string]interface{}); ok {
+		if _, ok := resp.Data["metadata"]; ok {
+			return data, nil
+		}
+	}
+	return resp.Data, nil
+}
+
+func (v *vault) authenticate(ctx context.Context) error {
+	now := time.Now()
+	if v.current.token != "" && (v.current.ttl == 0 || now.Before(v.renewAt)) {
+		return nil
+	}
+	if v.current.token != "" && v.current.renewable && now.Before(v.expires) {
+		var resp struct {
+			Auth struct {
+				LeaseDuration int  `json:"lease_duration"`
+				Renewable     bool `json:"renewable"`
+			} `json:"auth"`
+		}
+		if err := v.call(ctx, http.MethodPost, "auth/token/renew-self", struct{}{}, &resp); err == nil {
+			v.issued(vaultToken{v.current.token, time.Duration(resp.Auth.LeaseDuration) * time.Second, resp.Auth.Renewable})
+			return nil
+		}
+	}
+
+	v.token = ""
+	token, err := v.auth.login(ctx, v)
+	if err != nil {
+		v.current = vaultToken{}
+		return fmt.Errorf("login: %w", err)
+	}
+	v.issued(token)
+	return nil
+}
+
+func (v *vault) issued(token vaultToken) {
+	now := time.Now()
+	v.current, v.token = token, token.token
+	v.renewAt = now.Add(token.ttl * 2 / 3)
+	v.expires = now.Add(token.ttl)
+}
+
+// login posts credentials to auth method and returns token it issues
+func (v *vault) login(ctx context.Context, path string, credentials map[string]string) (vaultToken, error) {
+	var resp struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+			Renewable     bool   `json:"renewable"`
+		} `json:"auth"`
+	}
+	if err := v.call(ctx, http.MethodPost, path, credentials, &resp); err != nil {
+		return vaultToken{}, err
+	}
+	if resp.Auth.ClientToken == "" {
+		return vaultToken{}, errors.New("no token issued")
+	}
+	return vaultToken{resp.Auth.ClientToken, time.Duration(resp.Auth.LeaseDuration) * time.Second, resp.Auth.Renewable}, nil
+}
+
+// call sends request to path of Vault API, req is encoded as json body unless it is nil
+func (v *vault) call(ctx context.Context, method, path string, req, resp interface{}) error {
+	var body string
+	if req != nil {
+		barr, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		body = string(barr)
+	}
+	r, err := v.request(ctx, method, v.addr+"/v1/"+path, body)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if r.StatusCode == http.StatusNotFound {
+		return errors.New("not found")
+	}
+	return json.NewDecoder(r.Body).Decode(resp)
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/conc"
+	"github.com/242617/core/config/source"
+)
+
+// watchDelay groups bursts of file events, e.g. editor writing file in several steps
+const watchDelay = 100 * time.Millisecond
+
+// Watch scans config into target and then rescans sources into fresh value of the same type
+// once directories of file and dir sources or watch sources like Consul change, until ctx is done.
+// onChange is called with pointers to previous and new values if they differ, error returned
+// by it rejects new value.
+// Target itself is not modified after first scan, use Swap to publish new values atomically.
+func (c *config) Watch(ctx context.Context, target interface{}, onChange func(old, next interface{}) error) error {
+	if err := c.Scan(target); err != nil {
+		return err
+	}
+
+	dirs := map[string]bool{}
+	var watched []source.WatchSource
+	for _, s := range c.sources {
+		switch s := s.(type) {
+		case source.FileSource:
+			dirs[filepath.Dir(s.Path())] = true
+		case source.DirSource:
+			matched, err := s.Dirs()
+			if err != nil {
+				return errors.Wrap(err, "watched dirs")
+			}
+			for _, dir := range matched {
+				dirs[dir] = true
+			}
+		case source.WatchSource:
+			watched = append(watched, s)
+		}
+	}
+	if len(dirs) == 0 && len(watched) == 0 {
+		return errors.New("no watched sources")
+	}
+	var watcher *fsnotify.Watcher
+	if len(dirs) > 0 {
+		var err error
+		if watcher, err = fsnotify.NewWatcher(); err != nil {
+			return errors.Wrap(err, "new watcher")
+		}
+		for dir := range dirs {
+			if err := watcher.Add(dir); err != nil {
+				watcher.Close()
+				return errors.Wrapf(err, "watch %s", dir)
+			}
+		}
+	}
+
+	t := reflect.TypeOf(target).Elem()
+	current := reflect.New(t)
+	current.Elem().Set(reflect.ValueOf(target).Elem())
+	var mu sync.Mutex
+	reload := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		next := reflect.New(t)
+		if err := c.Scan(next.Interface()); err != nil {
+			c.origins.forget(next.Interface())
+			l.Warn().Err(err).Msg("reload config")
+			return
+		}
+		if reflect.DeepEqual(current.Interface(), next.Interface()) {
+			c.origins.forget(next.Interface())
+			return
+		}
+		if err := onChange(current.Interface(), next.Interface()); err != nil {
+			c.origins.forget(next.Interface())
+			l.Warn().Err(err).Msg("config change rejected")
+			return
+		}
+		// origins of target stay, only previous reloaded value is forgotten
+		c.origins.forget(current.Interface())
+		current = next
+	}
+	changed := conc.Debounce(ctx, watchDelay, conc.Trailing, reload)
+
+	for _, s := range watched {
+		go s.Watch(ctx, changed)
+	}
+	if watcher == nil {
+		return nil
+	}
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				changed()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				l.Warn().Err(err).Msg("watch config")
+			}
+		}
+	}()
+	return nil
+}
+
+// Swap returns onChange callback of Watch storing new value in p, so readers load it atomically
+func Swap[T any](p *atomic.Pointer[T]) func(old, next interface{}) error {
+	return func(_, next interface{}) error {
+		p.Store(next.(*T))
+		return nil
+	}
+}
@@ -0,0 +1,168 @@
+package coretest
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Container is a docker container living until the end of a test
+type Container struct {
+	ID    string
+	Host  string
+	ports map[string]string
+}
+
+// Addr returns host address of published container port, e.g. "5432/tcp"
+func (c *Container) Addr(port string) string {
+	return net.JoinHostPort(c.Host, c.ports[port])
+}
+
+// Exec runs command inside container
+func (c *Container) Exec(ctx context.Context, cmd ...string) (string, error) {
+	return docker(ctx, append([]string{"exec", c.ID}, cmd...)...)
+}
+
+// Logs returns container output
+func (c *Container) Logs(ctx context.Context) (string, error) {
+	return docker(ctx, "logs", c.ID)
+}
+
+// Request describes container to run
+type Request struct {
+	Image string
+	Env   map[string]string
+	Cmd   []string
+	// Ports are container ports to publish on random host ports
+	Ports []string
+	// FixedPorts maps host ports to container ports for services which advertise their address
+	FixedPorts map[string]string
+	// Ready is polled until it succeeds or Timeout elapses
+	Ready   func(ctx context.Context, c *Container) error
+	Timeout time.Duration
+}
+
+// Run starts container and removes it on test cleanup.
+// Test is skipped when docker is not available.
+func Run(t testing.TB, req Request) *Container {
+	t.Helper()
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker is not available")
+	}
+	ctx := context.Background()
+	if _, err := docker(ctx, "info"); err != nil {
+		t.Skip("docker is not available")
+	}
+
+	args := []string{"run", "-d", "--rm", "--label", "coretest=" + t.Name()}
+	for k, v := range req.Env {
+		args = append(args, "-e", k+"="+v)
+	}
+	for _, port := range req.Ports {
+		args = append(args, "-p", "127.0.0.1::"+port)
+	}
+	for host, port := range req.FixedPorts {
+		args = append(args, "-p", "127.0.0.1:"+host+":"+port)
+	}
+	args = append(args, req.Image)
+	args = append(args, req.Cmd...)
+
+	id, err := docker(ctx, args...)
+	if err != nil {
+		t.Fatalf("run %s: %v", req.Image, err)
+	}
+	c := &Container{ID: strings.TrimSpace(id), Host: "127.0.0.1", ports: map[string]string{}}
+	t.Cleanup(func() { _, _ = docker(context.Background(), "rm", "-f", "-v", c.ID) })
+
+	for _, port := range req.Ports {
+		out, err := docker(ctx, "port", c.ID, port)
+		if err != nil {
+			t.Fatalf("port %s: %v", port, err)
+		}
+		_, hostPort, err := net.SplitHostPort(strings.TrimSpace(strings.Split(out, "\n")[0]))
+		if err != nil {
+			t.Fatalf("parse port %q: %v", out, err)
+		}
+		c.ports[port] = hostPort
+	}
+	for host, port := range req.FixedPorts {
+		c.ports[port] = host
+	}
+
+	if req.Ready != nil {
+		if err := wait(ctx, c, req.Ready, req.Timeout); err != nil {
+			logs, _ := c.Logs(ctx)
+			t.Fatalf("%s is not ready: %v\n%s", req.Image, err, logs)
+		}
+	}
+	return c
+}
+
+// ExecReady waits for command inside container to succeed
+func ExecReady(cmd ...string) func(context.Context, *Container) error {
+	return func(ctx context.Context, c *Container) error {
+		_, err := c.Exec(ctx, cmd...)
+		return err
+	}
+}
+
+// LogReady waits for container output to contain s
+func LogReady(s string) func(context.Context, *Container) error {
+	return func(ctx context.Context, c *Container) error {
+		logs, err := c.Logs(ctx)
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(logs, s) {
+			return errors.Errorf("no %q in logs", s)
+		}
+		return nil
+	}
+}
+
+// FreePort returns port not used on host
+func FreePort(t testing.TB) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	return port
+}
+
+func wait(ctx context.Context, c *Container, ready func(context.Context, *Container) error, timeout time.Duration) error {
+	if timeout == 0 {
+		timeout = time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		err := ready(ctx, c)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+func docker(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", errors.Wrapf(err, "docker %s: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
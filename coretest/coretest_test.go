@@ -0,0 +1,26 @@
+package coretest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/coretest"
+)
+
+func TestRedis(t *testing.T) {
+	if testing.Short() {
+		t.Skip("short mode")
+	}
+	cfg := coretest.Redis(t)
+	client := redis.NewClient(&redis.Options{Addr: cfg.Addrs[0]})
+	defer client.Close()
+	require.NoError(t, client.Ping(context.Background()).Err(), "ping")
+}
+
+func TestFreePort(t *testing.T) {
+	assert.NotEqual(t, coretest.FreePort(t), "0", "port allocated")
+}
@@ -0,0 +1,90 @@
+package coretest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/242617/core/mongorepo"
+	"github.com/242617/core/redisrepo"
+)
+
+var (
+	PostgresImage = "postgres:16-alpine"
+	RedisImage    = "redis:7-alpine"
+	MongoImage    = "mongo:7"
+	KafkaImage    = "apache/kafka:3.7.0"
+)
+
+// Postgres starts postgres and returns its DSN
+func Postgres(t testing.TB) string {
+	t.Helper()
+	c := Run(t, Request{
+		Image: PostgresImage,
+		Env:   map[string]string{"POSTGRES_USER": "test", "POSTGRES_PASSWORD": "test", "POSTGRES_DB": "test"},
+		Ports: []string{"5432/tcp"},
+		// temporary server started by init scripts does not listen on tcp
+		Ready: ExecReady("pg_isready", "-h", "127.0.0.1", "-U", "test", "-d", "test"),
+	})
+	return fmt.Sprintf("postgres://test:test@%s/test?sslmode=disable", c.Addr("5432/tcp"))
+}
+
+// Redis starts redis and returns config for redisrepo
+func Redis(t testing.TB) redisrepo.Config {
+	t.Helper()
+	c := Run(t, Request{
+		Image: RedisImage,
+		Ports: []string{"6379/tcp"},
+		Ready: ExecReady("redis-cli", "ping"),
+	})
+	return redisrepo.Config{
+		Mode:         redisrepo.ModeSingle,
+		Addrs:        []string{c.Addr("6379/tcp")},
+		DialTimeout:  5 * time.Second,
+		ReadTimeout:  3 * time.Second,
+		WriteTimeout: 3 * time.Second,
+	}
+}
+
+// Mongo starts mongodb and returns config for mongorepo
+func Mongo(t testing.TB) mongorepo.Config {
+	t.Helper()
+	c := Run(t, Request{
+		Image: MongoImage,
+		Ports: []string{"27017/tcp"},
+		Ready: ExecReady("mongosh", "--quiet", "--eval", "db.runCommand({ping: 1})"),
+	})
+	return mongorepo.Config{
+		URI:                    "mongodb://" + c.Addr("27017/tcp"),
+		Database:               "test",
+		ConnectTimeout:         5 * time.Second,
+		ServerSelectionTimeout: 5 * time.Second,
+	}
+}
+
+// Kafka starts single node kafka in KRaft mode and returns bootstrap brokers.
+// Host port is fixed since broker advertises it to clients.
+func Kafka(t testing.TB) []string {
+	t.Helper()
+	port := FreePort(t)
+	Run(t, Request{
+		Image: KafkaImage,
+		Env: map[string]string{
+			"KAFKA_NODE_ID":                                  "1",
+			"KAFKA_PROCESS_ROLES":                            "broker,controller",
+			"KAFKA_LISTENERS":                                "PLAINTEXT://:9092,CONTROLLER://:9093",
+			"KAFKA_ADVERTISED_LISTENERS":                     "PLAINTEXT://127.0.0.1:" + port,
+			"KAFKA_CONTROLLER_LISTENER_NAMES":                "CONTROLLER",
+			"KAFKA_LISTENER_SECURITY_PROTOCOL_MAP":           "CONTROLLER:PLAINTEXT,PLAINTEXT:PLAINTEXT",
+			"KAFKA_CONTROLLER_QUORUM_VOTERS":                 "1@localhost:9093",
+			"KAFKA_OFFSETS_TOPIC_REPLICATION_FACTOR":         "1",
+			"KAFKA_TRANSACTION_STATE_LOG_REPLICATION_FACTOR": "1",
+			"KAFKA_TRANSACTION_STATE_LOG_MIN_ISR":            "1",
+			"KAFKA_GROUP_INITIAL_REBALANCE_DELAY_MS":         "0",
+		},
+		FixedPorts: map[string]string{port: "9092/tcp"},
+		Ready:      LogReady("Kafka Server started"),
+		Timeout:    2 * time.Minute,
+	})
+	return []string{"127.0.0.1:" + port}
+}
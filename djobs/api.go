@@ -0,0 +1,141 @@
+package djobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrUnknownJob = errors.New("unknown job")
+
+// Trigger requests manual run of job, it is picked up by leader on next poll
+func (j *Jobs) Trigger(ctx context.Context, name string) error {
+	res, err := j.db.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET triggered = true WHERE name = $1", j.cfg.Table), name)
+	if err != nil {
+		return errors.Wrap(err, "update")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrUnknownJob
+	}
+	return nil
+}
+
+type JobInfo struct {
+	Name      string     `json:"name"`
+	NextRunAt time.Time  `json:"next_run_at"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	Triggered bool       `json:"triggered"`
+}
+
+// List returns schedules of all jobs stored in db
+func (j *Jobs) List(ctx context.Context) ([]JobInfo, error) {
+	rows, err := j.db.QueryContext(ctx, fmt.Sprintf("SELECT name, next_run_at, last_run_at, triggered FROM %s ORDER BY name", j.cfg.Table))
+	if err != nil {
+		return nil, errors.Wrap(err, "select")
+	}
+	defer rows.Close()
+
+	var infos []JobInfo
+	for rows.Next() {
+		var info JobInfo
+		if err := rows.Scan(&info.Name, &info.NextRunAt, &info.LastRunAt, &info.Triggered); err != nil {
+			return nil, errors.Wrap(err, "scan")
+		}
+		infos = append(infos, info)
+	}
+	return infos, errors.Wrap(rows.Err(), "rows")
+}
+
+type RunInfo struct {
+	ID          int64      `json:"id"`
+	Job         string     `json:"job"`
+	ScheduledAt time.Time  `json:"scheduled_at"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	Manual      bool       `json:"manual"`
+}
+
+// Runs returns latest runs of job, newest first
+func (j *Jobs) Runs(ctx context.Context, name string, limit int) ([]RunInfo, error) {
+	query := fmt.Sprintf("SELECT id, job, scheduled_at, started_at, finished_at, status, error, manual FROM %s WHERE job = $1 ORDER BY id DESC LIMIT $2", j.cfg.RunsTable)
+	rows, err := j.db.QueryContext(ctx, query, name, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "select")
+	}
+	defer rows.Close()
+
+	var runs []RunInfo
+	for rows.Next() {
+		var r RunInfo
+		if err := rows.Scan(&r.ID, &r.Job, &r.ScheduledAt, &r.StartedAt, &r.FinishedAt, &r.Status, &r.Error, &r.Manual); err != nil {
+			return nil, errors.Wrap(err, "scan")
+		}
+		runs = append(runs, r)
+	}
+	return runs, errors.Wrap(rows.Err(), "rows")
+}
+
+// Handler serves jobs API, e.g. to be mounted by adminserver.WithHandler:
+//
+//	GET  /jobs                     lists jobs
+//	GET  /jobs/{name}/runs?limit=  lists latest runs
+//	POST /jobs/{name}/trigger      requests manual run
+func (j *Jobs) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /jobs", func(w http.ResponseWriter, r *http.Request) {
+		infos, err := j.List(r.Context())
+		if err != nil {
+			j.fail(w, err)
+			return
+		}
+		respond(w, http.StatusOK, infos)
+	})
+	mux.HandleFunc("GET /jobs/{name}/runs", func(w http.ResponseWriter, r *http.Request) {
+		limit := 20
+		if s := r.URL.Query().Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 {
+				respond(w, http.StatusBadRequest, map[string]string{"error": "invalid limit"})
+				return
+			}
+			limit = n
+		}
+		runs, err := j.Runs(r.Context(), r.PathValue("name"), limit)
+		if err != nil {
+			j.fail(w, err)
+			return
+		}
+		respond(w, http.StatusOK, runs)
+	})
+	mux.HandleFunc("POST /jobs/{name}/trigger", func(w http.ResponseWriter, r *http.Request) {
+		err := j.Trigger(r.Context(), r.PathValue("name"))
+		if errors.Is(err, ErrUnknownJob) {
+			respond(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			j.fail(w, err)
+			return
+		}
+		respond(w, http.StatusAccepted, map[string]string{"status": "triggered"})
+	})
+	return mux
+}
+
+func (j *Jobs) fail(w http.ResponseWriter, err error) {
+	j.log.Error().Err(err).Msg("api")
+	respond(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+}
+
+func respond(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,530 @@
+package djobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/lock"
+	"github.com/242617/core/protocol"
+	"github.com/242617/core/scheduler"
+)
+
+const (
+	MetricRunsTotal   = "djobs_runs_total"
+	MetricRunDuration = "djobs_run_duration_seconds"
+	MetricMissedTotal = "djobs_missed_total"
+	MetricLeader      = "djobs_leader"
+)
+
+const (
+	StatusRunning = "running"
+	StatusOK      = "ok"
+	StatusError   = "error"
+)
+
+// Schema creates tables with default names expected by jobs
+const Schema = `CREATE TABLE IF NOT EXISTS djobs (
+	name TEXT PRIMARY KEY,
+	next_run_at TIMESTAMPTZ NOT NULL,
+	last_run_at TIMESTAMPTZ,
+	triggered BOOLEAN NOT NULL DEFAULT false
+);
+CREATE TABLE IF NOT EXISTS djob_runs (
+	id BIGSERIAL PRIMARY KEY,
+	job TEXT NOT NULL REFERENCES djobs (name) ON DELETE CASCADE,
+	scheduled_at TIMESTAMPTZ NOT NULL,
+	started_at TIMESTAMPTZ NOT NULL,
+	finished_at TIMESTAMPTZ,
+	status TEXT NOT NULL,
+	error TEXT NOT NULL DEFAULT '',
+	manual BOOLEAN NOT NULL DEFAULT false
+);
+CREATE INDEX IF NOT EXISTS djob_runs_job_idx ON djob_runs (job, id DESC)`
+
+// CatchUp defines what to do with activations missed while no instance was leading
+type CatchUp int
+
+const (
+	// CatchUpOnce runs job once for all missed activations
+	CatchUpOnce CatchUp = iota
+	// CatchUpAll runs job for every missed activation in order
+	CatchUpAll
+	// CatchUpSkip drops missed activations and waits for the next one
+	CatchUpSkip
+)
+
+// maxCatchUp limits activations run by CatchUpAll at once, older ones are counted as missed
+const maxCatchUp = 1000
+
+// never is stored as next run of job whose schedule has no more activations
+var never = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+// Run describes single execution passed to job
+type Run struct {
+	Job         string
+	ScheduledAt time.Time
+	Manual      bool
+}
+
+type Job struct {
+	Name     string
+	Schedule scheduler.Schedule
+	Func     func(ctx context.Context, run Run) error
+	// Timeout limits duration of a single run, zero means no limit
+	Timeout time.Duration
+	CatchUp CatchUp
+}
+
+type Config struct {
+	Table     string `yaml:"table"`
+	RunsTable string `yaml:"runs_table"`
+	// PollInterval is interval of due jobs lookup and leadership attempts
+	PollInterval time.Duration `yaml:"poll_interval"`
+}
+
+type option = func(j *Jobs) error
+
+func withDefaults() option {
+	return func(j *Jobs) error {
+		j.name = "djobs"
+		j.cfg = Config{Table: "djobs", RunsTable: "djob_runs", PollInterval: time.Second}
+		j.log = l.With().Str("component", "djobs").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(j *Jobs) error {
+		j.name = name
+		return nil
+	}
+}
+
+func WithConfig(cfg Config) option {
+	return func(j *Jobs) error {
+		if cfg.Table != "" {
+			j.cfg.Table = cfg.Table
+		}
+		if cfg.RunsTable != "" {
+			j.cfg.RunsTable = cfg.RunsTable
+		}
+		if cfg.PollInterval > 0 {
+			j.cfg.PollInterval = cfg.PollInterval
+		}
+		return nil
+	}
+}
+
+func WithJob(job Job) option {
+	return func(j *Jobs) error { return j.Add(job) }
+}
+
+// WithLocker sets locker electing leader, postgres advisory lock over db is used by default
+func WithLocker(locker lock.Locker) option {
+	return func(j *Jobs) error {
+		j.locker = locker
+		return nil
+	}
+}
+
+func WithMetrics(recorder protocol.MetricsRecorder) option {
+	return func(j *Jobs) error {
+		j.metrics = recorder
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(j *Jobs) error {
+		j.log = log
+		return nil
+	}
+}
+
+// New creates distributed cron component. Schedules and run history are stored in db,
+// jobs are run by a single leader instance elected among ones sharing db.
+func New(db *sql.DB, options ...option) (*Jobs, error) {
+	j := Jobs{db: db, running: map[string]bool{}}
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&j); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	if j.locker == nil {
+		locker, err := lock.NewPostgres(db)
+		if err != nil {
+			return nil, errors.Wrap(err, "new locker")
+		}
+		j.locker = locker
+	}
+	return &j, nil
+}
+
+type Jobs struct {
+	name    string
+	db      *sql.DB
+	cfg     Config
+	locker  lock.Locker
+	metrics protocol.MetricsRecorder
+	log     zerolog.Logger
+
+	mu      sync.Mutex
+	jobs    []Job
+	running map[string]bool
+	started bool
+	stop    context.CancelFunc
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// Add adds job, jobs can't be added after start
+func (j *Jobs) Add(job Job) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.started {
+		return errors.New("already started")
+	}
+	if job.Name == "" || job.Schedule == nil || job.Func == nil {
+		return errors.New("job must have name, schedule and func")
+	}
+	if now := time.Now(); !job.Schedule.Next(now).After(now) {
+		return errors.Errorf("job %q: schedule has no activations", job.Name)
+	}
+	if _, ok := j.job(job.Name); ok {
+		return errors.Errorf("job %q already added", job.Name)
+	}
+	j.jobs = append(j.jobs, job)
+	return nil
+}
+
+func (j *Jobs) job(name string) (Job, bool) {
+	for _, job := range j.jobs {
+		if job.Name == name {
+			return job, true
+		}
+	}
+	return Job{}, false
+}
+
+// Start registers schedules of new jobs and starts competing for leadership
+func (j *Jobs) Start(ctx context.Context) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.started {
+		return errors.New("already started")
+	}
+	query := fmt.Sprintf("INSERT INTO %s (name, next_run_at) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING", j.cfg.Table)
+	for _, job := range j.jobs {
+		next := job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			return errors.Errorf("job %q: schedule has no activations", job.Name)
+		}
+		if _, err := j.db.ExecContext(ctx, query, job.Name, next); err != nil {
+			return errors.Wrapf(err, "register job %q", job.Name)
+		}
+	}
+
+	var loopCtx context.Context
+	loopCtx, j.stop = context.WithCancel(context.Background())
+	j.done = make(chan struct{})
+	j.started = true
+	go j.lead(loopCtx)
+	return nil
+}
+
+// Stop resigns leadership and waits for running jobs, their context is cancelled
+func (j *Jobs) Stop(ctx context.Context) error {
+	j.mu.Lock()
+	if !j.started {
+		j.mu.Unlock()
+		return nil
+	}
+	j.started = false
+	j.mu.Unlock()
+
+	j.stop()
+	select {
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "wait for jobs")
+	case <-j.done:
+	}
+	return nil
+}
+
+func (j *Jobs) String() string { return j.name }
+
+func (j *Jobs) lead(ctx context.Context) {
+	defer close(j.done)
+	ticker := time.NewTicker(j.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		lk, err := j.locker.TryLock(ctx, "djobs:"+j.cfg.Table)
+		switch {
+		case err == nil:
+			j.log.Info().Msg("became leader")
+			j.set(MetricLeader, 1)
+			j.serve(ctx, lk)
+			j.set(MetricLeader, 0)
+			if err := lk.Unlock(context.Background()); err != nil {
+				j.log.Warn().Err(err).Msg("unlock")
+			}
+		case errors.Is(err, lock.ErrNotAcquired):
+		case ctx.Err() == nil:
+			j.log.Error().Err(err).Msg("lock")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// serve dispatches due jobs while leadership is held
+func (j *Jobs) serve(ctx context.Context, lk *lock.Lock) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		j.wg.Wait()
+	}()
+
+	ticker := time.NewTicker(j.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := j.dispatch(ctx, false); err != nil && ctx.Err() == nil {
+			j.log.Error().Err(err).Msg("dispatch")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-lk.Lost():
+			j.log.Warn().Msg("leadership lost")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue runs due and triggered jobs waiting for them to finish.
+// It doesn't check leadership, so it is meant for tests and one-off invocations.
+func (j *Jobs) RunDue(ctx context.Context) error {
+	return j.dispatch(ctx, true)
+}
+
+type due struct {
+	job       Job
+	nextRunAt time.Time
+	triggered bool
+}
+
+func (j *Jobs) dispatch(ctx context.Context, wait bool) error {
+	rows, err := j.db.QueryContext(ctx, fmt.Sprintf("SELECT name, next_run_at, triggered FROM %s WHERE next_run_at <= $1 OR triggered", j.cfg.Table), time.Now())
+	if err != nil {
+		return errors.Wrap(err, "select due jobs")
+	}
+	var dues []due
+	for rows.Next() {
+		var (
+			d    due
+			name string
+		)
+		if err := rows.Scan(&name, &d.nextRunAt, &d.triggered); err != nil {
+			rows.Close()
+			return errors.Wrap(err, "scan")
+		}
+		j.mu.Lock()
+		job, ok := j.job(name)
+		j.mu.Unlock()
+		if ok {
+			d.job = job
+			dues = append(dues, d)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(err, "rows")
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range dues {
+		j.mu.Lock()
+		if j.running[d.job.Name] {
+			j.mu.Unlock()
+			continue
+		}
+		j.running[d.job.Name] = true
+		j.mu.Unlock()
+
+		j.wg.Add(1)
+		wg.Add(1)
+		go func(d due) {
+			defer j.wg.Done()
+			defer wg.Done()
+			defer func() {
+				j.mu.Lock()
+				delete(j.running, d.job.Name)
+				j.mu.Unlock()
+			}()
+			j.execute(ctx, d)
+		}(d)
+	}
+	if wait {
+		wg.Wait()
+	}
+	return nil
+}
+
+func (j *Jobs) execute(ctx context.Context, d due) {
+	job, now := d.job, time.Now()
+	if d.triggered {
+		query := fmt.Sprintf("UPDATE %s SET triggered = false WHERE name = $1", j.cfg.Table)
+		// trigger is kept on failure, so manual run is retried by next dispatch while scheduled one goes on
+		if _, err := j.db.ExecContext(ctx, query, job.Name); err != nil {
+			j.log.Error().Err(err).Str("job", job.Name).Msg("reset trigger")
+		} else {
+			j.run(ctx, job, Run{Job: job.Name, ScheduledAt: now, Manual: true})
+		}
+	}
+	if d.nextRunAt.After(now) {
+		return
+	}
+
+	// latest activations are kept in ring, oldest one is at head once it is full
+	ring := make([]time.Time, 0, maxCatchUp)
+	head, dropped := 0, 0
+	for t := d.nextRunAt; !t.After(now); {
+		if len(ring) < maxCatchUp {
+			ring = append(ring, t)
+		} else {
+			ring[head] = t
+			head = (head + 1) % maxCatchUp
+			dropped++
+		}
+		next := job.Schedule.Next(t)
+		if !next.After(t) {
+			break
+		}
+		t = next
+	}
+	if dropped > 0 {
+		j.missed(job, dropped)
+	}
+	activations := append(ring[head:], ring[:head]...)
+	next := job.Schedule.Next(activations[len(activations)-1])
+	if !next.After(activations[len(activations)-1]) {
+		j.log.Warn().Str("job", job.Name).Msg("no more activations")
+		next = never
+	}
+	switch job.CatchUp {
+	case CatchUpOnce:
+		j.missed(job, len(activations)-1)
+		activations = activations[len(activations)-1:]
+	case CatchUpSkip:
+		// activation is considered missed if it is late more than poll interval
+		last := activations[len(activations)-1]
+		if now.Sub(last) > j.cfg.PollInterval {
+			j.missed(job, len(activations))
+			j.advance(ctx, job, next, false)
+			return
+		}
+		j.missed(job, len(activations)-1)
+		activations = activations[len(activations)-1:]
+	}
+
+	for i, t := range activations {
+		if ctx.Err() != nil {
+			return
+		}
+		j.run(ctx, job, Run{Job: job.Name, ScheduledAt: t})
+		// schedule is advanced after every run, so new leader continues catching up
+		if i < len(activations)-1 {
+			j.advance(ctx, job, activations[i+1], true)
+		} else {
+			j.advance(ctx, job, next, true)
+		}
+	}
+}
+
+func (j *Jobs) advance(ctx context.Context, job Job, next time.Time, ran bool) {
+	query := fmt.Sprintf("UPDATE %s SET next_run_at = $2 WHERE name = $1", j.cfg.Table)
+	if ran {
+		query = fmt.Sprintf("UPDATE %s SET next_run_at = $2, last_run_at = now() WHERE name = $1", j.cfg.Table)
+	}
+	if _, err := j.db.ExecContext(context.WithoutCancel(ctx), query, job.Name, next); err != nil {
+		j.log.Error().Err(err).Str("job", job.Name).Msg("advance schedule")
+	}
+}
+
+func (j *Jobs) run(ctx context.Context, job Job, run Run) {
+	start := time.Now()
+	var id int64
+	query := fmt.Sprintf("INSERT INTO %s (job, scheduled_at, started_at, status, manual) VALUES ($1, $2, $3, '%s', $4) RETURNING id",
+		j.cfg.RunsTable, StatusRunning)
+	if err := j.db.QueryRowContext(ctx, query, job.Name, run.ScheduledAt, start, run.Manual).Scan(&id); err != nil {
+		j.log.Error().Err(err).Str("job", job.Name).Msg("record run")
+		return
+	}
+
+	runCtx := ctx
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+	err := j.call(runCtx, job, run)
+	duration := time.Since(start)
+
+	status, message := StatusOK, ""
+	event := j.log.Info()
+	if err != nil {
+		status, message = StatusError, err.Error()
+		event = j.log.Error().Err(err)
+	}
+	event.Str("job", job.Name).Time("scheduled_at", run.ScheduledAt).Bool("manual", run.Manual).Dur("duration", duration).Msg("run")
+
+	// results are stored even if run is interrupted
+	query = fmt.Sprintf("UPDATE %s SET finished_at = $2, status = $3, error = $4 WHERE id = $1", j.cfg.RunsTable)
+	if _, err := j.db.ExecContext(context.WithoutCancel(ctx), query, id, time.Now(), status, message); err != nil {
+		j.log.Error().Err(err).Str("job", job.Name).Msg("store run")
+	}
+
+	if j.metrics != nil {
+		j.metrics.Add(MetricRunsTotal, 1, "job", job.Name, "status", status)
+		j.metrics.Observe(MetricRunDuration, duration.Seconds(), "job", job.Name)
+	}
+}
+
+func (j *Jobs) call(ctx context.Context, job Job, run Run) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			j.log.Error().Interface("panic", v).Bytes("stack", debug.Stack()).Msg("recovered")
+			err = errors.Errorf("panic: %v", v)
+		}
+	}()
+	return job.Func(ctx, run)
+}
+
+func (j *Jobs) missed(job Job, n int) {
+	if n > 0 && j.metrics != nil {
+		j.metrics.Add(MetricMissedTotal, float64(n), "job", job.Name)
+	}
+}
+
+func (j *Jobs) set(name string, value float64) {
+	if j.metrics != nil {
+		j.metrics.Set(name, value, "name", j.name)
+	}
+}
@@ -0,0 +1,306 @@
+package djobs_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/djobs"
+	"github.com/242617/core/scheduler"
+)
+
+var period = 10 * time.Millisecond
+
+type withRecorder struct {
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func (r *withRecorder) Add(name string, delta float64, _ ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.values == nil {
+		r.values = map[string]float64{}
+	}
+	r.values[name] += delta
+}
+func (r *withRecorder) Set(string, float64, ...string)     {}
+func (r *withRecorder) Observe(string, float64, ...string) {}
+
+func (r *withRecorder) Value(name string) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.values[name]
+}
+
+type journal struct {
+	mu   sync.Mutex
+	runs []djobs.Run
+}
+
+func (j *journal) Func(_ context.Context, run djobs.Run) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.runs = append(j.runs, run)
+	return nil
+}
+
+func expectRun(mock sqlmock.Sqlmock, id int64) {
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO djob_runs (job, scheduled_at, started_at, status, manual) VALUES ($1, $2, $3, 'running', $4) RETURNING id")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE djob_runs SET finished_at = $2, status = $3, error = $4 WHERE id = $1")).
+		WithArgs(id, sqlmock.AnyArg(), djobs.StatusOK, "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestCatchUp(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		catchUp djobs.CatchUp
+		runs    int
+		missed  float64
+	}{
+		{"all", djobs.CatchUpAll, 3, 0},
+		{"once", djobs.CatchUpOnce, 1, 2},
+		{"skip", djobs.CatchUpSkip, 0, 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err, "new mock")
+			defer db.Close()
+
+			var (
+				j        journal
+				recorder withRecorder
+			)
+			jobs, err := djobs.New(db,
+				djobs.WithJob(djobs.Job{Name: "report", Schedule: scheduler.Every(time.Minute), Func: j.Func, CatchUp: tc.catchUp}),
+				djobs.WithMetrics(&recorder),
+			)
+			require.NoError(t, err, "new")
+
+			first := time.Now().Add(-150 * time.Second)
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT name, next_run_at, triggered FROM djobs WHERE next_run_at <= $1 OR triggered")).
+				WillReturnRows(sqlmock.NewRows([]string{"name", "next_run_at", "triggered"}).AddRow("report", first, false))
+			for i := 0; i < tc.runs; i++ {
+				expectRun(mock, int64(i+1))
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE djobs SET next_run_at = $2, last_run_at = now() WHERE name = $1")).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			if tc.runs == 0 {
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE djobs SET next_run_at = $2 WHERE name = $1")).
+					WithArgs("report", first.Add(3*time.Minute)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			require.NoError(t, jobs.RunDue(context.Background()), "run due")
+			assert.NoError(t, mock.ExpectationsWereMet(), "expectations")
+			require.Len(t, j.runs, tc.runs, "runs")
+			if tc.runs > 0 {
+				assert.True(t, j.runs[tc.runs-1].ScheduledAt.Equal(first.Add(2*time.Minute)), "latest activation")
+			}
+			assert.Equal(t, tc.missed, recorder.Value(djobs.MetricMissedTotal), "missed")
+			assert.Equal(t, float64(tc.runs), recorder.Value(djobs.MetricRunsTotal), "runs metric")
+		})
+	}
+}
+
+func TestCatchUpLimit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "new mock")
+	defer db.Close()
+
+	var (
+		j        journal
+		recorder withRecorder
+	)
+	jobs, err := djobs.New(db,
+		djobs.WithJob(djobs.Job{Name: "report", Schedule: scheduler.Every(time.Minute), Func: j.Func}),
+		djobs.WithMetrics(&recorder),
+	)
+	require.NoError(t, err, "new")
+
+	first := time.Now().Add(-2000*time.Minute - 30*time.Second)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT name, next_run_at, triggered FROM djobs WHERE next_run_at <= $1 OR triggered")).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "next_run_at", "triggered"}).AddRow("report", first, false))
+	expectRun(mock, 1)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE djobs SET next_run_at = $2, last_run_at = now() WHERE name = $1")).
+		WithArgs("report", first.Add(2001*time.Minute)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, jobs.RunDue(context.Background()), "run due")
+	assert.NoError(t, mock.ExpectationsWereMet(), "expectations")
+	require.Len(t, j.runs, 1, "runs")
+	assert.True(t, j.runs[0].ScheduledAt.Equal(first.Add(2000*time.Minute)), "latest activation")
+	assert.Equal(t, float64(2000), recorder.Value(djobs.MetricMissedTotal), "missed")
+}
+
+func TestTriggerResetFailed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "new mock")
+	defer db.Close()
+
+	var j journal
+	jobs, err := djobs.New(db, djobs.WithJob(djobs.Job{Name: "report", Schedule: scheduler.Every(time.Hour), Func: j.Func}))
+	require.NoError(t, err, "new")
+
+	first := time.Now().Add(-time.Minute)
+	mock.ExpectQuery("SELECT name, next_run_at, triggered").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "next_run_at", "triggered"}).AddRow("report", first, true))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE djobs SET triggered = false WHERE name = $1")).
+		WithArgs("report").
+		WillReturnError(errors.New("sample error"))
+	expectRun(mock, 1)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE djobs SET next_run_at = $2, last_run_at = now() WHERE name = $1")).
+		WithArgs("report", first.Add(time.Hour)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, jobs.RunDue(context.Background()), "run due")
+	require.Len(t, j.runs, 1, "scheduled run")
+	assert.False(t, j.runs[0].Manual, "manual run is left for next dispatch")
+	assert.NoError(t, mock.ExpectationsWereMet(), "expectations")
+}
+
+func TestTrigger(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "new mock")
+	defer db.Close()
+
+	var j journal
+	jobs, err := djobs.New(db, djobs.WithJob(djobs.Job{Name: "report", Schedule: scheduler.Every(time.Hour), Func: j.Func}))
+	require.NoError(t, err, "new")
+	h := jobs.Handler()
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE djobs SET triggered = true WHERE name = $1")).
+		WithArgs("report").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE djobs SET triggered = true WHERE name = $1")).
+		WithArgs("unknown").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/jobs/report/trigger", nil))
+	assert.Equal(t, http.StatusAccepted, w.Code, "triggered")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/jobs/unknown/trigger", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code, "unknown job")
+
+	mock.ExpectQuery("SELECT name, next_run_at, triggered").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "next_run_at", "triggered"}).AddRow("report", time.Now().Add(time.Hour), true))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE djobs SET triggered = false WHERE name = $1")).
+		WithArgs("report").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectRun(mock, 1)
+
+	require.NoError(t, jobs.RunDue(context.Background()), "run due")
+	require.Len(t, j.runs, 1, "run")
+	assert.True(t, j.runs[0].Manual, "manual")
+	assert.NoError(t, mock.ExpectationsWereMet(), "expectations")
+}
+
+func TestRuns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "new mock")
+	defer db.Close()
+
+	jobs, err := djobs.New(db)
+	require.NoError(t, err, "new")
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT id, job, scheduled_at, started_at, finished_at, status, error, manual FROM djob_runs").
+		WithArgs("report", 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "job", "scheduled_at", "started_at", "finished_at", "status", "error", "manual"}).
+			AddRow(2, "report", now, now, nil, djobs.StatusRunning, "", false).
+			AddRow(1, "report", now, now, now, djobs.StatusError, "sample error", true))
+
+	w := httptest.NewRecorder()
+	jobs.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/jobs/report/runs?limit=5", nil))
+	assert.Equal(t, http.StatusOK, w.Code, "status")
+	assert.Contains(t, w.Body.String(), `"error":"sample error"`, "history")
+	assert.NoError(t, mock.ExpectationsWereMet(), "expectations")
+}
+
+func TestStartStop(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "new mock")
+	defer db.Close()
+
+	var j journal
+	jobs, err := djobs.New(db,
+		djobs.WithJob(djobs.Job{Name: "report", Schedule: scheduler.Every(time.Hour), Func: j.Func}),
+		djobs.WithConfig(djobs.Config{PollInterval: time.Hour}),
+	)
+	require.NoError(t, err, "new")
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO djobs (name, next_run_at) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING")).
+		WithArgs("report", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_try_advisory_lock($1)")).
+		WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(false))
+
+	require.NoError(t, jobs.Start(context.Background()), "start")
+	assert.Eventually(t, func() bool { return mock.ExpectationsWereMet() == nil }, 10*period, period, "follower")
+	assert.Error(t, jobs.Add(djobs.Job{Name: "late", Schedule: scheduler.Every(time.Hour), Func: j.Func}), "add after start")
+	assert.NoError(t, jobs.Stop(context.Background()), "stop")
+}
+
+// until is a schedule every minute which has no activations after end
+type until struct{ end time.Time }
+
+func (u *until) Next(t time.Time) time.Time {
+	if next := t.Add(time.Minute); !next.After(u.end) {
+		return next
+	}
+	return time.Time{}
+}
+
+func TestInvalidSchedule(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err, "new mock")
+	defer db.Close()
+
+	var j journal
+	jobs, err := djobs.New(db)
+	require.NoError(t, err, "new")
+	assert.Error(t, jobs.Add(djobs.Job{Name: "zero", Schedule: scheduler.Every(0), Func: j.Func}), "zero interval")
+	assert.Error(t, jobs.Add(djobs.Job{Name: "ended", Schedule: &until{time.Now()}, Func: j.Func}), "no activations")
+}
+
+func TestScheduleEnds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "new mock")
+	defer db.Close()
+
+	var j journal
+	schedule := &until{time.Now().Add(time.Hour)}
+	jobs, err := djobs.New(db,
+		djobs.WithJob(djobs.Job{Name: "report", Schedule: schedule, Func: j.Func, CatchUp: djobs.CatchUpAll}),
+	)
+	require.NoError(t, err, "new")
+	first := time.Now().Add(-90 * time.Second)
+	schedule.end = first.Add(time.Minute)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT name, next_run_at, triggered FROM djobs WHERE next_run_at <= $1 OR triggered")).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "next_run_at", "triggered"}).AddRow("report", first, false))
+	expectRun(mock, 1)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE djobs SET next_run_at = $2, last_run_at = now() WHERE name = $1")).
+		WithArgs("report", first.Add(time.Minute)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectRun(mock, 2)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE djobs SET next_run_at = $2, last_run_at = now() WHERE name = $1")).
+		WithArgs("report", time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, jobs.RunDue(context.Background()), "run due")
+	assert.NoError(t, mock.ExpectationsWereMet(), "expectations")
+	assert.Len(t, j.runs, 2, "runs")
+}
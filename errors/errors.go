@@ -0,0 +1,130 @@
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+)
+
+// Code classifies error independently of transport
+type Code int
+
+const (
+	OK Code = iota
+	Internal
+	Invalid
+	NotFound
+	Conflict
+	Unauthenticated
+	PermissionDenied
+	FailedPrecondition
+	TooManyRequests
+	Unavailable
+	Timeout
+	Canceled
+	Unimplemented
+)
+
+var codeNames = [...]string{
+	OK:                 "ok",
+	Internal:           "internal",
+	Invalid:            "invalid",
+	NotFound:           "not_found",
+	Conflict:           "conflict",
+	Unauthenticated:    "unauthenticated",
+	PermissionDenied:   "permission_denied",
+	FailedPrecondition: "failed_precondition",
+	TooManyRequests:    "too_many_requests",
+	Unavailable:        "unavailable",
+	Timeout:            "timeout",
+	Canceled:           "canceled",
+	Unimplemented:      "unimplemented",
+}
+
+func (c Code) String() string {
+	if c < 0 || int(c) >= len(codeNames) {
+		return fmt.Sprintf("code(%d)", int(c))
+	}
+	return codeNames[c]
+}
+
+// Error carries code and message safe to expose to clients. Cause is kept for logs only.
+type Error struct {
+	Code    Code
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	if e.Message == "" {
+		return e.Err.Error()
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func New(code Code, message string) error {
+	return &Error{Code: code, Message: message}
+}
+
+func Newf(code Code, format string, args ...any) error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap annotates err with code and message, it returns nil if err is nil
+func Wrap(err error, code Code, message string) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+func Wrapf(err error, code Code, format string, args ...any) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+// Is is errors.Is of standard library
+func Is(err, target error) bool { return stderrors.Is(err, target) }
+
+// As is errors.As of standard library
+func As(err error, target any) bool { return stderrors.As(err, target) }
+
+// CodeOf returns code of the outermost Error in chain.
+// Context errors are recognized, any other error is Internal.
+func CodeOf(err error) Code {
+	if err == nil {
+		return OK
+	}
+	var e *Error
+	if stderrors.As(err, &e) {
+		return e.Code
+	}
+	switch {
+	case stderrors.Is(err, context.Canceled):
+		return Canceled
+	case stderrors.Is(err, context.DeadlineExceeded):
+		return Timeout
+	}
+	return Internal
+}
+
+// HasCode reports whether err is classified with code
+func HasCode(err error, code Code) bool { return CodeOf(err) == code }
+
+// MessageOf returns message safe to expose to clients.
+// Internal errors and errors without Error in chain are hidden behind generic text.
+func MessageOf(err error) string {
+	code := CodeOf(err)
+	var e *Error
+	if code == Internal || !stderrors.As(err, &e) || e.Message == "" {
+		return code.String()
+	}
+	return e.Message
+}
@@ -0,0 +1,81 @@
+package errors_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/242617/core/errors"
+)
+
+func TestCodeOf(t *testing.T) {
+	cause := fmt.Errorf("sql: no rows")
+	for _, tc := range []struct {
+		name    string
+		err     error
+		code    errors.Code
+		message string
+	}{
+		{"nil", nil, errors.OK, "ok"},
+		{"plain", cause, errors.Internal, "internal"},
+		{"new", errors.New(errors.NotFound, "user not found"), errors.NotFound, "user not found"},
+		{"wrapped", errors.Wrapf(cause, errors.NotFound, "user %d not found", 1), errors.NotFound, "user 1 not found"},
+		{"annotated", pkgerrors.Wrap(errors.New(errors.Conflict, "exists"), "create"), errors.Conflict, "exists"},
+		{"internal hidden", errors.Wrap(cause, errors.Internal, "query failed"), errors.Internal, "internal"},
+		{"canceled", pkgerrors.Wrap(context.Canceled, "query"), errors.Canceled, "canceled"},
+		{"deadline", context.DeadlineExceeded, errors.Timeout, "timeout"},
+	} {
+		assert.Equal(t, tc.code, errors.CodeOf(tc.err), tc.name)
+		assert.Equal(t, tc.message, errors.MessageOf(tc.err), tc.name)
+	}
+
+	err := errors.Wrap(cause, errors.Invalid, "bad input")
+	assert.True(t, errors.Is(err, cause), "unwraps to cause")
+	assert.True(t, errors.HasCode(err, errors.Invalid), "has code")
+	assert.Equal(t, "bad input: sql: no rows", err.Error(), "error text")
+	assert.Nil(t, errors.Wrap(nil, errors.Invalid, "bad input"), "wrap nil")
+}
+
+func TestTransport(t *testing.T) {
+	for _, tc := range []struct {
+		code   errors.Code
+		status int
+		grpc   codes.Code
+	}{
+		{errors.Invalid, http.StatusBadRequest, codes.InvalidArgument},
+		{errors.NotFound, http.StatusNotFound, codes.NotFound},
+		{errors.Conflict, http.StatusConflict, codes.AlreadyExists},
+		{errors.Unauthenticated, http.StatusUnauthorized, codes.Unauthenticated},
+		{errors.PermissionDenied, http.StatusForbidden, codes.PermissionDenied},
+		{errors.TooManyRequests, http.StatusTooManyRequests, codes.ResourceExhausted},
+		{errors.Unavailable, http.StatusServiceUnavailable, codes.Unavailable},
+		{errors.Internal, http.StatusInternalServerError, codes.Internal},
+	} {
+		err := errors.New(tc.code, "message")
+		assert.Equal(t, tc.status, errors.HTTPStatus(err), tc.code.String())
+		assert.Equal(t, tc.grpc, errors.GRPCCode(err), tc.code.String())
+		assert.Equal(t, tc.grpc, status.Code(err), "grpc recognizes %s", tc.code)
+
+		back := errors.FromGRPC(errors.ToGRPC(err))
+		assert.Equal(t, tc.code, errors.CodeOf(back), "grpc round trip %s", tc.code)
+		assert.Equal(t, tc.code, errors.CodeOf(errors.FromHTTP(tc.status, "message")), "http round trip %s", tc.code)
+	}
+	assert.Nil(t, errors.FromGRPC(nil), "nil grpc error")
+	assert.Nil(t, errors.FromHTTP(http.StatusOK, ""), "success status")
+
+	rec := httptest.NewRecorder()
+	errors.WriteHTTP(rec, pkgerrors.Wrap(errors.New(errors.NotFound, "user not found"), "get user"))
+	assert.Equal(t, http.StatusNotFound, rec.Code, "status")
+	var res errors.Response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res), "decode body")
+	assert.Equal(t, errors.Response{Code: "not_found", Message: "user not found"}, res, "body")
+}
@@ -0,0 +1,123 @@
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var httpStatuses = map[Code]int{
+	OK:                 http.StatusOK,
+	Internal:           http.StatusInternalServerError,
+	Invalid:            http.StatusBadRequest,
+	NotFound:           http.StatusNotFound,
+	Conflict:           http.StatusConflict,
+	Unauthenticated:    http.StatusUnauthorized,
+	PermissionDenied:   http.StatusForbidden,
+	FailedPrecondition: http.StatusPreconditionFailed,
+	TooManyRequests:    http.StatusTooManyRequests,
+	Unavailable:        http.StatusServiceUnavailable,
+	Timeout:            http.StatusGatewayTimeout,
+	Canceled:           499,
+	Unimplemented:      http.StatusNotImplemented,
+}
+
+var grpcCodes = map[Code]codes.Code{
+	OK:                 codes.OK,
+	Internal:           codes.Internal,
+	Invalid:            codes.InvalidArgument,
+	NotFound:           codes.NotFound,
+	Conflict:           codes.AlreadyExists,
+	Unauthenticated:    codes.Unauthenticated,
+	PermissionDenied:   codes.PermissionDenied,
+	FailedPrecondition: codes.FailedPrecondition,
+	TooManyRequests:    codes.ResourceExhausted,
+	Unavailable:        codes.Unavailable,
+	Timeout:            codes.DeadlineExceeded,
+	Canceled:           codes.Canceled,
+	Unimplemented:      codes.Unimplemented,
+}
+
+// HTTPStatus maps error to http status code
+func HTTPStatus(err error) int {
+	if s, ok := httpStatuses[CodeOf(err)]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPCCode maps error to grpc code
+func GRPCCode(err error) codes.Code {
+	if c, ok := grpcCodes[CodeOf(err)]; ok {
+		return c
+	}
+	return codes.Internal
+}
+
+// GRPCStatus makes Error recognized by grpc when returned from handlers
+func (e *Error) GRPCStatus() *status.Status {
+	return status.New(GRPCCode(e), MessageOf(e))
+}
+
+// ToGRPC converts err to grpc status error exposing only safe message
+func ToGRPC(err error) error {
+	if err == nil {
+		return nil
+	}
+	return status.Error(GRPCCode(err), MessageOf(err))
+}
+
+// FromGRPC converts grpc status error received by client back to Error
+func FromGRPC(err error) error {
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	if s.Code() == codes.OK {
+		return nil
+	}
+	code := Internal
+	for c, g := range grpcCodes {
+		if g == s.Code() {
+			code = c
+			break
+		}
+	}
+	return &Error{Code: code, Message: s.Message(), Err: err}
+}
+
+// Response is a body written by WriteHTTP
+type Response struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// WriteHTTP responds with status and json body matching err
+func WriteHTTP(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(HTTPStatus(err))
+	_ = json.NewEncoder(w).Encode(Response{
+		Code:    CodeOf(err).String(),
+		Message: MessageOf(err),
+	})
+}
+
+// FromHTTP classifies http status received by client
+func FromHTTP(statusCode int, message string) error {
+	if statusCode < http.StatusBadRequest {
+		return nil
+	}
+	code := Internal
+	for c, s := range httpStatuses {
+		if s == statusCode {
+			code = c
+			break
+		}
+	}
+	if code == Internal && statusCode < http.StatusInternalServerError {
+		code = Invalid
+	}
+	return &Error{Code: code, Message: message}
+}
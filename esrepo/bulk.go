@@ -0,0 +1,222 @@
+package esrepo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/242617/core/batcher"
+	"github.com/242617/core/retry"
+)
+
+// Action is a bulk operation type
+type Action string
+
+const (
+	ActionIndex  Action = "index"
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// BulkItem is a single bulk operation, Doc is ignored for delete
+type BulkItem struct {
+	Action Action
+	Index  string
+	ID     string
+	Doc    any
+}
+
+// BulkError lists items failed within bulk request
+type BulkError struct {
+	Items  []BulkItem
+	Errors []*Error
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("%d items failed, first: %s", len(e.Items), e.Errors[0])
+}
+
+// Bulk executes items in a single request. Items rejected by cluster are returned as *BulkError.
+func (r *Repo) Bulk(ctx context.Context, items []BulkItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, item := range items {
+		action := item.Action
+		if action == "" {
+			action = ActionIndex
+		}
+		meta := map[Action]map[string]string{action: {"_index": item.Index}}
+		if item.ID != "" {
+			meta[action]["_id"] = item.ID
+		}
+		if err := enc.Encode(meta); err != nil {
+			return errors.Wrap(err, "encode meta")
+		}
+		switch action {
+		case ActionDelete:
+		case ActionUpdate:
+			if err := enc.Encode(map[string]any{"doc": item.Doc}); err != nil {
+				return errors.Wrap(err, "encode doc")
+			}
+		default:
+			if err := enc.Encode(item.Doc); err != nil {
+				return errors.Wrap(err, "encode doc")
+			}
+		}
+	}
+
+	var res struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  *struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := r.do(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", &buf, &res); err != nil {
+		return errors.Wrap(err, "bulk")
+	}
+	if !res.Errors {
+		return nil
+	}
+
+	var failed BulkError
+	for i, item := range res.Items {
+		for _, result := range item {
+			if result.Error == nil || i >= len(items) {
+				continue
+			}
+			failed.Items = append(failed.Items, items[i])
+			failed.Errors = append(failed.Errors, &Error{Status: result.Status, Type: result.Error.Type, Reason: result.Error.Reason})
+		}
+	}
+	if len(failed.Items) == 0 {
+		return nil
+	}
+	return &failed
+}
+
+type indexerOption = func(i *Indexer) error
+
+// WithBatchSize sets number of items sent in a single bulk request
+func WithBatchSize(size int) indexerOption {
+	return func(i *Indexer) error {
+		if size < 1 {
+			return errors.New("batch size must be positive")
+		}
+		i.size = size
+		return nil
+	}
+}
+
+// WithFlushInterval sets max time items are buffered
+func WithFlushInterval(interval time.Duration) indexerOption {
+	return func(i *Indexer) error {
+		if interval <= 0 {
+			return errors.New("interval must be positive")
+		}
+		i.interval = interval
+		return nil
+	}
+}
+
+// WithRetry sets retry options used when cluster rejects items with 429
+func WithRetry(options ...retry.Option) indexerOption {
+	return func(i *Indexer) error {
+		i.retry = append(i.retry, options...)
+		return nil
+	}
+}
+
+// NewIndexer creates helper buffering items and sending them in bulk requests.
+// Items rejected with 429 are resent with backoff, buffered items are flushed on Stop.
+func NewIndexer(repo *Repo, options ...indexerOption) (*Indexer, error) {
+	i := Indexer{
+		repo:     repo,
+		size:     1000,
+		interval: time.Second,
+		retry:    []retry.Option{retry.WithMaxAttempts(5), retry.WithExponentialBackoff(100*time.Millisecond, 5*time.Second)},
+	}
+	for _, option := range options {
+		if err := option(&i); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+
+	var err error
+	i.batcher, err = batcher.New(i.bulk,
+		batcher.WithName[BulkItem](i.String()),
+		batcher.WithSize[BulkItem](i.size),
+		batcher.WithInterval[BulkItem](i.interval),
+		batcher.WithLogger[BulkItem](repo.log),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "new batcher")
+	}
+	return &i, nil
+}
+
+type Indexer struct {
+	repo     *Repo
+	size     int
+	interval time.Duration
+	retry    []retry.Option
+	batcher  *batcher.Batcher[BulkItem]
+}
+
+// Add buffers item triggering flush once batch is full
+func (i *Indexer) Add(item BulkItem) error { return i.batcher.Add(item) }
+
+// Flush sends buffered items, failed ones are dropped
+func (i *Indexer) Flush(ctx context.Context) error { return i.batcher.Flush(ctx) }
+
+func (i *Indexer) Start(ctx context.Context) error { return i.batcher.Start(ctx) }
+
+func (i *Indexer) Stop(ctx context.Context) error { return i.batcher.Stop(ctx) }
+
+func (i *Indexer) String() string { return i.repo.name + ":indexer" }
+
+func (i *Indexer) bulk(ctx context.Context, items []BulkItem) error {
+	pending := items
+	return retry.Do(ctx, func(ctx context.Context) error {
+		err := i.repo.Bulk(ctx, pending)
+		if err == nil || IsTooManyRequests(err) {
+			return err
+		}
+
+		var failed *BulkError
+		if !errors.As(err, &failed) {
+			return retry.Permanent(err)
+		}
+		// only items rejected due to load are worth resending
+		var rejected []BulkItem
+		for n, e := range failed.Errors {
+			if e.Status == http.StatusTooManyRequests {
+				rejected = append(rejected, failed.Items[n])
+			}
+		}
+		if len(rejected) < len(failed.Items) {
+			i.repo.log.Error().Err(err).Int("dropped", len(failed.Items)-len(rejected)).Msg("bulk items failed")
+		}
+		if len(rejected) == 0 {
+			return nil
+		}
+		pending = rejected
+		return failed
+	}, append([]retry.Option{retry.RetryIf(func(err error) bool {
+		var failed *BulkError
+		return IsTooManyRequests(err) || errors.As(err, &failed)
+	})}, i.retry...)...)
+}
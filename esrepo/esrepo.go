@@ -0,0 +1,226 @@
+package esrepo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+)
+
+// Config is an elasticsearch/opensearch configuration suitable for config.Scan
+type Config struct {
+	// Addrs are addresses of cluster nodes, e.g. http://localhost:9200, requests are balanced round-robin
+	Addrs    []string      `yaml:"addrs"`
+	Username string        `yaml:"username" env:"ELASTICSEARCH_USERNAME"`
+	Password string        `yaml:"password" env:"ELASTICSEARCH_PASSWORD"`
+	APIKey   string        `yaml:"api_key" env:"ELASTICSEARCH_API_KEY"`
+	Timeout  time.Duration `yaml:"timeout" default:"30s"`
+}
+
+type option = func(r *Repo) error
+
+func withDefaults() option {
+	return func(r *Repo) error {
+		r.name = "esrepo"
+		r.log = l.With().Str("component", "esrepo").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(r *Repo) error {
+		r.name = name
+		return nil
+	}
+}
+
+func WithClient(client *http.Client) option {
+	return func(r *Repo) error {
+		r.client = client
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(r *Repo) error {
+		r.log = log
+		return nil
+	}
+}
+
+// New creates elasticsearch component talking to REST API, it works with opensearch as well.
+// Connection is checked on Start.
+func New(cfg Config, options ...option) (*Repo, error) {
+	if len(cfg.Addrs) == 0 {
+		return nil, errors.New("empty addrs")
+	}
+	for _, addr := range cfg.Addrs {
+		if _, err := url.Parse(addr); err != nil {
+			return nil, errors.Wrapf(err, "parse addr %q", addr)
+		}
+	}
+
+	r := Repo{cfg: cfg}
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&r); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	if r.client == nil {
+		r.client = &http.Client{Timeout: cfg.Timeout}
+	}
+	return &r, nil
+}
+
+type Repo struct {
+	name   string
+	cfg    Config
+	client *http.Client
+	next   atomic.Uint64
+	log    zerolog.Logger
+}
+
+func (r *Repo) Start(ctx context.Context) error {
+	if err := r.HealthCheck(ctx); err != nil {
+		return err
+	}
+	r.log.Info().Msgf("connected to elasticsearch %v", r.cfg.Addrs)
+	return nil
+}
+
+func (r *Repo) Stop(context.Context) error {
+	r.client.CloseIdleConnections()
+	return nil
+}
+
+// HealthCheck fails when cluster is unreachable or its status is red
+func (r *Repo) HealthCheck(ctx context.Context) error {
+	var health struct {
+		Status string `json:"status"`
+	}
+	if err := r.Do(ctx, http.MethodGet, "/_cluster/health", nil, &health); err != nil {
+		return errors.Wrap(err, "cluster health")
+	}
+	if health.Status == "red" {
+		return errors.New("cluster status is red")
+	}
+	return nil
+}
+
+func (r *Repo) String() string { return r.name }
+
+// Error is an error response of elasticsearch
+type Error struct {
+	Status int
+	Type   string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("elasticsearch: status %d: %s", e.Status, e.Reason)
+	}
+	return fmt.Sprintf("elasticsearch: %s: %s", e.Type, e.Reason)
+}
+
+// IsNotFound reports whether err is caused by missing index or document
+func IsNotFound(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Status == http.StatusNotFound
+}
+
+// IsTooManyRequests reports whether err is caused by cluster rejecting request due to load
+func IsTooManyRequests(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Status == http.StatusTooManyRequests
+}
+
+// Do sends body marshalled to JSON and unmarshals response into out if it is not nil
+func (r *Repo) Do(ctx context.Context, method, path string, body, out any) error {
+	var data io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return errors.Wrap(err, "encode body")
+		}
+		data = bytes.NewReader(b)
+	}
+	return r.do(ctx, method, path, "application/json", data, out)
+}
+
+func (r *Repo) do(ctx context.Context, method, path, contentType string, data io.Reader, out any) error {
+	start := time.Now()
+	status, err := r.request(ctx, method, path, contentType, data, out)
+
+	event := r.log.Debug()
+	if err != nil {
+		event = r.log.Error().Err(err)
+	}
+	event.Str("method", method).Str("path", path).Int("status", status).Dur("duration", time.Since(start)).Msg("request")
+	return err
+}
+
+func (r *Repo) request(ctx context.Context, method, path, contentType string, data io.Reader, out any) (int, error) {
+	addr := r.cfg.Addrs[int(r.next.Add(1)-1)%len(r.cfg.Addrs)]
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(addr, "/")+path, data)
+	if err != nil {
+		return 0, errors.Wrap(err, "new request")
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	switch {
+	case r.cfg.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+r.cfg.APIKey)
+	case r.cfg.Username != "":
+		req.SetBasicAuth(r.cfg.Username, r.cfg.Password)
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "do request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return res.StatusCode, decodeError(res)
+	}
+	if out == nil {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return res.StatusCode, nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return res.StatusCode, errors.Wrap(err, "decode response")
+	}
+	return res.StatusCode, nil
+}
+
+func decodeError(res *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(res.Body, 4<<10))
+	e := Error{Status: res.StatusCode, Reason: strings.TrimSpace(string(msg))}
+
+	var body struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(msg, &body) == nil && len(body.Error) > 0 {
+		var cause struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		}
+		if json.Unmarshal(body.Error, &cause) == nil && cause.Type != "" {
+			e.Type, e.Reason = cause.Type, cause.Reason
+		}
+	}
+	return &e
+}
@@ -0,0 +1,149 @@
+package esrepo_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/esrepo"
+	"github.com/242617/core/retry"
+)
+
+var period = 10 * time.Millisecond
+
+type doc struct {
+	Name string `json:"name"`
+}
+
+type server struct {
+	*httptest.Server
+	mu     sync.Mutex
+	reject int
+	bulks  [][]string
+}
+
+func newServer(t *testing.T) *server {
+	s := server{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /_cluster/health", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ApiKey secret", r.Header.Get("Authorization"), "auth")
+		w.Write([]byte(`{"status":"yellow"}`))
+	})
+	mux.HandleFunc("POST /users/_search", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hits":{"total":{"value":1},"hits":[{"_index":"users","_id":"1","_score":1.5,"_source":{"name":"ivan"}}]},"aggregations":{"names":{"buckets":[]}}}`))
+	})
+	mux.HandleFunc("GET /users/_doc/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "1" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"_index":"users","_id":"2","found":false}`))
+			return
+		}
+		w.Write([]byte(`{"_index":"users","_id":"1","found":true,"_source":{"name":"ivan"}}`))
+	})
+	mux.HandleFunc("POST /_bulk", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"), "content type")
+		var lines []string
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.bulks = append(s.bulks, lines)
+		var items []map[string]any
+		for n := 0; n < len(lines)/2; n++ {
+			result := map[string]any{"status": 201}
+			if s.reject > 0 {
+				s.reject--
+				result = map[string]any{"status": 429, "error": map[string]any{"type": "es_rejected_execution_exception", "reason": "queue is full"}}
+			}
+			items = append(items, map[string]any{"index": result})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"errors": true, "items": items})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"type":"illegal_argument_exception","reason":"unknown"},"status":400}`))
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return &s
+}
+
+func (s *server) repo(t *testing.T) *esrepo.Repo {
+	r, err := esrepo.New(esrepo.Config{Addrs: []string{s.URL}, APIKey: "secret"})
+	require.NoError(t, err, "new")
+	return r
+}
+
+func TestNew(t *testing.T) {
+	_, err := esrepo.New(esrepo.Config{})
+	assert.Error(t, err, "empty addrs")
+}
+
+func TestStart(t *testing.T) {
+	r := newServer(t).repo(t)
+	require.NoError(t, r.Start(context.Background()), "start")
+	assert.NoError(t, r.Stop(context.Background()), "stop")
+}
+
+func TestSearch(t *testing.T) {
+	r := newServer(t).repo(t)
+	ctx := context.Background()
+
+	res, err := esrepo.Search[doc](ctx, r, "users", map[string]any{"query": map[string]any{"match_all": map[string]any{}}})
+	require.NoError(t, err, "search")
+	assert.Equal(t, int64(1), res.Total, "total")
+	require.Len(t, res.Hits, 1, "hits")
+	assert.Equal(t, "1", res.Hits[0].ID, "id")
+	assert.Equal(t, doc{Name: "ivan"}, res.Hits[0].Source, "source")
+	assert.Contains(t, res.Aggregations, "names", "aggregations")
+
+	got, err := esrepo.Get[doc](ctx, r, "users", "1")
+	require.NoError(t, err, "get")
+	assert.Equal(t, doc{Name: "ivan"}, got, "document")
+
+	_, err = esrepo.Get[doc](ctx, r, "users", "2")
+	assert.True(t, esrepo.IsNotFound(err), "not found")
+
+	err = r.Index(ctx, "users", "1", doc{Name: "ivan"})
+	var e *esrepo.Error
+	require.ErrorAs(t, err, &e, "error response")
+	assert.Equal(t, "illegal_argument_exception", e.Type, "error type")
+}
+
+func TestIndexer(t *testing.T) {
+	s := newServer(t)
+	s.reject = 2
+
+	i, err := esrepo.NewIndexer(s.repo(t),
+		esrepo.WithBatchSize(3),
+		esrepo.WithFlushInterval(time.Hour),
+		esrepo.WithRetry(retry.WithConstantBackoff(period)),
+	)
+	require.NoError(t, err, "new indexer")
+	require.NoError(t, i.Start(context.Background()), "start")
+
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, i.Add(esrepo.BulkItem{Index: "users", ID: name, Doc: doc{Name: name}}), "add")
+	}
+	require.NoError(t, i.Stop(context.Background()), "stop")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	require.Len(t, s.bulks, 2, "retried")
+	assert.Len(t, s.bulks[0], 6, "full batch")
+	assert.Equal(t, []string{
+		`{"index":{"_id":"a","_index":"users"}}`, `{"name":"a"}`,
+		`{"index":{"_id":"b","_index":"users"}}`, `{"name":"b"}`,
+	}, s.bulks[1], "rejected items resent")
+}
@@ -0,0 +1,79 @@
+package esrepo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// Hit is a found document
+type Hit[T any] struct {
+	Index  string   `json:"_index"`
+	ID     string   `json:"_id"`
+	Score  *float64 `json:"_score"`
+	Source T        `json:"_source"`
+	Sort   []any    `json:"sort,omitempty"`
+}
+
+// SearchResult is a decoded search response, Aggregations are left raw
+type SearchResult[T any] struct {
+	Total        int64
+	Hits         []Hit[T]
+	Aggregations map[string]json.RawMessage
+}
+
+// Search executes search request against index and unmarshals found sources into T
+func Search[T any](ctx context.Context, r *Repo, index string, query any) (*SearchResult[T], error) {
+	var res struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+			Hits []Hit[T] `json:"hits"`
+		} `json:"hits"`
+		Aggregations map[string]json.RawMessage `json:"aggregations"`
+	}
+	if err := r.Do(ctx, http.MethodPost, "/"+url.PathEscape(index)+"/_search", query, &res); err != nil {
+		return nil, errors.Wrap(err, "search")
+	}
+	return &SearchResult[T]{
+		Total:        res.Hits.Total.Value,
+		Hits:         res.Hits.Hits,
+		Aggregations: res.Aggregations,
+	}, nil
+}
+
+// Get returns document source by id, use IsNotFound to check missing document
+func Get[T any](ctx context.Context, r *Repo, index, id string) (T, error) {
+	var res struct {
+		Source T `json:"_source"`
+	}
+	err := r.Do(ctx, http.MethodGet, "/"+url.PathEscape(index)+"/_doc/"+url.PathEscape(id), nil, &res)
+	if err != nil {
+		return res.Source, errors.Wrap(err, "get")
+	}
+	return res.Source, nil
+}
+
+// Index creates or replaces document, empty id lets cluster generate it
+func (r *Repo) Index(ctx context.Context, index, id string, doc any) error {
+	method, path := http.MethodPut, "/"+url.PathEscape(index)+"/_doc/"+url.PathEscape(id)
+	if id == "" {
+		method, path = http.MethodPost, "/"+url.PathEscape(index)+"/_doc"
+	}
+	if err := r.Do(ctx, method, path, doc, nil); err != nil {
+		return errors.Wrap(err, "index")
+	}
+	return nil
+}
+
+// Delete removes document by id
+func (r *Repo) Delete(ctx context.Context, index, id string) error {
+	if err := r.Do(ctx, http.MethodDelete, "/"+url.PathEscape(index)+"/_doc/"+url.PathEscape(id), nil, nil); err != nil {
+		return errors.Wrap(err, "delete")
+	}
+	return nil
+}
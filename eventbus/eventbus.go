@@ -0,0 +1,101 @@
+package eventbus
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/protocol"
+)
+
+const (
+	MetricPublishedTotal = "eventbus_published_total"
+	MetricDroppedTotal   = "eventbus_dropped_total"
+)
+
+var ErrStopped = errors.New("bus is stopped")
+
+type option = func(b *Bus) error
+
+func withDefaults() option {
+	return func(b *Bus) error {
+		b.name = "eventbus"
+		b.log = l.With().Str("component", "eventbus").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(b *Bus) error {
+		b.name = name
+		return nil
+	}
+}
+
+func WithMetrics(recorder protocol.MetricsRecorder) option {
+	return func(b *Bus) error {
+		b.metrics = recorder
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(b *Bus) error {
+		b.log = log
+		return nil
+	}
+}
+
+// New creates bus dispatching events of topics created with NewTopic
+func New(options ...option) (*Bus, error) {
+	var b Bus
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&b); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	return &b, nil
+}
+
+type Bus struct {
+	name    string
+	metrics protocol.MetricsRecorder
+	log     zerolog.Logger
+
+	mu      sync.RWMutex
+	stopped bool
+	topics  []interface{ close() }
+	wg      sync.WaitGroup
+}
+
+func (b *Bus) Start(context.Context) error { return nil }
+
+// Stop rejects new events and waits for async subscribers to handle buffered ones
+func (b *Bus) Stop(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.stopped {
+		b.stopped = true
+		for _, t := range b.topics {
+			t.close()
+		}
+	}
+	b.mu.Unlock()
+
+	doneCh := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(doneCh)
+	}()
+	select {
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "drain")
+	case <-doneCh:
+		return nil
+	}
+}
+
+func (b *Bus) String() string { return b.name }
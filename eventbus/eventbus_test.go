@@ -0,0 +1,146 @@
+package eventbus_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/eventbus"
+	"github.com/242617/core/requestid"
+)
+
+var period = 10 * time.Millisecond
+
+type created struct{ ID int }
+
+func TestSync(t *testing.T) {
+	bus, err := eventbus.New()
+	require.NoError(t, err, "new")
+	topic := eventbus.NewTopic[created](bus, "created")
+
+	var got []int
+	unsubscribe, err := topic.Subscribe(func(_ context.Context, e created) { got = append(got, e.ID) })
+	require.NoError(t, err, "subscribe")
+	_, err = topic.Subscribe(func(context.Context, created) { panic("sample") })
+	require.NoError(t, err, "subscribe panicking")
+
+	require.NoError(t, topic.Publish(context.Background(), created{1}), "publish")
+	unsubscribe()
+	require.NoError(t, topic.Publish(context.Background(), created{2}), "publish after unsubscribe")
+	assert.Equal(t, []int{1}, got, "handled synchronously")
+
+	require.NoError(t, bus.Stop(context.Background()), "stop")
+	assert.ErrorIs(t, topic.Publish(context.Background(), created{3}), eventbus.ErrStopped, "stopped")
+}
+
+func TestAsync(t *testing.T) {
+	bus, err := eventbus.New()
+	require.NoError(t, err, "new")
+	topic := eventbus.NewTopic[created](bus, "created")
+
+	var (
+		mu  sync.Mutex
+		got []int
+		ids []string
+	)
+	_, err = topic.Subscribe(func(ctx context.Context, e created) {
+		time.Sleep(period / 10)
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, e.ID)
+		ids = append(ids, requestid.FromContext(ctx))
+	}, eventbus.WithBuffer(2, eventbus.Block))
+	require.NoError(t, err, "subscribe")
+
+	ctx, cancel := context.WithCancel(requestid.NewContext(context.Background(), "sample"))
+	for i := 0; i < 5; i++ {
+		require.NoError(t, topic.Publish(ctx, created{i}), "publish")
+	}
+	cancel()
+
+	require.NoError(t, bus.Stop(context.Background()), "drain")
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, got, "all handled in order")
+	assert.Equal(t, "sample", ids[4], "context values preserved")
+}
+
+func TestPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		policy eventbus.Policy
+		want   []int
+	}{
+		{eventbus.DropNewest, []int{0, 1, 2}},
+		{eventbus.DropOldest, []int{0, 3, 4}},
+	} {
+		bus, err := eventbus.New()
+		require.NoError(t, err, "new")
+		topic := eventbus.NewTopic[created](bus, "created")
+
+		release := make(chan struct{})
+		var (
+			mu  sync.Mutex
+			got []int
+		)
+		var handled atomic.Int32
+		_, err = topic.Subscribe(func(_ context.Context, e created) {
+			if handled.Add(1) == 1 {
+				<-release
+			}
+			mu.Lock()
+			got = append(got, e.ID)
+			mu.Unlock()
+		}, eventbus.WithBuffer(2, tc.policy))
+		require.NoError(t, err, "subscribe")
+
+		require.NoError(t, topic.Publish(context.Background(), created{0}), "publish first")
+		assert.Eventually(t, func() bool { return handled.Load() == 1 }, 10*period, period/10, "first taken")
+		for i := 1; i < 5; i++ {
+			require.NoError(t, topic.Publish(context.Background(), created{i}), "publish")
+		}
+		close(release)
+
+		require.NoError(t, bus.Stop(context.Background()), "drain")
+		assert.Equal(t, tc.want, got, "policy %d", tc.policy)
+	}
+}
+
+func TestUnsubscribeBlocked(t *testing.T) {
+	bus, err := eventbus.New()
+	require.NoError(t, err, "new")
+	topic := eventbus.NewTopic[created](bus, "created")
+
+	release := make(chan struct{})
+	unsubscribe, err := topic.Subscribe(func(context.Context, created) { <-release }, eventbus.WithBuffer(1, eventbus.Block))
+	require.NoError(t, err, "subscribe")
+	for i := 0; i < 2; i++ {
+		require.NoError(t, topic.Publish(context.Background(), created{i}), "fill buffer")
+	}
+
+	published := make(chan error, 1)
+	go func() { published <- topic.Publish(context.Background(), created{2}) }()
+	time.Sleep(period)
+
+	unsubscribed := make(chan struct{})
+	go func() {
+		unsubscribe()
+		close(unsubscribed)
+	}()
+	select {
+	case <-unsubscribed:
+	case <-time.After(10 * period):
+		t.Fatal("unsubscribe blocked by publisher")
+	}
+	select {
+	case err := <-published:
+		assert.NoError(t, err, "publish to removed subscriber")
+	case <-time.After(10 * period):
+		t.Fatal("publisher not released")
+	}
+
+	close(release)
+	require.NoError(t, bus.Stop(context.Background()), "stop")
+}
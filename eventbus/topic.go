@@ -0,0 +1,224 @@
+package eventbus
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Policy defines what happens when buffer of async subscriber is full
+type Policy int
+
+const (
+	// Block waits for free space in buffer until publish context is done
+	Block Policy = iota
+	// DropNewest drops published event
+	DropNewest
+	// DropOldest drops the oldest buffered event
+	DropOldest
+)
+
+type subscribeOption = func(c *subscription) error
+
+// WithBuffer makes subscriber async handling events in own goroutine,
+// subscribers without buffer are called synchronously by Publish
+func WithBuffer(size int, policy Policy) subscribeOption {
+	return func(c *subscription) error {
+		if size < 1 {
+			return errors.New("buffer size must be positive")
+		}
+		c.buffer, c.policy = size, policy
+		return nil
+	}
+}
+
+// NewTopic creates topic of events with type T
+func NewTopic[T any](bus *Bus, name string) *Topic[T] {
+	t := Topic[T]{bus: bus, name: name}
+	bus.mu.Lock()
+	bus.topics = append(bus.topics, &t)
+	bus.mu.Unlock()
+	return &t
+}
+
+type Topic[T any] struct {
+	bus  *Bus
+	name string
+
+	mu     sync.RWMutex
+	subs   []*subscriber[T]
+	closed bool
+}
+
+type subscription struct {
+	buffer int
+	policy Policy
+}
+
+// subscriber buffer is never closed, so publishers may send to it without lock,
+// done tells publishers and consumer that subscriber is removed
+type subscriber[T any] struct {
+	subscription
+	handler func(context.Context, T)
+	ch      chan event[T]
+	done    chan struct{}
+	once    sync.Once
+}
+
+func (s *subscriber[T]) close() {
+	s.once.Do(func() { close(s.done) })
+}
+
+type event[T any] struct {
+	ctx   context.Context
+	value T
+}
+
+// Subscribe adds handler of topic events, returned function removes it
+func (t *Topic[T]) Subscribe(handler func(ctx context.Context, event T), options ...subscribeOption) (func(), error) {
+	s := subscriber[T]{handler: handler, done: make(chan struct{})}
+	for _, option := range options {
+		if err := option(&s.subscription); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, ErrStopped
+	}
+	if s.buffer > 0 {
+		s.ch = make(chan event[T], s.buffer)
+		t.bus.wg.Add(1)
+		go t.consume(&s)
+	}
+	t.subs = append(t.subs, &s)
+
+	var once sync.Once
+	return func() { once.Do(func() { t.unsubscribe(&s) }) }, nil
+}
+
+// Publish dispatches event to all subscribers. Async subscribers get context
+// without cancellation so that values like request ID are preserved.
+func (t *Topic[T]) Publish(ctx context.Context, value T) error {
+	t.mu.RLock()
+	closed, subs := t.closed, t.subs
+	t.mu.RUnlock()
+	if closed {
+		return ErrStopped
+	}
+	t.record(MetricPublishedTotal)
+
+	for _, s := range subs {
+		if s.ch == nil {
+			t.call(s, ctx, value)
+			continue
+		}
+		if err := t.send(ctx, s, event[T]{context.WithoutCancel(ctx), value}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Topic[T]) send(ctx context.Context, s *subscriber[T], e event[T]) error {
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+
+	switch s.policy {
+	case Block:
+		select {
+		case s.ch <- e:
+		case <-s.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	case DropNewest:
+		select {
+		case s.ch <- e:
+		default:
+			t.record(MetricDroppedTotal)
+		}
+	case DropOldest:
+		for sent := false; !sent; {
+			select {
+			case s.ch <- e:
+				sent = true
+			default:
+				select {
+				case <-s.ch:
+					t.record(MetricDroppedTotal)
+				default:
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (t *Topic[T]) unsubscribe(s *subscriber[T]) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, sub := range t.subs {
+		if sub == s {
+			t.subs = append(t.subs[:i:i], t.subs[i+1:]...)
+			s.close()
+			return
+		}
+	}
+}
+
+func (t *Topic[T]) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	for _, s := range t.subs {
+		s.close()
+	}
+	t.subs = nil
+}
+
+// consume handles events until subscriber is closed, then buffered events are handled
+func (t *Topic[T]) consume(s *subscriber[T]) {
+	defer t.bus.wg.Done()
+	for {
+		select {
+		case e := <-s.ch:
+			t.call(s, e.ctx, e.value)
+		case <-s.done:
+			for {
+				select {
+				case e := <-s.ch:
+					t.call(s, e.ctx, e.value)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (t *Topic[T]) call(s *subscriber[T], ctx context.Context, value T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.bus.log.Error().
+				Str("topic", t.name).
+				Interface("panic", r).
+				Bytes("stack", debug.Stack()).
+				Msg("handler panicked")
+		}
+	}()
+	s.handler(ctx, value)
+}
+
+func (t *Topic[T]) record(name string) {
+	if t.bus.metrics != nil {
+		t.bus.metrics.Add(name, 1, "topic", t.name)
+	}
+}
@@ -0,0 +1,209 @@
+package featureflag
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/requestid"
+)
+
+type option = func(f *Flags) error
+
+func withDefaults() option {
+	return func(f *Flags) error {
+		f.name = "featureflag"
+		f.log = l.With().Str("component", "featureflag").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(f *Flags) error {
+		f.name = name
+		return nil
+	}
+}
+
+// WithProviders adds flag providers, flags of later providers override earlier ones
+func WithProviders(providers ...Provider) option {
+	return func(f *Flags) error {
+		f.providers = append(f.providers, providers...)
+		return nil
+	}
+}
+
+// WithReloadInterval enables periodic reload of flags after Start
+func WithReloadInterval(interval time.Duration) option {
+	return func(f *Flags) error {
+		if interval <= 0 {
+			return errors.New("interval must be positive")
+		}
+		f.interval = interval
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(f *Flags) error {
+		f.log = log
+		return nil
+	}
+}
+
+func New(options ...option) (*Flags, error) {
+	var f Flags
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&f); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	return &f, nil
+}
+
+type Flags struct {
+	name      string
+	providers []Provider
+	interval  time.Duration
+	log       zerolog.Logger
+
+	mu     sync.RWMutex
+	flags  map[string]Flag
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Start loads flags and starts reloading them if reload interval is set
+func (f *Flags) Start(ctx context.Context) error {
+	if err := f.Reload(ctx); err != nil {
+		return errors.Wrap(err, "load")
+	}
+	if f.interval == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancel, f.done = cancel, make(chan struct{})
+	go func() {
+		defer close(f.done)
+		ticker := time.NewTicker(f.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := f.Reload(ctx); err != nil {
+					f.log.Error().Err(err).Msg("reload flags")
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (f *Flags) Stop(ctx context.Context) error {
+	if f.cancel == nil {
+		return nil
+	}
+	f.cancel()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-f.done:
+	}
+	return nil
+}
+
+func (f *Flags) String() string { return f.name }
+
+// Reload loads flags from all providers replacing current ones
+func (f *Flags) Reload(ctx context.Context) error {
+	flags := map[string]Flag{}
+	for _, provider := range f.providers {
+		loaded, err := provider.Load(ctx)
+		if err != nil {
+			return err
+		}
+		for name, flag := range loaded {
+			flags[name] = flag
+		}
+	}
+
+	f.mu.Lock()
+	f.flags = flags
+	f.mu.Unlock()
+	return nil
+}
+
+// Bool returns flag value for ctx or def if flag is not set or not a bool
+func (f *Flags) Bool(ctx context.Context, name string, def bool) bool {
+	switch v := f.value(ctx, name).(type) {
+	case bool:
+		return v
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+// StringValue returns flag value for ctx or def if flag is not set
+func (f *Flags) StringValue(ctx context.Context, name string, def string) string {
+	switch v := f.value(ctx, name).(type) {
+	case nil:
+		return def
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Int returns flag value for ctx or def if flag is not set or not an integer
+func (f *Flags) Int(ctx context.Context, name string, def int) int {
+	switch v := f.value(ctx, name).(type) {
+	case int:
+		return v
+	case string:
+		if i, err := strconv.Atoi(v); err == nil {
+			return i
+		}
+	}
+	return def
+}
+
+func (f *Flags) value(ctx context.Context, name string) any {
+	f.mu.RLock()
+	flag, ok := f.flags[name]
+	f.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	for _, target := range []string{TenantFromContext(ctx), requestid.FromContext(ctx)} {
+		if v, ok := flag.Targets[target]; ok && target != "" {
+			return v
+		}
+	}
+	return flag.Value
+}
+
+type tenantKey struct{}
+
+// NewContext returns context carrying tenant used for flags targeting
+func NewContext(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
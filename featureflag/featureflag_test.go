@@ -0,0 +1,76 @@
+package featureflag_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/config/source/file"
+	"github.com/242617/core/featureflag"
+	"github.com/242617/core/requestid"
+)
+
+var period = 10 * time.Millisecond
+
+const sample = `
+flags:
+  checkout:
+    value: false
+    targets:
+      tenant-a: true
+      request-b: true
+  title:
+    value: sample
+  limit:
+    value: 10
+`
+
+func TestFlags(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "flags.yaml")
+	require.NoError(t, os.WriteFile(name, []byte(sample), 0o600), "write file")
+	t.Setenv("FEATURE_LIMIT", "20")
+
+	f, err := featureflag.New(featureflag.WithProviders(
+		featureflag.FromConfig(file.YAML(name)),
+		featureflag.Env("FEATURE_"),
+	))
+	require.NoError(t, err, "new")
+	require.NoError(t, f.Start(context.Background()), "start")
+	defer f.Stop(context.Background())
+
+	ctx := context.Background()
+	assert.False(t, f.Bool(ctx, "checkout", true), "default value")
+	assert.True(t, f.Bool(featureflag.NewContext(ctx, "tenant-a"), "checkout", false), "tenant target")
+	assert.True(t, f.Bool(requestid.NewContext(ctx, "request-b"), "checkout", false), "request target")
+	assert.True(t, f.Bool(ctx, "unknown", true), "unknown flag")
+	assert.Equal(t, "sample", f.StringValue(ctx, "title", ""), "string")
+	assert.Equal(t, 20, f.Int(ctx, "limit", 0), "env override")
+	assert.Equal(t, 5, f.Int(ctx, "title", 5), "not an integer")
+}
+
+func TestReload(t *testing.T) {
+	var enabled atomic.Bool
+	provider := featureflag.ProviderFunc(func(context.Context) (map[string]featureflag.Flag, error) {
+		return map[string]featureflag.Flag{"sample": {Value: enabled.Load()}}, nil
+	})
+
+	f, err := featureflag.New(
+		featureflag.WithProviders(provider),
+		featureflag.WithReloadInterval(period),
+	)
+	require.NoError(t, err, "new")
+	require.NoError(t, f.Start(context.Background()), "start")
+	defer f.Stop(context.Background())
+
+	assert.False(t, f.Bool(context.Background(), "sample", true), "initial value")
+	enabled.Store(true)
+	assert.Eventually(t, func() bool {
+		return f.Bool(context.Background(), "sample", false)
+	}, 10*period, period, "reloaded value")
+}
@@ -0,0 +1,57 @@
+package featureflag
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/242617/core/config/source"
+)
+
+type Flag struct {
+	// Value is returned when no target matches
+	Value any `yaml:"value"`
+	// Targets maps tenant or request ID to value
+	Targets map[string]any `yaml:"targets"`
+}
+
+type Provider interface {
+	Load(ctx context.Context) (map[string]Flag, error)
+}
+
+type ProviderFunc func(ctx context.Context) (map[string]Flag, error)
+
+func (f ProviderFunc) Load(ctx context.Context) (map[string]Flag, error) { return f(ctx) }
+
+// Static returns provider with fixed set of flags
+func Static(flags map[string]Flag) Provider {
+	return ProviderFunc(func(context.Context) (map[string]Flag, error) { return flags, nil })
+}
+
+// FromConfig returns provider scanning flags from "flags" section of config source, e.g. file.YAML
+func FromConfig(src source.ConfigSource) Provider {
+	return ProviderFunc(func(context.Context) (map[string]Flag, error) {
+		var cfg struct {
+			Flags map[string]Flag `yaml:"flags"`
+		}
+		if err := src.Scan(&cfg); err != nil {
+			return nil, err
+		}
+		return cfg.Flags, nil
+	})
+}
+
+// Env returns provider reading flags from environment variables with prefix,
+// e.g. FEATURE_NEW_CHECKOUT=true sets flag "new_checkout" for prefix "FEATURE_"
+func Env(prefix string) Provider {
+	return ProviderFunc(func(context.Context) (map[string]Flag, error) {
+		flags := map[string]Flag{}
+		for _, kv := range os.Environ() {
+			key, value, _ := strings.Cut(kv, "=")
+			if name, ok := strings.CutPrefix(key, prefix); ok && name != "" {
+				flags[strings.ToLower(name)] = Flag{Value: value}
+			}
+		}
+		return flags, nil
+	})
+}
@@ -0,0 +1,225 @@
+package grpcclient
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+
+	"github.com/242617/core/protocol"
+)
+
+// Config is a grpc client configuration suitable for config.Scan
+type Config struct {
+	Target           string        `yaml:"target" env:"GRPC_TARGET"`
+	KeepaliveTime    time.Duration `yaml:"keepalive_time" default:"30s"`
+	KeepaliveTimeout time.Duration `yaml:"keepalive_timeout" default:"10s"`
+	MaxAttempts      int           `yaml:"max_attempts" default:"3"`
+}
+
+type option = func(c *Conn) error
+
+func withDefaults() option {
+	return func(c *Conn) error {
+		c.name = "grpcclient"
+		c.creds = insecure.NewCredentials()
+		c.keepalive = keepalive.ClientParameters{Time: 30 * time.Second, Timeout: 10 * time.Second}
+		c.maxAttempts = 3
+		c.log = l.With().Str("component", "grpcclient").Logger()
+		return nil
+	}
+}
+
+func WithConfig(cfg Config) option {
+	return func(c *Conn) error {
+		if cfg.Target != "" {
+			c.target = cfg.Target
+		}
+		if cfg.KeepaliveTime > 0 {
+			c.keepalive.Time = cfg.KeepaliveTime
+		}
+		if cfg.KeepaliveTimeout > 0 {
+			c.keepalive.Timeout = cfg.KeepaliveTimeout
+		}
+		if cfg.MaxAttempts > 0 {
+			c.maxAttempts = cfg.MaxAttempts
+		}
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(c *Conn) error {
+		c.name = name
+		return nil
+	}
+}
+
+func WithTLS(config *tls.Config) option {
+	return func(c *Conn) error {
+		c.creds = credentials.NewTLS(config)
+		return nil
+	}
+}
+
+// WithMaxAttempts sets number of attempts for calls failed with Unavailable code, 1 disables retries
+func WithMaxAttempts(attempts int) option {
+	return func(c *Conn) error {
+		if attempts < 1 {
+			return errors.New("attempts must be positive")
+		}
+		c.maxAttempts = attempts
+		return nil
+	}
+}
+
+func WithMetrics(recorder protocol.MetricsRecorder) option {
+	return func(c *Conn) error {
+		c.metrics = recorder
+		return nil
+	}
+}
+
+// WaitForReady makes Start block until connection is ready
+func WaitForReady() option {
+	return func(c *Conn) error {
+		c.waitForReady = true
+		return nil
+	}
+}
+
+// WithUnaryInterceptors appends interceptors after the default ones, e.g. for tracing
+func WithUnaryInterceptors(interceptors ...grpc.UnaryClientInterceptor) option {
+	return func(c *Conn) error {
+		c.unary = append(c.unary, interceptors...)
+		return nil
+	}
+}
+
+// WithStreamInterceptors appends interceptors after the default ones
+func WithStreamInterceptors(interceptors ...grpc.StreamClientInterceptor) option {
+	return func(c *Conn) error {
+		c.stream = append(c.stream, interceptors...)
+		return nil
+	}
+}
+
+func WithDialOptions(options ...grpc.DialOption) option {
+	return func(c *Conn) error {
+		c.options = append(c.options, options...)
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(c *Conn) error {
+		c.log = log
+		return nil
+	}
+}
+
+// New creates client connection component. Connection is established on Start and closed on Stop.
+func New(target string, options ...option) (*Conn, error) {
+	c := Conn{target: target}
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&c); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	if c.target == "" {
+		return nil, errors.New("empty target")
+	}
+
+	unary := []grpc.UnaryClientInterceptor{UnaryRequestID()}
+	stream := []grpc.StreamClientInterceptor{StreamRequestID()}
+	if c.metrics != nil {
+		unary = append(unary, UnaryMetrics(c.metrics))
+		stream = append(stream, StreamMetrics(c.metrics))
+	}
+
+	conn, err := grpc.NewClient(c.target, append([]grpc.DialOption{
+		grpc.WithTransportCredentials(c.creds),
+		grpc.WithKeepaliveParams(c.keepalive),
+		grpc.WithDefaultServiceConfig(serviceConfig(c.maxAttempts)),
+		grpc.WithChainUnaryInterceptor(append(unary, c.unary...)...),
+		grpc.WithChainStreamInterceptor(append(stream, c.stream...)...),
+	}, c.options...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "new client")
+	}
+	c.ClientConn = conn
+
+	return &c, nil
+}
+
+type Conn struct {
+	*grpc.ClientConn
+
+	name, target string
+	creds        credentials.TransportCredentials
+	keepalive    keepalive.ClientParameters
+	maxAttempts  int
+	metrics      protocol.MetricsRecorder
+	waitForReady bool
+	unary        []grpc.UnaryClientInterceptor
+	stream       []grpc.StreamClientInterceptor
+	options      []grpc.DialOption
+	log          zerolog.Logger
+}
+
+func (c *Conn) Start(ctx context.Context) error {
+	c.ClientConn.Connect()
+	if !c.waitForReady {
+		return nil
+	}
+
+	for {
+		state := c.ClientConn.GetState()
+		if state == connectivity.Ready {
+			break
+		}
+		if state == connectivity.Shutdown {
+			return errors.New("connection closed")
+		}
+		if !c.ClientConn.WaitForStateChange(ctx, state) {
+			return errors.Wrapf(ctx.Err(), "connect %s", c.target)
+		}
+	}
+	c.log.Info().Msgf("connected to %s", c.target)
+	return nil
+}
+
+func (c *Conn) Stop(context.Context) error {
+	if err := c.ClientConn.Close(); err != nil {
+		return errors.Wrap(err, "close")
+	}
+	return nil
+}
+
+func (c *Conn) String() string { return c.name }
+
+func serviceConfig(attempts int) string {
+	if attempts < 2 {
+		return `{}`
+	}
+	return fmt.Sprintf(`{"methodConfig": [{
+		"name": [{}],
+		"retryPolicy": {
+			"maxAttempts": %d,
+			"initialBackoff": "0.1s",
+			"maxBackoff": "1s",
+			"backoffMultiplier": 2,
+			"retryableStatusCodes": ["UNAVAILABLE"]
+		}
+	}]}`, attempts)
+}
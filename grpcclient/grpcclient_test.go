@@ -0,0 +1,87 @@
+package grpcclient_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/242617/core/grpcclient"
+	"github.com/242617/core/grpcserver"
+	"github.com/242617/core/requestid"
+)
+
+func TestBasic(t *testing.T) {
+	s, err := grpcserver.New(grpcserver.WithAddr("127.0.0.1:0"))
+	require.NoError(t, err, "new server")
+	require.NoError(t, s.Start(context.Background()), "start server")
+	defer s.Stop(context.Background())
+
+	var recorder withRecorder
+	c, err := grpcclient.New(s.Addr(),
+		grpcclient.WithMetrics(&recorder),
+		grpcclient.WaitForReady(),
+	)
+	require.NoError(t, err, "new client")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, c.Start(ctx), "start client")
+
+	var header metadata.MD
+	ctx = requestid.NewContext(context.Background(), "sample")
+	res, err := healthpb.NewHealthClient(c).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Header(&header))
+	require.NoError(t, err, "check")
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.Status, "serving")
+	assert.Equal(t, []string{"sample"}, header.Get(grpcserver.MetadataRequestID), "request id propagated")
+	assert.Equal(t, []string{
+		"grpc_client_calls_total method=/grpc.health.v1.Health/Check code=OK",
+		"grpc_client_call_duration_seconds method=/grpc.health.v1.Health/Check code=OK",
+	}, recorder.calls, "unexpected metrics")
+
+	require.NoError(t, c.Stop(context.Background()), "stop client")
+	_, err = healthpb.NewHealthClient(c).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.Error(t, err, "connection closed")
+}
+
+func TestStartTimeout(t *testing.T) {
+	c, err := grpcclient.New("127.0.0.1:1", grpcclient.WaitForReady())
+	require.NoError(t, err, "new client")
+	defer c.Stop(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, c.Start(ctx), context.DeadlineExceeded, "start timeout")
+}
+
+func TestConfig(t *testing.T) {
+	_, err := grpcclient.New("", grpcclient.WithConfig(grpcclient.Config{}))
+	assert.Error(t, err, "empty target")
+
+	_, err = grpcclient.New("", grpcclient.WithConfig(grpcclient.Config{Target: "localhost:9090"}))
+	assert.NoError(t, err, "target from config")
+}
+
+type withRecorder struct {
+	sync.Mutex
+	calls []string
+}
+
+func (r *withRecorder) Add(name string, _ float64, labels ...string)     { r.record(name, labels) }
+func (r *withRecorder) Set(name string, _ float64, labels ...string)     { r.record(name, labels) }
+func (r *withRecorder) Observe(name string, _ float64, labels ...string) { r.record(name, labels) }
+
+func (r *withRecorder) record(name string, labels []string) {
+	r.Lock()
+	defer r.Unlock()
+	for i := 0; i+1 < len(labels); i += 2 {
+		name += " " + labels[i] + "=" + labels[i+1]
+	}
+	r.calls = append(r.calls, name)
+}
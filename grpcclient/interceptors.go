@@ -0,0 +1,71 @@
+package grpcclient
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/242617/core/protocol"
+	"github.com/242617/core/requestid"
+)
+
+const (
+	MetricCallsTotal   = "grpc_client_calls_total"
+	MetricCallDuration = "grpc_client_call_duration_seconds"
+)
+
+var metadataRequestID = strings.ToLower(requestid.Header)
+
+// UnaryRequestID passes request id from context to outgoing metadata
+func UnaryRequestID() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(withRequestID(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+func StreamRequestID() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(withRequestID(ctx), desc, cc, method, opts...)
+	}
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	id := requestid.FromContext(ctx)
+	if id == "" {
+		return ctx
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(metadataRequestID)) > 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, metadataRequestID, id)
+}
+
+// UnaryMetrics records number and duration of calls by method and status code
+func UnaryMetrics(recorder protocol.MetricsRecorder) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		record(recorder, method, start, err)
+		return err
+	}
+}
+
+// StreamMetrics records number of streams by method and status code of stream creation
+func StreamMetrics(recorder protocol.MetricsRecorder) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		record(recorder, method, start, err)
+		return stream, err
+	}
+}
+
+func record(recorder protocol.MetricsRecorder, method string, start time.Time, err error) {
+	code := status.Code(err).String()
+	recorder.Add(MetricCallsTotal, 1, "method", method, "code", code)
+	recorder.Observe(MetricCallDuration, time.Since(start).Seconds(), "method", method, "code", code)
+}
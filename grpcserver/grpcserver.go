@@ -0,0 +1,231 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Config is a grpc server configuration suitable for config.Scan
+type Config struct {
+	Addr       string `yaml:"addr" env:"GRPC_ADDR" default:":9090"`
+	Reflection bool   `yaml:"reflection" env:"GRPC_REFLECTION"`
+}
+
+type option = func(s *Server) error
+
+func withDefaults() option {
+	return func(s *Server) error {
+		s.name, s.addr = "grpcserver", ":9090"
+		s.log = l.With().Str("component", "grpcserver").Logger()
+		return nil
+	}
+}
+
+func WithConfig(cfg Config) option {
+	return func(s *Server) error {
+		if cfg.Addr != "" {
+			s.addr = cfg.Addr
+		}
+		s.reflection = cfg.Reflection
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(s *Server) error {
+		s.name = name
+		return nil
+	}
+}
+
+func WithAddr(addr string) option {
+	return func(s *Server) error {
+		s.addr = addr
+		return nil
+	}
+}
+
+// WithReflection toggles registration of reflection service
+func WithReflection(enabled bool) option {
+	return func(s *Server) error {
+		s.reflection = enabled
+		return nil
+	}
+}
+
+// WithUnaryInterceptors appends interceptors after the default ones
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) option {
+	return func(s *Server) error {
+		s.unary = append(s.unary, interceptors...)
+		return nil
+	}
+}
+
+// WithStreamInterceptors appends interceptors after the default ones
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) option {
+	return func(s *Server) error {
+		s.stream = append(s.stream, interceptors...)
+		return nil
+	}
+}
+
+// WithTLS makes server accept TLS connections using config, e.g. built by tlsutil
+func WithTLS(config *tls.Config) option {
+	return func(s *Server) error {
+		s.options = append(s.options, grpc.Creds(credentials.NewTLS(config)))
+		return nil
+	}
+}
+
+func WithServerOptions(options ...grpc.ServerOption) option {
+	return func(s *Server) error {
+		s.options = append(s.options, options...)
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(s *Server) error {
+		s.log = log
+		return nil
+	}
+}
+
+// New creates grpc server component with health service registered and
+// interceptors for request id, logging and panic recovery
+func New(options ...option) (*Server, error) {
+	var s Server
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&s); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+
+	unary := append([]grpc.UnaryServerInterceptor{
+		UnaryRequestID(),
+		UnaryLogging(s.log),
+		UnaryRecovery(s.log),
+	}, s.unary...)
+	stream := append([]grpc.StreamServerInterceptor{
+		StreamRequestID(),
+		StreamLogging(s.log),
+		StreamRecovery(s.log),
+	}, s.stream...)
+	s.server = grpc.NewServer(append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}, s.options...)...)
+
+	s.health = health.NewServer()
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(s.server, s.health)
+	if s.reflection {
+		reflection.Register(s.server)
+	}
+
+	return &s, nil
+}
+
+type Server struct {
+	name, addr string
+	reflection bool
+	unary      []grpc.UnaryServerInterceptor
+	stream     []grpc.StreamServerInterceptor
+	options    []grpc.ServerOption
+	log        zerolog.Logger
+
+	server *grpc.Server
+	health *health.Server
+
+	mu       sync.Mutex
+	listener net.Listener
+	doneCh   chan struct{}
+}
+
+// RegisterService makes Server a grpc.ServiceRegistrar, so generated Register functions accept it
+func (s *Server) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	s.server.RegisterService(desc, impl)
+}
+
+// Health returns health service to set statuses of particular services
+func (s *Server) Health() *health.Server { return s.health }
+
+func (s *Server) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener != nil {
+		return errors.New("already started")
+	}
+
+	var lc net.ListenConfig
+	listener, err := lc.Listen(ctx, "tcp", s.addr)
+	if err != nil {
+		return errors.Wrapf(err, "listen %s", s.addr)
+	}
+	s.listener = listener
+	s.doneCh = make(chan struct{})
+
+	go func(doneCh chan struct{}) {
+		defer close(doneCh)
+		if err := s.server.Serve(listener); err != nil {
+			s.log.Error().Err(err).Msg("serve")
+		}
+	}(s.doneCh)
+
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	s.log.Info().Msgf("listening on %s", listener.Addr())
+	return nil
+}
+
+// Stop marks server as not serving and waits for pending RPCs to finish.
+// Remaining RPCs are cancelled when ctx is done.
+func (s *Server) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+	s.health.Shutdown()
+
+	stoppedCh := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stoppedCh)
+	}()
+
+	select {
+	case <-ctx.Done():
+		s.server.Stop()
+		return errors.Wrap(ctx.Err(), "graceful stop")
+	case <-stoppedCh:
+	}
+	<-s.doneCh
+	return nil
+}
+
+// Addr returns address server is listening on or configured address if server is not started
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener != nil {
+		return s.listener.Addr().String()
+	}
+	return s.addr
+}
+
+func (s *Server) String() string { return s.name }
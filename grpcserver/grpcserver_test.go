@@ -0,0 +1,71 @@
+package grpcserver_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+
+	"github.com/242617/core/config"
+	"github.com/242617/core/grpcserver"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg grpcserver.Config
+	require.NoError(t, config.New().Scan(&cfg), "scan config")
+	assert.Equal(t, ":9090", cfg.Addr, "default address")
+	assert.False(t, cfg.Reflection, "reflection disabled by default")
+}
+
+func TestHealth(t *testing.T) {
+	s, err := grpcserver.New(grpcserver.WithConfig(grpcserver.Config{Addr: "127.0.0.1:0"}))
+	require.NoError(t, err, "new server")
+	require.NoError(t, s.Start(context.Background()), "start server")
+
+	conn, err := grpc.NewClient(s.Addr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err, "new client")
+	defer conn.Close()
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.Background(), grpcserver.MetadataRequestID, "sample")
+	res, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Header(&header))
+	require.NoError(t, err, "check")
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.Status, "serving")
+	assert.Equal(t, []string{"sample"}, header.Get(grpcserver.MetadataRequestID), "request id propagated")
+
+	require.NoError(t, s.Stop(context.Background()), "stop server")
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.Error(t, err, "server stopped")
+}
+
+func TestReflection(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		s, err := grpcserver.New(
+			grpcserver.WithAddr("127.0.0.1:0"),
+			grpcserver.WithReflection(enabled),
+		)
+		require.NoError(t, err, "new server")
+		require.NoError(t, s.Start(context.Background()), "start server")
+
+		conn, err := grpc.NewClient(s.Addr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err, "new client")
+
+		stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+		require.NoError(t, err, "reflection stream")
+		err = stream.Send(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+		})
+		require.NoError(t, err, "send")
+		_, err = stream.Recv()
+		assert.Equal(t, enabled, err == nil, "reflection enabled: %t", enabled)
+
+		conn.Close()
+		require.NoError(t, s.Stop(context.Background()), "stop server")
+	}
+}
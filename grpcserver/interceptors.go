@@ -0,0 +1,122 @@
+package grpcserver
+
+import (
+	"context"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/242617/core/requestid"
+)
+
+// MetadataRequestID is a metadata key used to pass request id
+var MetadataRequestID = strings.ToLower(requestid.Header)
+
+// UnaryRequestID takes request id from incoming metadata or generates a new one
+func UnaryRequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withRequestID(ctx), req)
+	}
+}
+
+func StreamRequestID() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ss, withRequestID(ss.Context())})
+	}
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(MetadataRequestID); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	if id == "" {
+		id = requestid.New()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(MetadataRequestID, id))
+	return requestid.NewContext(ctx, id)
+}
+
+// UnaryLogging logs every call with its status code and duration
+func UnaryLogging(log zerolog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		res, err := handler(ctx, req)
+		logCall(log, ctx, info.FullMethod, start, err)
+		return res, err
+	}
+}
+
+func StreamLogging(log zerolog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(log, ss.Context(), info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logCall(log zerolog.Logger, ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	event := log.Info()
+	switch code {
+	case codes.OK, codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.FailedPrecondition:
+	default:
+		event = log.Error().Err(err)
+	}
+	event.
+		Str("request_id", requestid.FromContext(ctx)).
+		Str("method", method).
+		Str("code", code.String()).
+		Dur("duration", time.Since(start)).
+		Msg("call")
+}
+
+// UnaryRecovery recovers from panics in handlers and returns internal error
+func UnaryRecovery(log zerolog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res any, err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				err = recovered(log, ctx, info.FullMethod, v)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func StreamRecovery(log zerolog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				err = recovered(log, ss.Context(), info.FullMethod, v)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func recovered(log zerolog.Logger, ctx context.Context, method string, v any) error {
+	log.Error().
+		Str("request_id", requestid.FromContext(ctx)).
+		Str("method", method).
+		Interface("panic", v).
+		Bytes("stack", debug.Stack()).
+		Msg("recovered")
+	return status.Error(codes.Internal, "internal error")
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
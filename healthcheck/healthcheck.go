@@ -0,0 +1,282 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/application"
+	"github.com/242617/core/protocol"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+type CheckFunc = func(context.Context) error
+
+// Check describes named check. Readiness fails only if critical check fails.
+// Zero interval and timeout are replaced with defaults.
+type Check struct {
+	Name     string
+	Func     CheckFunc
+	Interval time.Duration
+	Timeout  time.Duration
+	Critical bool
+}
+
+type Result struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Critical  bool      `json:"critical"`
+	CheckedAt time.Time `json:"checked_at"`
+	Duration  string    `json:"duration"`
+}
+
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+type option = func(h *Health) error
+
+func withDefaults() option {
+	return func(h *Health) error {
+		h.name = "healthcheck"
+		h.interval, h.timeout = 10*time.Second, time.Second
+		h.log = l.With().Str("component", "healthcheck").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(h *Health) error {
+		h.name = name
+		return nil
+	}
+}
+
+// WithDefaults sets interval and timeout for checks not specifying their own
+func WithDefaults(interval, timeout time.Duration) option {
+	return func(h *Health) error {
+		if interval <= 0 || timeout <= 0 {
+			return errors.New("interval and timeout must be positive")
+		}
+		h.interval, h.timeout = interval, timeout
+		return nil
+	}
+}
+
+func WithCheck(check Check) option {
+	return func(h *Health) error { return h.Register(check) }
+}
+
+// WithChecker registers component implementing protocol.HealthChecker as a check
+func WithChecker(name string, checker protocol.HealthChecker, critical bool) option {
+	return WithCheck(Check{Name: name, Func: checker.HealthCheck, Critical: critical})
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(h *Health) error {
+		h.log = log
+		return nil
+	}
+}
+
+// New creates health component. Being added to application after other components,
+// it reports readiness only after they are started and stops reporting it first on shutdown.
+func New(options ...option) (*Health, error) {
+	h := Health{results: map[string]Result{}}
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&h); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	return &h, nil
+}
+
+type Health struct {
+	name              string
+	interval, timeout time.Duration
+	log               zerolog.Logger
+
+	mu      sync.RWMutex
+	checks  []Check
+	results map[string]Result
+	started bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// Register adds check, checks can't be registered after start
+func (h *Health) Register(check Check) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.started {
+		return errors.New("already started")
+	}
+	if check.Name == "" || check.Func == nil {
+		return errors.New("check must have name and func")
+	}
+	if _, ok := h.results[check.Name]; ok {
+		return errors.Errorf("check %q already registered", check.Name)
+	}
+	h.checks = append(h.checks, check)
+	h.results[check.Name] = Result{Status: StatusFail, Error: "not checked", Critical: check.Critical}
+	return nil
+}
+
+// Start runs every check once and then keeps running them periodically
+func (h *Health) Start(ctx context.Context) error {
+	h.mu.Lock()
+	if h.started {
+		h.mu.Unlock()
+		return errors.New("already started")
+	}
+	checks := h.checks
+	h.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			h.run(ctx, check)
+		}(check)
+	}
+	wg.Wait()
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	for _, check := range checks {
+		h.wg.Add(1)
+		go h.loop(runCtx, check)
+	}
+
+	h.mu.Lock()
+	h.started, h.cancel = true, cancel
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *Health) Stop(context.Context) error {
+	h.mu.Lock()
+	if !h.started {
+		h.mu.Unlock()
+		return nil
+	}
+	h.started = false
+	h.cancel()
+	h.mu.Unlock()
+
+	h.wg.Wait()
+	return nil
+}
+
+func (h *Health) loop(ctx context.Context, check Check) {
+	defer h.wg.Done()
+	interval := check.Interval
+	if interval <= 0 {
+		interval = h.interval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.run(ctx, check)
+		}
+	}
+}
+
+func (h *Health) run(ctx context.Context, check Check) {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = h.timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Func(ctx)
+	result := Result{
+		Status:    StatusOK,
+		Critical:  check.Critical,
+		CheckedAt: start,
+		Duration:  time.Since(start).String(),
+	}
+	if err != nil {
+		result.Status, result.Error = StatusFail, err.Error()
+	}
+
+	h.mu.Lock()
+	previous := h.results[check.Name]
+	h.results[check.Name] = result
+	h.mu.Unlock()
+
+	if previous.Status != result.Status || previous.CheckedAt.IsZero() {
+		event := h.log.Info()
+		if err != nil {
+			event = h.log.Warn().Err(err)
+		}
+		event.Str("check", check.Name).Str("status", result.Status).Msg("health changed")
+	}
+}
+
+// Report returns cached results of checks
+func (h *Health) Report() Report {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(h.results))}
+	if !h.started {
+		report.Status = StatusFail
+	}
+	for name, result := range h.results {
+		report.Checks[name] = result
+		if result.Critical && result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// Ready reports whether component is started and all critical checks pass
+func (h *Health) Ready() bool { return h.Report().Status == StatusOK }
+
+// LivenessHandler responds with application info while process is running
+func (h *Health) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, application.Healthz())
+	})
+}
+
+// ReadinessHandler responds with report, status is service unavailable unless ready
+func (h *Health) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := h.Report()
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		respond(w, status, report)
+	})
+}
+
+func (h *Health) String() string { return h.name }
+
+func respond(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,108 @@
+package healthcheck_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/healthcheck"
+	"github.com/242617/core/leaktest"
+)
+
+var period = 10 * time.Millisecond
+
+func TestReadiness(t *testing.T) {
+	defer leaktest.Check(t)()
+	var failing atomic.Bool
+	h, err := healthcheck.New(
+		healthcheck.WithCheck(healthcheck.Check{
+			Name:     "critical",
+			Critical: true,
+			Interval: period,
+			Func: func(context.Context) error {
+				if failing.Load() {
+					return errors.New("sample error")
+				}
+				return nil
+			},
+		}),
+		healthcheck.WithCheck(healthcheck.Check{
+			Name: "optional",
+			Func: func(context.Context) error { return errors.New("optional error") },
+		}),
+	)
+	require.NoError(t, err, "new health")
+
+	assert.Equal(t, http.StatusServiceUnavailable, readiness(t, h).Code, "not ready before start")
+
+	require.NoError(t, h.Start(context.Background()), "start health")
+	w := readiness(t, h)
+	assert.Equal(t, http.StatusOK, w.Code, "ready after start")
+
+	var report healthcheck.Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report), "unmarshal report")
+	assert.Equal(t, healthcheck.StatusOK, report.Checks["critical"].Status, "critical check passed")
+	assert.Equal(t, "optional error", report.Checks["optional"].Error, "optional check failed")
+
+	failing.Store(true)
+	assert.Eventually(t, func() bool { return !h.Ready() }, 10*period, period, "critical check failed")
+	failing.Store(false)
+	assert.Eventually(t, h.Ready, 10*period, period, "critical check recovered")
+
+	require.NoError(t, h.Stop(context.Background()), "stop health")
+	assert.Equal(t, http.StatusServiceUnavailable, readiness(t, h).Code, "not ready after stop")
+}
+
+func TestTimeout(t *testing.T) {
+	h, err := healthcheck.New(
+		healthcheck.WithDefaults(time.Minute, period),
+		healthcheck.WithCheck(healthcheck.Check{
+			Name:     "slow",
+			Critical: true,
+			Func: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		}),
+	)
+	require.NoError(t, err, "new health")
+	require.NoError(t, h.Start(context.Background()), "start health")
+	defer h.Stop(context.Background())
+
+	report := h.Report()
+	assert.Equal(t, healthcheck.StatusFail, report.Status, "not ready")
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error, "timeout")
+}
+
+func TestRegister(t *testing.T) {
+	h, err := healthcheck.New()
+	require.NoError(t, err, "new health")
+
+	check := healthcheck.Check{Name: "sample", Func: func(context.Context) error { return nil }}
+	require.NoError(t, h.Register(check), "register")
+	assert.Error(t, h.Register(check), "duplicate")
+	assert.Error(t, h.Register(healthcheck.Check{Name: "empty"}), "without func")
+
+	require.NoError(t, h.Start(context.Background()), "start health")
+	defer h.Stop(context.Background())
+	assert.Error(t, h.Register(healthcheck.Check{Name: "late", Func: check.Func}), "after start")
+
+	w := httptest.NewRecorder()
+	h.LivenessHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code, "alive")
+	assert.Contains(t, w.Body.String(), "uptime", "application info")
+}
+
+func readiness(t *testing.T, h *healthcheck.Health) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ReadinessHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w
+}
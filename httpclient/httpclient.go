@@ -0,0 +1,236 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/requestid"
+	"github.com/242617/core/retry"
+)
+
+var errRetryableStatus = errors.New("retryable status")
+
+// Breaker is a circuit breaker consulted before every attempt, result of attempt is reported
+// with generation returned by Allow
+type Breaker interface {
+	Allow() (uint64, error)
+	Success(generation uint64)
+	Failure(generation uint64)
+}
+
+type option = func(c *Client) error
+
+func withDefaults() option {
+	return func(c *Client) error {
+		c.client = &http.Client{}
+		c.attempts = 3
+		c.minBackoff, c.maxBackoff = 100*time.Millisecond, 2*time.Second
+		c.log = l.With().Str("component", "httpclient").Logger()
+		return nil
+	}
+}
+
+func WithClient(client *http.Client) option {
+	return func(c *Client) error {
+		c.client = client
+		return nil
+	}
+}
+
+// WithTimeout limits duration of every single attempt including reading of the body
+func WithTimeout(timeout time.Duration) option {
+	return func(c *Client) error {
+		c.timeout = timeout
+		return nil
+	}
+}
+
+func WithMaxAttempts(attempts int) option {
+	return func(c *Client) error {
+		if attempts < 1 {
+			return errors.New("attempts must be positive")
+		}
+		c.attempts = attempts
+		return nil
+	}
+}
+
+// WithBackoff sets bounds of exponential backoff between attempts
+func WithBackoff(min, max time.Duration) option {
+	return func(c *Client) error {
+		if min <= 0 || max < min {
+			return errors.New("invalid backoff bounds")
+		}
+		c.minBackoff, c.maxBackoff = min, max
+		return nil
+	}
+}
+
+func WithBreaker(breaker Breaker) option {
+	return func(c *Client) error {
+		c.breaker = breaker
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(c *Client) error {
+		c.log = log
+		return nil
+	}
+}
+
+// New creates http client which retries failed requests
+func New(options ...option) (*Client, error) {
+	var c Client
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&c); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	return &c, nil
+}
+
+type Client struct {
+	client                 *http.Client
+	timeout                time.Duration
+	attempts               int
+	minBackoff, maxBackoff time.Duration
+	breaker                Breaker
+	log                    zerolog.Logger
+}
+
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "new request")
+	}
+	return c.Do(req)
+}
+
+func (c *Client) Post(ctx context.Context, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, errors.Wrap(err, "new request")
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.Do(req)
+}
+
+// Do sends request retrying on network errors, 429 and 5xx responses.
+// Requests with body are retried only if body can be obtained again via GetBody.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	id := requestid.FromContext(ctx)
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	attempts := c.attempts
+	if !replayable {
+		attempts = 1
+	}
+
+	var (
+		res     *http.Response
+		attempt int
+	)
+	err := retry.Do(ctx, func(ctx context.Context) error {
+		attempt++
+		var generation uint64
+		if c.breaker != nil {
+			var err error
+			if generation, err = c.breaker.Allow(); err != nil {
+				return retry.Permanent(err)
+			}
+		}
+
+		var err error
+		res, err = c.attempt(req, id, attempt, generation)
+		if err != nil {
+			return err
+		}
+		if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError {
+			if attempt < attempts {
+				_, _ = io.Copy(io.Discard, res.Body)
+				res.Body.Close()
+			}
+			return errRetryableStatus
+		}
+		return nil
+	},
+		retry.WithMaxAttempts(attempts),
+		retry.WithExponentialBackoff(c.minBackoff, c.maxBackoff),
+	)
+	if err != nil && !errors.Is(err, errRetryableStatus) {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) attempt(req *http.Request, id string, attempt int, generation uint64) (*http.Response, error) {
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if c.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+	}
+
+	r := req.Clone(ctx)
+	if attempt > 1 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, errors.Wrap(err, "get body")
+		}
+		r.Body = body
+	}
+	if id != "" && r.Header.Get(requestid.Header) == "" {
+		r.Header.Set(requestid.Header, id)
+	}
+
+	start := time.Now()
+	res, err := c.client.Do(r)
+
+	event := c.log.Debug()
+	if err != nil {
+		event = c.log.Warn().Err(err)
+	} else {
+		event = event.Int("status", res.StatusCode)
+	}
+	event.
+		Str("request_id", id).
+		Str("method", r.Method).
+		Str("url", r.URL.Redacted()).
+		Int("attempt", attempt).
+		Dur("duration", time.Since(start)).
+		Msg("attempt")
+
+	if c.breaker != nil {
+		if err != nil || res.StatusCode >= http.StatusInternalServerError {
+			c.breaker.Failure(generation)
+		} else {
+			c.breaker.Success(generation)
+		}
+	}
+
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &cancelBody{res.Body, cancel}
+	return res, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
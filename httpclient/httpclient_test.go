@@ -0,0 +1,142 @@
+package httpclient_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/httpclient"
+	"github.com/242617/core/requestid"
+)
+
+var period = 10 * time.Millisecond
+
+func TestRetry(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	defer srv.Close()
+
+	c, err := httpclient.New(httpclient.WithBackoff(time.Millisecond, period))
+	require.NoError(t, err, "new client")
+
+	res, err := c.Post(context.Background(), srv.URL, "text/plain", strings.NewReader("sample"))
+	require.NoError(t, err, "post")
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err, "read body")
+
+	assert.Equal(t, http.StatusOK, res.StatusCode, "unexpected status")
+	assert.Equal(t, "sample", string(body), "body replayed")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls), "called three times")
+}
+
+func TestNoRetry(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	c, err := httpclient.New(httpclient.WithBackoff(time.Millisecond, period))
+	require.NoError(t, err, "new client")
+
+	res, err := c.Get(context.Background(), srv.URL)
+	require.NoError(t, err, "get")
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "unexpected status")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "called once")
+}
+
+func TestRequestID(t *testing.T) {
+	var id string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = r.Header.Get(requestid.Header)
+	}))
+	defer srv.Close()
+
+	c, err := httpclient.New()
+	require.NoError(t, err, "new client")
+
+	res, err := c.Get(requestid.NewContext(context.Background(), "sample"), srv.URL)
+	require.NoError(t, err, "get")
+	res.Body.Close()
+	assert.Equal(t, "sample", id, "request id header")
+}
+
+func TestTimeout(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(10 * period):
+			}
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	c, err := httpclient.New(
+		httpclient.WithTimeout(period),
+		httpclient.WithBackoff(time.Millisecond, period),
+	)
+	require.NoError(t, err, "new client")
+
+	res, err := c.Get(context.Background(), srv.URL)
+	require.NoError(t, err, "get")
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err, "read body after attempt")
+	assert.Equal(t, "ok", string(body), "unexpected body")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls), "called twice")
+}
+
+func TestBreaker(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	breaker := withBreaker{limit: 2}
+	c, err := httpclient.New(
+		httpclient.WithMaxAttempts(5),
+		httpclient.WithBackoff(time.Millisecond, period),
+		httpclient.WithBreaker(&breaker),
+	)
+	require.NoError(t, err, "new client")
+
+	_, err = c.Get(context.Background(), srv.URL)
+	assert.ErrorIs(t, err, errOpen, "breaker open")
+	assert.Equal(t, 2, breaker.failures, "two failures")
+}
+
+var errOpen = errors.New("open")
+
+type withBreaker struct{ limit, failures int }
+
+func (b *withBreaker) Allow() (uint64, error) {
+	if b.failures >= b.limit {
+		return 0, errOpen
+	}
+	return 0, nil
+}
+func (b *withBreaker) Success(uint64) {}
+func (b *withBreaker) Failure(uint64) { b.failures++ }
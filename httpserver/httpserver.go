@@ -0,0 +1,235 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/httpserver/middleware"
+	"github.com/242617/core/protocol"
+)
+
+type option = func(s *Server) error
+
+func withDefaults() option {
+	return func(s *Server) error {
+		s.name, s.addr = "httpserver", ":8080"
+		s.readHeaderTimeout = 10 * time.Second
+		s.readTimeout, s.writeTimeout, s.idleTimeout = 30*time.Second, 30*time.Second, 120*time.Second
+		s.log = l.With().Str("component", "httpserver").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(s *Server) error {
+		s.name = name
+		return nil
+	}
+}
+
+func WithAddr(addr string) option {
+	return func(s *Server) error {
+		s.addr = addr
+		return nil
+	}
+}
+
+func WithHandler(handler http.Handler) option {
+	return func(s *Server) error {
+		s.handler = handler
+		return nil
+	}
+}
+
+// WithTLS makes server serve HTTPS using certificate and key files
+func WithTLS(certFile, keyFile string) option {
+	return func(s *Server) error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return errors.Wrap(err, "load key pair")
+		}
+		s.tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+		return nil
+	}
+}
+
+// WithTLSConfig makes server serve HTTPS using config, e.g. built by tlsutil
+func WithTLSConfig(config *tls.Config) option {
+	return func(s *Server) error {
+		s.tlsConfig = config
+		return nil
+	}
+}
+
+func WithReadTimeout(timeout time.Duration) option {
+	return func(s *Server) error {
+		s.readTimeout = timeout
+		return nil
+	}
+}
+
+func WithReadHeaderTimeout(timeout time.Duration) option {
+	return func(s *Server) error {
+		s.readHeaderTimeout = timeout
+		return nil
+	}
+}
+
+func WithWriteTimeout(timeout time.Duration) option {
+	return func(s *Server) error {
+		s.writeTimeout = timeout
+		return nil
+	}
+}
+
+func WithIdleTimeout(timeout time.Duration) option {
+	return func(s *Server) error {
+		s.idleTimeout = timeout
+		return nil
+	}
+}
+
+// WithMiddlewares replaces default middlewares
+func WithMiddlewares(middlewares ...middleware.Middleware) option {
+	return func(s *Server) error {
+		s.middlewares = append([]middleware.Middleware{}, middlewares...)
+		return nil
+	}
+}
+
+// WithMetrics adds metrics middleware to default ones
+func WithMetrics(recorder protocol.MetricsRecorder) option {
+	return func(s *Server) error {
+		s.metrics = recorder
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(s *Server) error {
+		s.log = log
+		return nil
+	}
+}
+
+// New creates http server component, which listens on Start and drains connections on Stop
+func New(options ...option) (*Server, error) {
+	var s Server
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&s); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+
+	if s.middlewares == nil {
+		s.middlewares = middleware.Default(s.log)
+		if s.metrics != nil {
+			s.middlewares = append(s.middlewares, middleware.Metrics(s.metrics))
+		}
+	}
+	if s.handler == nil {
+		s.handler = http.DefaultServeMux
+	}
+	s.handler = middleware.Chain(s.middlewares...)(s.handler)
+
+	return &s, nil
+}
+
+type Server struct {
+	name, addr        string
+	handler           http.Handler
+	tlsConfig         *tls.Config
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	middlewares       []middleware.Middleware
+	metrics           protocol.MetricsRecorder
+	log               zerolog.Logger
+
+	mu       sync.Mutex
+	server   *http.Server
+	listener net.Listener
+	doneCh   chan struct{}
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.server != nil {
+		return errors.New("already started")
+	}
+
+	var lc net.ListenConfig
+	listener, err := lc.Listen(ctx, "tcp", s.addr)
+	if err != nil {
+		return errors.Wrapf(err, "listen %s", s.addr)
+	}
+	if s.tlsConfig != nil {
+		listener = tls.NewListener(listener, s.tlsConfig)
+	}
+
+	s.server = &http.Server{
+		Handler:           s.handler,
+		TLSConfig:         s.tlsConfig,
+		ReadTimeout:       s.readTimeout,
+		ReadHeaderTimeout: s.readHeaderTimeout,
+		WriteTimeout:      s.writeTimeout,
+		IdleTimeout:       s.idleTimeout,
+	}
+	s.listener = listener
+	s.doneCh = make(chan struct{})
+
+	go func(server *http.Server, doneCh chan struct{}) {
+		defer close(doneCh)
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Error().Err(err).Msg("serve")
+		}
+	}(s.server, s.doneCh)
+
+	s.log.Info().Msgf("listening on %s", listener.Addr())
+	return nil
+}
+
+// Stop stops accepting new connections and waits for active requests to complete.
+// Remaining connections are closed forcibly when ctx is done.
+func (s *Server) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.server == nil {
+		return nil
+	}
+	server, doneCh := s.server, s.doneCh
+	s.server, s.listener = nil, nil
+
+	if err := server.Shutdown(ctx); err != nil {
+		_ = server.Close()
+		return errors.Wrap(err, "shutdown")
+	}
+	<-doneCh
+	return nil
+}
+
+// Addr returns address server is listening on or configured address if server is not started
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener != nil {
+		return s.listener.Addr().String()
+	}
+	return s.addr
+}
+
+func (s *Server) String() string { return s.name }
@@ -0,0 +1,88 @@
+package httpserver_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/httpserver"
+)
+
+func TestBasic(t *testing.T) {
+	s, err := httpserver.New(
+		httpserver.WithAddr("127.0.0.1:0"),
+		httpserver.WithHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, "ok")
+		})),
+	)
+	require.NoError(t, err, "new server")
+	require.NoError(t, s.Start(context.Background()), "start server")
+
+	res, err := http.Get("http://" + s.Addr())
+	require.NoError(t, err, "get")
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(t, err, "read body")
+	assert.Equal(t, "ok", string(body), "unexpected body")
+
+	require.NoError(t, s.Stop(context.Background()), "stop server")
+	_, err = http.Get("http://" + s.Addr())
+	assert.Error(t, err, "server stopped")
+}
+
+func TestGracefulStop(t *testing.T) {
+	period := 50 * time.Millisecond
+	startedCh := make(chan struct{})
+	s, err := httpserver.New(
+		httpserver.WithAddr("127.0.0.1:0"),
+		httpserver.WithHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(startedCh)
+			time.Sleep(period)
+			_, _ = io.WriteString(w, "done")
+		})),
+	)
+	require.NoError(t, err, "new server")
+	require.NoError(t, s.Start(context.Background()), "start server")
+
+	bodyCh := make(chan string)
+	go func() {
+		res, err := http.Get("http://" + s.Addr())
+		if err != nil {
+			bodyCh <- err.Error()
+			return
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		bodyCh <- string(body)
+	}()
+
+	<-startedCh
+	require.NoError(t, s.Stop(context.Background()), "stop server")
+	assert.Equal(t, "done", <-bodyCh, "request drained")
+}
+
+func TestStopTimeout(t *testing.T) {
+	startedCh, releaseCh := make(chan struct{}), make(chan struct{})
+	defer close(releaseCh)
+	s, err := httpserver.New(
+		httpserver.WithAddr("127.0.0.1:0"),
+		httpserver.WithHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(startedCh)
+			<-releaseCh
+		})),
+	)
+	require.NoError(t, err, "new server")
+	require.NoError(t, s.Start(context.Background()), "start server")
+
+	go func() { _, _ = http.Get("http://" + s.Addr()) }()
+	<-startedCh
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.Stop(ctx), context.DeadlineExceeded, "stop timeout")
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// Timeout limits time of handling request, responds with service unavailable on timeout
+func Timeout(timeout time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, timeout, http.StatusText(http.StatusServiceUnavailable))
+	}
+}
+
+// BodyLimit limits size of request body
+func BodyLimit(limit int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
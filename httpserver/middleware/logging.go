@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/242617/core/requestid"
+)
+
+// Logging logs every request with its status, size and duration
+func Logging(log zerolog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := wrap(w)
+			next.ServeHTTP(rw, r)
+
+			event := log.Info()
+			if rw.Status() >= http.StatusInternalServerError {
+				event = log.Error()
+			}
+			event.
+				Str("request_id", requestid.FromContext(r.Context())).
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Int("status", rw.Status()).
+				Int("bytes", rw.bytes).
+				Dur("duration", time.Since(start)).
+				Msg("request")
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/242617/core/protocol"
+)
+
+const (
+	MetricRequestsTotal   = "http_requests_total"
+	MetricRequestDuration = "http_request_duration_seconds"
+)
+
+// Metrics records number and duration of requests by method and status code
+func Metrics(recorder protocol.MetricsRecorder) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := wrap(w)
+			next.ServeHTTP(rw, r)
+
+			code := strconv.Itoa(rw.Status())
+			recorder.Add(MetricRequestsTotal, 1, "method", r.Method, "code", code)
+			recorder.Observe(MetricRequestDuration, time.Since(start).Seconds(), "method", r.Method, "code", code)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package middleware_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/httpserver/middleware"
+	"github.com/242617/core/requestid"
+)
+
+func TestChain(t *testing.T) {
+	var order []string
+	mark := func(name string) middleware.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	h := middleware.Chain(mark("one"), mark("two"), mark("three"))(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		order = append(order, "handler")
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "one,two,three,handler", strings.Join(order, ","), "unexpected order")
+}
+
+func TestRequestID(t *testing.T) {
+	var id string
+	h := middleware.RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = requestid.FromContext(r.Context())
+	}))
+
+	{
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.NotEmpty(t, id, "generated request id")
+		assert.Equal(t, id, w.Header().Get(requestid.Header), "response header")
+	}
+
+	{
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(requestid.Header, "sample")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, "sample", id, "propagated request id")
+		assert.Equal(t, "sample", w.Header().Get(requestid.Header), "response header")
+	}
+}
+
+func TestLoggingAndRecovery(t *testing.T) {
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	h := middleware.Chain(middleware.Default(log)...)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("sample panic")
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/path", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code, "unexpected status")
+	assert.Contains(t, buf.String(), `"panic":"sample panic"`, "panic logged")
+	assert.Contains(t, buf.String(), `"path":"/path"`, "request logged")
+	assert.Contains(t, buf.String(), `"status":500`, "status logged")
+}
+
+func TestTimeout(t *testing.T) {
+	h := middleware.Timeout(10 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "unexpected status")
+}
+
+func TestBodyLimit(t *testing.T) {
+	h := middleware.BodyLimit(4)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		}
+	}))
+
+	{
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("1234")))
+		assert.Equal(t, http.StatusOK, w.Code, "within limit")
+	}
+
+	{
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("12345")))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code, "over limit")
+	}
+
+	{
+		r := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader("12345")))
+		r.ContentLength = -1
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code, "over limit without content length")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	var recorder withRecorder
+	h := middleware.Metrics(&recorder)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Len(t, recorder.calls, 2, "unexpected calls")
+	assert.Equal(t, "http_requests_total method=GET code=418", recorder.calls[0], "unexpected counter")
+	assert.Equal(t, "http_request_duration_seconds method=GET code=418", recorder.calls[1], "unexpected histogram")
+}
+
+type withRecorder struct {
+	sync.Mutex
+	calls []string
+}
+
+func (r *withRecorder) Add(name string, _ float64, labels ...string)     { r.record(name, labels) }
+func (r *withRecorder) Set(name string, _ float64, labels ...string)     { r.record(name, labels) }
+func (r *withRecorder) Observe(name string, _ float64, labels ...string) { r.record(name, labels) }
+
+func (r *withRecorder) record(name string, labels []string) {
+	r.Lock()
+	defer r.Unlock()
+	for i := 0; i+1 < len(labels); i += 2 {
+		name += " " + labels[i] + "=" + labels[i+1]
+	}
+	r.calls = append(r.calls, name)
+}
+
+func TestHijack(t *testing.T) {
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	h := middleware.Chain(middleware.Default(log)...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if !assert.NoError(t, err, "hijack") {
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\nhello")
+		rw.Flush()
+	}))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err, "get")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode, "status")
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "hello", string(body), "body")
+
+	w := httptest.NewRecorder()
+	middleware.Chain(middleware.Default(log)...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _, err := http.NewResponseController(w).Hijack()
+		assert.ErrorIs(t, err, http.ErrNotSupported, "recorder cannot hijack")
+	})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/rs/zerolog"
+
+	"github.com/242617/core/requestid"
+)
+
+// Recovery recovers from panics in handlers and responds with internal server error
+func Recovery(log zerolog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := wrap(w)
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				log.Error().
+					Str("request_id", requestid.FromContext(r.Context())).
+					Interface("panic", v).
+					Bytes("stack", debug.Stack()).
+					Msg("recovered")
+				if rw.status == 0 {
+					http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				}
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package id
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// UUID is a version 7 UUID, lexically sortable by creation time
+type UUID [16]byte
+
+var uuidState struct {
+	sync.Mutex
+	ms  int64
+	seq uint16
+}
+
+// NewUUID generates UUIDv7. Counter in rand_a keeps IDs generated within the same millisecond ordered.
+func NewUUID() UUID {
+	var u UUID
+	random(u[6:])
+
+	uuidState.Lock()
+	ms := time.Now().UnixMilli()
+	if ms <= uuidState.ms {
+		ms = uuidState.ms
+		uuidState.seq++
+		if uuidState.seq > 0xfff {
+			ms++
+			uuidState.seq = 0
+		}
+	} else {
+		// random start leaves room for increments
+		uuidState.seq = binary.BigEndian.Uint16(u[6:8]) & 0x7ff
+	}
+	uuidState.ms = ms
+	seq := uuidState.seq
+	uuidState.Unlock()
+
+	u[0], u[1], u[2], u[3], u[4], u[5] = byte(ms>>40), byte(ms>>32), byte(ms>>24), byte(ms>>16), byte(ms>>8), byte(ms)
+	u[6] = 0x70 | byte(seq>>8)
+	u[7] = byte(seq)
+	u[8] = 0x80 | u[8]&0x3f
+	return u
+}
+
+func (u UUID) String() string {
+	var b [36]byte
+	hex.Encode(b[0:8], u[0:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], u[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], u[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], u[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], u[10:])
+	return string(b[:])
+}
+
+// Time returns creation time with millisecond precision
+func (u UUID) Time() time.Time {
+	ms := int64(u[0])<<40 | int64(u[1])<<32 | int64(u[2])<<24 | int64(u[3])<<16 | int64(u[4])<<8 | int64(u[5])
+	return time.UnixMilli(ms)
+}
+
+// ULID is a lexically sortable identifier of 48-bit timestamp and 80-bit randomness
+type ULID [16]byte
+
+var ulidState struct {
+	sync.Mutex
+	ms   int64
+	last [10]byte
+}
+
+// NewULID generates ULID, randomness is incremented within the same millisecond to keep order
+func NewULID() ULID {
+	var u ULID
+
+	ulidState.Lock()
+	ms := time.Now().UnixMilli()
+	if ms <= ulidState.ms {
+		ms = ulidState.ms
+		if !increment(ulidState.last[:]) {
+			ms++
+			random(ulidState.last[:])
+		}
+	} else {
+		random(ulidState.last[:])
+	}
+	ulidState.ms = ms
+	copy(u[6:], ulidState.last[:])
+	ulidState.Unlock()
+
+	u[0], u[1], u[2], u[3], u[4], u[5] = byte(ms>>40), byte(ms>>32), byte(ms>>24), byte(ms>>16), byte(ms>>8), byte(ms)
+	return u
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// String returns 26 characters of Crockford's base32
+func (u ULID) String() string {
+	var b [26]byte
+	// 128 bits are encoded as 130 bits with two leading zero bits
+	var carry uint
+	var bits uint
+	i := 25
+	for j := 15; j >= 0; j-- {
+		carry |= uint(u[j]) << bits
+		bits += 8
+		for bits >= 5 {
+			b[i] = crockford[carry&0x1f]
+			carry >>= 5
+			bits -= 5
+			i--
+		}
+	}
+	b[i] = crockford[carry&0x1f]
+	return string(b[:])
+}
+
+// Time returns creation time with millisecond precision
+func (u ULID) Time() time.Time {
+	ms := int64(u[0])<<40 | int64(u[1])<<32 | int64(u[2])<<24 | int64(u[3])<<16 | int64(u[4])<<8 | int64(u[5])
+	return time.UnixMilli(ms)
+}
+
+func random(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+}
+
+// increment adds one to big-endian number reporting false on overflow
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
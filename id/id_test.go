@@ -0,0 +1,84 @@
+package id_test
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/id"
+)
+
+const n = 10000
+
+// unique generates ids concurrently checking none of them repeats
+func unique(t *testing.T, next func() string) []string {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		seen = make(map[string]struct{}, n)
+	)
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n/4; i++ {
+				v := next()
+				mu.Lock()
+				seen[v] = struct{}{}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	require.Len(t, seen, n, "no collisions")
+
+	// sequential ids must be sorted
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = next()
+	}
+	assert.True(t, sort.StringsAreSorted(ids), "sortable")
+	return ids
+}
+
+func TestUUID(t *testing.T) {
+	ids := unique(t, func() string { return id.NewUUID().String() })
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), ids[0], "format")
+	assert.WithinDuration(t, time.Now(), id.NewUUID().Time(), time.Second, "time")
+}
+
+func TestULID(t *testing.T) {
+	ids := unique(t, func() string { return id.NewULID().String() })
+	assert.Regexp(t, regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`), ids[0], "format")
+	assert.WithinDuration(t, time.Now(), id.NewULID().Time(), time.Second, "time")
+}
+
+func TestSnowflake(t *testing.T) {
+	_, err := id.NewSnowflake(-1)
+	assert.Error(t, err, "negative node")
+	_, err = id.NewSnowflake(id.MaxNode + 1)
+	assert.Error(t, err, "node out of range")
+
+	s, err := id.NewSnowflake(7)
+	require.NoError(t, err, "new snowflake")
+	unique(t, func() string { return strconv.FormatInt(s.Next(), 10) })
+
+	prev := s.Next()
+	for i := 0; i < n; i++ {
+		next := s.Next()
+		require.Greater(t, next, prev, "monotonic")
+		prev = next
+	}
+	assert.Equal(t, int64(7), prev>>12&id.MaxNode, "node")
+	assert.WithinDuration(t, time.Now(), s.Time(prev), time.Second, "time")
+
+	other, err := id.NewSnowflake(8)
+	require.NoError(t, err, "new snowflake")
+	assert.NotEqual(t, s.Next(), other.Next(), "different nodes")
+}
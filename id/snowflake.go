@@ -0,0 +1,74 @@
+package id
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	nodeBits     = 10
+	sequenceBits = 12
+	MaxNode      = 1<<nodeBits - 1
+)
+
+// Epoch is a default start of snowflake timestamps
+var Epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// SnowflakeConfig configures node of snowflake generator
+type SnowflakeConfig struct {
+	Node int64 `yaml:"node" env:"NODE_ID" default:"0"`
+}
+
+// NewSnowflake creates generator of 63-bit IDs made of milliseconds since Epoch,
+// node and per-millisecond sequence. Every instance must use unique node.
+func NewSnowflake(node int64) (*Snowflake, error) {
+	if node < 0 || node > MaxNode {
+		return nil, errors.Errorf("node must be within [0, %d]", MaxNode)
+	}
+	return &Snowflake{node: node, epoch: Epoch.UnixMilli()}, nil
+}
+
+type Snowflake struct {
+	node  int64
+	epoch int64
+
+	mu  sync.Mutex
+	ms  int64
+	seq int64
+}
+
+// Next returns next ID, it waits for the next millisecond once sequence is exhausted
+func (s *Snowflake) Next() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ms := time.Now().UnixMilli() - s.epoch
+	// clock moved backwards, stay on last timestamp
+	if ms < s.ms {
+		ms = s.ms
+	}
+	if ms == s.ms {
+		s.seq = (s.seq + 1) & (1<<sequenceBits - 1)
+		if s.seq == 0 {
+			for ms <= s.ms {
+				time.Sleep(100 * time.Microsecond)
+				ms = time.Now().UnixMilli() - s.epoch
+			}
+		}
+	} else {
+		s.seq = 0
+	}
+	s.ms = ms
+	return ms<<(nodeBits+sequenceBits) | s.node<<sequenceBits | s.seq
+}
+
+// NextString returns next ID in decimal form
+func (s *Snowflake) NextString() string { return strconv.FormatInt(s.Next(), 10) }
+
+// Time returns creation time of ID
+func (s *Snowflake) Time(id int64) time.Time {
+	return time.UnixMilli(id>>(nodeBits+sequenceBits) + s.epoch)
+}
@@ -0,0 +1,175 @@
+package leaktest
+
+import (
+	"context"
+	"database/sql"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/242617/core/protocol"
+)
+
+// TB is a subset of testing.TB used by helpers
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Cleanup(func())
+}
+
+// knownSafe are functions of goroutines started once per process by runtime, testing and dependencies
+var knownSafe = []string{
+	"testing.Main(",
+	"testing.(*T).Run(",
+	"testing.(*T).Parallel(",
+	"testing.tRunner(",
+	"testing.runTests(",
+	"os/signal.signal_recv(",
+	"os/signal.loop(",
+	"runtime.ensureSigM",
+	"go.opencensus.io/stats/view.(*worker).start(",
+	"github.com/rs/zerolog/diode.",
+	"go.opentelemetry.io/otel/sdk/trace.(*batchSpanProcessor).processQueue(",
+	"google.golang.org/grpc/internal/grpcsync.(*CallbackSerializer).run(",
+}
+
+type option = func(c *config)
+
+type config struct {
+	timeout time.Duration
+	ignore  []string
+}
+
+// WithTimeout sets time goroutines are given to exit
+func WithTimeout(timeout time.Duration) option {
+	return func(c *config) { c.timeout = timeout }
+}
+
+// Ignore ignores goroutines which stacks contain any of substrings, e.g. function names
+func Ignore(substrings ...string) option {
+	return func(c *config) { c.ignore = append(c.ignore, substrings...) }
+}
+
+func newConfig(options []option) config {
+	c := config{timeout: 5 * time.Second, ignore: knownSafe}
+	for _, option := range options {
+		option(&c)
+	}
+	return c
+}
+
+// Check snapshots running goroutines and returns func reporting ones started since then and still running.
+// Goroutines are given timeout to exit.
+//
+//	defer leaktest.Check(t)()
+func Check(t TB, options ...option) func() {
+	t.Helper()
+	c := newConfig(options)
+	before := map[string]bool{}
+	for _, g := range goroutines() {
+		before[g.id] = true
+	}
+
+	return func() {
+		t.Helper()
+		var leaked []goroutine
+		deadline := time.Now().Add(c.timeout)
+		for delay := time.Millisecond; ; delay *= 2 {
+			leaked = leaked[:0]
+			for _, g := range goroutines() {
+				if !before[g.id] && !c.ignored(g) {
+					leaked = append(leaked, g)
+				}
+			}
+			if len(leaked) == 0 || time.Now().After(deadline) {
+				break
+			}
+			if delay > 100*time.Millisecond {
+				delay = 100 * time.Millisecond
+			}
+			time.Sleep(delay)
+		}
+		for _, g := range leaked {
+			t.Errorf("leaked goroutine: %s", g.stack)
+		}
+	}
+}
+
+// Verify registers Check to be run on test cleanup
+func Verify(t TB, options ...option) {
+	t.Helper()
+	t.Cleanup(Check(t, options...))
+}
+
+// Lifecycle starts and stops component reporting errors and goroutines left after Stop
+func Lifecycle(t TB, component protocol.Lifecycle, options ...option) {
+	t.Helper()
+	check := Check(t, options...)
+	ctx, cancel := context.WithTimeout(context.Background(), newConfig(options).timeout)
+	defer cancel()
+	if err := component.Start(ctx); err != nil {
+		t.Errorf("start: %s", err)
+		return
+	}
+	if err := component.Stop(ctx); err != nil {
+		t.Errorf("stop: %s", err)
+	}
+	check()
+}
+
+// DBReleased reports connections of pool still in use, e.g. held by unclosed rows or transactions
+func DBReleased(t TB, db *sql.DB) {
+	t.Helper()
+	if n := db.Stats().InUse; n > 0 {
+		t.Errorf("%d connections are in use", n)
+	}
+}
+
+// DBClosed reports pool not closed
+func DBClosed(t TB, db *sql.DB) {
+	t.Helper()
+	if err := db.Ping(); err == nil || !strings.Contains(err.Error(), "database is closed") {
+		t.Errorf("database is not closed")
+	}
+}
+
+func (c config) ignored(g goroutine) bool {
+	for _, s := range c.ignore {
+		if strings.Contains(g.stack, s) {
+			return true
+		}
+	}
+	return false
+}
+
+type goroutine struct {
+	id    string
+	stack string
+}
+
+func goroutines() []goroutine {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	var res []goroutine
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		header, rest, _ := strings.Cut(stack, "\n")
+		// header looks like "goroutine 7 [chan receive]:"
+		fields := strings.Fields(header)
+		if len(fields) < 2 || fields[0] != "goroutine" {
+			continue
+		}
+		if rest == "" {
+			continue
+		}
+		res = append(res, goroutine{id: fields[1], stack: stack})
+	}
+	return res
+}
@@ -0,0 +1,109 @@
+package leaktest_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/application"
+	"github.com/242617/core/leaktest"
+)
+
+var period = 10 * time.Millisecond
+
+type withTB struct {
+	mu       sync.Mutex
+	errors   []string
+	cleanups []func()
+}
+
+func (t *withTB) Helper() {}
+
+func (t *withTB) Errorf(format string, args ...any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *withTB) Cleanup(f func()) { t.cleanups = append(t.cleanups, f) }
+
+func leakyFunc(stopCh chan struct{}) { <-stopCh }
+
+func TestCheck(t *testing.T) {
+	{
+		var tb withTB
+		check := leaktest.Check(&tb, leaktest.WithTimeout(period))
+		doneCh := make(chan struct{})
+		go func() { <-doneCh }()
+		close(doneCh)
+		check()
+		assert.Empty(t, tb.errors, "exited goroutine")
+	}
+
+	{
+		var tb withTB
+		check := leaktest.Check(&tb, leaktest.WithTimeout(period))
+		stopCh := make(chan struct{})
+		defer close(stopCh)
+		go leakyFunc(stopCh)
+		check()
+		require.Len(t, tb.errors, 1, "leaked goroutine")
+		assert.Contains(t, tb.errors[0], "leaktest_test.leakyFunc", "stack")
+	}
+
+	{
+		var tb withTB
+		check := leaktest.Check(&tb, leaktest.WithTimeout(period), leaktest.Ignore("leaktest_test.leakyFunc"))
+		stopCh := make(chan struct{})
+		defer close(stopCh)
+		go leakyFunc(stopCh)
+		check()
+		assert.Empty(t, tb.errors, "ignored goroutine")
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	var tb withTB
+	leaktest.Lifecycle(&tb, application.NewMethodsComponent("leaky",
+		func(context.Context) error {
+			go leakyFunc(stopCh)
+			return nil
+		}, nil,
+	), leaktest.WithTimeout(period))
+	assert.Len(t, tb.errors, 1, "goroutine left after stop")
+}
+
+func TestDB(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "new mock")
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	require.NoError(t, err, "begin")
+
+	var tb withTB
+	leaktest.DBReleased(&tb, db)
+	assert.Len(t, tb.errors, 1, "transaction holds connection")
+
+	mock.ExpectRollback()
+	require.NoError(t, tx.Rollback(), "rollback")
+	tb = withTB{}
+	leaktest.DBReleased(&tb, db)
+	leaktest.DBClosed(&tb, db)
+	assert.Equal(t, []string{"database is not closed"}, tb.errors, "released but open")
+
+	mock.ExpectClose()
+	require.NoError(t, db.Close(), "close")
+	tb = withTB{}
+	leaktest.DBClosed(&tb, db)
+	assert.Empty(t, tb.errors, "closed")
+}
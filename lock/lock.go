@@ -0,0 +1,161 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrNotAcquired is returned when lock is held by someone else
+	ErrNotAcquired = errors.New("lock not acquired")
+	// ErrLost is returned by renewal when lock is no longer owned
+	ErrLost = errors.New("lock lost")
+)
+
+// Locker makes sure a resource is owned by a single instance
+type Locker interface {
+	// TryLock acquires lock without waiting, ErrNotAcquired is returned if it is held by someone else
+	TryLock(ctx context.Context, key string) (*Lock, error)
+}
+
+type option = func(c *config) error
+
+type config struct {
+	ttl       time.Duration
+	heartbeat time.Duration
+	onLost    func(key string)
+	log       zerolog.Logger
+}
+
+func withDefaults() option {
+	return func(c *config) error {
+		c.ttl = 30 * time.Second
+		c.log = l.With().Str("component", "lock").Logger()
+		return nil
+	}
+}
+
+// WithTTL sets time lock is kept without renewal
+func WithTTL(ttl time.Duration) option {
+	return func(c *config) error {
+		if ttl <= 0 {
+			return errors.New("ttl must be positive")
+		}
+		c.ttl = ttl
+		return nil
+	}
+}
+
+// WithHeartbeat sets interval of lock renewal, defaults to third of ttl
+func WithHeartbeat(interval time.Duration) option {
+	return func(c *config) error {
+		if interval <= 0 {
+			return errors.New("interval must be positive")
+		}
+		c.heartbeat = interval
+		return nil
+	}
+}
+
+// WithOnLost sets callback called when held lock is lost
+func WithOnLost(f func(key string)) option {
+	return func(c *config) error {
+		c.onLost = f
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(c *config) error {
+		c.log = log
+		return nil
+	}
+}
+
+func newConfig(options []option) (config, error) {
+	var c config
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&c); err != nil {
+			return c, errors.Wrap(err, "apply option")
+		}
+	}
+	if c.heartbeat == 0 {
+		c.heartbeat = c.ttl / 3
+	}
+	if c.heartbeat >= c.ttl {
+		return c, errors.New("heartbeat must be less than ttl")
+	}
+	return c, nil
+}
+
+// Lock is an acquired lock renewed in background until Unlock
+type Lock struct {
+	key     string
+	lost    chan struct{}
+	once    sync.Once
+	cancel  context.CancelFunc
+	done    chan struct{}
+	release func(ctx context.Context) error
+}
+
+func newLock(c config, key string, renew, release func(ctx context.Context) error) *Lock {
+	ctx, cancel := context.WithCancel(context.Background())
+	lock := Lock{
+		key:     key,
+		lost:    make(chan struct{}),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		release: release,
+	}
+
+	go func() {
+		defer close(lock.done)
+		ticker := time.NewTicker(c.heartbeat)
+		defer ticker.Stop()
+		renewed := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			err := renew(ctx)
+			if err == nil {
+				renewed = time.Now()
+				continue
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			c.log.Warn().Err(err).Str("key", key).Msg("renew")
+			if errors.Is(err, ErrLost) || time.Since(renewed) >= c.ttl {
+				c.log.Error().Str("key", key).Msg("lock lost")
+				lock.once.Do(func() { close(lock.lost) })
+				if c.onLost != nil {
+					c.onLost(key)
+				}
+				return
+			}
+		}
+	}()
+	return &lock
+}
+
+func (l *Lock) Key() string { return l.key }
+
+// Lost is closed when lock ownership is lost
+func (l *Lock) Lost() <-chan struct{} { return l.lost }
+
+// Unlock stops renewal and releases lock
+func (l *Lock) Unlock(ctx context.Context) error {
+	l.cancel()
+	<-l.done
+	return l.release(ctx)
+}
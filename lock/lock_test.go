@@ -0,0 +1,116 @@
+package lock_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/lock"
+)
+
+var period = 10 * time.Millisecond
+
+func TestRedis(t *testing.T) {
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	defer client.Close()
+
+	locker, err := lock.NewRedis(client, lock.WithTTL(3*period), lock.WithHeartbeat(period))
+	require.NoError(t, err, "new locker")
+
+	lk, err := locker.TryLock(context.Background(), "sample")
+	require.NoError(t, err, "lock")
+	_, err = locker.TryLock(context.Background(), "sample")
+	assert.ErrorIs(t, err, lock.ErrNotAcquired, "held")
+
+	srv.FastForward(2 * period)
+	time.Sleep(2 * period)
+	srv.FastForward(2 * period)
+	assert.True(t, srv.Exists("sample"), "renewed")
+
+	require.NoError(t, lk.Unlock(context.Background()), "unlock")
+	assert.False(t, srv.Exists("sample"), "released")
+	lk, err = locker.TryLock(context.Background(), "sample")
+	require.NoError(t, err, "lock again")
+	require.NoError(t, lk.Unlock(context.Background()), "unlock again")
+}
+
+func TestRedisLost(t *testing.T) {
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	defer client.Close()
+
+	lostKeys := make(chan string, 1)
+	locker, err := lock.NewRedis(client,
+		lock.WithTTL(time.Minute),
+		lock.WithHeartbeat(period),
+		lock.WithOnLost(func(key string) { lostKeys <- key }),
+	)
+	require.NoError(t, err, "new locker")
+
+	lk, err := locker.TryLock(context.Background(), "sample")
+	require.NoError(t, err, "lock")
+	srv.Set("sample", "stolen")
+
+	select {
+	case <-lk.Lost():
+	case <-time.After(10 * period):
+		t.Fatal("lock is not lost")
+	}
+	assert.Equal(t, "sample", <-lostKeys, "callback")
+	require.NoError(t, lk.Unlock(context.Background()), "unlock")
+	value, _ := srv.Get("sample")
+	assert.Equal(t, "stolen", value, "foreign lock kept")
+}
+
+func TestRedlock(t *testing.T) {
+	var clients []redis.UniversalClient
+	var servers []*miniredis.Miniredis
+	for i := 0; i < 3; i++ {
+		srv := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+		defer client.Close()
+		servers, clients = append(servers, srv), append(clients, client)
+	}
+	servers[0].Set("sample", "foreign")
+
+	locker, err := lock.NewRedlock(clients)
+	require.NoError(t, err, "new locker")
+	lk, err := locker.TryLock(context.Background(), "sample")
+	require.NoError(t, err, "majority acquired")
+	require.NoError(t, lk.Unlock(context.Background()), "unlock")
+
+	servers[1].Set("sample", "foreign")
+	_, err = locker.TryLock(context.Background(), "sample")
+	assert.ErrorIs(t, err, lock.ErrNotAcquired, "no majority")
+	assert.False(t, servers[2].Exists("sample"), "partial lock released")
+}
+
+func TestPostgres(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err, "new mock")
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(true))
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(false))
+
+	locker, err := lock.NewPostgres(db)
+	require.NoError(t, err, "new locker")
+	lk, err := locker.TryLock(context.Background(), "sample")
+	require.NoError(t, err, "try lock")
+	require.NoError(t, lk.Unlock(context.Background()), "unlock")
+
+	_, err = locker.TryLock(context.Background(), "sample")
+	assert.ErrorIs(t, err, lock.ErrNotAcquired, "held by another session")
+	assert.NoError(t, mock.ExpectationsWereMet(), "expectations")
+}
@@ -0,0 +1,62 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+
+	"github.com/pkg/errors"
+)
+
+// NewPostgres creates locker using session-level advisory locks,
+// connection is held and pinged every heartbeat while lock is owned
+func NewPostgres(db *sql.DB, options ...option) (*PostgresLocker, error) {
+	c, err := newConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	return &PostgresLocker{db, c}, nil
+}
+
+type PostgresLocker struct {
+	db *sql.DB
+	config
+}
+
+func (p *PostgresLocker) TryLock(ctx context.Context, key string) (*Lock, error) {
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get connection")
+	}
+
+	id := advisoryKey(key)
+	var ok bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", id).Scan(&ok); err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "try advisory lock")
+	}
+	if !ok {
+		conn.Close()
+		return nil, ErrNotAcquired
+	}
+
+	renew := func(ctx context.Context) error {
+		// lock is released by server once session is gone
+		if err := conn.PingContext(ctx); err != nil {
+			return errors.Wrap(ErrLost, err.Error())
+		}
+		return nil
+	}
+	release := func(ctx context.Context) error {
+		defer conn.Close()
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", id)
+		return errors.Wrap(err, "advisory unlock")
+	}
+	return newLock(p.config, key, renew, release), nil
+}
+
+func advisoryKey(key string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return int64(h.Sum64())
+}
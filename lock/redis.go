@@ -0,0 +1,112 @@
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+	renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// NewRedis creates locker using SET NX with expiration renewed every heartbeat
+func NewRedis(client redis.UniversalClient, options ...option) (*RedisLocker, error) {
+	return NewRedlock([]redis.UniversalClient{client}, options...)
+}
+
+// NewRedlock creates locker acquiring lock on majority of independent Redis nodes
+func NewRedlock(clients []redis.UniversalClient, options ...option) (*RedisLocker, error) {
+	if len(clients) == 0 {
+		return nil, errors.New("no clients")
+	}
+	c, err := newConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisLocker{clients, c}, nil
+}
+
+type RedisLocker struct {
+	clients []redis.UniversalClient
+	config
+}
+
+func (r *RedisLocker) TryLock(ctx context.Context, key string) (*Lock, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, errors.Wrap(err, "generate token")
+	}
+	token := hex.EncodeToString(b)
+
+	start := time.Now()
+	var (
+		acquired int
+		lastErr  error
+	)
+	for _, client := range r.clients {
+		ok, err := client.SetNX(ctx, key, token, r.ttl).Result()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if ok {
+			acquired++
+		}
+	}
+
+	release := func(ctx context.Context) error {
+		var lastErr error
+		for _, client := range r.clients {
+			if err := unlockScript.Run(ctx, client, []string{key}, token).Err(); err != nil {
+				lastErr = err
+			}
+		}
+		return errors.Wrap(lastErr, "unlock")
+	}
+	if acquired < r.quorum() || time.Since(start) >= r.ttl {
+		_ = release(context.Background())
+		if lastErr != nil && acquired == 0 {
+			return nil, errors.Wrap(lastErr, "set")
+		}
+		return nil, ErrNotAcquired
+	}
+
+	renew := func(ctx context.Context) error {
+		var (
+			renewed int
+			lastErr error
+		)
+		for _, client := range r.clients {
+			n, err := renewScript.Run(ctx, client, []string{key}, token, r.ttl.Milliseconds()).Int()
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			renewed += n
+		}
+		if renewed >= r.quorum() {
+			return nil
+		}
+		if lastErr != nil {
+			return errors.Wrap(lastErr, "renew")
+		}
+		return ErrLost
+	}
+	return newLock(r.config, key, renew, release), nil
+}
+
+func (r *RedisLocker) quorum() int { return len(r.clients)/2 + 1 }
@@ -0,0 +1,203 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/242617/core/requestid"
+)
+
+// Formats of access log
+const (
+	// AccessFormatCompact is "time method path status bytes latency request_id"
+	AccessFormatCompact = "compact"
+	// AccessFormatCommon is Apache common log format
+	AccessFormatCommon = "common"
+	// AccessFormatCombined is Apache combined log format, common one with referer and user agent
+	AccessFormatCombined = "combined"
+)
+
+// AccessEntry is a served request, Method, Path and Status are mandatory
+type AccessEntry struct {
+	Time       time.Time
+	Method     string
+	Path       string
+	Proto      string
+	Status     int
+	Bytes      int64
+	Latency    time.Duration
+	RequestID  string
+	RemoteAddr string
+	User       string
+	Referer    string
+	UserAgent  string
+}
+
+type accessOption = func(a *AccessLogger) error
+
+func withAccessDefaults() accessOption {
+	return func(a *AccessLogger) error {
+		a.out = os.Stdout
+		a.format = AccessFormatCompact
+		return nil
+	}
+}
+
+// WithAccessWriter sets writer of access log, stdout by default
+func WithAccessWriter(w io.Writer) accessOption {
+	return func(a *AccessLogger) error {
+		a.out = w
+		return nil
+	}
+}
+
+// WithAccessOutput sets access log to stderr, stdout, file or socket like Output.Path
+func WithAccessOutput(path string, rotation Rotation) accessOption {
+	return func(a *AccessLogger) error {
+		w, closer, err := openOutput(Output{Path: path, Rotation: rotation})
+		if err != nil {
+			return err
+		}
+		a.out, a.closer = w, closer
+		return nil
+	}
+}
+
+// WithAccessFormat sets format of lines, compact by default
+func WithAccessFormat(format string) accessOption {
+	return func(a *AccessLogger) error {
+		switch format {
+		case AccessFormatCompact, AccessFormatCommon, AccessFormatCombined:
+		default:
+			return errors.Errorf("unknown access format %q", format)
+		}
+		a.format = format
+		return nil
+	}
+}
+
+// NewAccessLogger creates access log writing one line per request, separate from other logs
+func NewAccessLogger(options ...accessOption) (*AccessLogger, error) {
+	var a AccessLogger
+	options = append([]accessOption{withAccessDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&a); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	return &a, nil
+}
+
+type AccessLogger struct {
+	out    io.Writer
+	closer io.Closer
+	format string
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// Log writes entry filling its time and request id of ctx if they are empty
+func (a *AccessLogger) Log(ctx context.Context, entry AccessEntry) error {
+	for _, f := range []struct{ name, value string }{{"method", entry.Method}, {"path", entry.Path}} {
+		if f.value == "" {
+			return errors.Errorf("empty %s", f.name)
+		}
+	}
+	if entry.Status == 0 {
+		return errors.New("empty status")
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if entry.RequestID == "" {
+		entry.RequestID = requestid.FromContext(ctx)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.format == AccessFormatCompact {
+		a.buf = appendCompact(a.buf[:0], entry)
+	} else {
+		a.buf = appendCommon(a.buf[:0], entry, a.format == AccessFormatCombined)
+	}
+	if _, err := a.out.Write(a.buf); err != nil {
+		return errors.Wrap(err, "write")
+	}
+	return nil
+}
+
+func (a *AccessLogger) Start(context.Context) error { return nil }
+
+func (a *AccessLogger) Stop(context.Context) error {
+	if a.closer == nil {
+		return nil
+	}
+	return errors.Wrap(a.closer.Close(), "close output")
+}
+
+func (a *AccessLogger) String() string { return "access" }
+
+func appendCompact(b []byte, e AccessEntry) []byte {
+	b = e.Time.UTC().AppendFormat(b, time.RFC3339Nano)
+	b = append(b, ' ')
+	b = append(b, e.Method...)
+	b = append(b, ' ')
+	b = append(b, e.Path...)
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, int64(e.Status), 10)
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, e.Bytes, 10)
+	b = append(b, ' ')
+	b = append(b, e.Latency.String()...)
+	b = append(b, ' ')
+	b = append(b, orDash(e.RequestID)...)
+	return append(b, '\n')
+}
+
+func appendCommon(b []byte, e AccessEntry, combined bool) []byte {
+	host := e.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	b = append(b, orDash(host)...)
+	b = append(b, " - "...)
+	b = append(b, orDash(e.User)...)
+	b = append(b, " ["...)
+	b = e.Time.AppendFormat(b, "02/Jan/2006:15:04:05 -0700")
+	b = append(b, `] "`...)
+	b = append(b, e.Method...)
+	b = append(b, ' ')
+	b = append(b, e.Path...)
+	b = append(b, ' ')
+	b = append(b, orDash(e.Proto)...)
+	b = append(b, `" `...)
+	b = strconv.AppendInt(b, int64(e.Status), 10)
+	b = append(b, ' ')
+	if e.Bytes > 0 {
+		b = strconv.AppendInt(b, e.Bytes, 10)
+	} else {
+		b = append(b, '-')
+	}
+	if combined {
+		b = append(b, ' ')
+		b = strconv.AppendQuote(b, orDash(e.Referer))
+		b = append(b, ' ')
+		b = strconv.AppendQuote(b, orDash(e.UserAgent))
+	}
+	return append(b, '\n')
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
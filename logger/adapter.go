@@ -0,0 +1,114 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// FromZerolog creates logger writing records with log of service not migrated yet, so its outputs,
+// hooks and fields are kept while components are given Logger or its zerolog.Logger.
+// Level of log is used unless options set another one.
+func FromZerolog(log zerolog.Logger, options ...option) (*Logger, error) {
+	level := log.GetLevel()
+	if level == zerolog.NoLevel {
+		level = zerolog.TraceLevel
+	}
+	w := forwardWriter(func(level zerolog.Level, msg string, fields map[string]any) {
+		log.WithLevel(level).Fields(fields).Msg(msg)
+	})
+	return New(append([]option{WithWriter(w), WithLevel(level.String())}, options...)...)
+}
+
+// FromZap creates logger writing records with core of log like FromZerolog does, fatal and panic
+// records are written at their levels while exit and panic are left to Logger
+func FromZap(log *zap.Logger, options ...option) (*Logger, error) {
+	core := log.Core()
+	level := LevelTrace
+	switch zapcore.LevelOf(core) {
+	case zapcore.DebugLevel:
+		level = LevelDebug
+	case zapcore.InfoLevel:
+		level = LevelInfo
+	case zapcore.WarnLevel:
+		level = LevelWarn
+	case zapcore.ErrorLevel:
+		level = LevelError
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		level = LevelPanic
+	case zapcore.FatalLevel:
+		level = LevelFatal
+	}
+	w := forwardWriter(func(level zerolog.Level, msg string, fields map[string]any) {
+		entry := zapcore.Entry{Level: zapLevel(level), Time: time.Now(), LoggerName: log.Name(), Message: msg}
+		ce := core.Check(entry, nil)
+		if ce == nil {
+			return
+		}
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		zapFields := make([]zap.Field, 0, len(keys))
+		for _, key := range keys {
+			value := fields[key]
+			if n, ok := value.(json.Number); ok {
+				if i, err := n.Int64(); err == nil {
+					value = i
+				} else {
+					value, _ = n.Float64()
+				}
+			}
+			zapFields = append(zapFields, zap.Any(key, value))
+		}
+		ce.Write(zapFields...)
+	})
+	return New(append([]option{WithWriter(w), WithLevel(level)}, options...)...)
+}
+
+func zapLevel(level zerolog.Level) zapcore.Level {
+	switch level {
+	case zerolog.TraceLevel, zerolog.DebugLevel:
+		return zapcore.DebugLevel
+	case zerolog.WarnLevel:
+		return zapcore.WarnLevel
+	case zerolog.ErrorLevel:
+		return zapcore.ErrorLevel
+	case zerolog.FatalLevel:
+		return zapcore.FatalLevel
+	case zerolog.PanicLevel:
+		return zapcore.PanicLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+// forwardWriter decodes JSON records and passes their message and fields to another logger,
+// time and level are set by it
+type forwardWriter func(level zerolog.Level, msg string, fields map[string]any)
+
+func (w forwardWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w forwardWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	var fields map[string]any
+	d := json.NewDecoder(bytes.NewReader(p))
+	d.UseNumber()
+	if err := d.Decode(&fields); err != nil {
+		w(level, string(bytes.TrimRight(p, "\n")), nil)
+		return len(p), nil
+	}
+	msg, _ := fields[zerolog.MessageFieldName].(string)
+	delete(fields, zerolog.MessageFieldName)
+	delete(fields, zerolog.LevelFieldName)
+	delete(fields, zerolog.TimestampFieldName)
+	w(level, msg, fields)
+	return len(p), nil
+}
@@ -0,0 +1,172 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// WithAsync makes records to be queued in ring buffer of bufferSize and written by background
+// goroutine every flushInterval or once buffer is half full. The oldest records are dropped
+// on overflow. Use Flush to wait for queued records and Close or Stop on shutdown.
+func WithAsync(bufferSize int, flushInterval time.Duration) option {
+	return func(lg *Logger) error {
+		if bufferSize < 1 {
+			return errors.New("buffer size must be positive")
+		}
+		if flushInterval <= 0 {
+			return errors.New("flush interval must be positive")
+		}
+		lg.bufferSize, lg.flushInterval = bufferSize, flushInterval
+		return nil
+	}
+}
+
+func newAsyncWriter(out io.Writer, size int, interval time.Duration) *asyncWriter {
+	a := asyncWriter{
+		out:      zerolog.MultiLevelWriter(out),
+		interval: interval,
+		ring:     make([]asyncRecord, size),
+		wake:     make(chan struct{}, 1),
+		flush:    make(chan chan struct{}),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go a.run()
+	return &a
+}
+
+type asyncRecord struct {
+	level zerolog.Level
+	p     []byte
+}
+
+type asyncWriter struct {
+	out      zerolog.LevelWriter
+	outMu    sync.Mutex
+	interval time.Duration
+
+	mu         sync.Mutex
+	ring       []asyncRecord
+	head, size int
+	dropped    int
+	closed     bool
+
+	wake    chan struct{}
+	flush   chan chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	stopped chan struct{}
+}
+
+func (a *asyncWriter) Write(p []byte) (int, error) {
+	return a.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (a *asyncWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		a.outMu.Lock()
+		defer a.outMu.Unlock()
+		return a.out.WriteLevel(level, p)
+	}
+	record := asyncRecord{level, append([]byte(nil), p...)}
+	if a.size == len(a.ring) {
+		a.ring[a.head] = record
+		a.head = (a.head + 1) % len(a.ring)
+		a.dropped++
+	} else {
+		a.ring[(a.head+a.size)%len(a.ring)] = record
+		a.size++
+	}
+	full := a.size*2 >= len(a.ring)
+	a.mu.Unlock()
+
+	if full {
+		select {
+		case a.wake <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+func (a *asyncWriter) run() {
+	defer close(a.stopped)
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.drain()
+		case <-a.wake:
+			a.drain()
+		case req := <-a.flush:
+			a.drain()
+			close(req)
+		case <-a.done:
+			a.mu.Lock()
+			a.closed = true
+			a.mu.Unlock()
+			a.drain()
+			return
+		}
+	}
+}
+
+func (a *asyncWriter) drain() {
+	a.mu.Lock()
+	records := make([]asyncRecord, 0, a.size)
+	for i := 0; i < a.size; i++ {
+		j := (a.head + i) % len(a.ring)
+		records = append(records, a.ring[j])
+		a.ring[j] = asyncRecord{}
+	}
+	dropped := a.dropped
+	a.head, a.size, a.dropped = 0, 0, 0
+	a.mu.Unlock()
+
+	a.outMu.Lock()
+	defer a.outMu.Unlock()
+	if dropped > 0 {
+		log := zerolog.New(a.out)
+		log.Warn().Timestamp().Int("dropped", dropped).Msg("async buffer overflow")
+	}
+	for _, record := range records {
+		_, _ = a.out.WriteLevel(record.level, record.p)
+	}
+}
+
+// Flush waits until queued records are written
+func (a *asyncWriter) Flush(ctx context.Context) error {
+	req := make(chan struct{})
+	select {
+	case a.flush <- req:
+	case <-a.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-req:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close writes queued records and stops background goroutine, later records are written synchronously
+func (a *asyncWriter) Close(ctx context.Context) error {
+	a.once.Do(func() { close(a.done) })
+	select {
+	case <-a.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,185 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/242617/core/requestid"
+)
+
+// Outcomes of audited actions
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeUnknown = "unknown"
+)
+
+// AuditEvent is a record of audit stream, Actor, Action, Resource and Outcome are mandatory
+type AuditEvent struct {
+	Time      time.Time      `json:"time"`
+	Seq       uint64         `json:"seq"`
+	Actor     string         `json:"actor"`
+	Action    string         `json:"action"`
+	Resource  string         `json:"resource"`
+	Outcome   string         `json:"outcome"`
+	RequestID string         `json:"request_id,omitempty"`
+	Attrs     map[string]any `json:"attrs,omitempty"`
+	// PrevHMAC chains record to the previous one if HMAC key is set
+	PrevHMAC string `json:"prev_hmac,omitempty"`
+}
+
+type auditOption = func(a *Audit) error
+
+func withAuditDefaults() auditOption {
+	return func(a *Audit) error {
+		a.out = os.Stdout
+		return nil
+	}
+}
+
+// WithAuditWriter sets writer of audit stream, stdout by default
+func WithAuditWriter(w io.Writer) auditOption {
+	return func(a *Audit) error {
+		a.out = w
+		return nil
+	}
+}
+
+// WithAuditOutput sets audit stream to stderr, stdout, file or socket like Output.Path
+func WithAuditOutput(path string, rotation Rotation) auditOption {
+	return func(a *Audit) error {
+		w, closer, err := openOutput(Output{Path: path, Rotation: rotation})
+		if err != nil {
+			return err
+		}
+		a.out, a.closer = w, closer
+		return nil
+	}
+}
+
+// WithHMAC chains records with HMAC-SHA256 of key, so removed or modified records are detected by VerifyAudit
+func WithHMAC(key []byte) auditOption {
+	return func(a *Audit) error {
+		if len(key) == 0 {
+			return errors.New("empty key")
+		}
+		a.key = key
+		return nil
+	}
+}
+
+// NewAudit creates audit stream separate from logs. Records are written synchronously with
+// sequence numbers starting from 1, so sequence and HMAC chain restart with the process.
+func NewAudit(options ...auditOption) (*Audit, error) {
+	var a Audit
+	options = append([]auditOption{withAuditDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&a); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	return &a, nil
+}
+
+type Audit struct {
+	out    io.Writer
+	closer io.Closer
+	key    []byte
+
+	mu   sync.Mutex
+	seq  uint64
+	prev string
+}
+
+// Log writes event filling its time, sequence number, request id of ctx and HMAC chain
+func (a *Audit) Log(ctx context.Context, event AuditEvent) error {
+	for _, f := range []struct{ name, value string }{
+		{"actor", event.Actor}, {"action", event.Action}, {"resource", event.Resource}, {"outcome", event.Outcome},
+	} {
+		if f.value == "" {
+			return errors.Errorf("empty %s", f.name)
+		}
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	if event.RequestID == "" {
+		event.RequestID = requestid.FromContext(ctx)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	event.Seq = a.seq + 1
+	event.PrevHMAC = a.prev
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "marshal")
+	}
+	var mac string
+	if a.key != nil {
+		mac = sign(a.key, data)
+		data = append(data[:len(data)-1], `,"hmac":"`+mac+`"}`...)
+	}
+	if _, err := a.out.Write(append(data, '\n')); err != nil {
+		return errors.Wrap(err, "write")
+	}
+	a.seq, a.prev = event.Seq, mac
+	return nil
+}
+
+func (a *Audit) Start(context.Context) error { return nil }
+
+func (a *Audit) Stop(context.Context) error {
+	if a.closer == nil {
+		return nil
+	}
+	return errors.Wrap(a.closer.Close(), "close output")
+}
+
+func (a *Audit) String() string { return "audit" }
+
+// VerifyAudit checks sequence numbers and HMAC chain of audit stream written with key
+func VerifyAudit(r io.Reader, key []byte) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	var seq uint64
+	var prev string
+	for line := 1; scanner.Scan(); line++ {
+		data := scanner.Bytes()
+		i := bytes.LastIndex(data, []byte(`,"hmac":"`))
+		if i < 0 {
+			return errors.Errorf("line %d: no hmac", line)
+		}
+		mac := string(bytes.TrimSuffix(data[i+len(`,"hmac":"`):], []byte(`"}`)))
+		unsigned := append(append([]byte(nil), data[:i]...), '}')
+		if !hmac.Equal([]byte(mac), []byte(sign(key, unsigned))) {
+			return errors.Errorf("line %d: hmac mismatch", line)
+		}
+		var event AuditEvent
+		if err := json.Unmarshal(unsigned, &event); err != nil {
+			return errors.Wrapf(err, "line %d", line)
+		}
+		if seq > 0 && (event.Seq != seq+1 || event.PrevHMAC != prev) {
+			return errors.Errorf("line %d: chain broken after sequence %d", line, seq)
+		}
+		seq, prev = event.Seq, mac
+	}
+	return errors.Wrap(scanner.Err(), "read")
+}
+
+func sign(key, data []byte) string {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// bootstrapBufferSize is a number of records kept by bootstrap logger until FlushTo
+const bootstrapBufferSize = 1024
+
+var bootstrap struct {
+	once sync.Once
+	lg   *BootstrapLogger
+}
+
+// Bootstrap returns process-wide logger buffering records written before the configured logger
+// is created, e.g. while config is loaded. Records are replayed by FlushTo, up to 1024 latest
+// ones are kept.
+func Bootstrap() *BootstrapLogger {
+	bootstrap.once.Do(func() {
+		w := bootstrapWriter{}
+		lg, _ := New(WithWriter(&w), WithLevel(LevelTrace))
+		bootstrap.lg = &BootstrapLogger{Logger: lg, buf: &w}
+	})
+	return bootstrap.lg
+}
+
+type BootstrapLogger struct {
+	*Logger
+	buf *bootstrapWriter
+}
+
+// FlushTo writes buffered records to lg keeping their time and level, records below root level
+// of lg are skipped. Records written to bootstrap logger afterwards go to lg directly.
+func (b *BootstrapLogger) FlushTo(lg *Logger) {
+	b.buf.flushTo(lg)
+}
+
+type bootstrapWriter struct {
+	mu      sync.Mutex
+	records []asyncRecord
+	dropped int
+	target  *Logger
+	out     zerolog.LevelWriter
+}
+
+func (w *bootstrapWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w *bootstrapWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.target != nil {
+		return w.write(level, p)
+	}
+	if len(w.records) == bootstrapBufferSize {
+		w.records = w.records[1:]
+		w.dropped++
+	}
+	w.records = append(w.records, asyncRecord{level, append([]byte(nil), p...)})
+	return len(p), nil
+}
+
+func (w *bootstrapWriter) write(level zerolog.Level, p []byte) (int, error) {
+	if level != zerolog.NoLevel && level < w.target.levels.resolve("") {
+		return len(p), nil
+	}
+	return w.out.WriteLevel(level, p)
+}
+
+func (w *bootstrapWriter) flushTo(lg *Logger) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.target, w.out = lg, zerolog.MultiLevelWriter(lg.writer)
+	if w.dropped > 0 {
+		lg.Warn().Int("dropped", w.dropped).Msg("bootstrap buffer overflow")
+	}
+	for _, record := range w.records {
+		_, _ = w.write(record.level, record.p)
+	}
+	w.records, w.dropped = nil, 0
+}
@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/242617/core/requestid"
+)
+
+// ContextField is a field extracted from context by Logger.Ctx, empty values are skipped
+type ContextField struct {
+	Key     string
+	Extract func(context.Context) string
+}
+
+var (
+	contextFieldsMu sync.RWMutex
+	contextFields   = []ContextField{{"request_id", requestid.FromContext}}
+)
+
+// RegisterContextField adds field extracted from context by every logger, it replaces
+// extractor of the same key, e.g. the builtin request_id one
+func RegisterContextField(key string, extractor func(context.Context) string) {
+	contextFieldsMu.Lock()
+	defer contextFieldsMu.Unlock()
+	for i, f := range contextFields {
+		if f.Key == key {
+			contextFields[i].Extract = extractor
+			return
+		}
+	}
+	contextFields = append(contextFields, ContextField{key, extractor})
+}
+
+// WithContextFields adds fields extracted from context by the logger and its children only
+func WithContextFields(fields ...ContextField) option {
+	return func(lg *Logger) error {
+		lg.contextFields = append(lg.contextFields, fields...)
+		return nil
+	}
+}
+
+type attrsKey struct{}
+
+// ContextWithAttrs returns context carrying key/value pairs or slog.Attr added to records
+// by Logger.Ctx and slog handler, e.g. user id set once by middleware. Pairs are appended
+// to ones of parent context, values implementing slog.LogValuer are resolved on every record.
+func ContextWithAttrs(ctx context.Context, args ...any) context.Context {
+	parent, _ := ctx.Value(attrsKey{}).([]any)
+	attrs := append(append(make([]any, 0, len(parent)+len(args)), parent...), pairs(args)...)
+	return context.WithValue(ctx, attrsKey{}, attrs)
+}
+
+// Ctx creates child logger adding fields extracted from ctx, trace_id and span_id
+// and attributes of ContextWithAttrs included
+func (lg *Logger) Ctx(ctx context.Context) *Logger {
+	var args []any
+	contextFieldsMu.RLock()
+	for _, f := range contextFields {
+		if value := f.Extract(ctx); value != "" {
+			args = append(args, f.Key, value)
+		}
+	}
+	contextFieldsMu.RUnlock()
+	for _, f := range lg.contextFields {
+		if value := f.Extract(ctx); value != "" {
+			args = append(args, f.Key, value)
+		}
+	}
+	attrs, _ := ctx.Value(attrsKey{}).([]any)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		value := attrs[i+1]
+		if v, ok := value.(slog.LogValuer); ok {
+			value = attrValue(slog.AnyValue(v).Resolve())
+		}
+		args = append(args, attrs[i], value)
+	}
+	lg = lg.WithSpan(ctx)
+	if len(args) == 0 {
+		return lg
+	}
+	return lg.withTop(args...)
+}
+
+// DebugAttrs writes record at debug level with fields of ctx and attrs, unlike Ctx it creates
+// no child logger, so it does not allocate for records of usual attributes
+func (lg *Logger) DebugAttrs(ctx context.Context, msg string, attrs ...slog.Attr) {
+	lg.logAttrs(ctx, zerolog.DebugLevel, msg, attrs)
+}
+
+// InfoAttrs writes record at info level like DebugAttrs does
+func (lg *Logger) InfoAttrs(ctx context.Context, msg string, attrs ...slog.Attr) {
+	lg.logAttrs(ctx, zerolog.InfoLevel, msg, attrs)
+}
+
+// WarnAttrs writes record at warn level like DebugAttrs does
+func (lg *Logger) WarnAttrs(ctx context.Context, msg string, attrs ...slog.Attr) {
+	lg.logAttrs(ctx, zerolog.WarnLevel, msg, attrs)
+}
+
+// ErrorAttrs writes record at error level like DebugAttrs does
+func (lg *Logger) ErrorAttrs(ctx context.Context, msg string, attrs ...slog.Attr) {
+	lg.logAttrs(ctx, zerolog.ErrorLevel, msg, attrs)
+}
+
+func (lg *Logger) logAttrs(ctx context.Context, level zerolog.Level, msg string, attrs []slog.Attr) {
+	e := lg.ctxEvent(ctx, level)
+	if e == nil {
+		return
+	}
+	for _, a := range attrs {
+		e = appendAttr(e, a)
+	}
+	e.Msg(msg)
+}
+
+// ctxEvent starts record adding fields of ctx like Ctx does, it is nil if level is disabled.
+// Fields are added to record instead of child logger unless logger is grouped.
+func (lg *Logger) ctxEvent(ctx context.Context, level zerolog.Level) *zerolog.Event {
+	if level < zerolog.GlobalLevel() || level < lg.levels.floor(lg.name) {
+		return nil
+	}
+	if len(lg.groups) > 0 {
+		return lg.Ctx(ctx).WithLevel(level)
+	}
+	e := lg.WithLevel(level)
+	if e == nil {
+		return nil
+	}
+	contextFieldsMu.RLock()
+	for _, f := range contextFields {
+		if value := f.Extract(ctx); value != "" {
+			e = e.Str(f.Key, value)
+		}
+	}
+	contextFieldsMu.RUnlock()
+	for _, f := range lg.contextFields {
+		if value := f.Extract(ctx); value != "" {
+			e = e.Str(f.Key, value)
+		}
+	}
+	attrs, _ := ctx.Value(attrsKey{}).([]any)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		key, _ := attrs[i].(string)
+		e = appendAttr(e, slog.Any(key, attrs[i+1]))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		e = e.Str("trace_id", sc.TraceID().String()).Str("span_id", sc.SpanID().String())
+	}
+	return e
+}
@@ -0,0 +1,137 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// RepeatCountFieldName is a key of number of records collapsed by WithDeduplication
+const RepeatCountFieldName = "repeat_count"
+
+// WithDeduplication collapses records of the same level, message and fields written within window
+// since the first of them. The first record is written at once, the last repeated one is written
+// at the end of window with repeat_count field set to number of suppressed records.
+func WithDeduplication(window time.Duration) option {
+	return func(lg *Logger) error {
+		if window <= 0 {
+			return errors.New("window must be positive")
+		}
+		lg.dedupWindow = window
+		return nil
+	}
+}
+
+type dedupWriter struct {
+	out    zerolog.LevelWriter
+	window time.Duration
+
+	mu      sync.Mutex
+	records map[uint64]*dedupRecord
+	swept   time.Time
+}
+
+type dedupRecord struct {
+	start time.Time
+	count int
+	level zerolog.Level
+	last  []byte
+	timer *time.Timer
+}
+
+func newDedupWriter(out zerolog.LevelWriter, window time.Duration) *dedupWriter {
+	return &dedupWriter{out: out, window: window, records: map[uint64]*dedupRecord{}, swept: time.Now()}
+}
+
+func (w *dedupWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w *dedupWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	key, ok := dedupKey(level, p)
+	if !ok {
+		return w.out.WriteLevel(level, p)
+	}
+
+	now := time.Now()
+	w.mu.Lock()
+	if now.Sub(w.swept) > w.window {
+		for key, r := range w.records {
+			if r.count == 0 && now.Sub(r.start) > w.window {
+				delete(w.records, key)
+			}
+		}
+		w.swept = now
+	}
+	if r, ok := w.records[key]; ok && now.Sub(r.start) <= w.window {
+		r.count++
+		r.level, r.last = level, append(r.last[:0], p...)
+		if r.timer == nil {
+			r.timer = time.AfterFunc(r.start.Add(w.window).Sub(now), func() { w.emit(key) })
+		}
+		w.mu.Unlock()
+		return len(p), nil
+	}
+	w.records[key] = &dedupRecord{start: now}
+	w.mu.Unlock()
+	return w.out.WriteLevel(level, p)
+}
+
+// emit writes collapsed record of key
+func (w *dedupWriter) emit(key uint64) {
+	w.mu.Lock()
+	r, ok := w.records[key]
+	if ok {
+		delete(w.records, key)
+	}
+	w.mu.Unlock()
+	if ok && r.count > 0 {
+		w.write(r)
+	}
+}
+
+func (w *dedupWriter) write(r *dedupRecord) {
+	p := bytes.TrimRight(r.last, "\n")
+	p = append(p[:len(p)-1], `,"`+RepeatCountFieldName+`":`+strconv.Itoa(r.count)+"}\n"...)
+	_, _ = w.out.WriteLevel(r.level, p)
+}
+
+// Close writes collapsed records of windows not ended yet
+func (w *dedupWriter) Close() error {
+	w.mu.Lock()
+	records := w.records
+	w.records = map[uint64]*dedupRecord{}
+	w.mu.Unlock()
+	for _, r := range records {
+		if r.timer != nil {
+			r.timer.Stop()
+		}
+		if r.count > 0 {
+			w.write(r)
+		}
+	}
+	return nil
+}
+
+// dedupKey hashes level and JSON record without time, ok is false if p is not JSON object
+func dedupKey(level zerolog.Level, p []byte) (uint64, bool) {
+	var record map[string]json.RawMessage
+	if err := json.Unmarshal(p, &record); err != nil {
+		return 0, false
+	}
+	delete(record, zerolog.TimestampFieldName)
+	data, err := json.Marshal(record)
+	if err != nil {
+		return 0, false
+	}
+	h := fnv.New64a()
+	h.Write([]byte{byte(level)})
+	h.Write(data)
+	return h.Sum64(), true
+}
@@ -0,0 +1,197 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattn/go-isatty"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// Encodings of records written to output
+const (
+	EncodingJSON   = "json"
+	EncodingText   = "text"
+	EncodingLogfmt = "logfmt"
+)
+
+// WithEncoding sets encoding of records written to output, records exported over OTLP are not affected
+func WithEncoding(encoding string) option {
+	return func(lg *Logger) error {
+		if err := checkEncoding(encoding); err != nil {
+			return err
+		}
+		lg.encoding = encoding
+		return nil
+	}
+}
+
+// Colorize modes of text encoding
+const (
+	// ColorizeAuto enables color if output is a terminal and NO_COLOR is not set
+	ColorizeAuto   = "auto"
+	ColorizeAlways = "always"
+	ColorizeNever  = "never"
+)
+
+// WithColorize sets colorize mode of text encoding, auto by default
+func WithColorize(mode string) option {
+	return func(lg *Logger) error {
+		if err := checkColorize(mode); err != nil {
+			return err
+		}
+		lg.colorize = mode
+		return nil
+	}
+}
+
+func checkColorize(mode string) error {
+	switch mode {
+	case "", ColorizeAuto, ColorizeAlways, ColorizeNever:
+		return nil
+	default:
+		return errors.Errorf("unknown colorize mode %q", mode)
+	}
+}
+
+func colorEnabled(mode string, w io.Writer) bool {
+	switch mode {
+	case ColorizeAlways:
+		return true
+	case ColorizeNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	return ok && (isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd()))
+}
+
+func checkEncoding(encoding string) error {
+	switch encoding {
+	case "", EncodingJSON, EncodingText, EncodingLogfmt:
+		return nil
+	default:
+		return errors.Errorf("unknown encoding %q", encoding)
+	}
+}
+
+// encoder wraps w receiving JSON records into writer of encoding, format applies to JSON only
+// and color to text only
+func encoder(encoding string, preset *preset, ts *timestamp, w io.Writer, color bool) io.Writer {
+	if _, ok := w.(*netOutput); ok {
+		return w
+	}
+	switch encoding {
+	case EncodingText:
+		cw := zerolog.ConsoleWriter{Out: w, NoColor: !color, TimeFormat: time.RFC3339}
+		if ts != nil {
+			cw.FormatTimestamp = ts.console(color)
+		}
+		return cw
+	case EncodingLogfmt:
+		return logfmtWriter{w}
+	default:
+		if preset != nil {
+			return formatWriter{preset, w}
+		}
+		return w
+	}
+}
+
+type field struct {
+	key   string
+	value json.RawMessage
+}
+
+// objectFields decodes JSON object keeping order of its keys
+func objectFields(raw []byte) ([]field, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if token, err := dec.Token(); err != nil {
+		return nil, err
+	} else if token != json.Delim('{') {
+		return nil, errors.New("not an object")
+	}
+	var fields []field
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := token.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, field{key, value})
+	}
+	return fields, nil
+}
+
+// logfmtWriter writes records as logfmt lines starting with time, level and msg,
+// nested objects and arrays are written as JSON
+type logfmtWriter struct{ out io.Writer }
+
+func (w logfmtWriter) Write(p []byte) (int, error) {
+	fields, err := objectFields(p)
+	if err != nil {
+		return w.out.Write(p)
+	}
+	var buf bytes.Buffer
+	for _, key := range []string{zerolog.TimestampFieldName, zerolog.LevelFieldName, zerolog.MessageFieldName} {
+		for _, f := range fields {
+			if f.key == key {
+				writeLogfmt(&buf, f)
+			}
+		}
+	}
+	for _, f := range fields {
+		switch f.key {
+		case zerolog.TimestampFieldName, zerolog.LevelFieldName, zerolog.MessageFieldName:
+		default:
+			writeLogfmt(&buf, f)
+		}
+	}
+	buf.WriteByte('\n')
+	if _, err := w.out.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func writeLogfmt(buf *bytes.Buffer, f field) {
+	if buf.Len() > 0 {
+		buf.WriteByte(' ')
+	}
+	key := f.key
+	if key == zerolog.MessageFieldName {
+		key = "msg"
+	}
+	buf.WriteString(strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			return '_'
+		}
+		return r
+	}, key))
+	buf.WriteByte('=')
+
+	value := string(bytes.TrimSpace(f.value))
+	var s string
+	if err := json.Unmarshal(f.value, &s); err == nil {
+		value = s
+	} else if value == "null" {
+		value = ""
+	}
+	if value == "" || strings.ContainsAny(value, " =\"\\") || strings.IndexFunc(value, func(r rune) bool { return r < ' ' || r == utf8.RuneError }) >= 0 {
+		value = strconv.Quote(value)
+	}
+	buf.WriteString(value)
+}
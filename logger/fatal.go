@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// WithExitFunc replaces os.Exit called by Fatal, e.g. in tests
+func WithExitFunc(exit func(code int)) option {
+	return func(lg *Logger) error {
+		lg.exit = exit
+		return nil
+	}
+}
+
+// Fatal logs msg with key/value pairs and fields of ctx, closes logger to write queued records
+// and exits with code 1. It replaces Fatal of zerolog.Logger, which does not flush.
+func (lg *Logger) Fatal(ctx context.Context, msg string, args ...any) {
+	lg.log(ctx, zerolog.FatalLevel, msg, args)
+	if err := lg.Close(ctx); err != nil {
+		lg.Logger.Error().Err(err).Msg("close")
+	}
+	lg.exit(1)
+}
+
+// Panic logs msg like Fatal, flushes logger and panics with msg, so it can be recovered
+func (lg *Logger) Panic(ctx context.Context, msg string, args ...any) {
+	lg.log(ctx, zerolog.PanicLevel, msg, args)
+	if err := lg.Flush(ctx); err != nil {
+		lg.Logger.Error().Err(err).Msg("flush")
+	}
+	panic(msg)
+}
+
+func (lg *Logger) log(ctx context.Context, level zerolog.Level, msg string, args []any) {
+	log := lg.Ctx(ctx).Logger
+	event := log.WithLevel(level)
+	if len(args) > 0 {
+		event = event.Fields(pairs(args))
+	}
+	event.Msg(msg)
+}
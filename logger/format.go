@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// Formats of JSON records, fields are renamed according to conventions of log storage
+const (
+	FormatECS     = "ecs"
+	FormatGCP     = "gcp"
+	FormatDatadog = "datadog"
+)
+
+// WithFormat renames standard fields of JSON records: ecs for Elastic Common Schema,
+// gcp for Google Cloud Logging, trace is prefixed with GOOGLE_CLOUD_PROJECT if set,
+// and datadog for Datadog log pipelines
+func WithFormat(format string) option {
+	return func(lg *Logger) error {
+		if format == "" {
+			lg.preset = nil
+			return nil
+		}
+		p, ok := presets[format]
+		if !ok {
+			return errors.Errorf("unknown format %q", format)
+		}
+		if format == FormatGCP {
+			p = gcpPreset(os.Getenv("GOOGLE_CLOUD_PROJECT"))
+		}
+		lg.preset = &p
+		return nil
+	}
+}
+
+type preset struct {
+	// fields are renamed and their values are converted if func is set
+	fields map[string]presetField
+	extra  []field
+}
+
+type presetField struct {
+	key     string
+	convert func(json.RawMessage) json.RawMessage
+}
+
+var presets = map[string]preset{
+	FormatECS: {
+		fields: map[string]presetField{
+			zerolog.TimestampFieldName: {key: "@timestamp"},
+			zerolog.LevelFieldName:     {key: "log.level"},
+			"component":                {key: "log.logger"},
+			zerolog.ErrorFieldName:     {key: "error.message"},
+			StackFieldName:             {key: "error.stack_trace"},
+			"trace_id":                 {key: "trace.id"},
+			"span_id":                  {key: "span.id"},
+		},
+		extra: []field{{"ecs.version", json.RawMessage(`"8.11.0"`)}},
+	},
+	FormatGCP: gcpPreset(""),
+	FormatDatadog: {
+		fields: map[string]presetField{
+			zerolog.TimestampFieldName: {key: "timestamp"},
+			zerolog.LevelFieldName:     {key: "status"},
+			"component":                {key: "logger.name"},
+			zerolog.ErrorFieldName:     {key: "error.message"},
+			StackFieldName:             {key: "error.stack"},
+			// Datadog correlates with lower 64 bits of ids in decimal
+			"trace_id": {key: "dd.trace_id", convert: decimalID},
+			"span_id":  {key: "dd.span_id", convert: decimalID},
+		},
+	},
+}
+
+var gcpSeverities = map[string]string{
+	zerolog.LevelTraceValue: "DEBUG",
+	zerolog.LevelDebugValue: "DEBUG",
+	zerolog.LevelInfoValue:  "INFO",
+	zerolog.LevelWarnValue:  "WARNING",
+	zerolog.LevelErrorValue: "ERROR",
+	zerolog.LevelFatalValue: "CRITICAL",
+	zerolog.LevelPanicValue: "ALERT",
+}
+
+func gcpPreset(project string) preset {
+	return preset{
+		fields: map[string]presetField{
+			zerolog.LevelFieldName: {key: "severity", convert: func(raw json.RawMessage) json.RawMessage {
+				var level string
+				_ = json.Unmarshal(raw, &level)
+				severity, ok := gcpSeverities[level]
+				if !ok {
+					severity = "DEFAULT"
+				}
+				return quote(severity)
+			}},
+			StackFieldName: {key: "stack_trace"},
+			"trace_id": {key: "logging.googleapis.com/trace", convert: func(raw json.RawMessage) json.RawMessage {
+				if project == "" {
+					return raw
+				}
+				var id string
+				_ = json.Unmarshal(raw, &id)
+				return quote("projects/" + project + "/traces/" + id)
+			}},
+			"span_id": {key: "logging.googleapis.com/spanId"},
+		},
+	}
+}
+
+func decimalID(raw json.RawMessage) json.RawMessage {
+	var id string
+	if err := json.Unmarshal(raw, &id); err != nil || len(id) < 16 {
+		return raw
+	}
+	n, err := strconv.ParseUint(id[len(id)-16:], 16, 64)
+	if err != nil {
+		return raw
+	}
+	return quote(strconv.FormatUint(n, 10))
+}
+
+func quote(s string) json.RawMessage {
+	data, _ := json.Marshal(s)
+	return data
+}
+
+// formatWriter renames fields of JSON records keeping their order
+type formatWriter struct {
+	preset *preset
+	out    io.Writer
+}
+
+func (w formatWriter) Write(p []byte) (int, error) {
+	fields, err := objectFields(p)
+	if err != nil {
+		return w.out.Write(p)
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range append(fields, w.preset.extra...) {
+		if pf, ok := w.preset.fields[f.key]; ok {
+			f.key = pf.key
+			if pf.convert != nil {
+				f.value = pf.convert(f.value)
+			}
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(quote(f.key))
+		buf.WriteByte(':')
+		buf.Write(bytes.TrimSpace(f.value))
+	}
+	buf.WriteString("}\n")
+	if _, err := w.out.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
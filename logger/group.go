@@ -0,0 +1,153 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/rs/zerolog"
+)
+
+// WithGroup creates child logger nesting attributes added with With afterwards and fields
+// of its records into group object, like slog.Logger.WithGroup. Standard fields, attributes
+// added before and fields extracted by Ctx stay at top level, empty groups are omitted.
+func (lg *Logger) WithGroup(name string) *Logger {
+	if name == "" {
+		return lg
+	}
+	child := *lg
+	child.groups = append(append([]string(nil), lg.groups...), name)
+	child.build()
+	return &child
+}
+
+// groupAttr is an attribute added to group at depth
+type groupAttr struct {
+	depth int
+	field
+}
+
+func (lg *Logger) withGroupAttrs(args []any) *Logger {
+	child := *lg
+	child.groupAttrs = append([]groupAttr(nil), lg.groupAttrs...)
+	args = pairs(args)
+	for i := 0; i < len(args); i += 2 {
+		value := args[i+1]
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			data = quote(err.Error())
+		}
+		child.groupAttrs = append(child.groupAttrs, groupAttr{len(lg.groups) - 1, field{args[i].(string), data}})
+	}
+	child.build()
+	return &child
+}
+
+// withTop adds attributes at top level even for grouped logger
+func (lg *Logger) withTop(args ...any) *Logger {
+	child := *lg
+	child.fields = append(append([]any(nil), lg.fields...), pairs(args)...)
+	child.build()
+	return &child
+}
+
+// groupWriter moves fields of records which are not top level ones into groups
+type groupWriter struct {
+	groups []string
+	attrs  []groupAttr
+	top    map[string]bool
+	out    zerolog.LevelWriter
+}
+
+func newGroupWriter(lg *Logger, out zerolog.LevelWriter) groupWriter {
+	top := map[string]bool{
+		zerolog.TimestampFieldName: true,
+		zerolog.LevelFieldName:     true,
+		zerolog.MessageFieldName:   true,
+		zerolog.CallerFieldName:    true,
+		StackFieldName:             true,
+		SuppressedFieldName:        true,
+		"component":                true,
+	}
+	for i := 0; i < len(lg.fields); i += 2 {
+		if key, ok := lg.fields[i].(string); ok {
+			top[key] = true
+		}
+	}
+	return groupWriter{lg.groups, lg.groupAttrs, top, out}
+}
+
+func (w groupWriter) Write(p []byte) (int, error) { return w.WriteLevel(zerolog.NoLevel, p) }
+
+func (w groupWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	fields, err := objectFields(p)
+	if err != nil {
+		return w.out.WriteLevel(level, p)
+	}
+	var top, grouped []field
+	for _, f := range fields {
+		if w.top[f.key] {
+			top = append(top, f)
+		} else {
+			grouped = append(grouped, f)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range top {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeField(&buf, f)
+	}
+	if group := w.render(0, grouped); group != nil {
+		if len(top) > 0 {
+			buf.WriteByte(',')
+		}
+		writeField(&buf, field{w.groups[0], group})
+	}
+	buf.WriteString("}\n")
+	if _, err := w.out.WriteLevel(level, buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// render returns object of group at depth or nil if it is empty
+func (w groupWriter) render(depth int, fields []field) json.RawMessage {
+	var members []field
+	for _, attr := range w.attrs {
+		if attr.depth == depth {
+			members = append(members, attr.field)
+		}
+	}
+	if depth+1 < len(w.groups) {
+		if group := w.render(depth+1, fields); group != nil {
+			members = append(members, field{w.groups[depth+1], group})
+		}
+	} else {
+		members = append(members, fields...)
+	}
+	if len(members) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range members {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeField(&buf, f)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
+
+func writeField(buf *bytes.Buffer, f field) {
+	buf.Write(quote(f.key))
+	buf.WriteByte(':')
+	buf.Write(bytes.TrimSpace(f.value))
+}
@@ -0,0 +1,209 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// levels are shared by logger and its children
+type levels struct {
+	mu        sync.RWMutex
+	level     zerolog.Level
+	modules   map[string]zerolog.Level
+	installed bool
+	// tail is a level records are kept from by WithTailLogging, disabled by default
+	tail zerolog.Level
+}
+
+func (ls *levels) resolve(name string) zerolog.Level {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	for name != "" {
+		if level, ok := ls.modules[name]; ok {
+			return level
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return ls.level
+}
+
+// floor returns the most verbose level records of name are created at, it is below
+// resolved one for records kept by WithTailLogging
+func (ls *levels) floor(name string) zerolog.Level {
+	level := ls.resolve(name)
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	return min(level, ls.tail)
+}
+
+func (ls *levels) install() {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.installed = true
+	ls.updateGlobal()
+}
+
+// updateGlobal lowers global level to the most verbose one, it is called with lock held
+func (ls *levels) updateGlobal() {
+	if !ls.installed {
+		return
+	}
+	min := ls.level
+	if ls.tail < min {
+		min = ls.tail
+	}
+	for _, level := range ls.modules {
+		if level < min {
+			min = level
+		}
+	}
+	zerolog.SetGlobalLevel(min)
+}
+
+// Level returns level of logger
+func (lg *Logger) Level() string { return lg.levels.resolve(lg.name).String() }
+
+// SetLevel changes level of root logger or module level of named one at runtime
+func (lg *Logger) SetLevel(level string) error {
+	if lg.name != "" {
+		return lg.SetModuleLevel(lg.name, level)
+	}
+	lvl, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	lg.levels.mu.Lock()
+	defer lg.levels.mu.Unlock()
+	lg.levels.level = lvl
+	lg.levels.updateGlobal()
+	return nil
+}
+
+// SetModuleLevel changes level of named loggers at runtime, empty level removes override
+func (lg *Logger) SetModuleLevel(name, level string) error {
+	var lvl zerolog.Level
+	if level != "" {
+		var err error
+		if lvl, err = parseLevel(level); err != nil {
+			return errors.Wrapf(err, "module %q", name)
+		}
+	}
+	lg.levels.mu.Lock()
+	defer lg.levels.mu.Unlock()
+	if level == "" {
+		delete(lg.levels.modules, name)
+	} else {
+		lg.levels.modules[name] = lvl
+	}
+	lg.levels.updateGlobal()
+	return nil
+}
+
+// ModuleLevels returns module level overrides
+func (lg *Logger) ModuleLevels() map[string]string {
+	lg.levels.mu.RLock()
+	defer lg.levels.mu.RUnlock()
+	res := make(map[string]string, len(lg.levels.modules))
+	for name, level := range lg.levels.modules {
+		res[name] = level.String()
+	}
+	return res
+}
+
+type levelHook struct {
+	*levels
+	name string
+}
+
+func (h levelHook) Run(e *zerolog.Event, level zerolog.Level, _ string) {
+	if level != zerolog.NoLevel && level < h.floor(h.name) {
+		e.Discard()
+	}
+}
+
+// Levels describes levels on LevelHandler, empty module level removes override on PUT
+type Levels struct {
+	Level   string            `json:"level,omitempty"`
+	Modules map[string]string `json:"modules,omitempty"`
+}
+
+// LevelHandler returns root and module levels of logger on GET and changes them on PUT, e.g.
+//
+//	curl -X PUT -d '{"modules": {"kafka": "debug"}}' localhost:9090/loglevel
+func LevelHandler(lg *Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var req Levels
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+				respond(w, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+				return
+			}
+			if err := lg.setLevels(req); err != nil {
+				respond(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			lg.Logger.WithLevel(zerolog.NoLevel).Interface("levels", req).Msg("log level changed")
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			respond(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		respond(w, http.StatusOK, Levels{Level: lg.Level(), Modules: lg.ModuleLevels()})
+	})
+}
+
+// setLevels validates all levels before changing any of them
+func (lg *Logger) setLevels(req Levels) error {
+	if req.Level != "" {
+		if _, err := parseLevel(req.Level); err != nil {
+			return err
+		}
+	}
+	for name, level := range req.Modules {
+		if level == "" {
+			continue
+		}
+		if _, err := parseLevel(level); err != nil {
+			return errors.Wrapf(err, "module %q", name)
+		}
+	}
+	if req.Level != "" {
+		if err := lg.SetLevel(req.Level); err != nil {
+			return err
+		}
+	}
+	for name, level := range req.Modules {
+		if err := lg.SetModuleLevel(name, level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func respond(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func parseLevel(level string) (zerolog.Level, error) {
+	lvl, err := zerolog.ParseLevel(strings.ToLower(level))
+	if err != nil {
+		return lvl, errors.Wrapf(err, "parse level %q", level)
+	}
+	if lvl == zerolog.NoLevel {
+		return lvl, errors.Errorf("empty level")
+	}
+	return lvl, nil
+}
@@ -0,0 +1,405 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+
+	"github.com/242617/core/protocol"
+)
+
+// Config is a logger configuration suitable for config.Scan, e.g.
+//
+//	log:
+//	  level: info
+//	  modules: {pgrepo: debug, kafka: warn}
+type Config struct {
+	Level    string `yaml:"level" env:"LOG_LEVEL" default:"info"`
+	Encoding string `yaml:"encoding" env:"LOG_ENCODING" default:"json"`
+	// Colorize is auto, always or never, it applies to text encoding
+	Colorize string `yaml:"colorize" env:"LOG_COLORIZE" default:"auto"`
+	// TimeFormat is a layout of time of records for all encodings, RFC3339 by default
+	TimeFormat string `yaml:"time_format" env:"LOG_TIME_FORMAT"`
+	// UTC makes time of records to be in UTC instead of local time zone
+	UTC bool `yaml:"utc" env:"LOG_UTC"`
+	// AddSource adds caller file:line to records
+	AddSource bool `yaml:"add_source" env:"LOG_ADD_SOURCE"`
+	// Format renames fields of JSON records according to conventions of log storage
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+	// Modules override level of named loggers created by Logger.New
+	Modules map[string]string `yaml:"modules"`
+	// Output is stderr, stdout or path of file rotated according to Rotation
+	Output   string   `yaml:"output" env:"LOG_OUTPUT" default:"stderr"`
+	Rotation Rotation `yaml:"rotation"`
+	// SplitStreams writes warn and higher records to stderr and others to stdout, it replaces Output
+	SplitStreams bool      `yaml:"split_streams" env:"LOG_SPLIT_STREAMS"`
+	OTLP         OTLP      `yaml:"otlp"`
+	Redact       Redaction `yaml:"redact"`
+	// Outputs replace Output if set
+	Outputs []Output `yaml:"outputs"`
+}
+
+// Levels accepted by Config
+const (
+	LevelTrace    = "trace"
+	LevelDebug    = "debug"
+	LevelInfo     = "info"
+	LevelWarn     = "warn"
+	LevelError    = "error"
+	LevelFatal    = "fatal"
+	LevelPanic    = "panic"
+	LevelDisabled = "disabled"
+)
+
+type option = func(lg *Logger) error
+
+func withDefaults() option {
+	return func(lg *Logger) error {
+		lg.writer = os.Stderr
+		lg.levels = &levels{level: zerolog.InfoLevel, modules: map[string]zerolog.Level{}, tail: zerolog.Disabled}
+		lg.exit = os.Exit
+		lg.throttles = newThrottles(10, time.Minute)
+		return nil
+	}
+}
+
+func WithConfig(cfg Config) option {
+	return func(lg *Logger) error {
+		if cfg.Level != "" {
+			if err := WithLevel(cfg.Level)(lg); err != nil {
+				return err
+			}
+		}
+		if err := WithModuleLevels(cfg.Modules)(lg); err != nil {
+			return err
+		}
+		if cfg.AddSource {
+			if err := WithSource()(lg); err != nil {
+				return err
+			}
+		}
+		if cfg.Format != "" {
+			if err := WithFormat(cfg.Format)(lg); err != nil {
+				return err
+			}
+		}
+		if err := WithColorize(cfg.Colorize)(lg); err != nil {
+			return err
+		}
+		if cfg.TimeFormat != "" {
+			if err := WithTimeFormat(cfg.TimeFormat)(lg); err != nil {
+				return err
+			}
+		}
+		if cfg.UTC {
+			if err := WithUTC()(lg); err != nil {
+				return err
+			}
+		}
+		if cfg.Encoding != "" {
+			if err := WithEncoding(cfg.Encoding)(lg); err != nil {
+				return err
+			}
+		}
+		if cfg.Output != "" && len(cfg.Outputs) == 0 {
+			if err := WithOutput(cfg.Output, cfg.Rotation)(lg); err != nil {
+				return err
+			}
+		}
+		if cfg.SplitStreams {
+			if err := WithSplitStreams()(lg); err != nil {
+				return err
+			}
+		}
+		if len(cfg.Outputs) > 0 {
+			if err := WithOutputs(cfg.Outputs...)(lg); err != nil {
+				return err
+			}
+		}
+		if len(cfg.Redact.Keys) > 0 || len(cfg.Redact.Values) > 0 {
+			if err := WithRedaction(cfg.Redact)(lg); err != nil {
+				return err
+			}
+		}
+		return WithOTLP(cfg.OTLP)(lg)
+	}
+}
+
+func WithLevel(level string) option {
+	return func(lg *Logger) error {
+		lvl, err := parseLevel(level)
+		if err != nil {
+			return err
+		}
+		lg.levels.level = lvl
+		return nil
+	}
+}
+
+// WithModuleLevels sets minimum levels of named loggers, e.g. {"kafka": "warn"}
+func WithModuleLevels(levels map[string]string) option {
+	return func(lg *Logger) error {
+		for name, level := range levels {
+			lvl, err := parseLevel(level)
+			if err != nil {
+				return errors.Wrapf(err, "module %q", name)
+			}
+			lg.levels.modules[name] = lvl
+		}
+		return nil
+	}
+}
+
+func WithWriter(w io.Writer) option {
+	return func(lg *Logger) error {
+		lg.writer = w
+		return nil
+	}
+}
+
+// New creates root logger, pass Logger field of it or its children to components with WithLogger
+func New(options ...option) (*Logger, error) {
+	var lg Logger
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&lg); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	if lg.timeFormat != "" || lg.utc {
+		lg.timestamp = &timestamp{layout: time.RFC3339, utc: lg.utc}
+		if lg.timeFormat != "" {
+			lg.timestamp.layout = lg.timeFormat
+		}
+	}
+	if len(lg.outputs) > 0 {
+		w, err := lg.openOutputs()
+		if err != nil {
+			return nil, err
+		}
+		lg.writer = w
+	} else if lg.split {
+		lg.writer = splitWriter{
+			out: zerolog.MultiLevelWriter(encoder(lg.encoding, lg.preset, lg.timestamp, os.Stdout, colorEnabled(lg.colorize, os.Stdout))),
+			err: zerolog.MultiLevelWriter(encoder(lg.encoding, lg.preset, lg.timestamp, os.Stderr, colorEnabled(lg.colorize, os.Stderr))),
+		}
+	} else {
+		lg.writer = encoder(lg.encoding, lg.preset, lg.timestamp, lg.writer, colorEnabled(lg.colorize, lg.writer))
+	}
+	for _, closer := range lg.closers {
+		if o, ok := closer.(*netOutput); ok {
+			o.metrics = lg.metrics
+		}
+	}
+	if lg.exporter != nil {
+		provider, err := newProvider(lg.exporter)
+		if err != nil {
+			return nil, errors.Wrap(err, "new provider")
+		}
+		lg.provider = provider
+		lg.writer = zerolog.MultiLevelWriter(lg.writer, otlpWriter{provider.Logger("github.com/242617/core/logger")})
+	}
+	if lg.redactor != nil {
+		lg.writer = redactWriter{lg.redactor, zerolog.MultiLevelWriter(lg.writer)}
+	}
+	if lg.dedupWindow > 0 {
+		lg.dedup = newDedupWriter(zerolog.MultiLevelWriter(lg.writer), lg.dedupWindow)
+		lg.writer = lg.dedup
+	}
+	if lg.tail != nil {
+		lg.tail.out = zerolog.MultiLevelWriter(lg.writer)
+		lg.writer = lg.tail
+	}
+	if lg.bufferSize > 0 {
+		lg.async = newAsyncWriter(lg.writer, lg.bufferSize, lg.flushInterval)
+		lg.writer = lg.async
+	}
+	if lg.timestamp != nil {
+		lg.root = zerolog.New(lg.writer).Hook(*lg.timestamp)
+	} else {
+		lg.root = zerolog.New(lg.writer).With().Timestamp().Logger()
+	}
+	lg.build()
+	return &lg, nil
+}
+
+type Logger struct {
+	zerolog.Logger
+
+	name          string
+	root          zerolog.Logger
+	writer        io.Writer
+	closers       []io.Closer
+	encoding      string
+	colorize      string
+	preset        *preset
+	outputs       []Output
+	split         bool
+	timeFormat    string
+	utc           bool
+	timestamp     *timestamp
+	levels        *levels
+	fields        []any
+	contextFields []ContextField
+	groups        []string
+	groupAttrs    []groupAttr
+	sampler       *sampler
+	throttles     *throttles
+	rateLimited   bool
+	throttleKey   string
+	stack         *zerolog.Level
+	source        bool
+
+	bufferSize    int
+	flushInterval time.Duration
+	async         *asyncWriter
+	dedupWindow   time.Duration
+	dedup         *dedupWriter
+	tail          *tailWriter
+
+	exit     func(code int)
+	metrics  protocol.MetricsRecorder
+	redactor *redactor
+	exporter sdklog.Exporter
+	provider *sdklog.LoggerProvider
+}
+
+// New creates named child logger with component field. Its level is taken from module levels
+// by full name, e.g. "kafka.consumer", then by parent names, falling back to root level.
+// Levels are resolved on every record, so they can be changed at runtime.
+func (lg *Logger) New(name string) *Logger {
+	if lg.name != "" {
+		name = lg.name + "." + name
+	}
+	child := *lg
+	child.name = name
+	child.build()
+	return &child
+}
+
+// With creates child logger adding key/value pairs to every record, like slog.With.
+// Keys which are not strings are formatted, value of dangling key is logged as !BADKEY,
+// slog.Attr may be passed instead of a pair.
+func (lg *Logger) With(args ...any) *Logger {
+	if len(lg.groups) > 0 {
+		return lg.withGroupAttrs(args)
+	}
+	return lg.withTop(args...)
+}
+
+func (lg *Logger) build() {
+	root := lg.root
+	if len(lg.groups) > 0 {
+		root = root.Output(newGroupWriter(lg, zerolog.MultiLevelWriter(lg.writer)))
+	}
+	ctx := root.With()
+	if lg.name != "" {
+		ctx = ctx.Str("component", lg.name)
+	}
+	if len(lg.fields) > 0 {
+		ctx = ctx.Fields(lg.fields)
+	}
+	lg.Logger = ctx.Logger().Hook(levelHook{lg.levels, lg.name})
+	if lg.metrics != nil {
+		lg.Logger = lg.Logger.Hook(newMetricsHook(lg.metrics, lg.name))
+	}
+	if lg.throttleKey != "" {
+		lg.Logger = lg.Logger.Hook(throttleHook{lg.throttles.keys, lg.throttleKey})
+	} else if lg.rateLimited {
+		lg.Logger = lg.Logger.Hook(throttleHook{lg.throttles.names, lg.name})
+	}
+	if lg.sampler != nil {
+		lg.Logger = lg.Logger.Hook(sampleHook{lg.sampler, lg.name, lg.Logger})
+	}
+	if lg.source {
+		lg.Logger = lg.Logger.Hook(sourceHook{})
+	}
+	if lg.stack != nil {
+		lg.Logger = lg.Logger.Hook(stackHook{*lg.stack})
+	}
+}
+
+func pairs(args []any) []any {
+	res := make([]any, 0, len(args)+1)
+	for i := 0; i < len(args); i += 2 {
+		if a, ok := args[i].(slog.Attr); ok {
+			if a.Key != "" {
+				res = append(res, a.Key, attrValue(a.Value.Resolve()))
+			}
+			i--
+			continue
+		}
+		if i+1 == len(args) {
+			res = append(res, "!BADKEY", args[i])
+			break
+		}
+		key, ok := args[i].(string)
+		if !ok {
+			key = fmt.Sprint(args[i])
+		}
+		res = append(res, key, args[i+1])
+	}
+	return res
+}
+
+// Name returns name of logger, it is empty for root one
+func (lg *Logger) Name() string { return lg.name }
+
+// Install makes logger global, so it is used by components created without WithLogger.
+// Global level is kept at the most verbose of root and module levels, as it limits every logger
+// and makes records of disabled levels cheap.
+func (lg *Logger) Install() {
+	lg.levels.install()
+	l.Logger = lg.Logger
+}
+
+// Flush waits until records queued with WithAsync are written and exported ones are sent
+func (lg *Logger) Flush(ctx context.Context) error {
+	if lg.async != nil {
+		if err := lg.async.Flush(ctx); err != nil {
+			return errors.Wrap(err, "flush async")
+		}
+	}
+	if lg.provider != nil {
+		if err := lg.provider.ForceFlush(ctx); err != nil {
+			return errors.Wrap(err, "flush provider")
+		}
+	}
+	return nil
+}
+
+// Close writes queued and collapsed records, flushes OTLP export and closes file output, it is shared by all children of logger
+func (lg *Logger) Close(ctx context.Context) error {
+	if lg.async != nil {
+		if err := lg.async.Close(ctx); err != nil {
+			return errors.Wrap(err, "close async")
+		}
+	}
+	if lg.dedup != nil {
+		_ = lg.dedup.Close()
+	}
+	if lg.provider != nil {
+		if err := lg.provider.Shutdown(ctx); err != nil {
+			return errors.Wrap(err, "shutdown provider")
+		}
+	}
+	for _, closer := range lg.closers {
+		if err := closer.Close(); err != nil {
+			return errors.Wrap(err, "close output")
+		}
+	}
+	return nil
+}
+
+// Start does nothing, logger is registered as component to be closed on shutdown
+func (lg *Logger) Start(context.Context) error    { return nil }
+func (lg *Logger) Stop(ctx context.Context) error { return lg.Close(ctx) }
+func (lg *Logger) String() string                 { return "logger" }
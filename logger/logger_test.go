@@ -0,0 +1,1099 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/242617/core/config"
+	"github.com/242617/core/config/source/file"
+	"github.com/242617/core/logger"
+	"github.com/242617/core/requestid"
+)
+
+var period = 10 * time.Millisecond
+
+func records(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var res []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record), "unmarshal record")
+		res = append(res, record)
+	}
+	buf.Reset()
+	return res
+}
+
+func TestModuleLevels(t *testing.T) {
+	defer zerolog.SetGlobalLevel(zerolog.GlobalLevel())
+	zerolog.SetGlobalLevel(zerolog.TraceLevel)
+
+	var buf bytes.Buffer
+	lg, err := logger.New(
+		logger.WithWriter(&buf),
+		logger.WithConfig(logger.Config{Level: "info", Modules: map[string]string{"pgrepo": "debug", "kafka": "WARN"}}),
+	)
+	require.NoError(t, err, "new")
+
+	lg.Debug().Msg("root")
+	pg := lg.New("pgrepo")
+	pg.Debug().Msg("pgrepo")
+	kafka := lg.New("kafka")
+	kafka.Info().Msg("kafka")
+	consumer := kafka.New("consumer")
+	consumer.Warn().Msg("consumer")
+	other := lg.New("other")
+	other.Info().Msg("other")
+
+	got := records(t, &buf)
+	require.Len(t, got, 3, "filtered by module levels")
+	assert.Equal(t, "pgrepo", got[0]["component"], "module debug")
+	assert.Equal(t, "kafka.consumer", got[1]["component"], "nested name")
+	assert.Equal(t, "consumer", got[1]["message"], "parent module level")
+	assert.Equal(t, "other", got[2]["component"], "root level")
+}
+
+func TestWith(t *testing.T) {
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf))
+	require.NoError(t, err, "new")
+
+	tenant := lg.With("version", "1.2.3", "shard", 7).With("tenant", "acme")
+	tenant.Info().Msg("with")
+	tenant.New("kafka").Info().Msg("named")
+	lg.With("dangling").Info().Msg("bad key")
+	lg.Info().Msg("root")
+
+	got := records(t, &buf)
+	require.Len(t, got, 4, "records")
+	assert.Equal(t, "1.2.3", got[0]["version"], "string attr")
+	assert.Equal(t, float64(7), got[0]["shard"], "int attr")
+	assert.Equal(t, "acme", got[0]["tenant"], "chained attr")
+	assert.Equal(t, "kafka", got[1]["component"], "named child")
+	assert.Equal(t, "acme", got[1]["tenant"], "kept by named child")
+	assert.Equal(t, "dangling", got[2]["!BADKEY"], "dangling key")
+	assert.NotContains(t, got[3], "tenant", "root untouched")
+}
+
+func TestSampling(t *testing.T) {
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf), logger.WithSampling(2, 3, 5*period))
+	require.NoError(t, err, "new")
+
+	for i := 0; i < 10; i++ {
+		lg.Warn().Msg("identical")
+	}
+	lg.Info().Msg("other")
+	got := records(t, &buf)
+	require.Len(t, got, 5, "sampled")
+	assert.Equal(t, "identical", got[2]["message"], "every third after initial")
+	assert.Equal(t, "other", got[4]["message"], "distinct message")
+
+	time.Sleep(6 * period)
+	lg.Warn().Msg("identical")
+	got = records(t, &buf)
+	require.Len(t, got, 2, "summary and record")
+	assert.Equal(t, "dropped 6 records", got[0]["message"], "summary")
+	assert.Equal(t, "identical", got[0]["sampled"], "sampled message")
+	assert.Equal(t, "warn", got[0]["level"], "summary level")
+	assert.Equal(t, "identical", got[1]["message"], "new window")
+}
+
+func TestAsync(t *testing.T) {
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf), logger.WithAsync(100, time.Hour))
+	require.NoError(t, err, "new")
+
+	lg.Info().Msg("first")
+	lg.New("kafka").Info().Msg("second")
+	require.NoError(t, lg.Flush(context.Background()), "flush")
+	got := records(t, &buf)
+	require.Len(t, got, 2, "flushed")
+	assert.Equal(t, "first", got[0]["message"], "order")
+
+	lg.Info().Msg("queued")
+	require.NoError(t, lg.Stop(context.Background()), "stop")
+	lg.Info().Msg("after close")
+	got = records(t, &buf)
+	require.Len(t, got, 2, "written on close and synchronously after it")
+	assert.Equal(t, "queued", got[0]["message"], "queued")
+	assert.Equal(t, "after close", got[1]["message"], "synchronous")
+}
+
+type exporter struct{ records []sdklog.Record }
+
+func (e *exporter) Export(_ context.Context, records []sdklog.Record) error {
+	for _, record := range records {
+		e.records = append(e.records, record.Clone())
+	}
+	return nil
+}
+func (e *exporter) Shutdown(context.Context) error   { return nil }
+func (e *exporter) ForceFlush(context.Context) error { return nil }
+
+func TestExporter(t *testing.T) {
+	var buf bytes.Buffer
+	var exp exporter
+	lg, err := logger.New(logger.WithWriter(&buf), logger.WithExporter(&exp))
+	require.NoError(t, err, "new")
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1, 2, 3},
+		SpanID:  trace.SpanID{4, 5, 6},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+	lg.New("kafka").WithSpan(ctx).Warn().Int("partition", 3).Msg("exported")
+	require.NoError(t, lg.Close(context.Background()), "close")
+
+	assert.Len(t, records(t, &buf), 1, "written to output")
+	require.Len(t, exp.records, 1, "exported")
+	record := exp.records[0]
+	assert.Equal(t, "exported", record.Body().AsString(), "body")
+	assert.Equal(t, otellog.SeverityWarn, record.Severity(), "severity")
+	assert.Equal(t, sc.TraceID(), record.TraceID(), "trace id")
+	assert.Equal(t, sc.SpanID(), record.SpanID(), "span id")
+	attrs := map[string]otellog.Value{}
+	record.WalkAttributes(func(kv otellog.KeyValue) bool {
+		attrs[kv.Key] = kv.Value
+		return true
+	})
+	assert.Equal(t, "kafka", attrs["component"].AsString(), "component")
+	assert.Equal(t, int64(3), attrs["partition"].AsInt64(), "partition")
+	assert.NotContains(t, attrs, "trace_id", "trace id is not attribute")
+}
+
+func TestRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf), logger.WithRedaction(logger.Redaction{
+		Keys:   append(logger.DefaultRedactKeys, "/^x-.*-key$/"),
+		Values: []string{logger.MatchCreditCard, logger.MatchEmail},
+	}))
+	require.NoError(t, err, "new")
+
+	lg.Info().
+		Str("Password", "qwerty").
+		Str("access_token", "abc").
+		Str("x-service-key", "k").
+		Interface("user", map[string]any{"email": "john@example.com", "auth": map[string]string{"token": "t"}}).
+		Str("card", "paid with 4111 1111 1111 1111").
+		Str("order", "1234567890123").
+		Msg("sent to john@example.com")
+
+	got := records(t, &buf)
+	require.Len(t, got, 1, "records")
+	assert.Equal(t, logger.Redacted, got[0]["Password"], "case-insensitive key")
+	assert.Equal(t, logger.Redacted, got[0]["access_token"], "key substring")
+	assert.Equal(t, logger.Redacted, got[0]["x-service-key"], "key regex")
+	assert.Equal(t, map[string]any{"email": logger.Redacted, "auth": map[string]any{"token": logger.Redacted}}, got[0]["user"], "nested")
+	assert.Equal(t, "paid with "+logger.Redacted, got[0]["card"], "credit card")
+	assert.Equal(t, "1234567890123", got[0]["order"], "not passing luhn check")
+	assert.Equal(t, "sent to "+logger.Redacted, got[0]["message"], "message value")
+	assert.Equal(t, "info", got[0]["level"], "untouched")
+
+	_, err = logger.New(logger.WithRedaction(logger.Redaction{Keys: []string{"/(/"}}))
+	assert.Error(t, err, "invalid regex")
+}
+
+func TestFatal(t *testing.T) {
+	var buf bytes.Buffer
+	var code int
+	lg, err := logger.New(
+		logger.WithWriter(&buf),
+		logger.WithAsync(100, time.Hour),
+		logger.WithExitFunc(func(c int) { code = c }),
+	)
+	require.NoError(t, err, "new")
+
+	lg.New("kafka").Fatal(context.Background(), "broker unavailable", "attempts", 3)
+	assert.Equal(t, 1, code, "exit code")
+	got := records(t, &buf)
+	require.Len(t, got, 1, "flushed before exit")
+	assert.Equal(t, "fatal", got[0]["level"], "level")
+	assert.Equal(t, float64(3), got[0]["attempts"], "attrs")
+}
+
+func TestPanic(t *testing.T) {
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf), logger.WithLevel(logger.LevelFatal))
+	require.NoError(t, err, "new")
+
+	lg.Error().Msg("filtered")
+	assert.PanicsWithValue(t, "invariant violated", func() {
+		lg.Panic(context.Background(), "invariant violated")
+	}, "panics")
+	got := records(t, &buf)
+	require.Len(t, got, 1, "records")
+	assert.Equal(t, "panic", got[0]["level"], "level")
+}
+
+func TestLevelHandler(t *testing.T) {
+	defer zerolog.SetGlobalLevel(zerolog.GlobalLevel())
+
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf), logger.WithModuleLevels(map[string]string{"kafka": "warn"}))
+	require.NoError(t, err, "new")
+	lg.Install()
+	consumer := lg.New("kafka").New("consumer")
+	h := logger.LevelHandler(lg)
+
+	do := func(method, body string) (int, logger.Levels) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "/loglevel", strings.NewReader(body)))
+		var res logger.Levels
+		_ = json.Unmarshal(w.Body.Bytes(), &res)
+		return w.Code, res
+	}
+
+	code, res := do(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, code, "get")
+	assert.Equal(t, logger.Levels{Level: "info", Modules: map[string]string{"kafka": "warn"}}, res, "initial")
+
+	consumer.Info().Msg("filtered")
+	code, res = do(http.MethodPut, `{"level": "debug", "modules": {"kafka": "", "kafka.consumer": "trace"}}`)
+	assert.Equal(t, http.StatusOK, code, "put")
+	assert.Equal(t, logger.Levels{Level: "debug", Modules: map[string]string{"kafka.consumer": "trace"}}, res, "changed")
+	assert.Equal(t, zerolog.TraceLevel, zerolog.GlobalLevel(), "global lowered")
+	buf.Reset()
+	consumer.Trace().Msg("created before change")
+	lg.New("other").Trace().Msg("filtered")
+	got := records(t, &buf)
+	require.Len(t, got, 1, "changed at runtime")
+	assert.Equal(t, "created before change", got[0]["message"], "existing child")
+
+	code, _ = do(http.MethodPut, `{"level": "info", "modules": {"kafka": "loud"}}`)
+	assert.Equal(t, http.StatusBadRequest, code, "invalid level")
+	assert.Equal(t, "debug", lg.Level(), "nothing changed")
+	code, _ = do(http.MethodPost, "")
+	assert.Equal(t, http.StatusMethodNotAllowed, code, "method")
+}
+
+func TestStacktrace(t *testing.T) {
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf), logger.WithStacktrace(logger.LevelError))
+	require.NoError(t, err, "new")
+
+	cause := errors.New("connection refused")
+	lg.Err(pkgerrors.Wrap(fmt.Errorf("dial: %w", cause), "connect")).Msg("failed")
+	lg.Warn().Msg("warning")
+
+	got := records(t, &buf)
+	require.Len(t, got, 2, "records")
+	assert.Equal(t, "connect: dial: connection refused", got[0]["error"], "error")
+	assert.Equal(t, []any{"connect: dial: connection refused", "dial: connection refused", "connection refused"}, got[0]["error_chain"], "chain")
+	require.IsType(t, "", got[0]["stack"], "stack")
+	stack := got[0]["stack"].(string)
+	assert.True(t, strings.HasPrefix(stack, "github.com/242617/core/logger_test.TestStacktrace\n"), "starts with caller: %s", stack)
+	assert.NotContains(t, got[1], "stack", "below level")
+
+	assert.Equal(t, []string{"a\nb", "a", "b"}, logger.ErrorChain(errors.Join(errors.New("a"), errors.New("b"))), "join")
+}
+
+func TestErr(t *testing.T) {
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf))
+	require.NoError(t, err, "new")
+
+	cause := pkgerrors.New("connection refused")
+	err = fmt.Errorf("connect: %w", cause)
+	lg.With(logger.Err(err)).Error().Msg("with")
+	slog.New(lg.Handler()).Error("slog", logger.Err(err))
+	lg.With(logger.Err(nil), "key", "value").Info().Msg("nil")
+
+	got := records(t, &buf)
+	require.Len(t, got, 3, "records")
+	for i, name := range []string{"with", "slog"} {
+		require.IsType(t, map[string]any{}, got[i]["error"], name)
+		e := got[i]["error"].(map[string]any)
+		assert.Equal(t, "connect: connection refused", e["message"], name)
+		assert.Equal(t, "*errors.fundamental", e["type"], name)
+		assert.Equal(t, []any{"connect: connection refused", "connection refused"}, e["chain"], name)
+		require.IsType(t, "", e["stack"], name)
+		assert.True(t, strings.HasPrefix(e["stack"].(string), "github.com/242617/core/logger_test.TestErr\n"), "%s stack: %s", name, e["stack"])
+	}
+	assert.NotContains(t, got[2], "error", "nil")
+	assert.Equal(t, "value", got[2]["key"], "nil skipped")
+}
+
+func TestTimeFormat(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.FixedZone("MSK", 3*60*60))
+	defer func(f func() time.Time) { zerolog.TimestampFunc = f }(zerolog.TimestampFunc)
+	zerolog.TimestampFunc = func() time.Time { return at }
+
+	var js, text bytes.Buffer
+	lg, err := logger.New(
+		logger.WithConfig(logger.Config{TimeFormat: time.RFC3339Nano, UTC: true}),
+		logger.WithOutputs(
+			logger.Output{Writer: &js},
+			logger.Output{Writer: &text, Encoding: logger.EncodingText, Colorize: logger.ColorizeNever},
+		),
+	)
+	require.NoError(t, err, "new")
+	lg.Info().Msg("started")
+
+	got := records(t, &js)
+	require.Len(t, got, 1, "records")
+	assert.Equal(t, "2024-01-02T00:04:05.123456789Z", got[0]["time"], "json")
+	assert.True(t, strings.HasPrefix(text.String(), "2024-01-02T00:04:05.123456789Z INF started"), "text: %s", text.String())
+
+	_, err = logger.New(logger.WithTimeFormat(""))
+	assert.Error(t, err, "empty format")
+}
+
+func TestBootstrap(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	defer func(f func() time.Time) { zerolog.TimestampFunc = f }(zerolog.TimestampFunc)
+	zerolog.TimestampFunc = func() time.Time { return at }
+
+	boot := logger.Bootstrap()
+	boot.Debug().Msg("debug")
+	boot.Warn().Str("path", "config.yaml").Msg("loading")
+	zerolog.TimestampFunc = time.Now
+
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf))
+	require.NoError(t, err, "new")
+	lg.Info().Msg("configured")
+	boot.FlushTo(lg)
+	boot.Info().Msg("late")
+
+	got := records(t, &buf)
+	require.Len(t, got, 3, "records")
+	assert.Equal(t, "configured", got[0]["message"], "own record")
+	assert.Equal(t, "loading", got[1]["message"], "replayed")
+	assert.Equal(t, "warn", got[1]["level"], "level")
+	assert.Equal(t, "config.yaml", got[1]["path"], "fields")
+	assert.Equal(t, at.Format(time.RFC3339), got[1]["time"], "time")
+	assert.Equal(t, "late", got[2]["message"], "forwarded")
+	assert.Same(t, boot, logger.Bootstrap(), "process-wide")
+}
+
+func TestSplitStreams(t *testing.T) {
+	dir := t.TempDir()
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	require.NoError(t, err, "create stdout")
+	stderr, err := os.Create(filepath.Join(dir, "stderr"))
+	require.NoError(t, err, "create stderr")
+	defer func(stdout, stderr *os.File) { os.Stdout, os.Stderr = stdout, stderr }(os.Stdout, os.Stderr)
+	os.Stdout, os.Stderr = stdout, stderr
+
+	lg, err := logger.New(logger.WithConfig(logger.Config{Level: logger.LevelDebug, SplitStreams: true}))
+	require.NoError(t, err, "new")
+	lg.Debug().Msg("debug")
+	lg.Info().Msg("info")
+	lg.Warn().Msg("warn")
+	lg.Error().Msg("error")
+
+	for name, want := range map[string][]string{"stdout": {"debug", "info"}, "stderr": {"warn", "error"}} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err, "read %s", name)
+		var got []string
+		for _, r := range records(t, bytes.NewBuffer(data)) {
+			got = append(got, r["message"].(string))
+		}
+		assert.Equal(t, want, got, name)
+	}
+}
+
+func TestGELF(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err, "listen")
+	defer conn.Close()
+
+	lg, err := logger.New(logger.WithOutputs(logger.Output{Path: "gelf+udp://" + conn.LocalAddr().String()}))
+	require.NoError(t, err, "new")
+	defer lg.Close(context.Background())
+	lg.New("kafka").Warn().Str("topic", "orders").Dict("req", zerolog.Dict().Int("id", 7)).Msg("slow")
+
+	buf := make([]byte, 8192)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)), "deadline")
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err, "read")
+	var msg map[string]any
+	require.NoError(t, json.Unmarshal(buf[:n], &msg), "unmarshal")
+	assert.Equal(t, "1.1", msg["version"], "version")
+	assert.Equal(t, "slow", msg["short_message"], "message")
+	assert.Equal(t, float64(4), msg["level"], "level")
+	assert.Equal(t, "kafka", msg["_component"], "component")
+	assert.Equal(t, "orders", msg["_topic"], "field")
+	assert.Equal(t, float64(7), msg["_req_id"], "nested field")
+	assert.InDelta(t, float64(time.Now().Unix()), msg["timestamp"], 2, "timestamp")
+}
+
+func TestSyslog(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "listen")
+	lines := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 4096)
+		n, _ := conn.Read(buf)
+		lines <- string(buf[:n])
+	}()
+
+	rec := recorder{counts: map[string]float64{}}
+	lg, err := logger.New(logger.WithMetrics(&rec), logger.WithOutputs(
+		logger.Output{Path: "syslog+tcp://" + ln.Addr().String(), Facility: "local0"},
+	))
+	require.NoError(t, err, "new")
+	lg.Error().Msg("failed")
+
+	select {
+	case line := <-lines:
+		size, msg, ok := strings.Cut(line, " ")
+		require.True(t, ok, "octet counting: %s", line)
+		assert.Equal(t, strconv.Itoa(len(msg)), size, "size")
+		assert.True(t, strings.HasPrefix(msg, "<131>1 "), "priority: %s", msg)
+		assert.Contains(t, msg, ` - - {"level":"error"`, "json record")
+	case <-time.After(time.Second):
+		t.Fatal("no message")
+	}
+
+	require.NoError(t, lg.Close(context.Background()), "close")
+	require.NoError(t, ln.Close(), "close listener")
+	lg, err = logger.New(logger.WithMetrics(&rec), logger.WithOutputs(logger.Output{
+		Path:    "syslog+tcp://" + ln.Addr().String(),
+		Backoff: logger.Backoff{Min: time.Minute, Max: time.Minute},
+	}))
+	require.NoError(t, err, "new")
+	lg.Info().Msg("refused")
+	lg.Info().Msg("backoff")
+	assert.Equal(t, float64(2), rec.counts[logger.MetricOutputDroppedTotal+"output,syslog+tcp://"+ln.Addr().String()], "dropped")
+
+	_, err = logger.New(logger.WithOutputs(logger.Output{Path: "syslog+udp://localhost:514", Facility: "local9"}))
+	assert.Error(t, err, "unknown facility")
+}
+
+func TestLogfmt(t *testing.T) {
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf), logger.WithEncoding(logger.EncodingLogfmt))
+	require.NoError(t, err, "new")
+
+	lg.New("kafka").Info().
+		Str("topic", "orders").
+		Str("query", `a = "b"`).
+		Int("partition", 3).
+		Str("empty", "").
+		Interface("tags", []string{"x", "y"}).
+		Msg("consumer started")
+	line := strings.TrimSpace(buf.String())
+	assert.Regexp(t, `^time=\S+ level=info msg="consumer started" component=kafka `, line, "leading fields")
+	assert.True(t, strings.HasSuffix(line, ` topic=orders query="a = \"b\"" partition=3 empty="" tags="[\"x\",\"y\"]"`), "fields: %s", line)
+
+	_, err = logger.New(logger.WithEncoding("xml"))
+	assert.Error(t, err, "unknown encoding")
+}
+
+func TestText(t *testing.T) {
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf), logger.WithConfig(logger.Config{Encoding: logger.EncodingText}))
+	require.NoError(t, err, "new")
+
+	lg.Warn().Str("topic", "orders").Msg("lagging")
+	assert.Contains(t, buf.String(), "WRN lagging topic=orders", "text")
+}
+
+func TestOutputs(t *testing.T) {
+	var text, js bytes.Buffer
+	path := filepath.Join(t.TempDir(), "app.log")
+	lg, err := logger.New(
+		logger.WithLevel(logger.LevelDebug),
+		logger.WithOutputs(
+			logger.Output{Writer: &text, Encoding: logger.EncodingText},
+			logger.Output{Writer: &js, Level: logger.LevelWarn},
+			logger.Output{Path: path, Encoding: logger.EncodingLogfmt, Level: logger.LevelInfo},
+		),
+		logger.WithAsync(100, time.Hour),
+	)
+	require.NoError(t, err, "new")
+
+	lg.Debug().Msg("details")
+	lg.Warn().Msg("problem")
+	require.NoError(t, lg.Close(context.Background()), "close")
+
+	assert.Contains(t, text.String(), "DBG details", "text debug")
+	assert.Contains(t, text.String(), "WRN problem", "text warn")
+	got := records(t, &js)
+	require.Len(t, got, 1, "json filtered by level")
+	assert.Equal(t, "problem", got[0]["message"], "json warn")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "read file")
+	assert.NotContains(t, string(data), "details", "file filtered by level")
+	assert.Contains(t, string(data), "level=warn msg=problem", "file logfmt")
+
+	_, err = logger.New(logger.WithOutputs(logger.Output{Writer: &js, Level: "loud"}))
+	assert.Error(t, err, "invalid level")
+}
+
+type tenantKey struct{}
+
+func TestCtx(t *testing.T) {
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf), logger.WithContextFields(logger.ContextField{
+		Key:     "tenant_id",
+		Extract: func(ctx context.Context) string { s, _ := ctx.Value(tenantKey{}).(string); return s },
+	}))
+	require.NoError(t, err, "new")
+
+	ctx := requestid.NewContext(context.Background(), "r1")
+	ctx = context.WithValue(ctx, tenantKey{}, "acme")
+	lg.New("http").Ctx(ctx).Info().Msg("with context")
+	lg.Ctx(context.Background()).Info().Msg("empty context")
+
+	got := records(t, &buf)
+	require.Len(t, got, 2, "records")
+	assert.Equal(t, "r1", got[0]["request_id"], "builtin")
+	assert.Equal(t, "acme", got[0]["tenant_id"], "extractor")
+	assert.Equal(t, "http", got[0]["component"], "name kept")
+	assert.NotContains(t, got[1], "request_id", "empty skipped")
+}
+
+type lazyUser struct{ calls *int }
+
+func (u lazyUser) LogValue() slog.Value {
+	*u.calls++
+	return slog.StringValue("u" + strconv.Itoa(*u.calls))
+}
+
+func TestContextWithAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf))
+	require.NoError(t, err, "new")
+
+	var calls int
+	ctx := logger.ContextWithAttrs(context.Background(), "session", "s1", slog.Int("tenant", 3))
+	ctx = logger.ContextWithAttrs(ctx, "user", lazyUser{&calls})
+	lg.New("http").WithGroup("req").Ctx(ctx).Info().Msg("first")
+	slog.New(lg.Handler()).InfoContext(ctx, "second")
+
+	got := records(t, &buf)
+	require.Len(t, got, 2, "records")
+	for i, user := range []string{"u1", "u2"} {
+		assert.Equal(t, "s1", got[i]["session"], "pair")
+		assert.Equal(t, float64(3), got[i]["tenant"], "attr")
+		assert.Equal(t, user, got[i]["user"], "resolved per record")
+	}
+}
+
+type fakeTB struct {
+	errors   []string
+	cleanups []func()
+}
+
+func (tb *fakeTB) Helper() {}
+func (tb *fakeTB) Errorf(format string, args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+func (tb *fakeTB) Cleanup(f func()) { tb.cleanups = append(tb.cleanups, f) }
+
+func TestTestLogger(t *testing.T) {
+	tl := logger.NewTestLogger(t)
+	tl.New("kafka").Debug().Int("partition", 3).Msg("assigned")
+	tl.Err(errors.New("sample")).Msg("failed")
+
+	require.Len(t, tl.Entries(), 2, "entries")
+	assert.Equal(t, "debug", tl.Entries()[0].Level, "level")
+	assert.Len(t, tl.FilterLevel(logger.LevelInfo), 1, "filtered")
+	assert.True(t, tl.AssertLogged("assigned", "partition", 3, "component", "kafka"), "logged")
+	assert.True(t, tl.AssertLogged("failed", "error", errors.New("sample")), "error attr")
+
+	var tb fakeTB
+	fake := logger.NewTestLogger(&tb)
+	fake.Info().Msg("started")
+	assert.False(t, fake.AssertLogged("started", "attempt", 1), "attr mismatch")
+	assert.False(t, fake.AssertLogged("stopped"), "message mismatch")
+	assert.Len(t, tb.errors, 2, "reported")
+	assert.Len(t, tb.cleanups, 1, "closed on cleanup")
+	fake.Reset()
+	assert.Empty(t, fake.Entries(), "reset")
+}
+
+func TestFormat(t *testing.T) {
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "sample")
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0},
+		SpanID:  trace.SpanID{0, 0, 0, 0, 0, 0, 0, 2},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	for _, tt := range []struct {
+		format   string
+		expected map[string]any
+	}{
+		{logger.FormatECS, map[string]any{"log.level": "warn", "log.logger": "kafka", "message": "lagging", "trace.id": sc.TraceID().String(), "ecs.version": "8.11.0"}},
+		{logger.FormatGCP, map[string]any{"severity": "WARNING", "component": "kafka", "message": "lagging", "logging.googleapis.com/trace": "projects/sample/traces/" + sc.TraceID().String(), "logging.googleapis.com/spanId": sc.SpanID().String()}},
+		{logger.FormatDatadog, map[string]any{"status": "warn", "logger.name": "kafka", "message": "lagging", "dd.trace_id": "256", "dd.span_id": "2"}},
+	} {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			lg, err := logger.New(logger.WithWriter(&buf), logger.WithConfig(logger.Config{Format: tt.format}))
+			require.NoError(t, err, "new")
+			lg.New("kafka").Ctx(ctx).Warn().Msg("lagging")
+
+			got := records(t, &buf)
+			require.Len(t, got, 1, "records")
+			for key, value := range tt.expected {
+				assert.Equal(t, value, got[0][key], key)
+			}
+			assert.NotContains(t, got[0], "level", "renamed")
+		})
+	}
+
+	_, err := logger.New(logger.WithFormat("splunk"))
+	assert.Error(t, err, "unknown format")
+}
+
+func TestRateLimit(t *testing.T) {
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf), logger.WithRateLimit(2, 5*period))
+	require.NoError(t, err, "new")
+
+	kafka := lg.New("kafka")
+	for i := 0; i < 5; i++ {
+		kafka.Info().Msg("per logger")
+		lg.Throttled("db:5432").Info().Msg("per key")
+	}
+	lg.Info().Msg("root")
+	got := records(t, &buf)
+	assert.Len(t, got, 5, "limited")
+
+	time.Sleep(6 * period)
+	kafka.Info().Msg("after window")
+	got = records(t, &buf)
+	require.Len(t, got, 1, "records")
+	assert.Equal(t, float64(3), got[0][logger.SuppressedFieldName], "suppressed count")
+}
+
+func TestSource(t *testing.T) {
+	var buf bytes.Buffer
+	var code int
+	lg, err := logger.New(logger.WithWriter(&buf), logger.WithConfig(logger.Config{AddSource: true}), logger.WithExitFunc(func(c int) { code = c }))
+	require.NoError(t, err, "new")
+
+	lg.New("kafka").Info().Msg("direct")
+	lg.Fatal(context.Background(), "wrapped")
+	assert.Equal(t, 1, code, "exited")
+
+	got := records(t, &buf)
+	require.Len(t, got, 2, "records")
+	for _, record := range got {
+		assert.Regexp(t, `^logger/logger_test\.go:\d+$`, record["caller"], "caller of %s", record["message"])
+	}
+}
+
+func TestBindConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("log:\n  level: warn\n"), 0o600), "write config")
+
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf))
+	require.NoError(t, err, "new")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, lg.BindConfig(ctx, config.New().With(file.YAML(path)), "log"), "bind")
+	assert.Equal(t, "warn", lg.Level(), "initial")
+
+	require.NoError(t, os.WriteFile(path, []byte("log:\n  level: debug\n  modules: {kafka: error}\n"), 0o600), "update config")
+	assert.Eventually(t, func() bool {
+		return lg.Level() == "debug" && lg.New("kafka").Level() == "error"
+	}, 50*period, period, "reloaded")
+
+	assert.Error(t, lg.BindConfig(ctx, config.New(), "log"), "no watched sources")
+}
+
+func TestWithGroup(t *testing.T) {
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf))
+	require.NoError(t, err, "new")
+
+	api := lg.With("service", "api").WithGroup("http").With("method", "GET")
+	api.Ctx(requestid.NewContext(context.Background(), "r1")).
+		WithGroup("response").
+		Info().Int("status", 200).Msg("served")
+	api.WithGroup("empty").Info().Msg("no fields")
+
+	got := records(t, &buf)
+	require.Len(t, got, 2, "records")
+	assert.Equal(t, "api", got[0]["service"], "attr before group")
+	assert.Equal(t, "r1", got[0]["request_id"], "context field on top")
+	assert.Equal(t, "served", got[0]["message"], "message on top")
+	assert.Equal(t, map[string]any{"method": "GET", "response": map[string]any{"status": float64(200)}}, got[0]["http"], "nested")
+	assert.Equal(t, map[string]any{"method": "GET"}, got[1]["http"], "empty group omitted")
+}
+
+func TestAudit(t *testing.T) {
+	var buf bytes.Buffer
+	key := []byte("secret")
+	audit, err := logger.NewAudit(logger.WithAuditWriter(&buf), logger.WithHMAC(key))
+	require.NoError(t, err, "new")
+
+	ctx := requestid.NewContext(context.Background(), "r1")
+	for _, event := range []logger.AuditEvent{
+		{Actor: "alice", Action: "delete", Resource: "orders/1", Outcome: logger.OutcomeSuccess},
+		{Actor: "bob", Action: "read", Resource: "orders/2", Outcome: logger.OutcomeFailure, Attrs: map[string]any{"reason": "forbidden"}},
+		{Actor: "alice", Action: "update", Resource: "orders/3", Outcome: logger.OutcomeSuccess},
+	} {
+		require.NoError(t, audit.Log(ctx, event), "log")
+	}
+	assert.Error(t, audit.Log(ctx, logger.AuditEvent{Actor: "alice", Action: "read"}), "mandatory fields")
+
+	got := records(t, bytes.NewBuffer(append([]byte(nil), buf.Bytes()...)))
+	require.Len(t, got, 3, "records")
+	assert.Equal(t, float64(2), got[1]["seq"], "sequence")
+	assert.Equal(t, "r1", got[1]["request_id"], "request id")
+	assert.Equal(t, got[0]["hmac"], got[1]["prev_hmac"], "chained")
+	assert.NoError(t, logger.VerifyAudit(bytes.NewReader(buf.Bytes()), key), "verified")
+
+	lines := strings.SplitAfter(buf.String(), "\n")
+	assert.Error(t, logger.VerifyAudit(strings.NewReader(lines[0]+lines[2]), key), "removed record")
+	tampered := strings.Replace(buf.String(), "bob", "eve", 1)
+	assert.Error(t, logger.VerifyAudit(strings.NewReader(tampered), key), "modified record")
+	assert.Error(t, logger.VerifyAudit(bytes.NewReader(buf.Bytes()), []byte("other")), "wrong key")
+}
+
+type recorder struct {
+	mu     sync.Mutex
+	counts map[string]float64
+}
+
+func (r *recorder) Add(name string, delta float64, labels ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counts[name+strings.Join(labels, ",")] += delta
+}
+func (r *recorder) Set(string, float64, ...string)     {}
+func (r *recorder) Observe(string, float64, ...string) {}
+
+func TestAccessLogger(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	entry := logger.AccessEntry{
+		Time:       at,
+		Method:     http.MethodGet,
+		Path:       "/orders?id=1",
+		Proto:      "HTTP/1.1",
+		Status:     http.StatusOK,
+		Bytes:      512,
+		Latency:    12 * time.Millisecond,
+		RemoteAddr: "10.0.0.1:53000",
+		UserAgent:  "curl/8.0",
+	}
+
+	for _, tt := range []struct{ format, line string }{
+		{logger.AccessFormatCompact, "2024-01-02T03:04:05Z GET /orders?id=1 200 512 12ms r1\n"},
+		{logger.AccessFormatCommon, `10.0.0.1 - - [02/Jan/2024:03:04:05 +0000] "GET /orders?id=1 HTTP/1.1" 200 512` + "\n"},
+		{logger.AccessFormatCombined, `10.0.0.1 - - [02/Jan/2024:03:04:05 +0000] "GET /orders?id=1 HTTP/1.1" 200 512 "-" "curl/8.0"` + "\n"},
+	} {
+		var buf bytes.Buffer
+		access, err := logger.NewAccessLogger(logger.WithAccessWriter(&buf), logger.WithAccessFormat(tt.format))
+		require.NoError(t, err, "new %s", tt.format)
+		require.NoError(t, access.Log(requestid.NewContext(context.Background(), "r1"), entry), "log %s", tt.format)
+		assert.Equal(t, tt.line, buf.String(), tt.format)
+	}
+
+	access, err := logger.NewAccessLogger(logger.WithAccessWriter(io.Discard))
+	require.NoError(t, err, "new")
+	assert.Error(t, access.Log(context.Background(), logger.AccessEntry{Method: http.MethodGet, Path: "/"}), "mandatory fields")
+	_, err = logger.NewAccessLogger(logger.WithAccessFormat("nginx"))
+	assert.Error(t, err, "unknown format")
+}
+
+func TestTimer(t *testing.T) {
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf))
+	require.NoError(t, err, "new")
+
+	timer := lg.Timer("import").WarnIfOver(period).ErrorIfOver(time.Hour)
+	time.Sleep(period)
+	timer.Checkpoint("download")
+	timer.Checkpoint("parse")
+	d := timer.Stop()
+	lg.Timer("fast").WarnIfOver(time.Hour).Stop()
+
+	got := records(t, &buf)
+	require.Len(t, got, 2, "records")
+	assert.Equal(t, "warn", got[0]["level"], "escalated")
+	assert.Equal(t, float64(d.Milliseconds()), math.Floor(got[0]["duration"].(float64)), "duration")
+	require.IsType(t, map[string]any{}, got[0]["checkpoints"], "checkpoints")
+	checkpoints := got[0]["checkpoints"].(map[string]any)
+	assert.GreaterOrEqual(t, checkpoints["download"], float64(period.Milliseconds()), "download")
+	assert.Contains(t, checkpoints, "parse", "parse")
+	assert.Equal(t, "info", got[1]["level"], "below threshold")
+	assert.NotContains(t, got[1], "checkpoints", "no checkpoints")
+}
+
+func TestTailLogging(t *testing.T) {
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf), logger.WithTailLogging(logger.LevelDebug, 2, 1))
+	require.NoError(t, err, "new")
+	req := func(id string) *logger.Logger { return lg.Ctx(requestid.NewContext(context.Background(), id)) }
+
+	for _, msg := range []string{"a", "b", "c"} {
+		req("r1").Debug().Msg(msg)
+	}
+	req("r1").Info().Msg("info")
+	req("r1").Error().Msg("failed")
+	req("r2").Debug().Msg("evicted")
+	req("r3").Debug().Msg("kept")
+	req("r2").Error().Msg("failed")
+	lg.Debug().Msg("no request")
+	lg.Trace().Msg("below tail level")
+
+	var got []string
+	for _, r := range records(t, &buf) {
+		got = append(got, r["request_id"].(string)+":"+r["message"].(string))
+	}
+	assert.Equal(t, []string{"r1:info", "r1:b", "r1:c", "r1:failed", "r2:failed"}, got, "records")
+
+	_, err = logger.New(logger.WithTailLogging(logger.LevelDebug, 0, 1))
+	assert.Error(t, err, "invalid size")
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestDeduplication(t *testing.T) {
+	var buf syncBuffer
+	lg, err := logger.New(logger.WithWriter(&buf), logger.WithDeduplication(5*period))
+	require.NoError(t, err, "new")
+
+	for i := 0; i < 3; i++ {
+		lg.Error().Str("host", "db").Msg("connection refused")
+	}
+	lg.Error().Str("host", "cache").Msg("connection refused")
+	time.Sleep(10 * period)
+	lg.Error().Str("host", "db").Msg("connection refused")
+	lg.Error().Str("host", "db").Msg("connection refused")
+	require.NoError(t, lg.Close(context.Background()), "close")
+
+	got := records(t, bytes.NewBufferString(buf.String()))
+	require.Len(t, got, 5, "records")
+	assert.Equal(t, "db", got[0]["host"], "first")
+	assert.NotContains(t, got[0], "repeat_count", "first written at once")
+	assert.Equal(t, "cache", got[1]["host"], "different fields")
+	assert.Equal(t, float64(2), got[2]["repeat_count"], "collapsed at window end")
+	assert.Equal(t, "db", got[3]["host"], "new window")
+	assert.Equal(t, float64(1), got[4]["repeat_count"], "collapsed on close")
+
+	_, err = logger.New(logger.WithDeduplication(0))
+	assert.Error(t, err, "invalid window")
+}
+
+type nopRecorder struct{}
+
+func (nopRecorder) Add(string, float64, ...string)     {}
+func (nopRecorder) Set(string, float64, ...string)     {}
+func (nopRecorder) Observe(string, float64, ...string) {}
+
+func TestAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf))
+	require.NoError(t, err, "new")
+
+	ctx := logger.ContextWithAttrs(requestid.NewContext(context.Background(), "r1"), "user", "u1")
+	lg.New("kafka").InfoAttrs(ctx, "consumed", slog.String("topic", "orders"), slog.Group("offset", slog.Int("partition", 3)))
+	lg.DebugAttrs(ctx, "filtered")
+	lg.WithGroup("req").ErrorAttrs(ctx, "failed", slog.Int("status", 500))
+
+	got := records(t, &buf)
+	require.Len(t, got, 2, "records")
+	assert.Equal(t, map[string]any{
+		"level": "info", "component": "kafka", "request_id": "r1", "user": "u1", "topic": "orders",
+		"offset": map[string]any{"partition": float64(3)}, "time": got[0]["time"], "message": "consumed",
+	}, got[0], "record")
+	assert.Equal(t, "r1", got[1]["request_id"], "context field of grouped logger")
+	assert.Equal(t, map[string]any{"status": float64(500)}, got[1]["req"], "grouped attrs")
+
+	lg, err = logger.New(logger.WithWriter(io.Discard), logger.WithMetrics(nopRecorder{}))
+	require.NoError(t, err, "new")
+	ctx = requestid.NewContext(context.Background(), "r1")
+	allocs := testing.AllocsPerRun(100, func() {
+		lg.InfoAttrs(ctx, "consumed", slog.String("topic", "orders"), slog.Int("partition", 3))
+	})
+	assert.Zero(t, allocs, "allocations")
+}
+
+func TestFromZerolog(t *testing.T) {
+	var buf bytes.Buffer
+	lg, err := logger.FromZerolog(zerolog.New(&buf).Level(zerolog.InfoLevel).With().Str("service", "legacy").Logger())
+	require.NoError(t, err, "new")
+
+	lg.New("kafka").Warn().Int("partition", 3).Msg("rebalanced")
+	lg.Debug().Msg("filtered")
+
+	got := records(t, &buf)
+	require.Len(t, got, 1, "records")
+	assert.Equal(t, map[string]any{
+		"level": "warn", "service": "legacy", "component": "kafka", "partition": float64(3), "message": "rebalanced",
+	}, got[0], "record")
+	assert.Equal(t, logger.LevelInfo, lg.Level(), "level of zerolog logger")
+}
+
+func TestFromZap(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	lg, err := logger.FromZap(zap.New(core).Named("legacy"))
+	require.NoError(t, err, "new")
+
+	lg.New("kafka").Error().Int("partition", 3).Msg("failed")
+	lg.Debug().Msg("filtered")
+
+	require.Equal(t, 1, logs.Len(), "records")
+	entry := logs.All()[0]
+	assert.Equal(t, zapcore.ErrorLevel, entry.Level, "level")
+	assert.Equal(t, "failed", entry.Message, "message")
+	assert.Equal(t, "legacy", entry.LoggerName, "name")
+	assert.Equal(t, map[string]any{"component": "kafka", "partition": int64(3)}, entry.ContextMap(), "fields")
+	assert.Equal(t, logger.LevelInfo, lg.Level(), "level of zap core")
+}
+
+func TestMetrics(t *testing.T) {
+	rec := recorder{counts: map[string]float64{}}
+	lg, err := logger.New(logger.WithWriter(io.Discard), logger.WithMetrics(&rec))
+	require.NoError(t, err, "new")
+
+	kafka := lg.New("kafka")
+	kafka.Error().Msg("failed")
+	kafka.Error().Msg("failed")
+	kafka.Debug().Msg("filtered")
+	lg.Info().Msg("started")
+	assert.Equal(t, map[string]float64{
+		logger.MetricEntriesTotal + "level,error,name,kafka": 2,
+		logger.MetricEntriesTotal + "level,info,name,":       1,
+	}, rec.counts, "counted")
+}
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf))
+	require.NoError(t, err, "new")
+
+	sl := slog.New(lg.New("lib").Handler()).With("version", 2).WithGroup("http")
+	sl.InfoContext(requestid.NewContext(context.Background(), "r1"), "served", "status", 200, slog.Group("req", "method", "GET"))
+	sl.Debug("filtered")
+	lg.StdLogger(logger.LevelWarn).Print("legacy")
+
+	got := records(t, &buf)
+	require.Len(t, got, 2, "records")
+	assert.Equal(t, "served", got[0]["message"], "message")
+	assert.Equal(t, "lib", got[0]["component"], "component")
+	assert.Equal(t, "r1", got[0]["request_id"], "context field")
+	assert.Equal(t, float64(2), got[0]["version"], "attr")
+	assert.Equal(t, map[string]any{"status": float64(200), "req": map[string]any{"method": "GET"}}, got[0]["http"], "group")
+	assert.Equal(t, "warn", got[1]["level"], "std logger level")
+	assert.Equal(t, "legacy", got[1]["message"], "std logger message")
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	var buf bytes.Buffer
+	lg, err := logger.New(logger.WithWriter(&buf))
+	require.NoError(t, err, "new")
+
+	restore := logger.RedirectStdLog(lg)
+	log.Print("std")
+	slog.Warn("slog")
+	restore()
+
+	got := records(t, &buf)
+	require.Len(t, got, 2, "records")
+	assert.Equal(t, "std", got[0]["message"], "std log")
+	assert.Equal(t, "slog", got[1]["message"], "slog")
+}
+
+func TestColorize(t *testing.T) {
+	for _, tt := range []struct {
+		colorize, noColor string
+		colored           bool
+	}{
+		{logger.ColorizeAuto, "", false},
+		{logger.ColorizeAlways, "1", true},
+		{logger.ColorizeNever, "", false},
+	} {
+		t.Setenv("NO_COLOR", tt.noColor)
+		var buf bytes.Buffer
+		lg, err := logger.New(logger.WithWriter(&buf), logger.WithConfig(logger.Config{Encoding: logger.EncodingText, Colorize: tt.colorize}))
+		require.NoError(t, err, "new")
+		lg.Info().Msg("sample")
+		assert.Equal(t, tt.colored, strings.Contains(buf.String(), "\x1b["), tt.colorize)
+	}
+
+	_, err := logger.New(logger.WithColorize("rainbow"))
+	assert.Error(t, err, "unknown mode")
+}
+
+func TestInvalidLevel(t *testing.T) {
+	_, err := logger.New(logger.WithLevel("loud"))
+	assert.Error(t, err, "invalid level")
+	_, err = logger.New(logger.WithModuleLevels(map[string]string{"kafka": "loud"}))
+	assert.Error(t, err, "invalid module level")
+}
+
+func TestInstall(t *testing.T) {
+	defer zerolog.SetGlobalLevel(zerolog.GlobalLevel())
+
+	lg, err := logger.New(logger.WithModuleLevels(map[string]string{"pgrepo": "trace"}))
+	require.NoError(t, err, "new")
+	lg.Install()
+	assert.Equal(t, zerolog.TraceLevel, zerolog.GlobalLevel(), "lowered to most verbose module")
+}
+
+func TestFileOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	lg, err := logger.New(logger.WithConfig(logger.Config{
+		Level:    "info",
+		Output:   path,
+		Rotation: logger.Rotation{MaxSize: 1, MaxBackups: 2},
+	}))
+	require.NoError(t, err, "new")
+	lg.Info().Msg("sample")
+	require.NoError(t, lg.Close(context.Background()), "close")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "read file")
+	assert.Contains(t, string(data), `"message":"sample"`, "written to file")
+}
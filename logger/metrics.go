@@ -0,0 +1,39 @@
+package logger
+
+import (
+	"github.com/rs/zerolog"
+
+	"github.com/242617/core/protocol"
+)
+
+const MetricEntriesTotal = "log_entries_total"
+
+// WithMetrics counts records passing levels by level and logger name, empty for root,
+// records dropped by rate limit and sampling are counted too
+func WithMetrics(recorder protocol.MetricsRecorder) option {
+	return func(lg *Logger) error {
+		lg.metrics = recorder
+		return nil
+	}
+}
+
+type metricsHook struct {
+	metrics protocol.MetricsRecorder
+	// labels are built once per level from trace to no level, so records are counted without allocation
+	labels *[zerolog.NoLevel - zerolog.TraceLevel + 1][]string
+}
+
+func newMetricsHook(metrics protocol.MetricsRecorder, name string) metricsHook {
+	h := metricsHook{metrics: metrics, labels: new([zerolog.NoLevel - zerolog.TraceLevel + 1][]string)}
+	for level := zerolog.TraceLevel; level <= zerolog.NoLevel; level++ {
+		h.labels[level-zerolog.TraceLevel] = []string{"level", level.String(), "name", name}
+	}
+	return h
+}
+
+func (h metricsHook) Run(_ *zerolog.Event, level zerolog.Level, _ string) {
+	if level < zerolog.TraceLevel || level > zerolog.NoLevel {
+		return
+	}
+	h.metrics.Add(MetricEntriesTotal, 1, h.labels[level-zerolog.TraceLevel]...)
+}
@@ -0,0 +1,277 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/242617/core/protocol"
+)
+
+const MetricOutputDroppedTotal = "log_output_dropped_total"
+
+// Backoff limits reconnection of gelf and syslog outputs, delay doubles from Min up to Max
+// after every failed dial or write, records are dropped until next attempt
+type Backoff struct {
+	Min time.Duration `yaml:"min" default:"100ms"`
+	Max time.Duration `yaml:"max" default:"30s"`
+}
+
+const (
+	netTimeout     = 5 * time.Second
+	gelfChunkSize  = 8192
+	gelfChunkLimit = 128
+)
+
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+func checkFacility(facility string) error {
+	if _, ok := facilities[facility]; facility != "" && !ok {
+		return errors.Errorf("unknown facility %q", facility)
+	}
+	return nil
+}
+
+// netOutput writes JSON records to Graylog as GELF or to syslog as RFC 5424 messages,
+// it is dialed on first record and redialed with backoff once connection fails
+type netOutput struct {
+	path     string
+	protocol string
+	network  string
+	addr     string
+	facility int
+	backoff  Backoff
+	host     string
+	app      string
+	metrics  protocol.MetricsRecorder
+
+	mu    sync.Mutex
+	conn  net.Conn
+	delay time.Duration
+	next  time.Time
+}
+
+// newNetOutput returns output for path like gelf+udp://graylog:12201 or syslog+tcp://localhost:514,
+// ok is false if path is not one of them
+func newNetOutput(output Output) (*netOutput, bool) {
+	scheme, addr, ok := strings.Cut(output.Path, "://")
+	if !ok {
+		return nil, false
+	}
+	proto, network, ok := strings.Cut(scheme, "+")
+	if !ok || (proto != "gelf" && proto != "syslog") || (network != "udp" && network != "tcp") {
+		return nil, false
+	}
+	o := netOutput{
+		path:     output.Path,
+		protocol: proto,
+		network:  network,
+		addr:     addr,
+		facility: facilities["user"],
+		backoff:  output.Backoff,
+		app:      filepath.Base(os.Args[0]),
+	}
+	if output.Facility != "" {
+		o.facility = facilities[output.Facility]
+	}
+	if o.backoff.Min <= 0 {
+		o.backoff.Min = 100 * time.Millisecond
+	}
+	if o.backoff.Max < o.backoff.Min {
+		o.backoff.Max = max(30*time.Second, o.backoff.Min)
+	}
+	o.host, _ = os.Hostname()
+	if o.host == "" {
+		o.host = "-"
+	}
+	return &o, true
+}
+
+func (o *netOutput) Write(p []byte) (int, error) { return o.WriteLevel(zerolog.NoLevel, p) }
+
+func (o *netOutput) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.conn == nil {
+		if time.Now().Before(o.next) {
+			o.drop()
+			return len(p), nil
+		}
+		conn, err := net.DialTimeout(o.network, o.addr, netTimeout)
+		if err != nil {
+			o.fail()
+			o.drop()
+			return len(p), nil
+		}
+		o.conn, o.delay = conn, 0
+	}
+	if err := o.send(level, bytes.TrimRight(p, "\n")); err != nil {
+		_ = o.conn.Close()
+		o.conn = nil
+		o.fail()
+		o.drop()
+	}
+	return len(p), nil
+}
+
+func (o *netOutput) send(level zerolog.Level, p []byte) error {
+	_ = o.conn.SetWriteDeadline(time.Now().Add(netTimeout))
+	if o.protocol == "syslog" {
+		msg := fmt.Sprintf("<%d>1 %s %s %s %d - - %s",
+			o.facility*8+syslogSeverity(level), time.Now().Format(time.RFC3339Nano), o.host, o.app, os.Getpid(), p)
+		if o.network == "tcp" {
+			msg = strconv.Itoa(len(msg)) + " " + msg
+		}
+		_, err := o.conn.Write([]byte(msg))
+		return err
+	}
+
+	msg := gelfMessage(o.host, level, p)
+	if o.network == "tcp" {
+		_, err := o.conn.Write(append(msg, 0))
+		return err
+	}
+	return o.sendChunked(msg)
+}
+
+// sendChunked writes GELF message as one datagram or up to 128 chunks if it is too long
+func (o *netOutput) sendChunked(msg []byte) error {
+	if len(msg) <= gelfChunkSize {
+		_, err := o.conn.Write(msg)
+		return err
+	}
+	size := gelfChunkSize - 12
+	count := (len(msg) + size - 1) / size
+	if count > gelfChunkLimit {
+		return errors.New("message is too long")
+	}
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	for i := 0; i < count; i++ {
+		chunk := append([]byte{0x1e, 0x0f}, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, msg[i*size:min((i+1)*size, len(msg))]...)
+		if _, err := o.conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *netOutput) fail() {
+	o.delay = min(max(2*o.delay, o.backoff.Min), o.backoff.Max)
+	o.next = time.Now().Add(o.delay)
+}
+
+func (o *netOutput) drop() {
+	if o.metrics != nil {
+		o.metrics.Add(MetricOutputDroppedTotal, 1, "output", o.path)
+	}
+}
+
+func (o *netOutput) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.conn == nil {
+		return nil
+	}
+	err := o.conn.Close()
+	o.conn = nil
+	return err
+}
+
+// syslogSeverity maps level to syslog severity, it is used by GELF too
+func syslogSeverity(level zerolog.Level) int {
+	switch level {
+	case zerolog.TraceLevel, zerolog.DebugLevel:
+		return 7
+	case zerolog.InfoLevel:
+		return 6
+	case zerolog.WarnLevel:
+		return 4
+	case zerolog.ErrorLevel:
+		return 3
+	case zerolog.FatalLevel:
+		return 2
+	case zerolog.PanicLevel:
+		return 1
+	default:
+		return 5
+	}
+}
+
+// gelfMessage converts JSON record to GELF 1.1, fields become additional ones
+// with nested keys joined by underscore. Record which is not JSON is sent as message.
+func gelfMessage(host string, level zerolog.Level, p []byte) []byte {
+	var record map[string]any
+	d := json.NewDecoder(bytes.NewReader(p))
+	d.UseNumber()
+	if err := d.Decode(&record); err != nil {
+		record = map[string]any{zerolog.MessageFieldName: string(p)}
+	}
+	msg := map[string]any{
+		"version":       "1.1",
+		"host":          host,
+		"short_message": "-",
+		"level":         syslogSeverity(level),
+		"timestamp":     gelfTimestamp(record[zerolog.TimestampFieldName]),
+	}
+	if s, ok := record[zerolog.MessageFieldName].(string); ok && s != "" {
+		msg["short_message"] = s
+	}
+	delete(record, zerolog.MessageFieldName)
+	delete(record, zerolog.LevelFieldName)
+	delete(record, zerolog.TimestampFieldName)
+	var flatten func(prefix string, v any)
+	flatten = func(prefix string, v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for k, v := range v {
+				flatten(prefix+"_"+k, v)
+			}
+		case json.Number, string:
+			msg[prefix] = v
+		case nil:
+		default:
+			b, _ := json.Marshal(v)
+			msg[prefix] = string(b)
+		}
+	}
+	for k, v := range record {
+		if k == "id" {
+			k = "record_id"
+		}
+		flatten("_"+k, v)
+	}
+	b, _ := json.Marshal(msg)
+	return b
+}
+
+func gelfTimestamp(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		return v
+	case string:
+		if t, err := time.Parse(zerolog.TimeFieldFormat, v); err == nil {
+			return math.Round(float64(t.UnixNano())/1e6) / 1e3
+		}
+	}
+	return math.Round(float64(time.Now().UnixNano())/1e6) / 1e3
+}
@@ -0,0 +1,213 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/242617/core/application"
+	"github.com/242617/core/buildinfo"
+)
+
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// OTLP configures export of records to OpenTelemetry collector in addition to output.
+// Resource attributes are taken from OTEL_RESOURCE_ATTRIBUTES besides service ones.
+type OTLP struct {
+	Enabled  bool   `yaml:"enabled" env:"LOG_OTLP_ENABLED"`
+	Endpoint string `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_LOGS_ENDPOINT"`
+	Protocol string `yaml:"protocol" env:"OTEL_EXPORTER_OTLP_LOGS_PROTOCOL" default:"grpc"`
+	Insecure bool   `yaml:"insecure" env:"OTEL_EXPORTER_OTLP_LOGS_INSECURE"`
+}
+
+// WithOTLP exports records over OTLP with exporter created from cfg
+func WithOTLP(cfg OTLP) option {
+	return func(lg *Logger) error {
+		if !cfg.Enabled {
+			return nil
+		}
+		exporter, err := newExporter(cfg)
+		if err != nil {
+			return errors.Wrap(err, "new exporter")
+		}
+		lg.exporter = exporter
+		return nil
+	}
+}
+
+// WithExporter exports records with exporter, e.g. stdout one
+func WithExporter(exporter sdklog.Exporter) option {
+	return func(lg *Logger) error {
+		lg.exporter = exporter
+		return nil
+	}
+}
+
+func newExporter(cfg OTLP) (sdklog.Exporter, error) {
+	ctx := context.Background()
+	switch cfg.Protocol {
+	case ProtocolGRPC, "":
+		var options []otlploggrpc.Option
+		if cfg.Endpoint != "" {
+			options = append(options, otlploggrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			options = append(options, otlploggrpc.WithInsecure())
+		}
+		return otlploggrpc.New(ctx, options...)
+	case ProtocolHTTP:
+		var options []otlploghttp.Option
+		if cfg.Endpoint != "" {
+			options = append(options, otlploghttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			options = append(options, otlploghttp.WithInsecure())
+		}
+		return otlploghttp.New(ctx, options...)
+	default:
+		return nil, errors.Errorf("unknown protocol %q", cfg.Protocol)
+	}
+}
+
+func newProvider(exporter sdklog.Exporter) (*sdklog.LoggerProvider, error) {
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(application.Name),
+		semconv.ServiceVersion(buildinfo.Get().Version),
+		semconv.HostName(application.Hostname),
+	))
+	if err != nil {
+		return nil, errors.Wrap(err, "new resource")
+	}
+	return sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)),
+		sdklog.WithResource(res),
+	), nil
+}
+
+// WithSpan creates child logger adding trace_id and span_id of span in ctx to every record,
+// they are used for correlation by OTLP export
+func (lg *Logger) WithSpan(ctx context.Context) *Logger {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return lg
+	}
+	return lg.withTop("trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+}
+
+// otlpWriter converts JSON records into OpenTelemetry ones
+type otlpWriter struct{ logger otellog.Logger }
+
+func (w otlpWriter) Write(p []byte) (int, error) {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		return 0, errors.Wrap(err, "decode record")
+	}
+
+	var record otellog.Record
+	record.SetObservedTimestamp(time.Now())
+	ctx := context.Background()
+	var sc trace.SpanContextConfig
+	for key, value := range fields {
+		switch key {
+		case zerolog.TimestampFieldName:
+			if s, ok := value.(string); ok {
+				if t, err := time.Parse(zerolog.TimeFieldFormat, s); err == nil {
+					record.SetTimestamp(t)
+				}
+			}
+		case zerolog.LevelFieldName:
+			if s, ok := value.(string); ok {
+				record.SetSeverityText(s)
+				if level, err := zerolog.ParseLevel(s); err == nil {
+					record.SetSeverity(severity(level))
+				}
+			}
+		case zerolog.MessageFieldName:
+			record.SetBody(attributeValue(value))
+		case "trace_id":
+			if s, ok := value.(string); ok {
+				sc.TraceID, _ = trace.TraceIDFromHex(s)
+			}
+		case "span_id":
+			if s, ok := value.(string); ok {
+				sc.SpanID, _ = trace.SpanIDFromHex(s)
+			}
+		default:
+			record.AddAttributes(otellog.KeyValue{Key: key, Value: attributeValue(value)})
+		}
+	}
+	if sc.TraceID.IsValid() && sc.SpanID.IsValid() {
+		sc.TraceFlags = trace.FlagsSampled
+		ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(sc))
+	}
+	w.logger.Emit(ctx, record)
+	return len(p), nil
+}
+
+func (w otlpWriter) WriteLevel(_ zerolog.Level, p []byte) (int, error) { return w.Write(p) }
+
+func severity(level zerolog.Level) otellog.Severity {
+	switch level {
+	case zerolog.TraceLevel:
+		return otellog.SeverityTrace
+	case zerolog.DebugLevel:
+		return otellog.SeverityDebug
+	case zerolog.InfoLevel:
+		return otellog.SeverityInfo
+	case zerolog.WarnLevel:
+		return otellog.SeverityWarn
+	case zerolog.ErrorLevel:
+		return otellog.SeverityError
+	case zerolog.FatalLevel:
+		return otellog.SeverityFatal
+	case zerolog.PanicLevel:
+		return otellog.SeverityFatal4
+	default:
+		return otellog.SeverityUndefined
+	}
+}
+
+func attributeValue(value any) otellog.Value {
+	switch v := value.(type) {
+	case string:
+		return otellog.StringValue(v)
+	case bool:
+		return otellog.BoolValue(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return otellog.Int64Value(i)
+		}
+		f, _ := v.Float64()
+		return otellog.Float64Value(f)
+	case []any:
+		values := make([]otellog.Value, 0, len(v))
+		for _, item := range v {
+			values = append(values, attributeValue(item))
+		}
+		return otellog.SliceValue(values...)
+	case map[string]any:
+		kvs := make([]otellog.KeyValue, 0, len(v))
+		for key, item := range v {
+			kvs = append(kvs, otellog.KeyValue{Key: key, Value: attributeValue(item)})
+		}
+		return otellog.MapValue(kvs...)
+	default:
+		return otellog.Value{}
+	}
+}
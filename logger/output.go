@@ -0,0 +1,188 @@
+package logger
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Rotation configures rotation of file output, zero values mean no limit
+type Rotation struct {
+	// MaxSize is size in megabytes file is rotated at
+	MaxSize    int  `yaml:"max_size" default:"100"`
+	MaxAge     int  `yaml:"max_age"`
+	MaxBackups int  `yaml:"max_backups"`
+	Compress   bool `yaml:"compress"`
+}
+
+// Output is one of sinks set with WithOutputs, e.g.
+//
+//	outputs:
+//	  - {path: stdout, encoding: text, colorize: auto, level: debug}
+//	  - {path: /var/log/app.log, level: info}
+//	  - {path: gelf+udp://graylog:12201, level: warn}
+//	  - {path: syslog+tcp://localhost:514, facility: local0}
+type Output struct {
+	// Path is stderr, stdout, file path or socket address like tcp://host:port, it is ignored if Writer is set.
+	// Records are sent to Graylog with gelf+udp:// and gelf+tcp://, to syslog with syslog+udp:// and syslog+tcp://
+	// as RFC 5424 messages with JSON record, encoding is ignored for them.
+	Path     string    `yaml:"path"`
+	Writer   io.Writer `yaml:"-"`
+	Encoding string    `yaml:"encoding"`
+	// Level limits records of output additionally to logger levels
+	Level string `yaml:"level"`
+	// Colorize is a mode of text encoding, auto by default
+	Colorize string   `yaml:"colorize"`
+	Rotation Rotation `yaml:"rotation"`
+	// Facility is a syslog facility like local0, user by default
+	Facility string  `yaml:"facility"`
+	Backoff  Backoff `yaml:"backoff"`
+}
+
+// WithOutput sets output to stderr, stdout or file rotated according to rotation
+func WithOutput(output string, rotation Rotation) option {
+	return func(lg *Logger) error {
+		w, closer, err := openOutput(Output{Path: output, Rotation: rotation})
+		if err != nil {
+			return err
+		}
+		lg.writer, lg.closers = w, nil
+		if closer != nil {
+			lg.closers = []io.Closer{closer}
+		}
+		return nil
+	}
+}
+
+// WithOutputs writes records to every output with its own encoding and level, they replace output
+func WithOutputs(outputs ...Output) option {
+	return func(lg *Logger) error {
+		if len(outputs) == 0 {
+			return errors.New("no outputs")
+		}
+		for i, output := range outputs {
+			if output.Level != "" {
+				if _, err := parseLevel(output.Level); err != nil {
+					return errors.Wrapf(err, "output %d", i)
+				}
+			}
+			if err := checkEncoding(output.Encoding); err != nil {
+				return errors.Wrapf(err, "output %d", i)
+			}
+			if err := checkColorize(output.Colorize); err != nil {
+				return errors.Wrapf(err, "output %d", i)
+			}
+			if err := checkFacility(output.Facility); err != nil {
+				return errors.Wrapf(err, "output %d", i)
+			}
+		}
+		lg.outputs = outputs
+		return nil
+	}
+}
+
+// openOutputs opens outputs set with WithOutputs once all options are applied
+func (lg *Logger) openOutputs() (io.Writer, error) {
+	writers := make([]io.Writer, 0, len(lg.outputs))
+	var closers []io.Closer
+	for i, output := range lg.outputs {
+		level := zerolog.TraceLevel
+		if output.Level != "" {
+			level, _ = parseLevel(output.Level)
+		}
+		w := output.Writer
+		if w == nil {
+			var closer io.Closer
+			var err error
+			if w, closer, err = openOutput(output); err != nil {
+				for _, closer := range closers {
+					_ = closer.Close()
+				}
+				return nil, errors.Wrapf(err, "output %d", i)
+			}
+			if closer != nil {
+				closers = append(closers, closer)
+			}
+		}
+		w = encoder(output.Encoding, lg.preset, lg.timestamp, w, colorEnabled(output.Colorize, w))
+		writers = append(writers, levelFilter{level, zerolog.MultiLevelWriter(w)})
+	}
+	lg.closers = append(lg.closers, closers...)
+	return zerolog.MultiLevelWriter(writers...), nil
+}
+
+func openOutput(output Output) (io.Writer, io.Closer, error) {
+	path, rotation := output.Path, output.Rotation
+	switch path {
+	case "", "stderr":
+		return os.Stderr, nil, nil
+	case "stdout":
+		return os.Stdout, nil, nil
+	}
+	if o, ok := newNetOutput(output); ok {
+		return o, o, nil
+	}
+	for _, network := range []string{"tcp", "udp", "unix"} {
+		if addr, ok := strings.CutPrefix(path, network+"://"); ok {
+			conn, err := net.Dial(network, addr)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "dial")
+			}
+			return conn, conn, nil
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, nil, errors.Wrap(err, "create log directory")
+	}
+	file := &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    rotation.MaxSize,
+		MaxAge:     rotation.MaxAge,
+		MaxBackups: rotation.MaxBackups,
+		Compress:   rotation.Compress,
+		LocalTime:  true,
+	}
+	return file, file, nil
+}
+
+// WithSplitStreams writes warn and higher records to stderr and others to stdout,
+// it replaces output and is ignored if outputs are set
+func WithSplitStreams() option {
+	return func(lg *Logger) error {
+		lg.split = true
+		return nil
+	}
+}
+
+// splitWriter writes records at warn level or above to err and others to out
+type splitWriter struct{ out, err zerolog.LevelWriter }
+
+func (w splitWriter) Write(p []byte) (int, error) { return w.out.Write(p) }
+
+func (w splitWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level != zerolog.NoLevel && level >= zerolog.WarnLevel {
+		return w.err.WriteLevel(level, p)
+	}
+	return w.out.WriteLevel(level, p)
+}
+
+// levelFilter skips records below level, records without level are written
+type levelFilter struct {
+	level zerolog.Level
+	out   zerolog.LevelWriter
+}
+
+func (f levelFilter) Write(p []byte) (int, error) { return f.out.Write(p) }
+
+func (f levelFilter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level != zerolog.NoLevel && level < f.level {
+		return len(p), nil
+	}
+	return f.out.WriteLevel(level, p)
+}
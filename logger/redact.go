@@ -0,0 +1,219 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// Redacted replaces sensitive values
+const Redacted = "[REDACTED]"
+
+// Builtin value matchers of Redaction
+const (
+	MatchCreditCard = "credit_card"
+	MatchEmail      = "email"
+)
+
+// DefaultRedactKeys are key patterns usually holding secrets
+var DefaultRedactKeys = []string{"password", "secret", "token", "authorization", "api_key", "apikey"}
+
+// Redaction configures masking of sensitive attributes, e.g.
+//
+//	redact:
+//	  keys: [password, token, /^x-.*-key$/]
+//	  values: [credit_card, email]
+type Redaction struct {
+	// Keys are case-insensitive substrings of keys or regexes enclosed in slashes,
+	// values of matching keys are replaced entirely including nested objects
+	Keys []string `yaml:"keys"`
+	// Values are builtin matchers or regexes, matching parts of string values are replaced
+	Values []string `yaml:"values"`
+}
+
+// WithRedaction masks sensitive attributes of records before they are written or exported
+func WithRedaction(r Redaction) option {
+	return func(lg *Logger) error {
+		red, err := newRedactor(r)
+		if err != nil {
+			return err
+		}
+		lg.redactor = red
+		return nil
+	}
+}
+
+var (
+	creditCardRe = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	emailRe      = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+)
+
+type redactor struct {
+	substrings []string
+	keys       []*regexp.Regexp
+	values     []*regexp.Regexp
+	luhn       []bool
+}
+
+func newRedactor(r Redaction) (*redactor, error) {
+	var red redactor
+	for _, key := range r.Keys {
+		if len(key) > 2 && strings.HasPrefix(key, "/") && strings.HasSuffix(key, "/") {
+			re, err := regexp.Compile(key[1 : len(key)-1])
+			if err != nil {
+				return nil, errors.Wrapf(err, "key pattern %q", key)
+			}
+			red.keys = append(red.keys, re)
+			continue
+		}
+		red.substrings = append(red.substrings, strings.ToLower(key))
+	}
+	for _, value := range r.Values {
+		switch value {
+		case MatchCreditCard:
+			red.values, red.luhn = append(red.values, creditCardRe), append(red.luhn, true)
+		case MatchEmail:
+			red.values, red.luhn = append(red.values, emailRe), append(red.luhn, false)
+		default:
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "value pattern %q", value)
+			}
+			red.values, red.luhn = append(red.values, re), append(red.luhn, false)
+		}
+	}
+	return &red, nil
+}
+
+func (r *redactor) sensitiveKey(key string) bool {
+	lower := strings.ToLower(key)
+	for _, s := range r.substrings {
+		if strings.Contains(lower, s) {
+			return true
+		}
+	}
+	for _, re := range r.keys {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *redactor) redactString(s string) string {
+	for i, re := range r.values {
+		s = re.ReplaceAllStringFunc(s, func(match string) string {
+			if r.luhn[i] && !luhn(match) {
+				return match
+			}
+			return Redacted
+		})
+	}
+	return s
+}
+
+// redact rewrites JSON value keeping order of keys
+func (r *redactor) redact(buf *bytes.Buffer, raw json.RawMessage) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil
+	}
+	switch raw[0] {
+	case '{':
+		fields, err := objectFields(raw)
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('{')
+		for i, f := range fields {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			encoded, _ := json.Marshal(f.key)
+			buf.Write(encoded)
+			buf.WriteByte(':')
+			if f.key != zerolog.MessageFieldName && r.sensitiveKey(f.key) {
+				buf.WriteString(`"` + Redacted + `"`)
+				continue
+			}
+			if err := r.redact(buf, f.value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return err
+		}
+		buf.WriteByte('[')
+		for i, item := range items {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := r.redact(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return err
+		}
+		if redacted := r.redactString(s); redacted != s {
+			encoded, _ := json.Marshal(redacted)
+			raw = encoded
+		}
+		buf.Write(raw)
+	default:
+		buf.Write(raw)
+	}
+	return nil
+}
+
+// redactWriter masks JSON records before passing them to output and exporter
+type redactWriter struct {
+	*redactor
+	out zerolog.LevelWriter
+}
+
+func (w redactWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w redactWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	var buf bytes.Buffer
+	if err := w.redact(&buf, p); err != nil {
+		// not a JSON record, e.g. written with Logger.Write directly
+		return w.out.WriteLevel(level, p)
+	}
+	buf.WriteByte('\n')
+	if _, err := w.out.WriteLevel(level, buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func luhn(number string) bool {
+	var sum, n int
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/242617/core/config"
+)
+
+// SetConfig applies root and module levels of cfg at runtime, module levels missing in cfg
+// are removed. Other settings are applied on creation only.
+func (lg *Logger) SetConfig(cfg Config) error {
+	level := lg.levels.resolve("")
+	if cfg.Level != "" {
+		var err error
+		if level, err = parseLevel(cfg.Level); err != nil {
+			return err
+		}
+	}
+	modules := make(map[string]zerolog.Level, len(cfg.Modules))
+	for name, l := range cfg.Modules {
+		lvl, err := parseLevel(l)
+		if err != nil {
+			return errors.Wrapf(err, "module %q", name)
+		}
+		modules[name] = lvl
+	}
+
+	lg.levels.mu.Lock()
+	defer lg.levels.mu.Unlock()
+	lg.levels.level, lg.levels.modules = level, modules
+	lg.levels.updateGlobal()
+	return nil
+}
+
+// BindConfig scans key section of config, e.g. "log", into Config and applies changes of levels
+// with SetConfig once engine Watch reports changes of its sources, until ctx is done.
+// Engine must have watched sources like files or Consul.
+func (lg *Logger) BindConfig(ctx context.Context, engine config.ConfigEngine, key string) error {
+	section := reflect.StructOf([]reflect.StructField{{
+		Name: "Log",
+		Type: reflect.TypeOf(Config{}),
+		Tag:  reflect.StructTag(fmt.Sprintf("yaml:%q", key)),
+	}})
+	configOf := func(p interface{}) Config { return reflect.ValueOf(p).Elem().Field(0).Interface().(Config) }
+
+	target := reflect.New(section).Interface()
+	onChange := func(old, next interface{}) error {
+		cfg := configOf(next)
+		if reflect.DeepEqual(levelsOf(cfg), levelsOf(configOf(old))) {
+			return nil
+		}
+		if err := lg.SetConfig(cfg); err != nil {
+			lg.Logger.Error().Err(err).Msg("reload config")
+			return err
+		}
+		lg.Logger.WithLevel(zerolog.NoLevel).Interface("levels", levelsOf(cfg)).Msg("log level changed")
+		return nil
+	}
+	// initial config is applied before watching, so invalid levels do not leave watch running
+	if err := engine.Scan(target); err != nil {
+		return errors.Wrap(err, "scan config")
+	}
+	if err := lg.SetConfig(configOf(target)); err != nil {
+		return errors.Wrap(err, "set config")
+	}
+	return errors.Wrap(engine.Watch(ctx, target, onChange), "watch config")
+}
+
+func levelsOf(cfg Config) Levels { return Levels{Level: cfg.Level, Modules: cfg.Modules} }
@@ -0,0 +1,120 @@
+package logger
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// WithSampling limits identical records, i.e. of the same logger, level and message, to initial
+// ones per window and every thereafter-th one after them, zero thereafter drops the rest.
+// Number of dropped records is logged once window is over, with the next record of the logger.
+func WithSampling(initial, thereafter int, perWindow time.Duration) option {
+	return func(lg *Logger) error {
+		if initial < 1 {
+			return errors.New("initial must be positive")
+		}
+		if thereafter < 0 {
+			return errors.New("thereafter must not be negative")
+		}
+		if perWindow <= 0 {
+			return errors.New("window must be positive")
+		}
+		lg.sampler = &sampler{
+			initial:    initial,
+			thereafter: thereafter,
+			window:     perWindow,
+			counters:   map[sampleKey]*sampleCounter{},
+		}
+		return nil
+	}
+}
+
+type sampleKey struct {
+	name    string
+	level   zerolog.Level
+	message string
+}
+
+type sampleCounter struct {
+	start   time.Time
+	n       int
+	dropped int
+	log     zerolog.Logger
+}
+
+type sampler struct {
+	initial    int
+	thereafter int
+	window     time.Duration
+
+	mu       sync.Mutex
+	counters map[sampleKey]*sampleCounter
+	swept    time.Time
+}
+
+// sample reports whether record should be written and returns counters of windows which are over
+func (s *sampler) sample(key sampleKey, log zerolog.Logger) (bool, map[sampleKey]*sampleCounter) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired map[sampleKey]*sampleCounter
+	if now.Sub(s.swept) >= s.window {
+		s.swept = now
+		for k, c := range s.counters {
+			if now.Sub(c.start) < s.window {
+				continue
+			}
+			delete(s.counters, k)
+			if c.dropped > 0 {
+				if expired == nil {
+					expired = map[sampleKey]*sampleCounter{}
+				}
+				expired[k] = c
+			}
+		}
+	}
+
+	c, ok := s.counters[key]
+	if !ok || now.Sub(c.start) >= s.window {
+		if ok && c.dropped > 0 {
+			if expired == nil {
+				expired = map[sampleKey]*sampleCounter{}
+			}
+			expired[key] = c
+		}
+		c = &sampleCounter{start: now, log: log}
+		s.counters[key] = c
+	}
+	c.n++
+	if c.n <= s.initial || (s.thereafter > 0 && (c.n-s.initial)%s.thereafter == 0) {
+		return true, expired
+	}
+	c.dropped++
+	return false, expired
+}
+
+type sampleHook struct {
+	*sampler
+	name string
+	log  zerolog.Logger
+}
+
+func (h sampleHook) Run(e *zerolog.Event, level zerolog.Level, message string) {
+	if level == zerolog.Disabled {
+		return
+	}
+	keep, expired := h.sample(sampleKey{h.name, level, message}, h.log)
+	for k, c := range expired {
+		c.log.WithLevel(k.level).
+			Str("sampled", k.message).
+			Int("dropped", c.dropped).
+			Msgf("dropped %d records", c.dropped)
+	}
+	if !keep {
+		e.Discard()
+	}
+}
@@ -0,0 +1,163 @@
+package logger
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// Handler returns slog handler writing records with the logger, fields of context
+// are extracted like Logger.Ctx does
+func (lg *Logger) Handler() slog.Handler { return slogHandler{lg} }
+
+type slogHandler struct{ lg *Logger }
+
+func (h slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	lvl := zerologLevel(level)
+	return lvl >= zerolog.GlobalLevel() && lvl >= h.lg.levels.floor(h.lg.name)
+}
+
+func (h slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	e := h.lg.ctxEvent(ctx, zerologLevel(r.Level))
+	if e == nil {
+		return nil
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		e = appendAttr(e, a)
+		return true
+	})
+	e.Msg(r.Message)
+	return nil
+}
+
+func (h slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	args := make([]any, 0, 2*len(attrs))
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Key == "" && a.Value.Kind() != slog.KindGroup {
+			continue
+		}
+		if a.Value.Kind() == slog.KindGroup && a.Key == "" {
+			for _, a := range a.Value.Group() {
+				args = append(args, a.Key, attrValue(a.Value))
+			}
+			continue
+		}
+		args = append(args, a.Key, attrValue(a.Value))
+	}
+	if len(args) == 0 {
+		return h
+	}
+	return slogHandler{h.lg.With(args...)}
+}
+
+func (h slogHandler) WithGroup(name string) slog.Handler {
+	return slogHandler{h.lg.WithGroup(name)}
+}
+
+func zerologLevel(level slog.Level) zerolog.Level {
+	switch {
+	case level < slog.LevelDebug:
+		return zerolog.TraceLevel
+	case level < slog.LevelInfo:
+		return zerolog.DebugLevel
+	case level < slog.LevelWarn:
+		return zerolog.InfoLevel
+	case level < slog.LevelError:
+		return zerolog.WarnLevel
+	default:
+		return zerolog.ErrorLevel
+	}
+}
+
+func appendAttr(e *zerolog.Event, a slog.Attr) *zerolog.Event {
+	a.Value = a.Value.Resolve()
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		if len(a.Value.Group()) == 0 {
+			return e
+		}
+		if a.Key == "" {
+			for _, a := range a.Value.Group() {
+				e = appendAttr(e, a)
+			}
+			return e
+		}
+		dict := zerolog.Dict()
+		for _, a := range a.Value.Group() {
+			dict = appendAttr(dict, a)
+		}
+		return e.Dict(a.Key, dict)
+	case slog.KindString:
+		return e.Str(a.Key, a.Value.String())
+	case slog.KindInt64:
+		return e.Int64(a.Key, a.Value.Int64())
+	case slog.KindUint64:
+		return e.Uint64(a.Key, a.Value.Uint64())
+	case slog.KindFloat64:
+		return e.Float64(a.Key, a.Value.Float64())
+	case slog.KindBool:
+		return e.Bool(a.Key, a.Value.Bool())
+	case slog.KindDuration:
+		return e.Dur(a.Key, a.Value.Duration())
+	case slog.KindTime:
+		return e.Time(a.Key, a.Value.Time())
+	}
+	if a.Key == "" {
+		return e
+	}
+	if err, ok := a.Value.Any().(error); ok {
+		return e.AnErr(a.Key, err)
+	}
+	return e.Interface(a.Key, a.Value.Any())
+}
+
+func attrValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindGroup:
+		group := make(map[string]any, len(v.Group()))
+		for _, a := range v.Group() {
+			group[a.Key] = attrValue(a.Value.Resolve())
+		}
+		return group
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+	}
+	return v.Any()
+}
+
+// StdLogger returns standard logger writing every line as record at level, info if it is invalid
+func (lg *Logger) StdLogger(level string) *log.Logger {
+	lvl, err := parseLevel(level)
+	if err != nil {
+		lvl = zerolog.InfoLevel
+	}
+	return log.New(stdWriter{lg, lvl}, "", 0)
+}
+
+type stdWriter struct {
+	lg    *Logger
+	level zerolog.Level
+}
+
+func (w stdWriter) Write(p []byte) (int, error) {
+	w.lg.Logger.WithLevel(w.level).Msg(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+// RedirectStdLog makes default slog logger and standard log package write with lg,
+// standard log lines are written at info level. Returned func restores previous state.
+func RedirectStdLog(lg *Logger) func() {
+	prev, w, flags := slog.Default(), log.Writer(), log.Flags()
+	slog.SetDefault(slog.New(lg.Handler()))
+	return func() {
+		slog.SetDefault(prev)
+		log.SetOutput(w)
+		log.SetFlags(flags)
+	}
+}
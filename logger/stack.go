@@ -0,0 +1,164 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// StackFieldName is a key of stack trace added by WithStacktrace
+const StackFieldName = "stack"
+
+// WithStacktrace adds stack trace of caller to records at level or above it
+func WithStacktrace(level string) option {
+	return func(lg *Logger) error {
+		lvl, err := parseLevel(level)
+		if err != nil {
+			return err
+		}
+		lg.stack = &lvl
+		return nil
+	}
+}
+
+type stackHook struct{ level zerolog.Level }
+
+func (h stackHook) Run(e *zerolog.Event, level zerolog.Level, _ string) {
+	if level == zerolog.Disabled || level == zerolog.NoLevel || level < h.level {
+		return
+	}
+	e.Str(StackFieldName, stacktrace())
+}
+
+// WithSource adds caller location as short file:line, e.g. "kafka/consumer.go:42"
+func WithSource() option {
+	return func(lg *Logger) error {
+		lg.source = true
+		return nil
+	}
+}
+
+type sourceHook struct{}
+
+func (sourceHook) Run(e *zerolog.Event, level zerolog.Level, _ string) {
+	if level == zerolog.Disabled {
+		return
+	}
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !internalFrame(frame) {
+			file := frame.File
+			if i := strings.LastIndexByte(file, '/'); i >= 0 {
+				if j := strings.LastIndexByte(file[:i], '/'); j >= 0 {
+					file = file[j+1:]
+				}
+			}
+			e.Str(zerolog.CallerFieldName, file+":"+strconv.Itoa(frame.Line))
+			return
+		}
+		if !more {
+			return
+		}
+	}
+}
+
+// internalFrame reports whether frame belongs to zerolog or logger, so it is skipped
+func internalFrame(frame runtime.Frame) bool {
+	return strings.HasPrefix(frame.Function, "github.com/rs/zerolog") ||
+		strings.HasPrefix(frame.Function, "github.com/242617/core/logger.")
+}
+
+// stacktrace formats stack like panic does, frames of zerolog and logger are skipped
+func stacktrace() string {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	var b strings.Builder
+	for {
+		frame, more := frames.Next()
+		if !internalFrame(frame) {
+			b.WriteString(frame.Function)
+			b.WriteString("\n\t")
+			b.WriteString(frame.File)
+			b.WriteByte(':')
+			b.WriteString(strconv.Itoa(frame.Line))
+			b.WriteByte('\n')
+		}
+		if !more {
+			return b.String()
+		}
+	}
+}
+
+// Err starts record at error level, or info one for nil err like zerolog.Logger.Err does,
+// adding messages of wrapped errors as error_chain field
+func (lg *Logger) Err(err error) *zerolog.Event {
+	e := lg.Logger.Err(err)
+	if chain := ErrorChain(err); len(chain) > 1 {
+		e = e.Strs("error_chain", chain)
+	}
+	return e
+}
+
+// ErrorChain returns messages of err and errors wrapped by it with %w or errors.Join,
+// consecutive duplicates added by wrapping with stack only are skipped
+func ErrorChain(err error) []string {
+	var chain []string
+	var walk func(err error)
+	walk = func(err error) {
+		for err != nil {
+			if msg := err.Error(); len(chain) == 0 || chain[len(chain)-1] != msg {
+				chain = append(chain, msg)
+			}
+			switch u := err.(type) {
+			case interface{ Unwrap() []error }:
+				for _, err := range u.Unwrap() {
+					walk(err)
+				}
+				return
+			case interface{ Unwrap() error }:
+				err = u.Unwrap()
+			default:
+				return
+			}
+		}
+	}
+	walk(err)
+	return chain
+}
+
+// Err returns attribute group "error" with message, type of the innermost error, chain of
+// wrapped messages and stack recorded by github.com/pkg/errors if any. It is accepted by
+// With, Fatal, Panic and slog handler, so error is serialized the same way for any encoding.
+func Err(err error) slog.Attr {
+	if err == nil {
+		return slog.Attr{}
+	}
+	attrs := []any{slog.String("message", err.Error())}
+	var cause error
+	var trace errors.StackTrace
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		cause = e
+		if st, ok := e.(interface{ StackTrace() errors.StackTrace }); ok {
+			trace = st.StackTrace()
+		}
+	}
+	attrs = append(attrs, slog.String("type", fmt.Sprintf("%T", cause)))
+	if chain := ErrorChain(err); len(chain) > 1 {
+		attrs = append(attrs, slog.Any("chain", chain))
+	}
+	if len(trace) > 0 {
+		var b strings.Builder
+		for _, frame := range trace {
+			fmt.Fprintf(&b, "%+v\n", frame)
+		}
+		attrs = append(attrs, slog.String(StackFieldName, b.String()))
+	}
+	return slog.Group("error", attrs...)
+}
@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// WithTailLogging keeps records below logger level down to level in memory by request_id field,
+// up to records per request for up to requests latest requests. Once request logs at error level
+// or above, its kept records are written before, so debug records are seen for failed requests only.
+func WithTailLogging(level string, records, requests int) option {
+	return func(lg *Logger) error {
+		lvl, err := parseLevel(level)
+		if err != nil {
+			return err
+		}
+		if records < 1 || requests < 1 {
+			return errors.New("records and requests must be positive")
+		}
+		lg.levels.tail = lvl
+		lg.tail = &tailWriter{levels: lg.levels, records: records, requests: requests, buffers: map[string]*tailBuffer{}}
+		return nil
+	}
+}
+
+type tailWriter struct {
+	out      zerolog.LevelWriter
+	levels   *levels
+	records  int
+	requests int
+
+	mu      sync.Mutex
+	seq     uint64
+	buffers map[string]*tailBuffer
+	// order is buffers by creation, entries of written ones are skipped by seq
+	order []tailEntry
+}
+
+type tailBuffer struct {
+	seq     uint64
+	records []asyncRecord
+}
+
+type tailEntry struct {
+	id  string
+	seq uint64
+}
+
+func (w *tailWriter) live(e tailEntry) bool {
+	b, ok := w.buffers[e.id]
+	return ok && b.seq == e.seq
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w *tailWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	var record struct {
+		RequestID string `json:"request_id"`
+		Component string `json:"component"`
+	}
+	if level == zerolog.NoLevel || json.Unmarshal(p, &record) != nil {
+		return w.out.WriteLevel(level, p)
+	}
+	if level < w.levels.resolve(record.Component) {
+		if record.RequestID != "" {
+			w.keep(record.RequestID, level, p)
+		}
+		return len(p), nil
+	}
+	if level >= zerolog.ErrorLevel && record.RequestID != "" {
+		w.mu.Lock()
+		var kept []asyncRecord
+		if b, ok := w.buffers[record.RequestID]; ok {
+			kept = b.records
+			delete(w.buffers, record.RequestID)
+		}
+		w.mu.Unlock()
+		for _, r := range kept {
+			_, _ = w.out.WriteLevel(r.level, r.p)
+		}
+	}
+	return w.out.WriteLevel(level, p)
+}
+
+func (w *tailWriter) keep(id string, level zerolog.Level, p []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	b, ok := w.buffers[id]
+	if !ok {
+		for len(w.buffers) >= w.requests {
+			if oldest := w.order[0]; w.live(oldest) {
+				delete(w.buffers, oldest.id)
+			}
+			w.order = w.order[1:]
+		}
+		if len(w.order) > 2*w.requests {
+			order := make([]tailEntry, 0, len(w.buffers)+1)
+			for _, e := range w.order {
+				if w.live(e) {
+					order = append(order, e)
+				}
+			}
+			w.order = order
+		}
+		w.seq++
+		b = &tailBuffer{seq: w.seq}
+		w.buffers[id] = b
+		w.order = append(w.order, tailEntry{id, w.seq})
+	}
+	if len(b.records) == w.records {
+		b.records = b.records[1:]
+	}
+	b.records = append(b.records, asyncRecord{level, append([]byte(nil), p...)})
+}
@@ -0,0 +1,148 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// TB is a subset of testing.TB used by TestLogger
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Cleanup(func())
+}
+
+// Entry is a record captured by TestLogger
+type Entry struct {
+	Level   string
+	Message string
+	// Fields are decoded from JSON, so numbers are float64
+	Fields map[string]any
+}
+
+// NewTestLogger creates logger capturing records in memory at trace level,
+// pass its Logger field to components under test with WithLogger
+func NewTestLogger(t TB, options ...option) *TestLogger {
+	t.Helper()
+	tl := TestLogger{t: t}
+	options = append([]option{WithLevel(LevelTrace)}, options...)
+	lg, err := New(append(options, WithWriter(&tl))...)
+	if err != nil {
+		t.Errorf("new test logger: %v", err)
+		lg, _ = New(WithLevel(LevelTrace), WithWriter(&tl))
+	}
+	tl.Logger = lg
+	t.Cleanup(func() { _ = lg.Close(context.Background()) })
+	return &tl
+}
+
+type TestLogger struct {
+	*Logger
+
+	t       TB
+	mu      sync.Mutex
+	entries []Entry
+}
+
+func (tl *TestLogger) Write(p []byte) (int, error) {
+	var fields map[string]any
+	if err := json.Unmarshal(p, &fields); err != nil {
+		return 0, err
+	}
+	entry := Entry{Fields: fields}
+	entry.Level, _ = fields[zerolog.LevelFieldName].(string)
+	entry.Message, _ = fields[zerolog.MessageFieldName].(string)
+	delete(fields, zerolog.LevelFieldName)
+	delete(fields, zerolog.MessageFieldName)
+	delete(fields, zerolog.TimestampFieldName)
+
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	tl.entries = append(tl.entries, entry)
+	return len(p), nil
+}
+
+// Entries returns captured records
+func (tl *TestLogger) Entries() []Entry {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	return append([]Entry(nil), tl.entries...)
+}
+
+// FilterLevel returns captured records at level or above it
+func (tl *TestLogger) FilterLevel(level string) []Entry {
+	min, err := parseLevel(level)
+	if err != nil {
+		tl.t.Helper()
+		tl.t.Errorf("filter level: %v", err)
+		return nil
+	}
+	var res []Entry
+	for _, entry := range tl.Entries() {
+		if lvl, err := zerolog.ParseLevel(entry.Level); err == nil && lvl >= min {
+			res = append(res, entry)
+		}
+	}
+	return res
+}
+
+// Reset drops captured records
+func (tl *TestLogger) Reset() {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	tl.entries = nil
+}
+
+// AssertLogged reports error unless record with msg and key/value pairs of attrs was captured.
+// Values are compared as JSON, so any numeric type matches.
+func (tl *TestLogger) AssertLogged(msg string, attrs ...any) bool {
+	tl.t.Helper()
+	expected := map[string]any{}
+	args := pairs(attrs)
+	for i := 0; i < len(args); i += 2 {
+		expected[args[i].(string)] = normalize(args[i+1])
+	}
+
+	entries := tl.Entries()
+	for _, entry := range entries {
+		if entry.Message == msg && matches(entry.Fields, expected) {
+			return true
+		}
+	}
+	logged := make([]string, len(entries))
+	for i, entry := range entries {
+		logged[i] = fmt.Sprintf("%s %q %v", entry.Level, entry.Message, entry.Fields)
+	}
+	tl.t.Errorf("no record %q with %v, logged:\n%s", msg, expected, strings.Join(logged, "\n"))
+	return false
+}
+
+func matches(fields, expected map[string]any) bool {
+	for key, value := range expected {
+		if actual, ok := fields[key]; !ok || !reflect.DeepEqual(actual, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func normalize(v any) any {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var res any
+	if err := json.Unmarshal(data, &res); err != nil {
+		return v
+	}
+	return res
+}
@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/242617/core/ratelimit"
+)
+
+// SuppressedFieldName is a key of number of records dropped by rate limit before the record
+const SuppressedFieldName = "suppressed"
+
+// WithRateLimit limits records of every logger to n per window by its name,
+// the limit is also used by Logger.Throttled
+func WithRateLimit(n int, window time.Duration) option {
+	return func(lg *Logger) error {
+		if n < 1 {
+			return errors.New("limit must be positive")
+		}
+		if window <= 0 {
+			return errors.New("window must be positive")
+		}
+		lg.throttles = newThrottles(n, window)
+		lg.rateLimited = true
+		return nil
+	}
+}
+
+// Throttled creates child logger limiting records to n per window by key, e.g. address of
+// flapping downstream, with limit of WithRateLimit or 10 per minute. Number of dropped records
+// is added to the next written one. Records of it are not limited by name.
+func (lg *Logger) Throttled(key string) *Logger {
+	child := *lg
+	child.throttleKey = key
+	child.build()
+	return &child
+}
+
+type throttle struct {
+	limiter *ratelimit.Keyed
+
+	mu         sync.Mutex
+	suppressed map[string]int
+}
+
+func (t *throttle) allow(key string) (bool, int) {
+	allowed := t.limiter.Allow(key)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !allowed {
+		t.suppressed[key]++
+		return false, 0
+	}
+	suppressed := t.suppressed[key]
+	delete(t.suppressed, key)
+	return true, suppressed
+}
+
+// throttles are shared by logger and its children, by names and by keys of Throttled
+type throttles struct{ names, keys *throttle }
+
+func newThrottles(n int, window time.Duration) *throttles {
+	newThrottle := func() *throttle {
+		factory := func() ratelimit.Limiter { return ratelimit.NewSlidingWindow(n, window) }
+		return &throttle{limiter: ratelimit.NewKeyed(factory, window), suppressed: map[string]int{}}
+	}
+	return &throttles{newThrottle(), newThrottle()}
+}
+
+type throttleHook struct {
+	*throttle
+	key string
+}
+
+func (h throttleHook) Run(e *zerolog.Event, level zerolog.Level, _ string) {
+	if level == zerolog.Disabled {
+		return
+	}
+	allowed, suppressed := h.allow(h.key)
+	if !allowed {
+		e.Discard()
+		return
+	}
+	if suppressed > 0 {
+		e.Int(SuppressedFieldName, suppressed)
+	}
+}
@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DurationFieldName and CheckpointsFieldName are keys of record written by Timer.Stop
+const (
+	DurationFieldName    = "duration"
+	CheckpointsFieldName = "checkpoints"
+)
+
+// Timer measures operation and writes single record with its duration on Stop
+type Timer struct {
+	lg    *Logger
+	msg   string
+	start time.Time
+	warn  time.Duration
+	err   time.Duration
+
+	mu          sync.Mutex
+	last        time.Time
+	checkpoints []checkpoint
+}
+
+type checkpoint struct {
+	name     string
+	duration time.Duration
+}
+
+// Timer starts timer of operation logged with msg at info level by Stop
+func (lg *Logger) Timer(msg string) *Timer {
+	now := time.Now()
+	return &Timer{lg: lg, msg: msg, start: now, last: now}
+}
+
+// WarnIfOver makes record to be written at warn level if operation takes longer than d
+func (t *Timer) WarnIfOver(d time.Duration) *Timer {
+	t.warn = d
+	return t
+}
+
+// ErrorIfOver makes record to be written at error level if operation takes longer than d
+func (t *Timer) ErrorIfOver(d time.Duration) *Timer {
+	t.err = d
+	return t
+}
+
+// Checkpoint ends phase of operation, its duration since start or previous checkpoint
+// is added to checkpoints field of the record
+func (t *Timer) Checkpoint(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	t.checkpoints = append(t.checkpoints, checkpoint{name, now.Sub(t.last)})
+	t.last = now
+}
+
+// Stop writes record with duration of operation and returns it
+func (t *Timer) Stop() time.Duration {
+	d := time.Since(t.start)
+	level := zerolog.InfoLevel
+	switch {
+	case t.err > 0 && d > t.err:
+		level = zerolog.ErrorLevel
+	case t.warn > 0 && d > t.warn:
+		level = zerolog.WarnLevel
+	}
+	e := t.lg.WithLevel(level).Dur(DurationFieldName, d)
+
+	t.mu.Lock()
+	if len(t.checkpoints) > 0 {
+		dict := zerolog.Dict()
+		for _, c := range t.checkpoints {
+			dict = dict.Dur(c.name, c.duration)
+		}
+		e = e.Dict(CheckpointsFieldName, dict)
+	}
+	t.mu.Unlock()
+	e.Msg(t.msg)
+	return d
+}
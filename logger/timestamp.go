@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// WithTimeFormat sets layout of time of records for all encodings, e.g. time.RFC3339Nano,
+// time.RFC3339 is used by default
+func WithTimeFormat(layout string) option {
+	return func(lg *Logger) error {
+		if layout == "" {
+			return errors.New("empty time format")
+		}
+		lg.timeFormat = layout
+		return nil
+	}
+}
+
+// WithUTC makes time of records to be in UTC instead of local time zone
+func WithUTC() option {
+	return func(lg *Logger) error {
+		lg.utc = true
+		return nil
+	}
+}
+
+// timestamp adds time of record instead of zerolog one if time format or UTC is set
+type timestamp struct {
+	layout string
+	utc    bool
+}
+
+func (ts timestamp) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	t := zerolog.TimestampFunc()
+	if ts.utc {
+		t = t.UTC()
+	}
+	e.Str(zerolog.TimestampFieldName, t.Format(ts.layout))
+}
+
+// console formats time field for text encoding keeping layout and time zone
+func (ts timestamp) console(color bool) zerolog.Formatter {
+	return func(i any) string {
+		s, _ := i.(string)
+		if t, err := time.Parse(ts.layout, s); err == nil {
+			if ts.utc {
+				t = t.UTC()
+			} else {
+				t = t.Local()
+			}
+			s = t.Format(ts.layout)
+		}
+		if color {
+			return "\x1b[90m" + s + "\x1b[0m"
+		}
+		return s
+	}
+}
@@ -0,0 +1,242 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/httpserver"
+)
+
+// Config is a metrics configuration suitable for config.Scan.
+// Metrics are served on Addr only if it is set.
+type Config struct {
+	Namespace string    `yaml:"namespace" env:"METRICS_NAMESPACE"`
+	Addr      string    `yaml:"addr" env:"METRICS_ADDR"`
+	Path      string    `yaml:"path" default:"/metrics"`
+	Buckets   []float64 `yaml:"buckets"`
+}
+
+type option = func(m *Metrics) error
+
+func withDefaults() option {
+	return func(m *Metrics) error {
+		m.name, m.path = "metrics", "/metrics"
+		m.buckets = prometheus.DefBuckets
+		m.log = l.With().Str("component", "metrics").Logger()
+		return nil
+	}
+}
+
+func WithConfig(cfg Config) option {
+	return func(m *Metrics) error {
+		m.namespace, m.addr = cfg.Namespace, cfg.Addr
+		if cfg.Path != "" {
+			m.path = cfg.Path
+		}
+		if len(cfg.Buckets) > 0 {
+			m.buckets = cfg.Buckets
+		}
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(m *Metrics) error {
+		m.name = name
+		return nil
+	}
+}
+
+// WithBuckets sets buckets of histograms created by Observe
+func WithBuckets(buckets ...float64) option {
+	return func(m *Metrics) error {
+		m.buckets = buckets
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(m *Metrics) error {
+		m.log = log
+		return nil
+	}
+}
+
+// New creates metrics component implementing protocol.MetricsRecorder on top of prometheus registry
+// with go and process collectors registered
+func New(options ...option) (*Metrics, error) {
+	m := Metrics{
+		registry:   prometheus.NewRegistry(),
+		counters:   map[string]*prometheus.CounterVec{},
+		gauges:     map[string]*prometheus.GaugeVec{},
+		histograms: map[string]*prometheus.HistogramVec{},
+	}
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&m); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+
+	if err := m.registry.Register(collectors.NewGoCollector()); err != nil {
+		return nil, errors.Wrap(err, "register go collector")
+	}
+	if err := m.registry.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{Namespace: m.namespace})); err != nil {
+		return nil, errors.Wrap(err, "register process collector")
+	}
+
+	if m.addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle(m.path, m.Handler())
+		server, err := httpserver.New(
+			httpserver.WithName(m.name),
+			httpserver.WithAddr(m.addr),
+			httpserver.WithHandler(mux),
+			httpserver.WithMiddlewares(),
+			httpserver.WithLogger(m.log),
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "new server")
+		}
+		m.server = server
+	}
+
+	return &m, nil
+}
+
+type Metrics struct {
+	name, namespace string
+	addr, path      string
+	buckets         []float64
+	log             zerolog.Logger
+
+	registry   *prometheus.Registry
+	server     *httpserver.Server
+	mu         sync.Mutex
+	counters   map[string]*prometheus.CounterVec
+	gauges     map[string]*prometheus.GaugeVec
+	histograms map[string]*prometheus.HistogramVec
+}
+
+// Registry returns underlying registry to register custom collectors
+func (m *Metrics) Registry() *prometheus.Registry { return m.registry }
+
+// Handler returns handler exposing metrics in prometheus format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Addr returns address metrics are served on
+func (m *Metrics) Addr() string {
+	if m.server == nil {
+		return ""
+	}
+	return m.server.Addr()
+}
+
+func (m *Metrics) Add(name string, delta float64, labels ...string) {
+	keys, values := split(labels)
+	m.mu.Lock()
+	vec, ok := m.counters[name]
+	if !ok {
+		vec = prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: m.namespace, Name: name, Help: name}, keys)
+		if !m.register(name, vec) {
+			m.mu.Unlock()
+			return
+		}
+		m.counters[name] = vec
+	}
+	m.mu.Unlock()
+
+	counter, err := vec.GetMetricWith(values)
+	if err != nil {
+		m.log.Error().Err(err).Str("metric", name).Msg("add")
+		return
+	}
+	counter.Add(delta)
+}
+
+func (m *Metrics) Set(name string, value float64, labels ...string) {
+	keys, values := split(labels)
+	m.mu.Lock()
+	vec, ok := m.gauges[name]
+	if !ok {
+		vec = prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: m.namespace, Name: name, Help: name}, keys)
+		if !m.register(name, vec) {
+			m.mu.Unlock()
+			return
+		}
+		m.gauges[name] = vec
+	}
+	m.mu.Unlock()
+
+	gauge, err := vec.GetMetricWith(values)
+	if err != nil {
+		m.log.Error().Err(err).Str("metric", name).Msg("set")
+		return
+	}
+	gauge.Set(value)
+}
+
+func (m *Metrics) Observe(name string, value float64, labels ...string) {
+	keys, values := split(labels)
+	m.mu.Lock()
+	vec, ok := m.histograms[name]
+	if !ok {
+		vec = prometheus.NewHistogramVec(prometheus.HistogramOpts{Namespace: m.namespace, Name: name, Help: name, Buckets: m.buckets}, keys)
+		if !m.register(name, vec) {
+			m.mu.Unlock()
+			return
+		}
+		m.histograms[name] = vec
+	}
+	m.mu.Unlock()
+
+	histogram, err := vec.GetMetricWith(values)
+	if err != nil {
+		m.log.Error().Err(err).Str("metric", name).Msg("observe")
+		return
+	}
+	histogram.Observe(value)
+}
+
+func (m *Metrics) register(name string, collector prometheus.Collector) bool {
+	if err := m.registry.Register(collector); err != nil {
+		m.log.Error().Err(err).Str("metric", name).Msg("register")
+		return false
+	}
+	return true
+}
+
+func (m *Metrics) Start(ctx context.Context) error {
+	if m.server == nil {
+		return nil
+	}
+	return m.server.Start(ctx)
+}
+
+func (m *Metrics) Stop(ctx context.Context) error {
+	if m.server == nil {
+		return nil
+	}
+	return m.server.Stop(ctx)
+}
+
+func (m *Metrics) String() string { return m.name }
+
+func split(labels []string) ([]string, prometheus.Labels) {
+	keys, values := make([]string, 0, len(labels)/2), make(prometheus.Labels, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		keys = append(keys, labels[i])
+		values[labels[i]] = labels[i+1]
+	}
+	return keys, values
+}
@@ -0,0 +1,64 @@
+package metrics_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/config"
+	"github.com/242617/core/metrics"
+	"github.com/242617/core/protocol"
+)
+
+var _ protocol.MetricsRecorder = (*metrics.Metrics)(nil)
+
+func TestRecorder(t *testing.T) {
+	m, err := metrics.New(metrics.WithConfig(metrics.Config{Namespace: "app"}))
+	require.NoError(t, err, "new metrics")
+
+	m.Add("requests_total", 1, "method", "GET")
+	m.Add("requests_total", 2, "method", "GET")
+	m.Set("queue_depth", 5)
+	m.Observe("duration_seconds", 0.2, "method", "GET")
+	m.Add("requests_total", 1, "unexpected", "label")
+	m.Set("requests_total", 1, "method", "GET")
+
+	body := scrape(t, m.Handler())
+	assert.Contains(t, body, `app_requests_total{method="GET"} 3`, "counter")
+	assert.Contains(t, body, `app_queue_depth 5`, "gauge")
+	assert.Contains(t, body, `app_duration_seconds_bucket{method="GET",le="0.25"} 1`, "histogram")
+	assert.Contains(t, body, `go_goroutines`, "go collector")
+	assert.NotContains(t, body, `unexpected`, "inconsistent labels dropped")
+}
+
+func TestServer(t *testing.T) {
+	var cfg metrics.Config
+	require.NoError(t, config.New().Scan(&cfg), "scan config")
+	assert.Equal(t, "/metrics", cfg.Path, "default path")
+
+	cfg.Addr = "127.0.0.1:0"
+	m, err := metrics.New(metrics.WithConfig(cfg))
+	require.NoError(t, err, "new metrics")
+	require.NoError(t, m.Start(context.Background()), "start metrics")
+	defer m.Stop(context.Background())
+
+	m.Add("sample_total", 1)
+	res, err := http.Get("http://" + m.Addr() + "/metrics")
+	require.NoError(t, err, "get metrics")
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err, "read body")
+	assert.Contains(t, string(body), "sample_total 1", "counter served")
+}
+
+func scrape(t *testing.T, h http.Handler) string {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code, "scrape status")
+	return w.Body.String()
+}
@@ -0,0 +1,136 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// Config is a mongodb configuration suitable for config.Scan
+type Config struct {
+	URI                    string        `yaml:"uri" env:"MONGO_URI" default:"mongodb://localhost:27017"`
+	Database               string        `yaml:"database" env:"MONGO_DATABASE"`
+	Username               string        `yaml:"username" env:"MONGO_USERNAME"`
+	Password               string        `yaml:"password" env:"MONGO_PASSWORD"`
+	MaxPoolSize            uint64        `yaml:"max_pool_size"`
+	ConnectTimeout         time.Duration `yaml:"connect_timeout" default:"5s"`
+	ServerSelectionTimeout time.Duration `yaml:"server_selection_timeout" default:"5s"`
+}
+
+type option = func(r *Repo) error
+
+func withDefaults() option {
+	return func(r *Repo) error {
+		r.name = "mongorepo"
+		r.log = l.With().Str("component", "mongorepo").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(r *Repo) error {
+		r.name = name
+		return nil
+	}
+}
+
+// WithClientOptions sets driver options applied over the ones built from config
+func WithClientOptions(opts ...*options.ClientOptions) option {
+	return func(r *Repo) error {
+		r.opts = append(r.opts, opts...)
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(r *Repo) error {
+		r.log = log
+		return nil
+	}
+}
+
+// New creates mongodb component. Client is created immediately, connection is checked on Start.
+func New(cfg Config, options ...option) (*Repo, error) {
+	if cfg.URI == "" {
+		return nil, errors.New("empty uri")
+	}
+	if cfg.Database == "" {
+		return nil, errors.New("empty database")
+	}
+
+	r := Repo{cfg: cfg}
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&r); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+
+	client, err := mongo.Connect(context.Background(), r.clientOptions()...)
+	if err != nil {
+		return nil, errors.Wrap(err, "new client")
+	}
+	r.Client = client
+	return &r, nil
+}
+
+type Repo struct {
+	*mongo.Client
+
+	name string
+	cfg  Config
+	opts []*options.ClientOptions
+	log  zerolog.Logger
+}
+
+func (r *Repo) clientOptions() []*options.ClientOptions {
+	opts := options.Client().
+		ApplyURI(r.cfg.URI).
+		SetMonitor(newMonitor(r.log))
+	if r.cfg.Username != "" {
+		opts.SetAuth(options.Credential{Username: r.cfg.Username, Password: r.cfg.Password})
+	}
+	if r.cfg.MaxPoolSize > 0 {
+		opts.SetMaxPoolSize(r.cfg.MaxPoolSize)
+	}
+	if r.cfg.ConnectTimeout > 0 {
+		opts.SetConnectTimeout(r.cfg.ConnectTimeout)
+	}
+	if r.cfg.ServerSelectionTimeout > 0 {
+		opts.SetServerSelectionTimeout(r.cfg.ServerSelectionTimeout)
+	}
+	return append([]*options.ClientOptions{opts}, r.opts...)
+}
+
+// DB returns configured database
+func (r *Repo) DB() *mongo.Database { return r.Client.Database(r.cfg.Database) }
+
+func (r *Repo) Start(ctx context.Context) error {
+	if err := r.HealthCheck(ctx); err != nil {
+		return err
+	}
+	r.log.Info().Msgf("connected to mongodb %s", r.cfg.Database)
+	return nil
+}
+
+func (r *Repo) Stop(ctx context.Context) error {
+	if err := r.Client.Disconnect(ctx); err != nil {
+		return errors.Wrap(err, "disconnect")
+	}
+	return nil
+}
+
+func (r *Repo) HealthCheck(ctx context.Context) error {
+	if err := r.Client.Ping(ctx, readpref.Primary()); err != nil {
+		return errors.Wrap(err, "ping")
+	}
+	return nil
+}
+
+func (r *Repo) String() string { return r.name }
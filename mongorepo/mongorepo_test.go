@@ -0,0 +1,39 @@
+package mongorepo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/mongorepo"
+)
+
+func TestNew(t *testing.T) {
+	_, err := mongorepo.New(mongorepo.Config{Database: "sample"})
+	assert.Error(t, err, "empty uri")
+	_, err = mongorepo.New(mongorepo.Config{URI: "mongodb://localhost:27017"})
+	assert.Error(t, err, "empty database")
+	_, err = mongorepo.New(mongorepo.Config{URI: "invalid", Database: "sample"})
+	assert.Error(t, err, "invalid uri")
+
+	r, err := mongorepo.New(mongorepo.Config{URI: "mongodb://localhost:27017", Database: "sample"}, mongorepo.WithName("sample"))
+	require.NoError(t, err, "new")
+	assert.Equal(t, "sample", r.String(), "name")
+	assert.Equal(t, "sample", r.DB().Name(), "database")
+	require.NoError(t, r.Stop(context.Background()), "stop")
+}
+
+func TestUnavailable(t *testing.T) {
+	r, err := mongorepo.New(mongorepo.Config{
+		URI:                    "mongodb://127.0.0.1:1",
+		Database:               "sample",
+		ServerSelectionTimeout: 50 * time.Millisecond,
+	})
+	require.NoError(t, err, "new")
+	defer r.Stop(context.Background())
+
+	assert.Error(t, r.Start(context.Background()), "server unavailable")
+}
@@ -0,0 +1,36 @@
+package mongorepo
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/event"
+
+	"github.com/242617/core/requestid"
+)
+
+// newMonitor logs every command, failed ones with error level. Request id of context is logged
+// as request_id, id of wire protocol message as mongo_request_id.
+func newMonitor(log zerolog.Logger) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			log.Debug().
+				Str("command", e.CommandName).
+				Str("database", e.DatabaseName).
+				Str("request_id", requestid.FromContext(ctx)).
+				Int64("mongo_request_id", e.RequestID).
+				Dur("duration", e.Duration).
+				Msg("command")
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			log.Error().
+				Str("error", e.Failure).
+				Str("command", e.CommandName).
+				Str("database", e.DatabaseName).
+				Str("request_id", requestid.FromContext(ctx)).
+				Int64("mongo_request_id", e.RequestID).
+				Dur("duration", e.Duration).
+				Msg("command")
+		},
+	}
+}
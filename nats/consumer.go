@@ -0,0 +1,97 @@
+package nats
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/pkg/errors"
+)
+
+// ConsumerConfig is a durable JetStream consumer configuration suitable for config.Scan
+type ConsumerConfig struct {
+	Stream        string        `yaml:"stream"`
+	Durable       string        `yaml:"durable"`
+	FilterSubject string        `yaml:"filter_subject"`
+	AckWait       time.Duration `yaml:"ack_wait" default:"30s"`
+	MaxDeliver    int           `yaml:"max_deliver" default:"5"`
+	// NakDelay is a delay of redelivery of failed message
+	NakDelay time.Duration `yaml:"nak_delay" default:"1s"`
+}
+
+// NewConsumer creates component consuming durable JetStream consumer between Start and Stop.
+// Message is acked when handler succeeds and nacked with delay otherwise.
+func NewConsumer(conn *Conn, cfg ConsumerConfig, handler Handler) (*Consumer, error) {
+	if cfg.Stream == "" {
+		return nil, errors.New("empty stream")
+	}
+	if cfg.Durable == "" {
+		return nil, errors.New("empty durable")
+	}
+	return &Consumer{conn: conn, cfg: cfg, handler: handler}, nil
+}
+
+type Consumer struct {
+	conn    *Conn
+	cfg     ConsumerConfig
+	handler Handler
+
+	consume jetstream.ConsumeContext
+}
+
+func (c *Consumer) Start(ctx context.Context) error {
+	js, err := jetstream.New(c.conn.Conn())
+	if err != nil {
+		return errors.Wrap(err, "jetstream")
+	}
+	consumer, err := js.CreateOrUpdateConsumer(ctx, c.cfg.Stream, jetstream.ConsumerConfig{
+		Durable:       c.cfg.Durable,
+		FilterSubject: c.cfg.FilterSubject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       c.cfg.AckWait,
+		MaxDeliver:    c.cfg.MaxDeliver,
+	})
+	if err != nil {
+		return errors.Wrap(err, "create consumer")
+	}
+
+	c.consume, err = consumer.Consume(c.process, jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+		c.conn.log.Warn().Err(err).Str("consumer", c.cfg.Durable).Msg("consume")
+	}))
+	if err != nil {
+		return errors.Wrap(err, "consume")
+	}
+	return nil
+}
+
+// Stop stops fetching messages waiting for in-flight ones to be handled
+func (c *Consumer) Stop(ctx context.Context) error {
+	if c.consume == nil {
+		return nil
+	}
+	c.consume.Drain()
+	select {
+	case <-ctx.Done():
+		c.consume.Stop()
+		return ctx.Err()
+	case <-c.consume.Closed():
+		return nil
+	}
+}
+
+func (c *Consumer) String() string { return c.conn.name + ":" + c.cfg.Durable }
+
+func (c *Consumer) process(m jetstream.Msg) {
+	msg := &nats.Msg{Subject: m.Subject(), Data: m.Data(), Header: m.Headers()}
+	if err := c.conn.handle(c.handler, msg); err != nil {
+		c.conn.log.Error().Err(err).Str("subject", m.Subject()).Msg("handle")
+		if err := m.NakWithDelay(c.cfg.NakDelay); err != nil {
+			c.conn.log.Error().Err(err).Msg("nak")
+		}
+		return
+	}
+	if err := m.Ack(); err != nil {
+		c.conn.log.Error().Err(err).Msg("ack")
+	}
+}
@@ -0,0 +1,202 @@
+package nats
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/requestid"
+)
+
+// Config is a nats configuration suitable for config.Scan
+type Config struct {
+	URL           string        `yaml:"url" env:"NATS_URL" default:"nats://127.0.0.1:4222"`
+	Token         string        `yaml:"token" env:"NATS_TOKEN"`
+	MaxReconnects int           `yaml:"max_reconnects" default:"-1"`
+	ReconnectWait time.Duration `yaml:"reconnect_wait" default:"2s"`
+	Timeout       time.Duration `yaml:"timeout" default:"5s"`
+}
+
+type Message struct {
+	Subject string
+	Data    []byte
+	Header  map[string][]string
+}
+
+// Handler processes message, returned error makes JetStream redeliver it
+type Handler = func(ctx context.Context, msg Message) error
+
+type option = func(c *Conn) error
+
+func withDefaults() option {
+	return func(c *Conn) error {
+		c.name = "nats"
+		c.log = l.With().Str("component", "nats").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(c *Conn) error {
+		c.name = name
+		return nil
+	}
+}
+
+// WithOptions sets driver options applied over the ones built from config
+func WithOptions(opts ...nats.Option) option {
+	return func(c *Conn) error {
+		c.opts = append(c.opts, opts...)
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(c *Conn) error {
+		c.log = log
+		return nil
+	}
+}
+
+// New creates nats connection component, connection is established on Start
+func New(cfg Config, options ...option) (*Conn, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("empty url")
+	}
+
+	c := Conn{cfg: cfg}
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&c); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	return &c, nil
+}
+
+type Conn struct {
+	name string
+	cfg  Config
+	opts []nats.Option
+	log  zerolog.Logger
+
+	conn *nats.Conn
+}
+
+func (c *Conn) Start(context.Context) error {
+	opts := []nats.Option{
+		nats.Name(c.name),
+		nats.MaxReconnects(c.cfg.MaxReconnects),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			c.log.Warn().Err(err).Msg("disconnected")
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			c.log.Info().Msgf("reconnected to %s", nc.ConnectedUrl())
+		}),
+		nats.ErrorHandler(func(_ *nats.Conn, sub *nats.Subscription, err error) {
+			event := c.log.Error().Err(err)
+			if sub != nil {
+				event = event.Str("subject", sub.Subject)
+			}
+			event.Msg("async error")
+		}),
+	}
+	if c.cfg.Token != "" {
+		opts = append(opts, nats.Token(c.cfg.Token))
+	}
+	if c.cfg.ReconnectWait > 0 {
+		opts = append(opts, nats.ReconnectWait(c.cfg.ReconnectWait))
+	}
+	if c.cfg.Timeout > 0 {
+		opts = append(opts, nats.Timeout(c.cfg.Timeout))
+	}
+
+	conn, err := nats.Connect(c.cfg.URL, append(opts, c.opts...)...)
+	if err != nil {
+		return errors.Wrap(err, "connect")
+	}
+	c.conn = conn
+	c.log.Info().Msgf("connected to %s", conn.ConnectedUrl())
+	return nil
+}
+
+// Stop drains subscriptions and flushes pending messages before closing connection
+func (c *Conn) Stop(ctx context.Context) error {
+	if c.conn == nil {
+		return nil
+	}
+	closed := make(chan struct{})
+	c.conn.SetClosedHandler(func(*nats.Conn) { close(closed) })
+	if err := c.conn.Drain(); err != nil {
+		c.conn.Close()
+		return errors.Wrap(err, "drain")
+	}
+	select {
+	case <-ctx.Done():
+		c.conn.Close()
+		return ctx.Err()
+	case <-closed:
+		return nil
+	}
+}
+
+func (c *Conn) HealthCheck(context.Context) error {
+	if c.conn == nil || !c.conn.IsConnected() {
+		return errors.New("not connected")
+	}
+	return nil
+}
+
+func (c *Conn) String() string { return c.name }
+
+// Conn returns underlying connection, it is nil before Start
+func (c *Conn) Conn() *nats.Conn { return c.conn }
+
+// Publish sends message propagating request ID from ctx in header
+func (c *Conn) Publish(ctx context.Context, msg Message) error {
+	m := nats.NewMsg(msg.Subject)
+	m.Data = msg.Data
+	for key, values := range msg.Header {
+		m.Header[key] = values
+	}
+	if id := requestid.FromContext(ctx); id != "" && m.Header.Get(requestid.Header) == "" {
+		m.Header.Set(requestid.Header, id)
+	}
+	if err := c.conn.PublishMsg(m); err != nil {
+		return errors.Wrap(err, "publish")
+	}
+	return nil
+}
+
+// Subscribe handles core nats messages of subject, messages are shared within non-empty queue group
+func (c *Conn) Subscribe(subject, queue string, handler Handler) (unsubscribe func() error, err error) {
+	sub, err := c.conn.QueueSubscribe(subject, queue, func(m *nats.Msg) {
+		if err := c.handle(handler, m); err != nil {
+			c.log.Error().Err(err).Str("subject", m.Subject).Msg("handle")
+		}
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "subscribe")
+	}
+	return sub.Unsubscribe, nil
+}
+
+func (c *Conn) handle(handler Handler, m *nats.Msg) (err error) {
+	ctx := context.Background()
+	if id := m.Header.Get(requestid.Header); id != "" {
+		ctx = requestid.NewContext(ctx, id)
+	}
+
+	defer func() {
+		if v := recover(); v != nil {
+			c.log.Error().Interface("panic", v).Bytes("stack", debug.Stack()).Msg("recovered")
+			err = errors.Errorf("panic: %v", v)
+		}
+	}()
+	return handler(ctx, Message{Subject: m.Subject, Data: m.Data, Header: m.Header})
+}
@@ -0,0 +1,133 @@
+package nats_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/nats"
+	"github.com/242617/core/requestid"
+)
+
+var period = 10 * time.Millisecond
+
+// fakeServer implements subset of core nats protocol for single client
+type fakeServer struct {
+	net.Listener
+	mu   sync.Mutex
+	subs map[string]string // sid -> subject
+}
+
+func newServer(t *testing.T) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "listen")
+	s := fakeServer{Listener: ln, subs: map[string]string{}}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return &s
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"fake\",\"version\":\"2.10.0\",\"proto\":1,\"headers\":true,\"max_payload\":1048576}\r\n")
+
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		switch strings.ToUpper(args[0]) {
+		case "PING":
+			fmt.Fprintf(conn, "PONG\r\n")
+		case "SUB":
+			s.mu.Lock()
+			s.subs[args[len(args)-1]] = args[1]
+			s.mu.Unlock()
+		case "UNSUB":
+			s.mu.Lock()
+			delete(s.subs, args[1])
+			s.mu.Unlock()
+		case "HPUB":
+			var headerSize, totalSize int
+			fmt.Sscan(args[len(args)-2], &headerSize)
+			fmt.Sscan(args[len(args)-1], &totalSize)
+			payload := make([]byte, totalSize+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			s.mu.Lock()
+			for sid, subject := range s.subs {
+				if subject == args[1] {
+					fmt.Fprintf(conn, "HMSG %s %s %d %d\r\n%s", subject, sid, headerSize, totalSize, payload)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func TestConn(t *testing.T) {
+	s := newServer(t)
+	c, err := nats.New(nats.Config{URL: "nats://" + s.Addr().String()}, nats.WithName("sample"))
+	require.NoError(t, err, "new")
+	assert.Error(t, c.HealthCheck(context.Background()), "not started")
+	require.NoError(t, c.Start(context.Background()), "start")
+
+	received := make(chan nats.Message, 1)
+	ids := make(chan string, 1)
+	_, err = c.Subscribe("orders.created", "", func(ctx context.Context, msg nats.Message) error {
+		received <- msg
+		ids <- requestid.FromContext(ctx)
+		return nil
+	})
+	require.NoError(t, err, "subscribe")
+	require.NoError(t, c.Conn().Flush(), "flush subscription")
+
+	ctx := requestid.NewContext(context.Background(), "request")
+	require.NoError(t, c.Publish(ctx, nats.Message{Subject: "orders.created", Data: []byte("sample")}), "publish")
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, "sample", string(msg.Data), "data")
+		assert.Equal(t, "request", <-ids, "request id propagated")
+	case <-time.After(100 * period):
+		t.Fatal("message not received")
+	}
+
+	require.NoError(t, c.HealthCheck(context.Background()), "healthy")
+	require.NoError(t, c.Stop(context.Background()), "stop")
+}
+
+func TestValidation(t *testing.T) {
+	_, err := nats.New(nats.Config{})
+	assert.Error(t, err, "empty url")
+
+	c, err := nats.New(nats.Config{URL: "nats://127.0.0.1:4222"})
+	require.NoError(t, err, "new")
+	_, err = nats.NewConsumer(c, nats.ConsumerConfig{Durable: "sample"}, nil)
+	assert.Error(t, err, "empty stream")
+	_, err = nats.NewConsumer(c, nats.ConsumerConfig{Stream: "orders"}, nil)
+	assert.Error(t, err, "empty durable")
+}
@@ -0,0 +1,127 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/242617/core/ratelimit"
+)
+
+var ErrRateLimited = errors.New("rate limited")
+
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+type Notification struct {
+	Title    string            `json:"title"`
+	Text     string            `json:"text"`
+	Severity Severity          `json:"severity"`
+	Fields   map[string]string `json:"fields,omitempty"`
+	Time     time.Time         `json:"time"`
+}
+
+// Notifier delivers notifications to on-call
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+type NotifierFunc func(ctx context.Context, n Notification) error
+
+func (f NotifierFunc) Notify(ctx context.Context, n Notification) error { return f(ctx, n) }
+
+// Multi delivers notification to all notifiers returning first error
+func Multi(notifiers ...Notifier) Notifier {
+	return NotifierFunc(func(ctx context.Context, n Notification) error {
+		var (
+			mu    sync.Mutex
+			first error
+			wg    sync.WaitGroup
+		)
+		for _, notifier := range notifiers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := notifier.Notify(ctx, n); err != nil {
+					mu.Lock()
+					if first == nil {
+						first = err
+					}
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		return first
+	})
+}
+
+// Templated renders notification text with template executed on Notification
+func Templated(notifier Notifier, text string) (Notifier, error) {
+	tmpl, err := template.New("notification").Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse template")
+	}
+	return NotifierFunc(func(ctx context.Context, n Notification) error {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, n); err != nil {
+			return errors.Wrap(err, "execute template")
+		}
+		n.Text = buf.String()
+		return notifier.Notify(ctx, n)
+	}), nil
+}
+
+// RateLimited drops notifications with the same title exceeding limiter created by factory
+func RateLimited(notifier Notifier, factory func() ratelimit.Limiter) Notifier {
+	limiter := ratelimit.NewKeyed(factory, time.Hour)
+	return NotifierFunc(func(ctx context.Context, n Notification) error {
+		if !limiter.Allow(n.Title) {
+			return ErrRateLimited
+		}
+		return notifier.Notify(ctx, n)
+	})
+}
+
+// OnError returns callback notifying about errors, e.g. for application.WithOnError
+func OnError(notifier Notifier, title string, timeout time.Duration) func(error) {
+	return func(err error) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		_ = notifier.Notify(ctx, Notification{
+			Title:    title,
+			Text:     err.Error(),
+			Severity: SeverityCritical,
+			Time:     time.Now(),
+		})
+	}
+}
+
+// Hook returns zerolog hook notifying about events of level or higher in background
+func Hook(notifier Notifier, level zerolog.Level, timeout time.Duration) zerolog.Hook {
+	return zerolog.HookFunc(func(e *zerolog.Event, l zerolog.Level, msg string) {
+		if l < level || l == zerolog.NoLevel || l == zerolog.Disabled {
+			return
+		}
+		severity := SeverityWarning
+		if l >= zerolog.ErrorLevel {
+			severity = SeverityCritical
+		}
+		n := Notification{Title: msg, Text: msg, Severity: severity, Time: time.Now()}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			_ = notifier.Notify(ctx, n)
+		}()
+	})
+}
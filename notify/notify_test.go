@@ -0,0 +1,109 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/notify"
+	"github.com/242617/core/ratelimit"
+)
+
+var period = 10 * time.Millisecond
+
+type recorder struct {
+	mu   sync.Mutex
+	sent []notify.Notification
+}
+
+func (r *recorder) Notify(_ context.Context, n notify.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, n)
+	return nil
+}
+
+func (r *recorder) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sent)
+}
+
+func TestSenders(t *testing.T) {
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body), "decode")
+		bodies = append(bodies, body)
+	}))
+	defer srv.Close()
+
+	n := notify.Notification{
+		Title:    "db down",
+		Text:     "connection refused",
+		Severity: notify.SeverityCritical,
+		Fields:   map[string]string{"host": "db-1"},
+	}
+	require.NoError(t, notify.Webhook(nil, srv.URL).Notify(context.Background(), n), "webhook")
+	require.NoError(t, notify.Slack(nil, srv.URL).Notify(context.Background(), n), "slack")
+
+	require.Len(t, bodies, 2, "requests")
+	assert.Equal(t, "db down", bodies[0]["title"], "webhook body")
+	attachment := bodies[1]["attachments"].([]any)[0].(map[string]any)
+	assert.Equal(t, "connection refused", attachment["text"], "slack text")
+	assert.Equal(t, "#a30200", attachment["color"], "slack color")
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	assert.Error(t, notify.Webhook(nil, failing.URL).Notify(context.Background(), n), "bad status")
+
+	_, err := notify.Email(notify.EmailConfig{Addr: "localhost:25"})
+	assert.Error(t, err, "email requires recipients")
+}
+
+func TestWrappers(t *testing.T) {
+	var r recorder
+	tmpl, err := notify.Templated(&r, "{{.Severity}}: {{.Text}} on {{index .Fields \"host\"}}")
+	require.NoError(t, err, "template")
+	limited := notify.RateLimited(tmpl, func() ratelimit.Limiter { return ratelimit.NewTokenBucket(0.001, 1) })
+
+	n := notify.Notification{Title: "disk full", Text: "95%", Severity: notify.SeverityWarning, Fields: map[string]string{"host": "web-1"}}
+	require.NoError(t, limited.Notify(context.Background(), n), "first")
+	assert.ErrorIs(t, limited.Notify(context.Background(), n), notify.ErrRateLimited, "repeated title")
+	n.Title = "disk slow"
+	require.NoError(t, limited.Notify(context.Background(), n), "other title")
+	assert.Equal(t, "warning: 95% on web-1", r.sent[0].Text, "rendered")
+
+	_, err = notify.Templated(&r, "{{")
+	assert.Error(t, err, "invalid template")
+
+	fail := errors.New("fail")
+	var other recorder
+	multi := notify.Multi(&other, notify.NotifierFunc(func(context.Context, notify.Notification) error { return fail }))
+	assert.ErrorIs(t, multi.Notify(context.Background(), n), fail, "error returned")
+	assert.Equal(t, 1, other.len(), "delivered to the rest")
+
+	notify.OnError(&other, "application failed", time.Second)(errors.New("cannot start"))
+	assert.Equal(t, "cannot start", other.sent[1].Text, "error text")
+	assert.Equal(t, notify.SeverityCritical, other.sent[1].Severity, "critical")
+}
+
+func TestHook(t *testing.T) {
+	var r recorder
+	log := zerolog.Nop().Level(zerolog.InfoLevel).Hook(notify.Hook(&r, zerolog.ErrorLevel, time.Second))
+	log.Info().Msg("started")
+	log.Error().Msg("cannot connect")
+	assert.Eventually(t, func() bool { return r.len() == 1 }, 10*period, period, "error notified")
+	assert.Equal(t, "cannot connect", r.sent[0].Title, "title")
+}
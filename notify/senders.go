@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Webhook posts notification as json to url
+func Webhook(client *http.Client, url string) Notifier {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return NotifierFunc(func(ctx context.Context, n Notification) error {
+		return post(ctx, client, url, n)
+	})
+}
+
+// Slack posts notification to incoming webhook
+func Slack(client *http.Client, webhookURL string) Notifier {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	colors := map[Severity]string{
+		SeverityInfo:     "#2eb886",
+		SeverityWarning:  "#daa038",
+		SeverityCritical: "#a30200",
+	}
+	return NotifierFunc(func(ctx context.Context, n Notification) error {
+		type field struct {
+			Title string `json:"title"`
+			Value string `json:"value"`
+			Short bool   `json:"short"`
+		}
+		fields := make([]field, 0, len(n.Fields))
+		for _, k := range sortedKeys(n.Fields) {
+			fields = append(fields, field{k, n.Fields[k], true})
+		}
+		attachment := map[string]any{
+			"color":  colors[n.Severity],
+			"title":  n.Title,
+			"text":   n.Text,
+			"fields": fields,
+		}
+		if !n.Time.IsZero() {
+			attachment["ts"] = n.Time.Unix()
+		}
+		return post(ctx, client, webhookURL, map[string]any{"attachments": []any{attachment}})
+	})
+}
+
+// EmailConfig configures SMTP delivery
+type EmailConfig struct {
+	Addr     string   `yaml:"addr" env:"SMTP_ADDR"`
+	Username string   `yaml:"username" env:"SMTP_USERNAME"`
+	Password string   `yaml:"password" env:"SMTP_PASSWORD"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+// Email sends notification as plain text email
+func Email(cfg EmailConfig) (Notifier, error) {
+	if cfg.Addr == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return nil, errors.New("addr, from and to are required")
+	}
+	host, _, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse addr")
+	}
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+	return NotifierFunc(func(ctx context.Context, n Notification) error {
+		var msg bytes.Buffer
+		fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+		fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+		fmt.Fprintf(&msg, "Subject: [%s] %s\r\n", n.Severity, sanitize(n.Title))
+		msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		msg.WriteString(n.Text)
+		msg.WriteString("\r\n")
+		for _, k := range sortedKeys(n.Fields) {
+			fmt.Fprintf(&msg, "%s: %s\r\n", k, n.Fields[k])
+		}
+
+		errCh := make(chan error, 1)
+		go func() { errCh <- smtp.SendMail(cfg.Addr, auth, cfg.From, cfg.To, msg.Bytes()) }()
+		select {
+		case err := <-errCh:
+			return errors.Wrap(err, "send mail")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}), nil
+}
+
+func post(ctx context.Context, client *http.Client, url string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "marshal")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "new request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "post")
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode >= http.StatusBadRequest {
+		return errors.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sanitize prevents header injection via title
+func sanitize(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,265 @@
+package objstore
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/requestid"
+	"github.com/242617/core/retry"
+)
+
+// Config is an S3-compatible storage configuration suitable for config.Scan
+type Config struct {
+	Endpoint  string `yaml:"endpoint" env:"OBJSTORE_ENDPOINT" default:"s3.amazonaws.com"`
+	Region    string `yaml:"region" env:"OBJSTORE_REGION" default:"us-east-1"`
+	Bucket    string `yaml:"bucket" env:"OBJSTORE_BUCKET"`
+	AccessKey string `yaml:"access_key" env:"OBJSTORE_ACCESS_KEY"`
+	SecretKey string `yaml:"secret_key" env:"OBJSTORE_SECRET_KEY"`
+	Insecure  bool   `yaml:"insecure"`
+	// PartSize is a size of multipart upload parts, objects of unknown size are always uploaded in parts
+	PartSize uint64 `yaml:"part_size" default:"16777216"`
+}
+
+var ErrNotFound = errors.New("object not found")
+
+type Object struct {
+	Key          string
+	Size         int64
+	ETag         string
+	ContentType  string
+	LastModified time.Time
+}
+
+type option = func(s *Store) error
+
+func withDefaults() option {
+	return func(s *Store) error {
+		s.name = "objstore"
+		s.log = l.With().Str("component", "objstore").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(s *Store) error {
+		s.name = name
+		return nil
+	}
+}
+
+// WithRetry sets retry policy of idempotent operations and uploads of seekable readers
+func WithRetry(options ...retry.Option) option {
+	return func(s *Store) error {
+		s.retry = options
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(s *Store) error {
+		s.log = log
+		return nil
+	}
+}
+
+// New creates S3-compatible storage component, bucket existence is checked on Start
+func New(cfg Config, options ...option) (*Store, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("empty endpoint")
+	}
+	if cfg.Bucket == "" {
+		return nil, errors.New("empty bucket")
+	}
+
+	s := Store{cfg: cfg}
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&s); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: !cfg.Insecure,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "new client")
+	}
+	s.client = client
+	return &s, nil
+}
+
+type Store struct {
+	name   string
+	cfg    Config
+	client *minio.Client
+	retry  []retry.Option
+	log    zerolog.Logger
+}
+
+func (s *Store) Start(ctx context.Context) error {
+	if err := s.HealthCheck(ctx); err != nil {
+		return err
+	}
+	s.log.Info().Msgf("connected to bucket %s at %s", s.cfg.Bucket, s.cfg.Endpoint)
+	return nil
+}
+
+func (s *Store) Stop(context.Context) error { return nil }
+
+func (s *Store) HealthCheck(ctx context.Context) error {
+	ok, err := s.client.BucketExists(ctx, s.cfg.Bucket)
+	if err != nil {
+		return errors.Wrap(err, "check bucket")
+	}
+	if !ok {
+		return errors.Errorf("bucket %q does not exist", s.cfg.Bucket)
+	}
+	return nil
+}
+
+func (s *Store) String() string { return s.name }
+
+// Upload stores object, size -1 means unknown size. Seekable readers are rewound between attempts.
+func (s *Store) Upload(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	seeker, seekable := r.(io.Seeker)
+	options := s.retry
+	if !seekable {
+		options = append(options[:len(options):len(options)], retry.WithMaxAttempts(1))
+	}
+
+	var attempt int
+	return s.do(ctx, "upload", key, func(ctx context.Context) error {
+		if attempt++; attempt > 1 {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return retry.Permanent(errors.Wrap(err, "seek"))
+			}
+		}
+		_, err := s.client.PutObject(ctx, s.cfg.Bucket, key, r, size, minio.PutObjectOptions{
+			ContentType: contentType,
+			PartSize:    s.cfg.PartSize,
+		})
+		return err
+	}, options...)
+}
+
+// Download returns object content, it must be closed by caller
+func (s *Store) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	var obj *minio.Object
+	err := s.do(ctx, "download", key, func(ctx context.Context) error {
+		o, err := s.client.GetObject(ctx, s.cfg.Bucket, key, minio.GetObjectOptions{})
+		if err != nil {
+			return err
+		}
+		// request is sent lazily, stat makes errors like missing object visible here
+		if _, err := o.Stat(); err != nil {
+			o.Close()
+			return err
+		}
+		obj = o
+		return nil
+	}, s.retry...)
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *Store) Stat(ctx context.Context, key string) (Object, error) {
+	var obj Object
+	err := s.do(ctx, "stat", key, func(ctx context.Context) error {
+		info, err := s.client.StatObject(ctx, s.cfg.Bucket, key, minio.StatObjectOptions{})
+		if err != nil {
+			return err
+		}
+		obj = object(info)
+		return nil
+	}, s.retry...)
+	return obj, err
+}
+
+func (s *Store) Delete(ctx context.Context, key string) error {
+	return s.do(ctx, "delete", key, func(ctx context.Context) error {
+		return s.client.RemoveObject(ctx, s.cfg.Bucket, key, minio.RemoveObjectOptions{})
+	}, s.retry...)
+}
+
+// List returns all objects with prefix
+func (s *Store) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	err := s.do(ctx, "list", prefix, func(ctx context.Context) error {
+		objects = nil
+		for info := range s.client.ListObjects(ctx, s.cfg.Bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+			if info.Err != nil {
+				return info.Err
+			}
+			objects = append(objects, object(info))
+		}
+		return nil
+	}, s.retry...)
+	return objects, err
+}
+
+// PresignGet returns URL allowing to download object without credentials until expiration
+func (s *Store) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	u, err := s.client.PresignedGetObject(ctx, s.cfg.Bucket, key, expires, url.Values{})
+	if err != nil {
+		return "", errors.Wrap(err, "presign")
+	}
+	return u.String(), nil
+}
+
+// PresignPut returns URL allowing to upload object without credentials until expiration
+func (s *Store) PresignPut(ctx context.Context, key string, expires time.Duration) (string, error) {
+	u, err := s.client.PresignedPutObject(ctx, s.cfg.Bucket, key, expires)
+	if err != nil {
+		return "", errors.Wrap(err, "presign")
+	}
+	return u.String(), nil
+}
+
+func (s *Store) do(ctx context.Context, op, key string, fn func(ctx context.Context) error, options ...retry.Option) error {
+	start := time.Now()
+	err := retry.Do(ctx, func(ctx context.Context) error {
+		err := fn(ctx)
+		if code := minio.ToErrorResponse(err).StatusCode; code >= 400 && code < 500 && code != 429 {
+			return retry.Permanent(err)
+		}
+		return err
+	}, options...)
+	if code := minio.ToErrorResponse(err).Code; code == "NoSuchKey" {
+		err = ErrNotFound
+	}
+
+	event := s.log.Debug()
+	if err != nil {
+		event = s.log.Error().Err(err)
+	}
+	event.
+		Str("request_id", requestid.FromContext(ctx)).
+		Str("op", op).
+		Str("key", key).
+		Dur("duration", time.Since(start)).
+		Msg("object")
+	return errors.Wrap(err, op)
+}
+
+func object(info minio.ObjectInfo) Object {
+	return Object{
+		Key:          info.Key,
+		Size:         info.Size,
+		ETag:         info.ETag,
+		ContentType:  info.ContentType,
+		LastModified: info.LastModified,
+	}
+}
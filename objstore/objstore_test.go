@@ -0,0 +1,156 @@
+package objstore_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/objstore"
+)
+
+// fakeS3 implements subset of S3 API used by objstore for single bucket
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != "reports" {
+		f.error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	if key == "" {
+		if r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2" {
+			f.list(w, r.URL.Query().Get("prefix"))
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+			body = unchunk(body)
+		}
+		f.objects[key] = body
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodGet, http.MethodHead:
+		body, ok := f.objects[key]
+		if !ok {
+			f.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			w.Write(body)
+		}
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// unchunk decodes aws-chunked payload
+func unchunk(body []byte) []byte {
+	var data []byte
+	for {
+		line, rest, _ := bytes.Cut(body, []byte("\r\n"))
+		size, _, _ := bytes.Cut(line, []byte(";"))
+		var n int
+		fmt.Sscanf(string(size), "%x", &n)
+		if n == 0 || len(rest) < n {
+			return data
+		}
+		data, body = append(data, rest[:n]...), rest[n+2:]
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix string) {
+	var contents string
+	for key, body := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			contents += fmt.Sprintf("<Contents><Key>%s</Key><Size>%d</Size><ETag>&quot;etag&quot;</ETag><LastModified>2024-01-01T00:00:00.000Z</LastModified></Contents>", key, len(body))
+		}
+	}
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><ListBucketResult><Name>reports</Name><Prefix>%s</Prefix><IsTruncated>false</IsTruncated>%s</ListBucketResult>`, prefix, contents)
+}
+
+func (f *fakeS3) error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
+
+func newStore(t *testing.T, bucket string) *objstore.Store {
+	srv := httptest.NewServer(&fakeS3{objects: map[string][]byte{}})
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err, "parse url")
+
+	s, err := objstore.New(objstore.Config{
+		Endpoint:  u.Host,
+		Region:    "us-east-1",
+		Bucket:    bucket,
+		AccessKey: "access",
+		SecretKey: "secret",
+		Insecure:  true,
+	})
+	require.NoError(t, err, "new")
+	return s
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	s := newStore(t, "reports")
+	require.NoError(t, s.Start(ctx), "start")
+
+	require.NoError(t, s.Upload(ctx, "2024/report.csv", bytes.NewReader([]byte("a,b")), 3, "text/csv"), "upload")
+	require.NoError(t, s.Upload(ctx, "other.csv", strings.NewReader("c"), 1, "text/csv"), "upload other")
+
+	r, err := s.Download(ctx, "2024/report.csv")
+	require.NoError(t, err, "download")
+	body, err := io.ReadAll(r)
+	require.NoError(t, err, "read")
+	r.Close()
+	assert.Equal(t, "a,b", string(body), "content")
+
+	obj, err := s.Stat(ctx, "2024/report.csv")
+	require.NoError(t, err, "stat")
+	assert.EqualValues(t, 3, obj.Size, "size")
+
+	objects, err := s.List(ctx, "2024/")
+	require.NoError(t, err, "list")
+	require.Len(t, objects, 1, "listed")
+	assert.Equal(t, "2024/report.csv", objects[0].Key, "listed key")
+
+	require.NoError(t, s.Delete(ctx, "2024/report.csv"), "delete")
+	_, err = s.Download(ctx, "2024/report.csv")
+	assert.ErrorIs(t, err, objstore.ErrNotFound, "deleted")
+
+	link, err := s.PresignGet(ctx, "other.csv", time.Hour)
+	require.NoError(t, err, "presign")
+	assert.Contains(t, link, "X-Amz-Signature=", "signed")
+	assert.Contains(t, link, "/reports/other.csv", "object path")
+}
+
+func TestMissingBucket(t *testing.T) {
+	s := newStore(t, "missing")
+	assert.Error(t, s.Start(context.Background()), "bucket does not exist")
+}
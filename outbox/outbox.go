@@ -0,0 +1,249 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/protocol"
+	"github.com/242617/core/retry"
+)
+
+const (
+	MetricPublishedTotal = "outbox_published_total"
+	MetricErrorsTotal    = "outbox_errors_total"
+	MetricRelayDuration  = "outbox_relay_duration_seconds"
+)
+
+// Schema creates table with default name expected by outbox
+const Schema = `CREATE TABLE IF NOT EXISTS outbox (
+	id BIGSERIAL PRIMARY KEY,
+	topic TEXT NOT NULL,
+	key TEXT NOT NULL DEFAULT '',
+	payload BYTEA NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+
+type Message struct {
+	ID        int64     `json:"id"`
+	Topic     string    `json:"topic"`
+	Key       string    `json:"key,omitempty"`
+	Payload   []byte    `json:"payload"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Config is an outbox configuration suitable for config.Scan, zero fields keep defaults in WithConfig
+type Config struct {
+	Table     string        `yaml:"table" default:"outbox"`
+	BatchSize int           `yaml:"batch_size" default:"100"`
+	Interval  time.Duration `yaml:"interval" default:"1s"`
+}
+
+type option = func(o *Outbox) error
+
+func withDefaults() option {
+	return func(o *Outbox) error {
+		o.name = "outbox"
+		o.cfg = Config{Table: "outbox", BatchSize: 100, Interval: time.Second}
+		o.log = l.With().Str("component", "outbox").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(o *Outbox) error {
+		o.name = name
+		return nil
+	}
+}
+
+func WithConfig(cfg Config) option {
+	return func(o *Outbox) error {
+		if cfg.Table != "" {
+			o.cfg.Table = cfg.Table
+		}
+		if cfg.BatchSize > 0 {
+			o.cfg.BatchSize = cfg.BatchSize
+		}
+		if cfg.Interval > 0 {
+			o.cfg.Interval = cfg.Interval
+		}
+		return nil
+	}
+}
+
+// WithRetry sets retry options used for publishing a batch
+func WithRetry(options ...retry.Option) option {
+	return func(o *Outbox) error {
+		o.retry = options
+		return nil
+	}
+}
+
+func WithMetrics(recorder protocol.MetricsRecorder) option {
+	return func(o *Outbox) error {
+		o.metrics = recorder
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(o *Outbox) error {
+		o.log = log
+		return nil
+	}
+}
+
+// New creates outbox storing messages in db and relaying them to publisher between Start and Stop
+func New(db *sql.DB, publisher Publisher, options ...option) (*Outbox, error) {
+	o := Outbox{db: db, publisher: publisher}
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&o); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	return &o, nil
+}
+
+type Outbox struct {
+	name      string
+	db        *sql.DB
+	publisher Publisher
+	cfg       Config
+	retry     []retry.Option
+	metrics   protocol.MetricsRecorder
+	log       zerolog.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Enqueue stores message within tx, it is published once tx is committed
+func (o *Outbox) Enqueue(ctx context.Context, tx *sql.Tx, topic, key string, payload []byte) error {
+	query := fmt.Sprintf("INSERT INTO %s (topic, key, payload) VALUES ($1, $2, $3)", o.cfg.Table)
+	if _, err := tx.ExecContext(ctx, query, topic, key, payload); err != nil {
+		return errors.Wrap(err, "insert")
+	}
+	return nil
+}
+
+func (o *Outbox) Start(context.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	o.cancel, o.done = cancel, make(chan struct{})
+	go func() {
+		defer close(o.done)
+		ticker := time.NewTicker(o.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			// keep relaying while batches are full
+			for {
+				n, err := o.Relay(ctx)
+				if err != nil {
+					if ctx.Err() == nil {
+						o.log.Error().Err(err).Msg("relay")
+					}
+					break
+				}
+				if n < o.cfg.BatchSize {
+					break
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (o *Outbox) Stop(ctx context.Context) error {
+	if o.cancel == nil {
+		return nil
+	}
+	o.cancel()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-o.done:
+	}
+	return nil
+}
+
+func (o *Outbox) String() string { return o.name }
+
+// Relay publishes single batch of pending messages returning number of published ones.
+// Rows are locked with SKIP LOCKED so multiple instances may relay concurrently. Messages are
+// published in order within batch only: concurrent relays take different batches, and a later
+// batch may be published before an earlier one, even for messages with the same key. If order of
+// messages of a key matters, relay in a single instance, e.g. start outbox on leader only.
+func (o *Outbox) Relay(ctx context.Context) (n int, err error) {
+	start := time.Now()
+	defer func() {
+		if o.metrics == nil {
+			return
+		}
+		if err != nil {
+			o.metrics.Add(MetricErrorsTotal, 1)
+		}
+		o.metrics.Add(MetricPublishedTotal, float64(n))
+		o.metrics.Observe(MetricRelayDuration, time.Since(start).Seconds())
+	}()
+
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "begin")
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf("SELECT id, topic, key, payload, created_at FROM %s ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED", o.cfg.Table)
+	rows, err := tx.QueryContext(ctx, query, o.cfg.BatchSize)
+	if err != nil {
+		return 0, errors.Wrap(err, "select")
+	}
+	var messages []Message
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(&m.ID, &m.Topic, &m.Key, &m.Payload, &m.CreatedAt); err != nil {
+			rows.Close()
+			return 0, errors.Wrap(err, "scan")
+		}
+		messages = append(messages, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, errors.Wrap(err, "rows")
+	}
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	if err := retry.Do(ctx, func(ctx context.Context) error {
+		return o.publisher.Publish(ctx, messages)
+	}, o.retry...); err != nil {
+		return 0, errors.Wrap(err, "publish")
+	}
+
+	placeholders := make([]string, len(messages))
+	ids := make([]any, len(messages))
+	for i, m := range messages {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		ids[i] = m.ID
+	}
+	query = fmt.Sprintf("DELETE FROM %s WHERE id IN (%s)", o.cfg.Table, strings.Join(placeholders, ", "))
+	if _, err := tx.ExecContext(ctx, query, ids...); err != nil {
+		return 0, errors.Wrap(err, "delete")
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, errors.Wrap(err, "commit")
+	}
+	return len(messages), nil
+}
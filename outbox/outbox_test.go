@@ -0,0 +1,112 @@
+package outbox_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/config"
+	"github.com/242617/core/outbox"
+	"github.com/242617/core/retry"
+)
+
+var period = 10 * time.Millisecond
+
+func TestEnqueue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "new mock")
+	defer db.Close()
+
+	o, err := outbox.New(db, outbox.PublisherFunc(func(context.Context, []outbox.Message) error { return nil }))
+	require.NoError(t, err, "new")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox (topic, key, payload) VALUES ($1, $2, $3)")).
+		WithArgs("orders", "1", []byte("created")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	tx, err := db.Begin()
+	require.NoError(t, err, "begin")
+	require.NoError(t, o.Enqueue(context.Background(), tx, "orders", "1", []byte("created")), "enqueue")
+	require.NoError(t, tx.Commit(), "commit")
+	assert.NoError(t, mock.ExpectationsWereMet(), "expectations")
+}
+
+func TestRelay(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "new mock")
+	defer db.Close()
+
+	var got []outbox.Message
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got), "decode")
+	}))
+	defer s.Close()
+
+	o, err := outbox.New(db, outbox.Webhook(nil, s.URL), outbox.WithConfig(outbox.Config{BatchSize: 10}))
+	require.NoError(t, err, "new")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, topic, key, payload, created_at FROM outbox ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED")).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "topic", "key", "payload", "created_at"}).
+			AddRow(1, "orders", "1", []byte("created"), now).
+			AddRow(2, "orders", "1", []byte("paid"), now))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM outbox WHERE id IN ($1, $2)")).
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	n, err := o.Relay(context.Background())
+	require.NoError(t, err, "relay")
+	assert.Equal(t, 2, n, "published")
+	assert.Equal(t, []outbox.Message{
+		{ID: 1, Topic: "orders", Key: "1", Payload: []byte("created"), CreatedAt: now},
+		{ID: 2, Topic: "orders", Key: "1", Payload: []byte("paid"), CreatedAt: now},
+	}, got, "webhook batch")
+	assert.NoError(t, mock.ExpectationsWereMet(), "expectations")
+}
+
+func TestRelayFailed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "new mock")
+	defer db.Close()
+
+	var calls int
+	o, err := outbox.New(db,
+		outbox.PublisherFunc(func(context.Context, []outbox.Message) error {
+			calls++
+			return errors.New("sample")
+		}),
+		outbox.WithRetry(retry.WithMaxAttempts(2), retry.WithConstantBackoff(period)),
+	)
+	require.NoError(t, err, "new")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "topic", "key", "payload", "created_at"}).
+			AddRow(1, "orders", "", []byte("created"), time.Now()))
+	mock.ExpectRollback()
+
+	_, err = o.Relay(context.Background())
+	assert.Error(t, err, "publish failed")
+	assert.Equal(t, 2, calls, "retried")
+	assert.NoError(t, mock.ExpectationsWereMet(), "messages kept")
+}
+
+func TestConfigDefaults(t *testing.T) {
+	var cfg outbox.Config
+	require.NoError(t, config.New().Scan(&cfg), "scan")
+	assert.Equal(t, outbox.Config{Table: "outbox", BatchSize: 100, Interval: time.Second}, cfg, "defaults")
+}
@@ -0,0 +1,49 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// Publisher delivers batch of messages, whole batch is retried on error
+type Publisher interface {
+	Publish(ctx context.Context, messages []Message) error
+}
+
+type PublisherFunc func(ctx context.Context, messages []Message) error
+
+func (f PublisherFunc) Publish(ctx context.Context, messages []Message) error {
+	return f(ctx, messages)
+}
+
+// Webhook returns publisher posting batch as JSON array to url, any non-2xx status is an error
+func Webhook(client *http.Client, url string) Publisher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return PublisherFunc(func(ctx context.Context, messages []Message) error {
+		body, err := json.Marshal(messages)
+		if err != nil {
+			return errors.Wrap(err, "marshal")
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return errors.Wrap(err, "new request")
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := client.Do(req)
+		if err != nil {
+			return errors.Wrap(err, "do request")
+		}
+		defer res.Body.Close()
+		if res.StatusCode < 200 || res.StatusCode >= 300 {
+			return errors.Errorf("unexpected status %d", res.StatusCode)
+		}
+		return nil
+	})
+}
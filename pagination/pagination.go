@@ -0,0 +1,147 @@
+package pagination
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strconv"
+
+	"github.com/242617/core/errors"
+)
+
+// Limits bound page size requested by clients
+type Limits struct {
+	Default int `yaml:"default" default:"20"`
+	Max     int `yaml:"max" default:"100"`
+}
+
+var DefaultLimits = Limits{Default: 20, Max: 100}
+
+// Page is a parsed page request, either Cursor or Offset is set
+type Page struct {
+	Limit  int
+	Offset int
+	Cursor string
+}
+
+// Parse reads limit, offset and cursor query parameters
+func Parse(query url.Values, limits Limits) (Page, error) {
+	page := Page{Limit: limits.Default, Cursor: query.Get("cursor")}
+	if s := query.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 1 {
+			return Page{}, errors.New(errors.Invalid, "limit must be positive integer")
+		}
+		page.Limit = limit
+	}
+	if limits.Max > 0 && page.Limit > limits.Max {
+		return Page{}, errors.Newf(errors.Invalid, "limit must not exceed %d", limits.Max)
+	}
+	if s := query.Get("offset"); s != "" {
+		offset, err := strconv.Atoi(s)
+		if err != nil || offset < 0 {
+			return Page{}, errors.New(errors.Invalid, "offset must be non-negative integer")
+		}
+		page.Offset = offset
+	}
+	if page.Cursor != "" && page.Offset > 0 {
+		return Page{}, errors.New(errors.Invalid, "cursor and offset are mutually exclusive")
+	}
+	return page, nil
+}
+
+// Result is a page of items returned to clients
+type Result[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// CursorResult builds result from limit+1 fetched items, cursor of the last returned item is built by key
+func CursorResult[T any](items []T, limit int, key func(T) []any) (Result[T], error) {
+	res := OffsetResult(items, limit)
+	if res.HasMore {
+		cursor, err := EncodeCursor(key(res.Items[len(res.Items)-1])...)
+		if err != nil {
+			return Result[T]{}, err
+		}
+		res.NextCursor = cursor
+	}
+	return res, nil
+}
+
+// OffsetResult builds result from limit+1 fetched items
+func OffsetResult[T any](items []T, limit int) Result[T] {
+	res := Result[T]{Items: items}
+	if len(items) > limit {
+		res.Items, res.HasMore = items[:limit], true
+	}
+	if res.Items == nil {
+		res.Items = []T{}
+	}
+	return res
+}
+
+// EncodeCursor encodes keyset values into opaque string
+func EncodeCursor(values ...any) (string, error) {
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", errors.Wrap(err, errors.Internal, "encode cursor")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeCursor decodes opaque cursor into values pointed by dest
+func DecodeCursor(cursor string, dest ...any) error {
+	values, err := decode(cursor, len(dest))
+	if err != nil {
+		return err
+	}
+	for i, v := range values {
+		if err := json.Unmarshal(v, dest[i]); err != nil {
+			return errors.Wrap(err, errors.Invalid, "invalid cursor")
+		}
+	}
+	return nil
+}
+
+func decode(cursor string, n int) ([]json.RawMessage, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.Invalid, "invalid cursor")
+	}
+	var values []json.RawMessage
+	if err := json.Unmarshal(b, &values); err != nil {
+		return nil, errors.Wrap(err, errors.Invalid, "invalid cursor")
+	}
+	if len(values) != n {
+		return nil, errors.New(errors.Invalid, "invalid cursor")
+	}
+	return values, nil
+}
+
+// decodeArgs decodes cursor into values suitable as query arguments,
+// integers are decoded as int64 and other numbers as float64
+func decodeArgs(cursor string, n int) ([]any, error) {
+	values, err := decode(cursor, n)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]any, n)
+	for i, v := range values {
+		d := json.NewDecoder(bytes.NewReader(v))
+		d.UseNumber()
+		if err := d.Decode(&args[i]); err != nil {
+			return nil, errors.Wrap(err, errors.Invalid, "invalid cursor")
+		}
+		if n, ok := args[i].(json.Number); ok {
+			if args[i], err = n.Int64(); err != nil {
+				if args[i], err = n.Float64(); err != nil {
+					return nil, errors.Wrap(err, errors.Invalid, "invalid cursor")
+				}
+			}
+		}
+	}
+	return args, nil
+}
@@ -0,0 +1,112 @@
+package pagination_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/errors"
+	"github.com/242617/core/pagination"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		want  pagination.Page
+		err   bool
+	}{
+		{"", pagination.Page{Limit: 20}, false},
+		{"limit=50&offset=100", pagination.Page{Limit: 50, Offset: 100}, false},
+		{"limit=10&cursor=abc", pagination.Page{Limit: 10, Cursor: "abc"}, false},
+		{"limit=0", pagination.Page{}, true},
+		{"limit=x", pagination.Page{}, true},
+		{"limit=101", pagination.Page{}, true},
+		{"offset=-1", pagination.Page{}, true},
+		{"offset=1&cursor=abc", pagination.Page{}, true},
+	} {
+		query, err := url.ParseQuery(tc.query)
+		require.NoError(t, err, "parse query")
+		page, err := pagination.Parse(query, pagination.DefaultLimits)
+		if tc.err {
+			assert.True(t, errors.HasCode(err, errors.Invalid), "invalid %q", tc.query)
+			continue
+		}
+		require.NoError(t, err, "parse %q", tc.query)
+		assert.Equal(t, tc.want, page, "page %q", tc.query)
+	}
+}
+
+type item struct {
+	ID        int64
+	CreatedAt time.Time
+}
+
+func TestCursor(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	items := []item{{1, now}, {2, now}, {3, now.Add(time.Second)}}
+	key := func(i item) []any { return []any{i.CreatedAt, i.ID} }
+
+	res, err := pagination.CursorResult(items, 2, key)
+	require.NoError(t, err, "result")
+	assert.Equal(t, items[:2], res.Items, "trimmed")
+	assert.True(t, res.HasMore, "has more")
+
+	var (
+		createdAt time.Time
+		id        int64
+	)
+	require.NoError(t, pagination.DecodeCursor(res.NextCursor, &createdAt, &id), "decode")
+	assert.Equal(t, now, createdAt, "created at")
+	assert.Equal(t, int64(2), id, "id")
+
+	assert.Error(t, pagination.DecodeCursor(res.NextCursor, &id), "values count mismatch")
+	assert.Error(t, pagination.DecodeCursor("!", &id), "malformed")
+
+	res, err = pagination.CursorResult(items[:1], 2, key)
+	require.NoError(t, err, "last page")
+	assert.False(t, res.HasMore, "no more")
+	assert.Empty(t, res.NextCursor, "no cursor")
+
+	assert.Equal(t, []item{}, pagination.OffsetResult[item](nil, 2).Items, "empty items")
+}
+
+func TestKeyset(t *testing.T) {
+	k := pagination.Keyset{Columns: []string{"created_at", "id"}, Desc: true}
+
+	query, args, err := k.Apply("SELECT id FROM users", "tenant = $1", []any{"t1"}, pagination.Page{Limit: 10})
+	require.NoError(t, err, "first page")
+	assert.Equal(t, "SELECT id FROM users WHERE tenant = $1 ORDER BY created_at DESC, id DESC LIMIT $2", query, "query")
+	assert.Equal(t, []any{"t1", 11}, args, "args")
+
+	cursor, err := pagination.EncodeCursor("2024-01-01T00:00:00Z", int64(9007199254740993))
+	require.NoError(t, err, "encode")
+	query, args, err = k.Apply("SELECT id FROM users", "", nil, pagination.Page{Limit: 10, Cursor: cursor})
+	require.NoError(t, err, "next page")
+	assert.Equal(t, "SELECT id FROM users WHERE (created_at, id) < ($1, $2) ORDER BY created_at DESC, id DESC LIMIT $3", query, "query")
+	assert.Equal(t, []any{"2024-01-01T00:00:00Z", int64(9007199254740993), 11}, args, "args keep precision")
+
+	cursor, err = pagination.EncodeCursor(1.5, int64(7))
+	require.NoError(t, err, "encode")
+	query, args, err = k.Apply(
+		"SELECT id FROM (SELECT id, created_at FROM users WHERE active) u", "", nil,
+		pagination.Page{Limit: 10, Cursor: cursor},
+	)
+	require.NoError(t, err, "subquery")
+	assert.Equal(t, "SELECT id FROM (SELECT id, created_at FROM users WHERE active) u WHERE (created_at, id) < ($1, $2) ORDER BY created_at DESC, id DESC LIMIT $3", query, "query")
+	assert.Equal(t, []any{1.5, int64(7), 11}, args, "numeric args")
+
+	query, args, err = k.Apply("SELECT id FROM users", "tenant = $1 OR tenant = $2", []any{"t1", "t2"}, pagination.Page{Limit: 10, Cursor: cursor})
+	require.NoError(t, err, "filtered next page")
+	assert.Equal(t, "SELECT id FROM users WHERE (tenant = $1 OR tenant = $2) AND (created_at, id) < ($3, $4) ORDER BY created_at DESC, id DESC LIMIT $5", query, "query")
+	assert.Equal(t, []any{"t1", "t2", 1.5, int64(7), 11}, args, "filtered args")
+
+	_, _, err = k.Apply("SELECT id FROM users", "", nil, pagination.Page{Limit: 10, Cursor: "invalid"})
+	assert.True(t, errors.HasCode(err, errors.Invalid), "invalid cursor")
+
+	query, args = pagination.ApplyOffset("SELECT id FROM users ORDER BY id", nil, pagination.Page{Limit: 10, Offset: 20})
+	assert.Equal(t, "SELECT id FROM users ORDER BY id LIMIT $1 OFFSET $2", query, "offset query")
+	assert.Equal(t, []any{11, 20}, args, "offset args")
+}
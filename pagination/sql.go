@@ -0,0 +1,63 @@
+package pagination
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Keyset describes ordering used for cursor pagination. Columns must identify row uniquely.
+type Keyset struct {
+	Columns []string
+	Desc    bool
+}
+
+// Apply appends where condition combined with keyset condition, ordering and limit selecting
+// limit+1 rows to postgres query. Query must not contain top-level WHERE, ORDER BY or LIMIT,
+// filter of caller is passed as where instead and may be empty, its placeholders refer to args.
+// Cursor must be built from values of Columns.
+func (k Keyset) Apply(query, where string, args []any, page Page) (string, []any, error) {
+	var b strings.Builder
+	b.WriteString(query)
+	if page.Cursor == "" && where != "" {
+		b.WriteString(" WHERE " + where)
+	}
+	if page.Cursor != "" {
+		values, err := decodeArgs(page.Cursor, len(k.Columns))
+		if err != nil {
+			return "", nil, err
+		}
+		b.WriteString(" WHERE ")
+		if where != "" {
+			b.WriteString("(" + where + ") AND ")
+		}
+		op := ">"
+		if k.Desc {
+			op = "<"
+		}
+		placeholders := make([]string, len(values))
+		for i := range values {
+			placeholders[i] = fmt.Sprintf("$%d", len(args)+i+1)
+		}
+		fmt.Fprintf(&b, "(%s) %s (%s)", strings.Join(k.Columns, ", "), op, strings.Join(placeholders, ", "))
+		args = append(args, values...)
+	}
+	b.WriteString(" ORDER BY ")
+	for i, column := range k.Columns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(column)
+		if k.Desc {
+			b.WriteString(" DESC")
+		}
+	}
+	args = append(args, page.Limit+1)
+	fmt.Fprintf(&b, " LIMIT $%d", len(args))
+	return b.String(), args, nil
+}
+
+// ApplyOffset appends limit selecting limit+1 rows and offset to ordered postgres query
+func ApplyOffset(query string, args []any, page Page) (string, []any) {
+	args = append(args, page.Limit+1, page.Offset)
+	return fmt.Sprintf("%s LIMIT $%d OFFSET $%d", query, len(args)-1, len(args)), args
+}
@@ -0,0 +1,239 @@
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"runtime/debug"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+)
+
+const (
+	TypeCPU       = "cpu"
+	TypeHeap      = "heap"
+	TypeAllocs    = "allocs"
+	TypeGoroutine = "goroutine"
+	TypeMutex     = "mutex"
+	TypeBlock     = "block"
+)
+
+// Profile is a captured profile in pprof format
+type Profile struct {
+	Type       string
+	Start, End time.Time
+	Data       []byte
+}
+
+type option = func(p *Profiler) error
+
+func withDefaults() option {
+	return func(p *Profiler) error {
+		p.name = "profiling"
+		p.interval, p.cpuDuration = time.Minute, 10*time.Second
+		p.types = []string{TypeCPU, TypeHeap}
+		p.enabled.Store(true)
+		p.log = l.With().Str("component", "profiling").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(p *Profiler) error {
+		p.name = name
+		return nil
+	}
+}
+
+// WithInterval sets period between captures
+func WithInterval(interval time.Duration) option {
+	return func(p *Profiler) error {
+		if interval <= 0 {
+			return errors.New("interval must be positive")
+		}
+		p.interval = interval
+		return nil
+	}
+}
+
+// WithCPUDuration sets duration of cpu profile, it must not exceed interval
+func WithCPUDuration(duration time.Duration) option {
+	return func(p *Profiler) error {
+		if duration <= 0 {
+			return errors.New("cpu duration must be positive")
+		}
+		p.cpuDuration = duration
+		return nil
+	}
+}
+
+// WithTypes sets captured profiles, cpu and heap by default
+func WithTypes(types ...string) option {
+	return func(p *Profiler) error {
+		for _, t := range types {
+			if t != TypeCPU && pprof.Lookup(t) == nil {
+				return errors.Errorf("unknown profile %q", t)
+			}
+		}
+		p.types = types
+		return nil
+	}
+}
+
+// WithEnabled sets initial state, profiler is enabled by default
+func WithEnabled(enabled bool) option {
+	return func(p *Profiler) error {
+		p.enabled.Store(enabled)
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(p *Profiler) error {
+		p.log = log
+		return nil
+	}
+}
+
+// New creates component capturing profiles periodically and passing them to sink
+func New(sink Sink, options ...option) (*Profiler, error) {
+	p := Profiler{sink: sink}
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&p); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	if p.cpuDuration > p.interval {
+		return nil, errors.New("cpu duration exceeds interval")
+	}
+	return &p, nil
+}
+
+type Profiler struct {
+	name                  string
+	sink                  Sink
+	interval, cpuDuration time.Duration
+	types                 []string
+	enabled               atomic.Bool
+	log                   zerolog.Logger
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	doneCh chan struct{}
+}
+
+// SetEnabled toggles capturing at runtime, capture in progress is completed
+func (p *Profiler) SetEnabled(enabled bool) {
+	if p.enabled.Swap(enabled) != enabled {
+		p.log.Info().Bool("enabled", enabled).Msg("toggled")
+	}
+}
+
+func (p *Profiler) Enabled() bool { return p.enabled.Load() }
+
+func (p *Profiler) Start(context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cancel != nil {
+		return errors.New("already started")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel, p.doneCh = cancel, make(chan struct{})
+	go p.run(ctx, p.doneCh)
+	return nil
+}
+
+// Stop interrupts capture in progress and waits for it to complete
+func (p *Profiler) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cancel == nil {
+		return nil
+	}
+	p.cancel()
+	select {
+	case <-p.doneCh:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	p.cancel = nil
+	return nil
+}
+
+func (p *Profiler) String() string { return p.name }
+
+func (p *Profiler) run(ctx context.Context, doneCh chan struct{}) {
+	defer close(doneCh)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if p.enabled.Load() {
+			p.capture(ctx)
+		}
+	}
+}
+
+// Capture captures configured profiles once and passes them to sink
+func (p *Profiler) Capture(ctx context.Context) error {
+	var first error
+	for _, t := range p.types {
+		profile, err := p.collect(ctx, t)
+		if err == nil {
+			err = p.write(ctx, profile)
+		}
+		if err != nil && first == nil {
+			first = errors.Wrapf(err, "%s profile", t)
+		}
+	}
+	return first
+}
+
+func (p *Profiler) capture(ctx context.Context) {
+	defer func() {
+		if v := recover(); v != nil {
+			p.log.Error().Interface("panic", v).Bytes("stack", debug.Stack()).Msg("recovered")
+		}
+	}()
+	if err := p.Capture(ctx); err != nil && ctx.Err() == nil {
+		p.log.Error().Err(err).Msg("capture")
+	}
+}
+
+func (p *Profiler) collect(ctx context.Context, t string) (Profile, error) {
+	var buf bytes.Buffer
+	profile := Profile{Type: t, Start: time.Now()}
+	if t == TypeCPU {
+		if err := pprof.StartCPUProfile(&buf); err != nil {
+			return Profile{}, err
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(p.cpuDuration):
+		}
+		pprof.StopCPUProfile()
+	} else if err := pprof.Lookup(t).WriteTo(&buf, 0); err != nil {
+		return Profile{}, err
+	}
+	profile.End, profile.Data = time.Now(), buf.Bytes()
+	return profile, nil
+}
+
+func (p *Profiler) write(ctx context.Context, profile Profile) error {
+	// interrupted cpu profile is still delivered on stop
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), p.interval)
+	defer cancel()
+	return p.sink.Write(ctx, profile)
+}
@@ -0,0 +1,121 @@
+package profiling_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/profiling"
+)
+
+var period = 10 * time.Millisecond
+
+type collector struct {
+	mu    sync.Mutex
+	types []string
+}
+
+func (c *collector) Write(_ context.Context, p profiling.Profile) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(p.Data) > 0 {
+		c.types = append(c.types, p.Type)
+	}
+	return nil
+}
+
+func (c *collector) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.types)
+}
+
+func TestProfiler(t *testing.T) {
+	var c collector
+	p, err := profiling.New(&c,
+		profiling.WithInterval(5*period),
+		profiling.WithCPUDuration(period),
+		profiling.WithTypes(profiling.TypeCPU, profiling.TypeHeap, profiling.TypeGoroutine),
+	)
+	require.NoError(t, err, "new profiler")
+	require.NoError(t, p.Start(context.Background()), "start")
+
+	assert.Eventually(t, func() bool { return c.len() >= 3 }, 50*period, period, "profiles captured")
+	p.SetEnabled(false)
+	assert.False(t, p.Enabled(), "disabled")
+	time.Sleep(6 * period)
+	n := c.len()
+	time.Sleep(10 * period)
+	assert.Equal(t, n, c.len(), "no captures while disabled")
+
+	require.NoError(t, p.Stop(context.Background()), "stop")
+	c.mu.Lock()
+	assert.Equal(t, []string{"cpu", "heap", "goroutine"}, c.types[:3], "types in order")
+	c.mu.Unlock()
+}
+
+func TestOptions(t *testing.T) {
+	sink := profiling.Dir(t.TempDir())
+	_, err := profiling.New(sink, profiling.WithTypes("unknown"))
+	assert.Error(t, err, "unknown type")
+	_, err = profiling.New(sink, profiling.WithInterval(period), profiling.WithCPUDuration(time.Second))
+	assert.Error(t, err, "cpu duration exceeds interval")
+}
+
+func TestSinks(t *testing.T) {
+	dir := t.TempDir()
+	p, err := profiling.New(profiling.Dir(dir), profiling.WithTypes(profiling.TypeHeap))
+	require.NoError(t, err, "new profiler")
+	require.NoError(t, p.Capture(context.Background()), "capture to dir")
+	files, err := filepath.Glob(filepath.Join(dir, "heap-*.pb.gz"))
+	require.NoError(t, err, "glob")
+	require.Len(t, files, 1, "file written")
+	info, err := os.Stat(files[0])
+	require.NoError(t, err, "stat")
+	assert.NotZero(t, info.Size(), "not empty")
+
+	var (
+		query url.Values
+		size  int
+		auth  string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/ingest", r.URL.Path, "path")
+		query = r.URL.Query()
+		auth = r.Header.Get("Authorization")
+		file, _, err := r.FormFile("profile")
+		if assert.NoError(t, err, "profile part") {
+			b, _ := io.ReadAll(file)
+			size = len(b)
+		}
+	}))
+	defer srv.Close()
+
+	sink, err := profiling.Pyroscope(profiling.PyroscopeConfig{
+		URL:       srv.URL,
+		AppName:   "svc",
+		Tags:      map[string]string{"env": "test", "region": "eu"},
+		BasicAuth: "user:secret",
+	})
+	require.NoError(t, err, "pyroscope sink")
+	p, err = profiling.New(sink, profiling.WithTypes(profiling.TypeGoroutine))
+	require.NoError(t, err, "new profiler")
+	require.NoError(t, p.Capture(context.Background()), "push")
+	assert.Equal(t, "svc{env=test,region=eu}", query.Get("name"), "name with tags")
+	assert.Equal(t, "pprof", query.Get("format"), "format")
+	assert.NotZero(t, size, "profile pushed")
+	assert.NotEmpty(t, auth, "basic auth")
+
+	_, err = profiling.Pyroscope(profiling.PyroscopeConfig{URL: srv.URL})
+	assert.Error(t, err, "app name required")
+}
@@ -0,0 +1,134 @@
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Sink stores or ships captured profiles
+type Sink interface {
+	Write(ctx context.Context, profile Profile) error
+}
+
+type SinkFunc func(ctx context.Context, profile Profile) error
+
+func (f SinkFunc) Write(ctx context.Context, profile Profile) error { return f(ctx, profile) }
+
+func filename(profile Profile) string {
+	return fmt.Sprintf("%s-%s.pb.gz", profile.Type, profile.Start.UTC().Format("20060102T150405.000Z"))
+}
+
+// Dir writes profiles to files in dir
+func Dir(dir string) Sink {
+	return SinkFunc(func(_ context.Context, profile Profile) error {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return errors.Wrap(err, "create dir")
+		}
+		return os.WriteFile(filepath.Join(dir, filename(profile)), profile.Data, 0o644)
+	})
+}
+
+// Uploader is implemented by objstore.Store
+type Uploader interface {
+	Upload(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+}
+
+// Objects uploads profiles to object storage under prefix
+func Objects(store Uploader, prefix string) Sink {
+	return SinkFunc(func(ctx context.Context, profile Profile) error {
+		key := path.Join(prefix, filename(profile))
+		return store.Upload(ctx, key, bytes.NewReader(profile.Data), int64(len(profile.Data)), "application/octet-stream")
+	})
+}
+
+// PyroscopeConfig configures pushing to Pyroscope ingest API
+type PyroscopeConfig struct {
+	URL       string            `yaml:"url" env:"PYROSCOPE_URL"`
+	AppName   string            `yaml:"app_name" env:"PYROSCOPE_APP_NAME"`
+	Tags      map[string]string `yaml:"tags"`
+	BasicAuth string            `yaml:"basic_auth" env:"PYROSCOPE_BASIC_AUTH"`
+	TenantID  string            `yaml:"tenant_id" env:"PYROSCOPE_TENANT_ID"`
+	Timeout   time.Duration     `yaml:"timeout" default:"10s"`
+}
+
+// Pyroscope pushes profiles to Pyroscope server
+func Pyroscope(cfg PyroscopeConfig) (Sink, error) {
+	if cfg.URL == "" || cfg.AppName == "" {
+		return nil, errors.New("url and app name are required")
+	}
+	endpoint, err := url.JoinPath(cfg.URL, "ingest")
+	if err != nil {
+		return nil, errors.Wrap(err, "parse url")
+	}
+	client := &http.Client{Timeout: cfg.Timeout}
+
+	name := cfg.AppName
+	if len(cfg.Tags) > 0 {
+		tags := make([]string, 0, len(cfg.Tags))
+		for k, v := range cfg.Tags {
+			tags = append(tags, k+"="+v)
+		}
+		sort.Strings(tags)
+		name += "{" + strings.Join(tags, ",") + "}"
+	}
+
+	return SinkFunc(func(ctx context.Context, profile Profile) error {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		part, err := w.CreateFormFile("profile", "profile.pprof")
+		if err != nil {
+			return errors.Wrap(err, "create form")
+		}
+		if _, err := part.Write(profile.Data); err != nil {
+			return errors.Wrap(err, "write form")
+		}
+		if err := w.Close(); err != nil {
+			return errors.Wrap(err, "close form")
+		}
+
+		query := url.Values{}
+		query.Set("name", name)
+		query.Set("from", strconv.FormatInt(profile.Start.Unix(), 10))
+		query.Set("until", strconv.FormatInt(profile.End.Unix(), 10))
+		query.Set("spyName", "gospy")
+		query.Set("format", "pprof")
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"?"+query.Encode(), &body)
+		if err != nil {
+			return errors.Wrap(err, "new request")
+		}
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		if cfg.BasicAuth != "" {
+			user, password, _ := strings.Cut(cfg.BasicAuth, ":")
+			req.SetBasicAuth(user, password)
+		}
+		if cfg.TenantID != "" {
+			req.Header.Set("X-Scope-OrgID", cfg.TenantID)
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			return errors.Wrap(err, "push")
+		}
+		defer res.Body.Close()
+		_, _ = io.Copy(io.Discard, res.Body)
+		if res.StatusCode >= http.StatusBadRequest {
+			return errors.Errorf("push: unexpected status %d", res.StatusCode)
+		}
+		return nil
+	}), nil
+}
@@ -6,3 +6,29 @@ type Lifecycle interface {
 	Start(context.Context) error
 	Stop(context.Context) error
 }
+
+// MetricsRecorder records metrics, labels are passed as key-value pairs
+type MetricsRecorder interface {
+	// Add increases counter by delta
+	Add(name string, delta float64, labels ...string)
+	// Set sets gauge value
+	Set(name string, value float64, labels ...string)
+	// Observe adds value to histogram
+	Observe(name string, value float64, labels ...string)
+}
+
+// HealthChecker reports whether component is able to serve
+type HealthChecker interface {
+	HealthCheck(context.Context) error
+}
+
+// Tracer starts spans, attributes are passed as key-value pairs
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...string) (context.Context, Span)
+}
+
+type Span interface {
+	SetAttributes(attrs ...string)
+	RecordError(error)
+	End()
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NewKeyed creates limiter keeping separate limiter per key,
+// limiters unused for ttl are removed
+func NewKeyed(factory func() Limiter, ttl time.Duration) *Keyed {
+	return &Keyed{factory: factory, ttl: ttl, limiters: map[string]*entry{}, swept: time.Now()}
+}
+
+type Keyed struct {
+	factory func() Limiter
+	ttl     time.Duration
+
+	mu       sync.Mutex
+	limiters map[string]*entry
+	swept    time.Time
+}
+
+type entry struct {
+	limiter Limiter
+	used    time.Time
+}
+
+func (k *Keyed) Allow(key string) bool { return k.get(key).Allow() }
+
+func (k *Keyed) Wait(ctx context.Context, key string) error { return k.get(key).Wait(ctx) }
+
+// Len returns number of tracked keys
+func (k *Keyed) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.limiters)
+}
+
+func (k *Keyed) get(key string) Limiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(k.swept) >= k.ttl {
+		for key, e := range k.limiters {
+			if now.Sub(e.used) >= k.ttl {
+				delete(k.limiters, key)
+			}
+		}
+		k.swept = now
+	}
+
+	e, ok := k.limiters[key]
+	if !ok {
+		e = &entry{limiter: k.factory()}
+		k.limiters[key] = e
+	}
+	e.used = now
+	return e.limiter
+}
@@ -0,0 +1,32 @@
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/242617/core/httpserver/middleware"
+)
+
+type KeyFunc = func(*http.Request) string
+
+// KeyByIP returns remote IP address of request
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Middleware responds with too many requests when limit for request key is exceeded
+func Middleware(limiter *Keyed, key KeyFunc) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !limiter.Allow(key(r)) {
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type Limiter interface {
+	// Allow reports whether event may happen now, consuming the permit if so
+	Allow() bool
+	// Wait blocks until event may happen or ctx is done
+	Wait(ctx context.Context) error
+}
+
+// NewTokenBucket creates limiter refilled with rate tokens per second up to burst tokens
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+type TokenBucket struct {
+	mu                  sync.Mutex
+	rate, burst, tokens float64
+	last                time.Time
+}
+
+func (b *TokenBucket) Allow() bool { return b.reserve() == 0 }
+
+func (b *TokenBucket) Wait(ctx context.Context) error { return wait(ctx, b.reserve) }
+
+// reserve takes token if available, otherwise returns time until it becomes available
+func (b *TokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	if b.rate <= 0 {
+		return time.Hour
+	}
+	// wait is rounded up, so short bucket is never reported as allowed
+	if d := time.Duration(math.Ceil((1 - b.tokens) / b.rate * float64(time.Second))); d > 0 {
+		return d
+	}
+	return time.Nanosecond
+}
+
+// NewSlidingWindow creates limiter allowing at most limit events within any window
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{window: window, events: make([]time.Time, limit)}
+}
+
+type SlidingWindow struct {
+	mu     sync.Mutex
+	window time.Duration
+	events []time.Time
+	next   int
+}
+
+func (w *SlidingWindow) Allow() bool { return w.reserve() == 0 }
+
+func (w *SlidingWindow) Wait(ctx context.Context) error { return wait(ctx, w.reserve) }
+
+func (w *SlidingWindow) reserve() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.events) == 0 {
+		return w.window
+	}
+	now := time.Now()
+	oldest := w.events[w.next]
+	if d := oldest.Add(w.window).Sub(now); !oldest.IsZero() && d > 0 {
+		return d
+	}
+	w.events[w.next] = now
+	w.next = (w.next + 1) % len(w.events)
+	return 0
+}
+
+func wait(ctx context.Context, reserve func() time.Duration) error {
+	for {
+		d := reserve()
+		if d == 0 {
+			return nil
+		}
+		timer := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
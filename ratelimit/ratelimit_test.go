@@ -0,0 +1,81 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/ratelimit"
+)
+
+var period = 10 * time.Millisecond
+
+func TestTokenBucket(t *testing.T) {
+	b := ratelimit.NewTokenBucket(float64(time.Second/period), 2)
+	assert.True(t, b.Allow(), "first token")
+	assert.True(t, b.Allow(), "second token")
+	assert.False(t, b.Allow(), "bucket empty")
+
+	start := time.Now()
+	require.NoError(t, b.Wait(context.Background()), "wait")
+	assert.GreaterOrEqual(t, time.Since(start), period/2, "waited for refill")
+
+	ctx, cancel := context.WithTimeout(context.Background(), period/10)
+	defer cancel()
+	assert.ErrorIs(t, b.Wait(ctx), context.DeadlineExceeded, "wait cancelled")
+}
+
+func TestTokenBucketShortWait(t *testing.T) {
+	// missing token is refilled in less than nanosecond, but bucket never holds one
+	b := ratelimit.NewTokenBucket(1e12, 0)
+	assert.False(t, b.Allow(), "bucket empty")
+
+	ctx, cancel := context.WithTimeout(context.Background(), period)
+	defer cancel()
+	assert.ErrorIs(t, b.Wait(ctx), context.DeadlineExceeded, "wait cancelled")
+}
+
+func TestSlidingWindow(t *testing.T) {
+	w := ratelimit.NewSlidingWindow(3, 5*period)
+	for i := 0; i < 3; i++ {
+		assert.True(t, w.Allow(), "within limit")
+	}
+	assert.False(t, w.Allow(), "limit reached")
+
+	start := time.Now()
+	require.NoError(t, w.Wait(context.Background()), "wait")
+	assert.GreaterOrEqual(t, time.Since(start), 4*period, "waited for window")
+}
+
+func TestKeyed(t *testing.T) {
+	k := ratelimit.NewKeyed(func() ratelimit.Limiter { return ratelimit.NewSlidingWindow(1, time.Minute) }, 2*period)
+	assert.True(t, k.Allow("one"), "first key")
+	assert.False(t, k.Allow("one"), "first key limited")
+	assert.True(t, k.Allow("two"), "second key")
+	assert.Equal(t, 2, k.Len(), "two keys")
+
+	time.Sleep(3 * period)
+	assert.True(t, k.Allow("three"), "third key")
+	assert.Equal(t, 1, k.Len(), "expired keys removed")
+}
+
+func TestMiddleware(t *testing.T) {
+	k := ratelimit.NewKeyed(func() ratelimit.Limiter { return ratelimit.NewTokenBucket(0, 1) }, time.Minute)
+	h := ratelimit.Middleware(k, ratelimit.KeyByIP)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	serve := func(addr string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, serve("10.0.0.1:1000"), "first request")
+	assert.Equal(t, http.StatusTooManyRequests, serve("10.0.0.1:2000"), "same ip limited")
+	assert.Equal(t, http.StatusOK, serve("10.0.0.2:1000"), "another ip")
+}
@@ -0,0 +1,58 @@
+package redisrepo
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+
+	"github.com/242617/core/requestid"
+)
+
+// hook logs every command with its duration, commands are logged on debug level unless failed
+type hook struct{ log zerolog.Logger }
+
+func (h *hook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			h.log.Error().Err(err).Str("addr", addr).Msg("dial")
+		}
+		return conn, err
+	}
+}
+
+func (h *hook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.event(err).
+			Str("request_id", requestid.FromContext(ctx)).
+			Str("command", cmd.FullName()).
+			Dur("duration", time.Since(start)).
+			Msg("command")
+		return err
+	}
+}
+
+func (h *hook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		h.event(err).
+			Str("request_id", requestid.FromContext(ctx)).
+			Int("commands", len(cmds)).
+			Dur("duration", time.Since(start)).
+			Msg("pipeline")
+		return err
+	}
+}
+
+func (h *hook) event(err error) *zerolog.Event {
+	if err != nil && err != redis.Nil {
+		return h.log.Error().Err(err)
+	}
+	return h.log.Debug()
+}
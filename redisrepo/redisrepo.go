@@ -0,0 +1,205 @@
+package redisrepo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/protocol"
+)
+
+const (
+	ModeSingle   = "single"
+	ModeSentinel = "sentinel"
+	ModeCluster  = "cluster"
+)
+
+// Config is a redis configuration suitable for config.Scan
+type Config struct {
+	Mode         string        `yaml:"mode" env:"REDIS_MODE" default:"single"`
+	Addrs        []string      `yaml:"addrs"`
+	MasterName   string        `yaml:"master_name" env:"REDIS_MASTER_NAME"`
+	Username     string        `yaml:"username" env:"REDIS_USERNAME"`
+	Password     string        `yaml:"password" env:"REDIS_PASSWORD"`
+	DB           int           `yaml:"db" env:"REDIS_DB"`
+	PoolSize     int           `yaml:"pool_size"`
+	DialTimeout  time.Duration `yaml:"dial_timeout" default:"5s"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" default:"3s"`
+	WriteTimeout time.Duration `yaml:"write_timeout" default:"3s"`
+}
+
+type option = func(r *Repo) error
+
+func withDefaults() option {
+	return func(r *Repo) error {
+		r.name = "redisrepo"
+		r.metricsInterval = 10 * time.Second
+		r.log = l.With().Str("component", "redisrepo").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(r *Repo) error {
+		r.name = name
+		return nil
+	}
+}
+
+// WithMetrics enables periodic recording of connection pool stats
+func WithMetrics(recorder protocol.MetricsRecorder, interval time.Duration) option {
+	return func(r *Repo) error {
+		if interval <= 0 {
+			return errors.New("interval must be positive")
+		}
+		r.metrics, r.metricsInterval = recorder, interval
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(r *Repo) error {
+		r.log = log
+		return nil
+	}
+}
+
+// New creates redis component. Client is created immediately, connection is checked on Start.
+func New(cfg Config, options ...option) (*Repo, error) {
+	r := Repo{cfg: cfg}
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&r); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "new client")
+	}
+	client.AddHook(&hook{r.log})
+	r.UniversalClient = client
+
+	return &r, nil
+}
+
+type Repo struct {
+	redis.UniversalClient
+
+	name            string
+	cfg             Config
+	metrics         protocol.MetricsRecorder
+	metricsInterval time.Duration
+	log             zerolog.Logger
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+}
+
+func newClient(cfg Config) (redis.UniversalClient, error) {
+	if len(cfg.Addrs) == 0 {
+		return nil, errors.New("empty addrs")
+	}
+	switch cfg.Mode {
+	case ModeSingle, "":
+		return redis.NewClient(&redis.Options{
+			Addr:         cfg.Addrs[0],
+			Username:     cfg.Username,
+			Password:     cfg.Password,
+			DB:           cfg.DB,
+			PoolSize:     cfg.PoolSize,
+			DialTimeout:  cfg.DialTimeout,
+			ReadTimeout:  cfg.ReadTimeout,
+			WriteTimeout: cfg.WriteTimeout,
+		}), nil
+	case ModeSentinel:
+		if cfg.MasterName == "" {
+			return nil, errors.New("empty master name")
+		}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    cfg.MasterName,
+			SentinelAddrs: cfg.Addrs,
+			Username:      cfg.Username,
+			Password:      cfg.Password,
+			DB:            cfg.DB,
+			PoolSize:      cfg.PoolSize,
+			DialTimeout:   cfg.DialTimeout,
+			ReadTimeout:   cfg.ReadTimeout,
+			WriteTimeout:  cfg.WriteTimeout,
+		}), nil
+	case ModeCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        cfg.Addrs,
+			Username:     cfg.Username,
+			Password:     cfg.Password,
+			PoolSize:     cfg.PoolSize,
+			DialTimeout:  cfg.DialTimeout,
+			ReadTimeout:  cfg.ReadTimeout,
+			WriteTimeout: cfg.WriteTimeout,
+		}), nil
+	default:
+		return nil, errors.Errorf("unknown mode %q", cfg.Mode)
+	}
+}
+
+func (r *Repo) Start(ctx context.Context) error {
+	if err := r.HealthCheck(ctx); err != nil {
+		return err
+	}
+
+	if r.metrics != nil {
+		r.stopCh = make(chan struct{})
+		r.wg.Add(1)
+		go r.recordPoolStats()
+	}
+
+	r.log.Info().Msgf("connected to %s redis %v", r.cfg.Mode, r.cfg.Addrs)
+	return nil
+}
+
+func (r *Repo) Stop(context.Context) error {
+	if r.stopCh != nil {
+		close(r.stopCh)
+		r.wg.Wait()
+		r.stopCh = nil
+	}
+	if err := r.UniversalClient.Close(); err != nil {
+		return errors.Wrap(err, "close")
+	}
+	return nil
+}
+
+func (r *Repo) HealthCheck(ctx context.Context) error {
+	if err := r.UniversalClient.Ping(ctx).Err(); err != nil {
+		return errors.Wrap(err, "ping")
+	}
+	return nil
+}
+
+func (r *Repo) String() string { return r.name }
+
+func (r *Repo) recordPoolStats() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.metricsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+			stats := r.UniversalClient.PoolStats()
+			r.metrics.Set("redis_pool_hits", float64(stats.Hits), "name", r.name)
+			r.metrics.Set("redis_pool_misses", float64(stats.Misses), "name", r.name)
+			r.metrics.Set("redis_pool_timeouts", float64(stats.Timeouts), "name", r.name)
+			r.metrics.Set("redis_pool_total_conns", float64(stats.TotalConns), "name", r.name)
+			r.metrics.Set("redis_pool_idle_conns", float64(stats.IdleConns), "name", r.name)
+			r.metrics.Set("redis_pool_stale_conns", float64(stats.StaleConns), "name", r.name)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package redisrepo_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/config"
+	"github.com/242617/core/protocol"
+	"github.com/242617/core/redisrepo"
+)
+
+var _ protocol.HealthChecker = (*redisrepo.Repo)(nil)
+
+func TestBasic(t *testing.T) {
+	srv := miniredis.RunT(t)
+
+	var buf bytes.Buffer
+	r, err := redisrepo.New(
+		redisrepo.Config{Addrs: []string{srv.Addr()}},
+		redisrepo.WithLogger(zerolog.New(&buf)),
+	)
+	require.NoError(t, err, "new repo")
+	require.NoError(t, r.Start(context.Background()), "start repo")
+
+	require.NoError(t, r.Set(context.Background(), "key", "value", time.Minute).Err(), "set")
+	value, err := r.Get(context.Background(), "key").Result()
+	require.NoError(t, err, "get")
+	assert.Equal(t, "value", value, "unexpected value")
+	assert.Contains(t, buf.String(), `"command":"set"`, "command logged")
+
+	srv.Close()
+	assert.Error(t, r.HealthCheck(context.Background()), "server closed")
+	require.NoError(t, r.Stop(context.Background()), "stop repo")
+}
+
+func TestConfig(t *testing.T) {
+	var cfg redisrepo.Config
+	require.NoError(t, config.New().Scan(&cfg), "scan config")
+	assert.Equal(t, redisrepo.ModeSingle, cfg.Mode, "default mode")
+
+	_, err := redisrepo.New(cfg)
+	assert.Error(t, err, "empty addrs")
+
+	_, err = redisrepo.New(redisrepo.Config{Mode: redisrepo.ModeSentinel, Addrs: []string{"localhost:26379"}})
+	assert.Error(t, err, "empty master name")
+
+	_, err = redisrepo.New(redisrepo.Config{Mode: "unknown", Addrs: []string{"localhost:6379"}})
+	assert.Error(t, err, "unknown mode")
+
+	_, err = redisrepo.New(redisrepo.Config{Mode: redisrepo.ModeCluster, Addrs: []string{"localhost:7000", "localhost:7001"}})
+	assert.NoError(t, err, "cluster mode")
+}
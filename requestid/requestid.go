@@ -0,0 +1,25 @@
+package requestid
+
+import (
+	"context"
+
+	"github.com/242617/core/id"
+)
+
+// Header is a header used to pass request id between services
+const Header = "X-Request-Id"
+
+type ctxKey struct{}
+
+// New generates time-ordered request id (UUIDv7)
+func New() string { return id.NewUUID().String() }
+
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns request id stored in context or empty string
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
@@ -0,0 +1,160 @@
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type Option = func(c *config) error
+
+type config struct {
+	attempts   int
+	min, max   time.Duration
+	jitter     float64
+	retryIf    func(error) bool
+	onRetry    func(attempt int, err error)
+	multiplier float64
+}
+
+func withDefaults() Option {
+	return func(c *config) error {
+		c.attempts = 3
+		c.min, c.max, c.multiplier = 100*time.Millisecond, 2*time.Second, 2
+		c.jitter = 0.5
+		c.retryIf = func(error) bool { return true }
+		return nil
+	}
+}
+
+// WithMaxAttempts sets total number of attempts including the first one
+func WithMaxAttempts(attempts int) Option {
+	return func(c *config) error {
+		if attempts < 1 {
+			return errors.New("attempts must be positive")
+		}
+		c.attempts = attempts
+		return nil
+	}
+}
+
+// WithExponentialBackoff sets delay doubling from min up to max between attempts
+func WithExponentialBackoff(min, max time.Duration) Option {
+	return func(c *config) error {
+		if min <= 0 || max < min {
+			return errors.New("invalid backoff bounds")
+		}
+		c.min, c.max, c.multiplier = min, max, 2
+		return nil
+	}
+}
+
+// WithConstantBackoff sets fixed delay between attempts
+func WithConstantBackoff(d time.Duration) Option {
+	return func(c *config) error {
+		if d < 0 {
+			return errors.New("delay must not be negative")
+		}
+		c.min, c.max, c.multiplier = d, d, 1
+		return nil
+	}
+}
+
+// WithJitter sets fraction of delay randomized, e.g. 0.5 waits between half and full delay
+func WithJitter(fraction float64) Option {
+	return func(c *config) error {
+		if fraction < 0 || fraction > 1 {
+			return errors.New("jitter must be within [0, 1]")
+		}
+		c.jitter = fraction
+		return nil
+	}
+}
+
+// RetryIf sets predicate deciding whether error is worth retrying
+func RetryIf(f func(error) bool) Option {
+	return func(c *config) error {
+		c.retryIf = f
+		return nil
+	}
+}
+
+// WithOnRetry sets callback called before waiting for next attempt
+func WithOnRetry(f func(attempt int, err error)) Option {
+	return func(c *config) error {
+		c.onRetry = f
+		return nil
+	}
+}
+
+// Do calls fn until it succeeds, returns permanent or not retryable error,
+// attempts are exhausted or ctx is done. The last error of fn is returned.
+func Do(ctx context.Context, fn func(ctx context.Context) error, options ...Option) error {
+	var c config
+	options = append([]Option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&c); err != nil {
+			return errors.Wrap(err, "apply option")
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if attempt >= c.attempts || ctx.Err() != nil || !c.retryIf(err) {
+			return err
+		}
+
+		if c.onRetry != nil {
+			c.onRetry(attempt, err)
+		}
+		timer := time.NewTimer(c.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Permanent wraps error to stop retrying, Do returns err itself
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// IsPermanent reports whether err is wrapped by Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func (c *config) delay(attempt int) time.Duration {
+	d := float64(c.min)
+	for i := 1; i < attempt && d < float64(c.max); i++ {
+		d *= c.multiplier
+	}
+	if d > float64(c.max) {
+		d = float64(c.max)
+	}
+	if c.jitter > 0 {
+		d -= d * c.jitter * rand.Float64()
+	}
+	return time.Duration(d)
+}
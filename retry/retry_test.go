@@ -0,0 +1,92 @@
+package retry_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/retry"
+)
+
+var period = 10 * time.Millisecond
+
+var errSample = errors.New("sample")
+
+func TestDo(t *testing.T) {
+	var calls int
+	err := retry.Do(context.Background(), func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errSample
+		}
+		return nil
+	}, retry.WithConstantBackoff(period/10))
+	require.NoError(t, err, "eventually succeeded")
+	assert.Equal(t, 3, calls, "calls")
+
+	calls = 0
+	err = retry.Do(context.Background(), func(context.Context) error {
+		calls++
+		return errSample
+	}, retry.WithMaxAttempts(4), retry.WithConstantBackoff(0))
+	assert.ErrorIs(t, err, errSample, "last error")
+	assert.Equal(t, 4, calls, "attempts exhausted")
+}
+
+func TestStop(t *testing.T) {
+	var calls int
+	err := retry.Do(context.Background(), func(context.Context) error {
+		calls++
+		return retry.Permanent(errSample)
+	})
+	assert.Equal(t, errSample, err, "permanent error unwrapped")
+	assert.Equal(t, 1, calls, "permanent not retried")
+
+	calls = 0
+	err = retry.Do(context.Background(), func(context.Context) error {
+		calls++
+		return errSample
+	}, retry.RetryIf(func(err error) bool { return !errors.Is(err, errSample) }))
+	assert.ErrorIs(t, err, errSample, "not retryable")
+	assert.Equal(t, 1, calls, "not retryable not retried")
+
+	ctx, cancel := context.WithTimeout(context.Background(), period)
+	defer cancel()
+	start := time.Now()
+	err = retry.Do(ctx, func(context.Context) error { return errSample }, retry.WithConstantBackoff(time.Minute))
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "context done")
+	assert.Less(t, time.Since(start), 5*period, "wait interrupted")
+}
+
+func TestBackoff(t *testing.T) {
+	var (
+		last     time.Time
+		delays   []time.Duration
+		attempts []int
+	)
+	err := retry.Do(context.Background(), func(context.Context) error {
+		if !last.IsZero() {
+			delays = append(delays, time.Since(last))
+		}
+		last = time.Now()
+		return errSample
+	},
+		retry.WithMaxAttempts(4),
+		retry.WithExponentialBackoff(period, 3*period),
+		retry.WithJitter(0),
+		retry.WithOnRetry(func(attempt int, _ error) { attempts = append(attempts, attempt) }),
+	)
+	assert.ErrorIs(t, err, errSample, "exhausted")
+	assert.Equal(t, []int{1, 2, 3}, attempts, "retries")
+	require.Len(t, delays, 3, "delays")
+	for i, min := range []time.Duration{period, 2 * period, 3 * period} {
+		assert.GreaterOrEqual(t, delays[i], min, "delay %d", i)
+	}
+
+	err = retry.Do(context.Background(), nil, retry.WithJitter(2))
+	assert.Error(t, err, "invalid option")
+}
@@ -0,0 +1,329 @@
+package saga
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/pipeline"
+	"github.com/242617/core/retry"
+)
+
+type Status string
+
+const (
+	StatusRunning      Status = "running"
+	StatusCompleted    Status = "completed"
+	StatusCompensating Status = "compensating"
+	StatusCompensated  Status = "compensated"
+	// StatusFailed means compensation failed and saga requires manual intervention
+	StatusFailed Status = "failed"
+)
+
+var ErrFinished = errors.New("saga is finished")
+
+type (
+	ActionFunc     = func(context.Context) error
+	CompensateFunc = func(context.Context) error
+)
+
+// Step is an action with compensation undoing it, compensation may be nil for steps nothing to undo
+type Step struct {
+	Name       string
+	Action     ActionFunc
+	Compensate CompensateFunc
+}
+
+// State is a persisted progress of saga execution
+type State struct {
+	ID        string    `json:"id"`
+	Saga      string    `json:"saga"`
+	Status    Status    `json:"status"`
+	Completed []string  `json:"completed"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Store persists state after every step making execution resumable,
+// Load returns nil state for unknown id
+type Store interface {
+	Load(ctx context.Context, id string) (*State, error)
+	Save(ctx context.Context, state *State) error
+}
+
+// CompensationError is returned when some compensations failed after step failure
+type CompensationError struct {
+	Err           error
+	Compensations map[string]error
+}
+
+func (e *CompensationError) Error() string {
+	return fmt.Sprintf("%s, %d compensations failed", e.Err, len(e.Compensations))
+}
+
+func (e *CompensationError) Unwrap() error { return e.Err }
+
+type option = func(s *Saga) error
+
+func withDefaults() option {
+	return func(s *Saga) error {
+		s.store = NewMemoryStore()
+		s.retry = []retry.Option{retry.WithMaxAttempts(3)}
+		s.log = l.With().Str("component", "saga").Logger()
+		return nil
+	}
+}
+
+// WithStore sets state store, in-memory one is used by default
+func WithStore(store Store) option {
+	return func(s *Saga) error {
+		s.store = store
+		return nil
+	}
+}
+
+// WithCompensationRetry sets retry options of compensations
+func WithCompensationRetry(options ...retry.Option) option {
+	return func(s *Saga) error {
+		s.retry = options
+		return nil
+	}
+}
+
+func WithStep(step Step) option {
+	return func(s *Saga) error { return s.add(step) }
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(s *Saga) error {
+		s.log = log
+		return nil
+	}
+}
+
+// New creates saga executing steps in order on top of pipeline.
+// When step fails, compensations of completed steps are executed in reverse order.
+//
+// Example:
+//
+//	s, err := saga.New("order",
+//		saga.WithStep(saga.Step{Name: "reserve", Action: reserve, Compensate: release}),
+//		saga.WithStep(saga.Step{Name: "charge", Action: charge, Compensate: refund}),
+//		saga.WithStep(saga.Step{Name: "notify", Action: notify}),
+//	)
+//	err = s.Execute(ctx, orderID)
+func New(name string, options ...option) (*Saga, error) {
+	if name == "" {
+		return nil, errors.New("empty name")
+	}
+	s := Saga{name: name}
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&s); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	if len(s.steps) == 0 {
+		return nil, errors.New("no steps")
+	}
+	s.log = s.log.With().Str("saga", name).Logger()
+	return &s, nil
+}
+
+type Saga struct {
+	name  string
+	steps []Step
+	store Store
+	retry []retry.Option
+	log   zerolog.Logger
+}
+
+func (s *Saga) add(step Step) error {
+	if step.Name == "" || step.Action == nil {
+		return errors.New("step must have name and action")
+	}
+	for _, existing := range s.steps {
+		if existing.Name == step.Name {
+			return errors.Errorf("step %q already added", step.Name)
+		}
+	}
+	s.steps = append(s.steps, step)
+	return nil
+}
+
+// Execute runs saga identified by id unique within store. Execution interrupted before is resumed:
+// completed steps are skipped, interrupted compensation is continued.
+// Finished saga returns ErrFinished.
+func (s *Saga) Execute(ctx context.Context, id string) error {
+	state, err := s.store.Load(ctx, id)
+	if err != nil {
+		return errors.Wrap(err, "load state")
+	}
+	if state == nil {
+		state = &State{ID: id, Saga: s.name, Status: StatusRunning}
+	}
+
+	switch state.Status {
+	case StatusRunning:
+	case StatusCompensating:
+		return s.compensate(ctx, state, errors.New(state.Error))
+	default:
+		return ErrFinished
+	}
+
+	completed := map[string]bool{}
+	for _, name := range state.Completed {
+		completed[name] = true
+	}
+
+	// pipeline returns once ctx is done while step may still run, so step records its completion
+	// itself and compensation waits for it, steps are not started after that
+	var (
+		mu      sync.Mutex
+		aborted bool
+		running chan struct{}
+	)
+	p := pipeline.New(ctx)
+	for _, step := range s.steps {
+		if completed[step.Name] {
+			continue
+		}
+		step := step
+		p = p.Then(func(stepCtx context.Context) error {
+			mu.Lock()
+			if aborted {
+				mu.Unlock()
+				return errors.New("saga aborted")
+			}
+			done := make(chan struct{})
+			running = done
+			mu.Unlock()
+			defer close(done)
+
+			if err := step.Action(stepCtx); err != nil {
+				return errors.Wrapf(err, "step %s", step.Name)
+			}
+			state.Completed = append(state.Completed, step.Name)
+			return s.save(context.WithoutCancel(ctx), state)
+		})
+	}
+
+	errCh := make(chan error, 1)
+	p.Run(func(err error) { errCh <- err })
+	if err := <-errCh; err != nil {
+		mu.Lock()
+		aborted = true
+		done := running
+		mu.Unlock()
+		if done != nil {
+			<-done
+		}
+		return s.compensate(ctx, state, err)
+	}
+
+	state.Status = StatusCompleted
+	if err := s.save(ctx, state); err != nil {
+		return err
+	}
+	s.log.Debug().Str("id", id).Msg("completed")
+	return nil
+}
+
+// compensate undoes completed steps in reverse order, failed compensations are retried
+// and don't stop the rest ones
+func (s *Saga) compensate(ctx context.Context, state *State, cause error) error {
+	s.log.Warn().Err(cause).Str("id", state.ID).Msg("compensating")
+	state.Status, state.Error = StatusCompensating, cause.Error()
+	if err := s.save(ctx, state); err != nil {
+		return err
+	}
+
+	failed := map[string]error{}
+	for i := len(state.Completed) - 1; i >= 0; i-- {
+		step, ok := s.step(state.Completed[i])
+		if ok && step.Compensate != nil {
+			if err := retry.Do(ctx, step.Compensate, s.retry...); err != nil {
+				if ctx.Err() != nil {
+					// state stays compensating to be resumed by next Execute
+					return errors.Wrap(ctx.Err(), "compensation interrupted")
+				}
+				s.log.Error().Err(err).Str("id", state.ID).Str("step", step.Name).Msg("compensation failed")
+				failed[step.Name] = err
+				continue
+			}
+		}
+		// compensated steps are forgotten, failed ones stay listed as completed
+		state.Completed = append(state.Completed[:i], state.Completed[i+1:]...)
+		if err := s.save(ctx, state); err != nil {
+			return err
+		}
+	}
+
+	if len(failed) > 0 {
+		state.Status = StatusFailed
+		if err := s.save(ctx, state); err != nil {
+			return err
+		}
+		return &CompensationError{Err: cause, Compensations: failed}
+	}
+
+	state.Status = StatusCompensated
+	if err := s.save(ctx, state); err != nil {
+		return err
+	}
+	return cause
+}
+
+func (s *Saga) step(name string) (Step, bool) {
+	for _, step := range s.steps {
+		if step.Name == name {
+			return step, true
+		}
+	}
+	return Step{}, false
+}
+
+func (s *Saga) save(ctx context.Context, state *State) error {
+	state.UpdatedAt = time.Now()
+	if err := s.store.Save(ctx, state); err != nil {
+		return errors.Wrap(err, "save state")
+	}
+	return nil
+}
+
+func (s *Saga) String() string { return s.name }
+
+// NewMemoryStore creates store keeping states in memory, it makes saga resumable within process only
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: map[string]State{}}
+}
+
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[string]State
+}
+
+func (m *MemoryStore) Load(_ context.Context, id string) (*State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, ok := m.states[id]
+	if !ok {
+		return nil, nil
+	}
+	state.Completed = append([]string{}, state.Completed...)
+	return &state, nil
+}
+
+func (m *MemoryStore) Save(_ context.Context, state *State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	saved := *state
+	saved.Completed = append([]string{}, state.Completed...)
+	m.states[state.ID] = saved
+	return nil
+}
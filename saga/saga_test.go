@@ -0,0 +1,144 @@
+package saga_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/retry"
+	"github.com/242617/core/saga"
+)
+
+var period = 10 * time.Millisecond
+
+type journal struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (j *journal) call(name string, err error) func(context.Context) error {
+	return func(context.Context) error {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		j.calls = append(j.calls, name)
+		return err
+	}
+}
+
+func (j *journal) Calls() []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]string{}, j.calls...)
+}
+
+func newSaga(t *testing.T, j *journal, store saga.Store, notifyErr, refundErr error) *saga.Saga {
+	s, err := saga.New("order",
+		saga.WithStore(store),
+		saga.WithCompensationRetry(retry.WithMaxAttempts(2), retry.WithConstantBackoff(period)),
+		saga.WithStep(saga.Step{Name: "reserve", Action: j.call("reserve", nil), Compensate: j.call("release", nil)}),
+		saga.WithStep(saga.Step{Name: "charge", Action: j.call("charge", nil), Compensate: j.call("refund", refundErr)}),
+		saga.WithStep(saga.Step{Name: "notify", Action: j.call("notify", notifyErr)}),
+	)
+	require.NoError(t, err, "new")
+	return s
+}
+
+func TestNew(t *testing.T) {
+	_, err := saga.New("order")
+	assert.Error(t, err, "no steps")
+
+	step := saga.Step{Name: "reserve", Action: func(context.Context) error { return nil }}
+	_, err = saga.New("order", saga.WithStep(step), saga.WithStep(step))
+	assert.Error(t, err, "duplicated step")
+}
+
+func TestExecute(t *testing.T) {
+	var j journal
+	store := saga.NewMemoryStore()
+	s := newSaga(t, &j, store, nil, nil)
+
+	require.NoError(t, s.Execute(context.Background(), "1"), "execute")
+	assert.Equal(t, []string{"reserve", "charge", "notify"}, j.Calls(), "steps")
+
+	state, err := store.Load(context.Background(), "1")
+	require.NoError(t, err, "load")
+	assert.Equal(t, saga.StatusCompleted, state.Status, "status")
+	assert.ErrorIs(t, s.Execute(context.Background(), "1"), saga.ErrFinished, "finished")
+}
+
+func TestCompensate(t *testing.T) {
+	var j journal
+	store := saga.NewMemoryStore()
+	sampleErr := errors.New("sample error")
+	s := newSaga(t, &j, store, sampleErr, nil)
+
+	err := s.Execute(context.Background(), "1")
+	assert.ErrorIs(t, err, sampleErr, "step error")
+	assert.Equal(t, []string{"reserve", "charge", "notify", "refund", "release"}, j.Calls(), "reverse compensation")
+
+	state, err := store.Load(context.Background(), "1")
+	require.NoError(t, err, "load")
+	assert.Equal(t, saga.StatusCompensated, state.Status, "status")
+	assert.Empty(t, state.Completed, "nothing left")
+}
+
+func TestCompensationFailed(t *testing.T) {
+	var j journal
+	store := saga.NewMemoryStore()
+	s := newSaga(t, &j, store, errors.New("sample error"), errors.New("refund error"))
+
+	err := s.Execute(context.Background(), "1")
+	var compensationErr *saga.CompensationError
+	require.ErrorAs(t, err, &compensationErr, "compensation error")
+	assert.Contains(t, compensationErr.Compensations, "charge", "failed compensation")
+	assert.Equal(t, []string{"reserve", "charge", "notify", "refund", "refund", "release"}, j.Calls(), "retried and continued")
+
+	state, err := store.Load(context.Background(), "1")
+	require.NoError(t, err, "load")
+	assert.Equal(t, saga.StatusFailed, state.Status, "status")
+	assert.Equal(t, []string{"charge"}, state.Completed, "not compensated")
+}
+
+func TestResume(t *testing.T) {
+	var j journal
+	store := saga.NewMemoryStore()
+	require.NoError(t, store.Save(context.Background(), &saga.State{
+		ID:        "1",
+		Saga:      "order",
+		Status:    saga.StatusRunning,
+		Completed: []string{"reserve"},
+	}), "save")
+
+	s := newSaga(t, &j, store, nil, nil)
+	require.NoError(t, s.Execute(context.Background(), "1"), "execute")
+	assert.Equal(t, []string{"charge", "notify"}, j.Calls(), "completed steps skipped")
+}
+
+func TestCanceledStep(t *testing.T) {
+	var j journal
+	store := saga.NewMemoryStore()
+	s, err := saga.New("order",
+		saga.WithStore(store),
+		saga.WithStep(saga.Step{Name: "reserve", Action: j.call("reserve", nil), Compensate: j.call("release", nil)}),
+		saga.WithStep(saga.Step{Name: "charge", Action: func(context.Context) error {
+			time.Sleep(3 * period)
+			return j.call("charge", nil)(context.Background())
+		}, Compensate: j.call("refund", nil)}),
+		saga.WithStep(saga.Step{Name: "notify", Action: j.call("notify", nil)}),
+	)
+	require.NoError(t, err, "new")
+
+	ctx, cancel := context.WithTimeout(context.Background(), period)
+	defer cancel()
+	assert.ErrorIs(t, s.Execute(ctx, "1"), context.DeadlineExceeded, "canceled")
+	assert.Equal(t, []string{"reserve", "charge", "refund", "release"}, j.Calls(), "late step compensated")
+
+	state, err := store.Load(context.Background(), "1")
+	require.NoError(t, err, "load")
+	assert.Equal(t, saga.StatusCompensated, state.Status, "status")
+}
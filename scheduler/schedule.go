@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule returns next activation time after given one
+type Schedule interface {
+	Next(time.Time) time.Time
+}
+
+// Cron parses standard five-field cron expression or descriptor like @hourly or @every 1m
+func Cron(expr string) (Schedule, error) {
+	schedule, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse %q", expr)
+	}
+	return schedule, nil
+}
+
+// MustCron is like Cron but panics on invalid expression
+func MustCron(expr string) Schedule {
+	schedule, err := Cron(expr)
+	if err != nil {
+		panic(err)
+	}
+	return schedule
+}
+
+// Every activates with fixed interval, it must be positive
+func Every(interval time.Duration) Schedule { return every(interval) }
+
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time { return t.Add(time.Duration(e)) }
@@ -0,0 +1,310 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/lock"
+	"github.com/242617/core/protocol"
+)
+
+const (
+	MetricRunsTotal    = "scheduler_runs_total"
+	MetricRunDuration  = "scheduler_run_duration_seconds"
+	MetricSkippedTotal = "scheduler_skipped_total"
+)
+
+// MissedPolicy defines what to do with activations missed while previous run was in progress
+type MissedPolicy int
+
+const (
+	// MissedSkip drops missed activations and waits for the next one
+	MissedSkip MissedPolicy = iota
+	// MissedRunOnce runs job once right away if any activation was missed
+	MissedRunOnce
+)
+
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Func     func(context.Context) error
+	// Timeout limits duration of a single run, zero means no limit
+	Timeout time.Duration
+	Missed  MissedPolicy
+	// Distributed makes job run under lock, so only one instance runs it per activation.
+	// Lock is held until the next activation of instance which ran the job.
+	Distributed bool
+}
+
+type option = func(s *Scheduler) error
+
+func withDefaults() option {
+	return func(s *Scheduler) error {
+		s.name = "scheduler"
+		s.log = l.With().Str("component", "scheduler").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(s *Scheduler) error {
+		s.name = name
+		return nil
+	}
+}
+
+func WithJob(job Job) option {
+	return func(s *Scheduler) error { return s.Add(job) }
+}
+
+// WithLocker sets locker used by distributed jobs
+func WithLocker(locker lock.Locker) option {
+	return func(s *Scheduler) error {
+		s.locker = locker
+		return nil
+	}
+}
+
+func WithMetrics(recorder protocol.MetricsRecorder) option {
+	return func(s *Scheduler) error {
+		s.metrics = recorder
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(s *Scheduler) error {
+		s.log = log
+		return nil
+	}
+}
+
+// New creates scheduler component, jobs are run between Start and Stop
+func New(options ...option) (*Scheduler, error) {
+	var s Scheduler
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&s); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	return &s, nil
+}
+
+type Scheduler struct {
+	name    string
+	locker  lock.Locker
+	metrics protocol.MetricsRecorder
+	log     zerolog.Logger
+
+	mu     sync.Mutex
+	jobs   []Job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Add adds job, jobs can't be added after start
+func (s *Scheduler) Add(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return errors.New("already started")
+	}
+	if job.Name == "" || job.Schedule == nil || job.Func == nil {
+		return errors.New("job must have name, schedule and func")
+	}
+	if e, ok := job.Schedule.(every); ok && e <= 0 {
+		return errors.Errorf("job %q: interval must be positive", job.Name)
+	}
+	if now := time.Now(); !job.Schedule.Next(now).After(now) {
+		return errors.Errorf("job %q: schedule has no activations", job.Name)
+	}
+	for _, j := range s.jobs {
+		if j.Name == job.Name {
+			return errors.Errorf("job %q already added", job.Name)
+		}
+	}
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+func (s *Scheduler) Start(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return errors.New("already started")
+	}
+	for _, job := range s.jobs {
+		if job.Distributed && s.locker == nil {
+			return errors.Errorf("job %q is distributed but locker is not set", job.Name)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, job)
+	}
+	return nil
+}
+
+// Stop stops scheduling and waits for running jobs, their context is cancelled
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	doneCh := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(doneCh)
+	}()
+	select {
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "wait for jobs")
+	case <-doneCh:
+	}
+	return nil
+}
+
+func (s *Scheduler) String() string { return s.name }
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	defer s.wg.Done()
+
+	// lock of distributed job is kept between runs, so other instances skip activation it covers
+	var held *lock.Lock
+	defer func() { s.unlock(job, held) }()
+
+	next := job.Schedule.Next(time.Now())
+	for {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		held = s.run(ctx, job, held)
+
+		now := time.Now()
+		if next = s.next(job, next); next.IsZero() {
+			return
+		}
+		if next.After(now) {
+			continue
+		}
+		s.skip(job, "missed")
+		if job.Missed == MissedRunOnce {
+			next = now
+			continue
+		}
+		for !next.After(now) {
+			if next = s.next(job, next); next.IsZero() {
+				return
+			}
+		}
+	}
+}
+
+// next returns activation after t or zero time if schedule does not move forward,
+// e.g. cron expression of date which never comes
+func (s *Scheduler) next(job Job, t time.Time) time.Time {
+	next := job.Schedule.Next(t)
+	if !next.After(t) {
+		s.log.Warn().Str("job", job.Name).Msg("no more activations")
+		return time.Time{}
+	}
+	return next
+}
+
+// run runs job once, lock of distributed job is returned to be held until the next activation
+func (s *Scheduler) run(ctx context.Context, job Job, held *lock.Lock) *lock.Lock {
+	if job.Distributed {
+		s.unlock(job, held)
+		lk, err := s.locker.TryLock(ctx, "scheduler:"+job.Name)
+		if errors.Is(err, lock.ErrNotAcquired) {
+			s.log.Debug().Str("job", job.Name).Msg("locked by another instance")
+			s.skip(job, "locked")
+			return nil
+		}
+		if err != nil {
+			s.log.Error().Err(err).Str("job", job.Name).Msg("lock")
+			s.skip(job, "lock_error")
+			return nil
+		}
+		held = lk
+
+		// job is cancelled once another instance may take over
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-lk.Lost():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := call(ctx, job.Func)
+	duration := time.Since(start)
+
+	status := "ok"
+	event := s.log.Info()
+	if err != nil {
+		status = "error"
+		event = s.log.Error().Err(err)
+	}
+	event.Str("job", job.Name).Dur("duration", duration).Msg("run")
+
+	if s.metrics != nil {
+		s.metrics.Add(MetricRunsTotal, 1, "job", job.Name, "status", status)
+		s.metrics.Observe(MetricRunDuration, duration.Seconds(), "job", job.Name)
+	}
+	return held
+}
+
+func (s *Scheduler) unlock(job Job, lk *lock.Lock) {
+	if lk == nil {
+		return
+	}
+	if err := lk.Unlock(context.Background()); err != nil {
+		s.log.Error().Err(err).Str("job", job.Name).Msg("unlock")
+	}
+}
+
+func (s *Scheduler) skip(job Job, reason string) {
+	if s.metrics != nil {
+		s.metrics.Add(MetricSkippedTotal, 1, "job", job.Name, "reason", reason)
+	}
+}
+
+func call(ctx context.Context, f func(context.Context) error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = errors.Errorf("panic: %v", v)
+		}
+	}()
+	return f(ctx)
+}
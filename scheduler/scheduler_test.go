@@ -0,0 +1,181 @@
+package scheduler_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/leaktest"
+	"github.com/242617/core/lock"
+	"github.com/242617/core/scheduler"
+)
+
+var period = 10 * time.Millisecond
+
+func TestCron(t *testing.T) {
+	schedule, err := scheduler.Cron("30 2 * * *")
+	require.NoError(t, err, "parse")
+	from := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 1, 2, 2, 30, 0, 0, time.UTC), schedule.Next(from), "next day")
+
+	_, err = scheduler.Cron("invalid")
+	assert.Error(t, err, "invalid expression")
+
+	assert.Equal(t, from.Add(time.Minute), scheduler.Every(time.Minute).Next(from), "interval")
+}
+
+func TestRun(t *testing.T) {
+	defer leaktest.Check(t)()
+	var calls int32
+	s, err := scheduler.New(scheduler.WithJob(scheduler.Job{
+		Name:     "sample",
+		Schedule: scheduler.Every(period),
+		Func: func(context.Context) error {
+			atomic.AddInt32(&calls, 1)
+			return nil
+		},
+	}))
+	require.NoError(t, err, "new scheduler")
+	require.NoError(t, s.Start(context.Background()), "start scheduler")
+	time.Sleep(5*period + period/2)
+	require.NoError(t, s.Stop(context.Background()), "stop scheduler")
+
+	n := atomic.LoadInt32(&calls)
+	assert.GreaterOrEqual(t, n, int32(3), "called periodically")
+	time.Sleep(2 * period)
+	assert.Equal(t, n, atomic.LoadInt32(&calls), "not called after stop")
+}
+
+func TestMissed(t *testing.T) {
+	period := 2 * period
+	for _, tc := range []struct {
+		policy scheduler.MissedPolicy
+		want   int32
+	}{
+		{scheduler.MissedSkip, 2},
+		{scheduler.MissedRunOnce, 3},
+	} {
+		var calls int32
+		s, err := scheduler.New(scheduler.WithJob(scheduler.Job{
+			Name:     "slow",
+			Schedule: scheduler.Every(4 * period),
+			Missed:   tc.policy,
+			Func: func(context.Context) error {
+				if atomic.AddInt32(&calls, 1) == 1 {
+					time.Sleep(9 * period)
+				}
+				return nil
+			},
+		}))
+		require.NoError(t, err, "new scheduler")
+		require.NoError(t, s.Start(context.Background()), "start scheduler")
+		time.Sleep(19 * period)
+		require.NoError(t, s.Stop(context.Background()), "stop scheduler")
+		assert.Equal(t, tc.want, atomic.LoadInt32(&calls), "policy %d", tc.policy)
+	}
+}
+
+func TestRedisLocker(t *testing.T) {
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	defer client.Close()
+
+	var calls int32
+	job := scheduler.Job{
+		Name:        "distributed",
+		Schedule:    scheduler.Every(period),
+		Distributed: true,
+		Func: func(context.Context) error {
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(period / 2)
+			return nil
+		},
+	}
+
+	var instances []*scheduler.Scheduler
+	for i := 0; i < 3; i++ {
+		locker, err := lock.NewRedis(client, lock.WithTTL(time.Minute))
+		require.NoError(t, err, "new locker")
+		s, err := scheduler.New(
+			scheduler.WithLocker(locker),
+			scheduler.WithJob(job),
+		)
+		require.NoError(t, err, "new scheduler")
+		instances = append(instances, s)
+	}
+	for _, s := range instances {
+		require.NoError(t, s.Start(context.Background()), "start scheduler")
+	}
+	time.Sleep(3*period + period/2)
+	for _, s := range instances {
+		require.NoError(t, s.Stop(context.Background()), "stop scheduler")
+	}
+
+	assert.LessOrEqual(t, atomic.LoadInt32(&calls), int32(3), "single instance per activation")
+	assert.False(t, srv.Exists("scheduler:distributed"), "lock released")
+}
+
+func TestDistributedShortJob(t *testing.T) {
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	defer client.Close()
+
+	period := 3 * period
+	var calls int32
+	job := scheduler.Job{
+		Name:        "short",
+		Schedule:    scheduler.Every(period),
+		Distributed: true,
+		Func: func(context.Context) error {
+			atomic.AddInt32(&calls, 1)
+			return nil
+		},
+	}
+
+	var instances []*scheduler.Scheduler
+	for i := 0; i < 3; i++ {
+		locker, err := lock.NewRedis(client, lock.WithTTL(time.Minute))
+		require.NoError(t, err, "new locker")
+		s, err := scheduler.New(scheduler.WithLocker(locker), scheduler.WithJob(job))
+		require.NoError(t, err, "new scheduler")
+		require.NoError(t, s.Start(context.Background()), "start scheduler")
+		instances = append(instances, s)
+		time.Sleep(period / 3)
+	}
+	time.Sleep(3 * period)
+	for _, s := range instances {
+		require.NoError(t, s.Stop(context.Background()), "stop scheduler")
+	}
+
+	assert.LessOrEqual(t, atomic.LoadInt32(&calls), int32(5), "lock is held until next activation")
+	assert.False(t, srv.Exists("scheduler:short"), "lock released")
+}
+
+func TestInvalidSchedule(t *testing.T) {
+	for _, schedule := range []scheduler.Schedule{
+		scheduler.Every(0),
+		scheduler.Every(-time.Second),
+		scheduler.MustCron("0 0 30 2 *"),
+	} {
+		s, err := scheduler.New()
+		require.NoError(t, err, "new scheduler")
+		assert.Error(t, s.Add(scheduler.Job{Name: "never", Schedule: schedule, Func: func(context.Context) error { return nil }}), "schedule without activations")
+	}
+}
+
+func TestDistributedWithoutLocker(t *testing.T) {
+	s, err := scheduler.New(scheduler.WithJob(scheduler.Job{
+		Name:        "distributed",
+		Schedule:    scheduler.Every(time.Minute),
+		Distributed: true,
+		Func:        func(context.Context) error { return nil },
+	}))
+	require.NoError(t, err, "new scheduler")
+	assert.Error(t, s.Start(context.Background()), "locker is required")
+}
@@ -0,0 +1,114 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrNotFound = errors.New("secret not found")
+
+type Provider interface {
+	Get(ctx context.Context, key string) (string, error)
+}
+
+type ProviderFunc func(ctx context.Context, key string) (string, error)
+
+func (f ProviderFunc) Get(ctx context.Context, key string) (string, error) { return f(ctx, key) }
+
+// Env returns provider reading secret from environment variable prefix+key
+func Env(prefix string) Provider {
+	return ProviderFunc(func(_ context.Context, key string) (string, error) {
+		value, ok := os.LookupEnv(prefix + key)
+		if !ok {
+			return "", ErrNotFound
+		}
+		return value, nil
+	})
+}
+
+// Dir returns provider reading secret from file named key in dir, e.g. /run/secrets
+func Dir(dir string) Provider {
+	return ProviderFunc(func(_ context.Context, key string) (string, error) {
+		if key == "" || strings.ContainsAny(key, `/\`) || key == ".." {
+			return "", errors.Errorf("invalid key %q", key)
+		}
+		b, err := os.ReadFile(filepath.Join(dir, key))
+		if os.IsNotExist(err) {
+			return "", ErrNotFound
+		}
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	})
+}
+
+type VaultConfig struct {
+	Addr  string `yaml:"addr"`
+	Token string `yaml:"token"`
+	// Mount is KV version 2 secrets engine path
+	Mount   string        `yaml:"mount"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Vault returns provider reading secrets from Vault KV version 2 engine.
+// Key has format "path#field", e.g. "db/postgres#password".
+func Vault(cfg VaultConfig) Provider {
+	if cfg.Mount == "" {
+		cfg.Mount = "secret"
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	client := &http.Client{Timeout: cfg.Timeout}
+
+	return ProviderFunc(func(ctx context.Context, key string) (string, error) {
+		path, field, ok := strings.Cut(key, "#")
+		if !ok {
+			return "", errors.Errorf("key %q has no field", key)
+		}
+		url := fmt.Sprintf("%s/v1/%s/data/%s", strings.TrimRight(cfg.Addr, "/"), cfg.Mount, strings.TrimLeft(path, "/"))
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return "", errors.Wrap(err, "create request")
+		}
+		req.Header.Set("X-Vault-Token", cfg.Token)
+
+		res, err := client.Do(req)
+		if err != nil {
+			return "", errors.Wrap(err, "do request")
+		}
+		defer res.Body.Close()
+		if res.StatusCode == http.StatusNotFound {
+			return "", ErrNotFound
+		}
+		if res.StatusCode != http.StatusOK {
+			return "", errors.Errorf("unexpected status %d", res.StatusCode)
+		}
+
+		var body struct {
+			Data struct {
+				Data map[string]any `json:"data"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+			return "", errors.Wrap(err, "decode response")
+		}
+		value, ok := body.Data.Data[field]
+		if !ok {
+			return "", ErrNotFound
+		}
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		return fmt.Sprint(value), nil
+	})
+}
@@ -0,0 +1,192 @@
+package secrets
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/cache"
+)
+
+type option = func(s *Store) error
+
+func withDefaults() option {
+	return func(s *Store) error {
+		s.name = "secrets"
+		s.ttl = 5 * time.Minute
+		s.log = l.With().Str("component", "secrets").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(s *Store) error {
+		s.name = name
+		return nil
+	}
+}
+
+// WithTTL sets how long fetched secrets are cached
+func WithTTL(ttl time.Duration) option {
+	return func(s *Store) error {
+		if ttl <= 0 {
+			return errors.New("ttl must be positive")
+		}
+		s.ttl = ttl
+		return nil
+	}
+}
+
+// WithRefreshInterval enables periodic refresh of secrets watched with OnRotate
+func WithRefreshInterval(interval time.Duration) option {
+	return func(s *Store) error {
+		if interval <= 0 {
+			return errors.New("interval must be positive")
+		}
+		s.interval = interval
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(s *Store) error {
+		s.log = log
+		return nil
+	}
+}
+
+// New creates store caching secrets fetched from provider
+func New(provider Provider, options ...option) (*Store, error) {
+	s := Store{provider: provider, watched: map[string]*watch{}}
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&s); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+
+	var err error
+	if s.cache, err = cache.New(cache.WithTTL[string, string](s.ttl)); err != nil {
+		return nil, errors.Wrap(err, "create cache")
+	}
+	return &s, nil
+}
+
+type Store struct {
+	name     string
+	provider Provider
+	ttl      time.Duration
+	interval time.Duration
+	log      zerolog.Logger
+
+	cache   *cache.Cache[string, string]
+	mu      sync.Mutex
+	watched map[string]*watch
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+type watch struct {
+	value     string
+	known     bool
+	callbacks []func(string)
+}
+
+func (s *Store) Get(ctx context.Context, key string) (string, error) {
+	return s.cache.GetOrLoad(ctx, key, func(ctx context.Context) (string, error) {
+		return s.provider.Get(ctx, key)
+	})
+}
+
+// OnRotate registers callback called with new value when secret changes
+func (s *Store) OnRotate(key string, f func(value string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.watched[key]
+	if !ok {
+		w = &watch{}
+		if value, ok := s.cache.Get(key); ok {
+			w.value, w.known = value, true
+		}
+		s.watched[key] = w
+	}
+	w.callbacks = append(w.callbacks, f)
+}
+
+// Refresh fetches watched secrets calling callbacks for changed ones
+func (s *Store) Refresh(ctx context.Context) error {
+	s.mu.Lock()
+	keys := make([]string, 0, len(s.watched))
+	for key := range s.watched {
+		keys = append(keys, key)
+	}
+	s.mu.Unlock()
+
+	for _, key := range keys {
+		value, err := s.provider.Get(ctx, key)
+		if err != nil {
+			return errors.Wrapf(err, "get %q", key)
+		}
+		s.cache.Set(key, value)
+
+		s.mu.Lock()
+		w := s.watched[key]
+		changed := w.known && w.value != value
+		w.value, w.known = value, true
+		callbacks := append([]func(string){}, w.callbacks...)
+		s.mu.Unlock()
+
+		if changed {
+			s.log.Info().Str("key", key).Msg("secret rotated")
+			for _, f := range callbacks {
+				f(value)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Store) Start(context.Context) error {
+	if s.interval == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel, s.done = cancel, make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Refresh(ctx); err != nil {
+					s.log.Error().Err(err).Msg("refresh secrets")
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *Store) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.done:
+	}
+	return nil
+}
+
+func (s *Store) String() string { return s.name }
@@ -0,0 +1,98 @@
+package secrets_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/secrets"
+)
+
+var period = 10 * time.Millisecond
+
+func TestProviders(t *testing.T) {
+	ctx := context.Background()
+
+	t.Setenv("SECRET_TOKEN", "env")
+	value, err := secrets.Env("SECRET_").Get(ctx, "TOKEN")
+	require.NoError(t, err, "env")
+	assert.Equal(t, "env", value, "env value")
+	_, err = secrets.Env("SECRET_").Get(ctx, "UNKNOWN")
+	assert.ErrorIs(t, err, secrets.ErrNotFound, "env not found")
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte("file\n"), 0o600), "write file")
+	value, err = secrets.Dir(dir).Get(ctx, "token")
+	require.NoError(t, err, "dir")
+	assert.Equal(t, "file", value, "dir value")
+	_, err = secrets.Dir(dir).Get(ctx, "../token")
+	assert.Error(t, err, "path traversal")
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" || r.URL.Path != "/v1/secret/data/db" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"data":{"data":{"password":"vault"}}}`))
+	}))
+	defer s.Close()
+	vault := secrets.Vault(secrets.VaultConfig{Addr: s.URL, Token: "root"})
+	value, err = vault.Get(ctx, "db#password")
+	require.NoError(t, err, "vault")
+	assert.Equal(t, "vault", value, "vault value")
+	_, err = vault.Get(ctx, "db#username")
+	assert.ErrorIs(t, err, secrets.ErrNotFound, "vault field not found")
+	_, err = vault.Get(ctx, "cache#password")
+	assert.ErrorIs(t, err, secrets.ErrNotFound, "vault path not found")
+}
+
+func TestRotation(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		current = "first"
+		loads   int
+	)
+	provider := secrets.ProviderFunc(func(context.Context, string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		loads++
+		return current, nil
+	})
+
+	s, err := secrets.New(provider, secrets.WithRefreshInterval(period))
+	require.NoError(t, err, "new")
+
+	value, err := s.Get(context.Background(), "key")
+	require.NoError(t, err, "get")
+	assert.Equal(t, "first", value, "first value")
+	_, err = s.Get(context.Background(), "key")
+	require.NoError(t, err, "get cached")
+	assert.Equal(t, 1, loads, "cached")
+
+	rotated := make(chan string, 1)
+	s.OnRotate("key", func(value string) { rotated <- value })
+	require.NoError(t, s.Start(context.Background()), "start")
+	defer s.Stop(context.Background())
+
+	mu.Lock()
+	current = "second"
+	mu.Unlock()
+
+	select {
+	case value := <-rotated:
+		assert.Equal(t, "second", value, "rotated value")
+	case <-time.After(10 * period):
+		t.Fatal("no rotation")
+	}
+	value, err = s.Get(context.Background(), "key")
+	require.NoError(t, err, "get rotated")
+	assert.Equal(t, "second", value, "cache updated")
+}
@@ -0,0 +1,222 @@
+package shutdown
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+)
+
+var ErrTimeout = errors.New("shutdown timeout")
+
+// Hook releases resource on shutdown
+type Hook = func(context.Context) error
+
+type option = func(r *Registry) error
+
+func withDefaults() option {
+	return func(r *Registry) error {
+		r.timeout = 10 * time.Second
+		r.signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+		r.exit = os.Exit
+		r.log = l.With().Str("component", "shutdown").Logger()
+		return nil
+	}
+}
+
+// WithTimeout limits total duration of shutdown
+func WithTimeout(timeout time.Duration) option {
+	return func(r *Registry) error {
+		if timeout <= 0 {
+			return errors.New("timeout must be positive")
+		}
+		r.timeout = timeout
+		return nil
+	}
+}
+
+// WithSignals overrides signals triggering shutdown, SIGINT and SIGTERM by default
+func WithSignals(signals ...os.Signal) option {
+	return func(r *Registry) error {
+		if len(signals) == 0 {
+			return errors.New("no signals")
+		}
+		r.signals = signals
+		return nil
+	}
+}
+
+// WithForceExit sets function called on second signal, os.Exit by default
+func WithForceExit(exit func(code int)) option {
+	return func(r *Registry) error {
+		r.exit = exit
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(r *Registry) error {
+		r.log = log
+		return nil
+	}
+}
+
+// New creates registry of hooks executed in reverse order of registration on shutdown
+func New(options ...option) (*Registry, error) {
+	var r Registry
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&r); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	return &r, nil
+}
+
+type Registry struct {
+	timeout time.Duration
+	signals []os.Signal
+	exit    func(int)
+	log     zerolog.Logger
+
+	mu    sync.Mutex
+	hooks []hook
+	once  sync.Once
+	err   error
+}
+
+type hook struct {
+	name    string
+	timeout time.Duration
+	fn      Hook
+}
+
+// Add registers hook, hooks added later are executed earlier
+func (r *Registry) Add(name string, fn Hook) { r.AddTimeout(name, 0, fn) }
+
+// AddTimeout registers hook limited by its own timeout within total one
+func (r *Registry) AddTimeout(name string, timeout time.Duration, fn Hook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = append(r.hooks, hook{name, timeout, fn})
+}
+
+// Shutdown executes hooks once, failed hooks do not prevent execution of the rest.
+// First error is returned, ErrTimeout is returned if ctx or total timeout expires.
+func (r *Registry) Shutdown(ctx context.Context) error {
+	r.once.Do(func() { r.err = r.shutdown(ctx) })
+	return r.err
+}
+
+func (r *Registry) shutdown(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	r.mu.Lock()
+	hooks := append([]hook(nil), r.hooks...)
+	r.mu.Unlock()
+
+	var first error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if ctx.Err() != nil {
+			r.log.Error().Int("skipped", i+1).Msg("shutdown timeout")
+			return ErrTimeout
+		}
+		h := hooks[i]
+		if err := r.run(ctx, h); err != nil {
+			r.log.Error().Err(err).Str("hook", h.name).Msg("hook failed")
+			if first == nil {
+				first = errors.Wrapf(err, "hook %q", h.name)
+			}
+		}
+	}
+	return first
+}
+
+func (r *Registry) run(ctx context.Context, h hook) error {
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+	r.log.Debug().Str("hook", h.name).Msg("running hook")
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- h.fn(ctx) }()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ErrTimeout
+	}
+}
+
+// Context returns context cancelled on first signal. Second signal terminates process immediately.
+func (r *Registry) Context(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, r.signals...)
+	go func() {
+		defer signal.Stop(sigCh)
+		select {
+		case sig := <-sigCh:
+			r.log.Info().Str("signal", sig.String()).Msg("shutting down")
+			cancel()
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case sig := <-sigCh:
+			r.log.Warn().Str("signal", sig.String()).Msg("forced exit")
+			r.exit(1)
+		case <-time.After(r.timeout):
+		}
+	}()
+	return ctx, cancel
+}
+
+// Wait blocks until signal is received or ctx is done and executes hooks
+func (r *Registry) Wait(ctx context.Context) error {
+	ctx, cancel := r.Context(ctx)
+	defer cancel()
+	<-ctx.Done()
+	return r.Shutdown(context.Background())
+}
+
+// Run executes fn with context cancelled on signal and executes hooks after fn returns
+func (r *Registry) Run(ctx context.Context, fn func(context.Context) error) error {
+	ctx, cancel := r.Context(ctx)
+	err := fn(ctx)
+	cancel()
+	if serr := r.Shutdown(context.Background()); err == nil {
+		err = serr
+	}
+	return err
+}
+
+type ctxKey struct{}
+
+// NewContext stores registry in context to let nested code register hooks
+func NewContext(ctx context.Context, r *Registry) context.Context {
+	return context.WithValue(ctx, ctxKey{}, r)
+}
+
+func FromContext(ctx context.Context) (*Registry, bool) {
+	r, ok := ctx.Value(ctxKey{}).(*Registry)
+	return r, ok
+}
+
+// Register adds hook to registry stored in context, it reports false if there is none
+func Register(ctx context.Context, name string, fn Hook) bool {
+	r, ok := FromContext(ctx)
+	if ok {
+		r.Add(name, fn)
+	}
+	return ok
+}
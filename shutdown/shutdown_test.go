@@ -0,0 +1,101 @@
+package shutdown_test
+
+import (
+	"context"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/shutdown"
+)
+
+var period = 10 * time.Millisecond
+
+func TestShutdown(t *testing.T) {
+	r, err := shutdown.New()
+	require.NoError(t, err, "new registry")
+
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	add := func(name string, err error) {
+		r.Add(name, func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return err
+		})
+	}
+	hookErr := errors.New("hook error")
+	add("db", nil)
+	add("cache", hookErr)
+	add("server", nil)
+	r.AddTimeout("slow", period, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	err = r.Shutdown(context.Background())
+	assert.ErrorIs(t, err, shutdown.ErrTimeout, "slow hook timed out first")
+	assert.Equal(t, []string{"server", "cache", "db"}, order, "reverse order, failures do not stop")
+	assert.Equal(t, err, r.Shutdown(context.Background()), "executed once")
+	assert.Len(t, order, 3, "not repeated")
+}
+
+func TestTotalTimeout(t *testing.T) {
+	r, err := shutdown.New(shutdown.WithTimeout(period))
+	require.NoError(t, err, "new registry")
+	var called bool
+	r.Add("first", func(context.Context) error {
+		called = true
+		return nil
+	})
+	r.Add("stuck", func(ctx context.Context) error {
+		time.Sleep(5 * period)
+		return nil
+	})
+
+	start := time.Now()
+	assert.ErrorIs(t, r.Shutdown(context.Background()), shutdown.ErrTimeout, "timeout")
+	assert.Less(t, time.Since(start), 3*period, "not waiting for stuck hook")
+	assert.False(t, called, "remaining hooks skipped")
+}
+
+func TestSignal(t *testing.T) {
+	exited := make(chan int, 1)
+	r, err := shutdown.New(shutdown.WithForceExit(func(code int) { exited <- code }))
+	require.NoError(t, err, "new registry")
+
+	var closed bool
+	ctx := shutdown.NewContext(context.Background(), r)
+	assert.True(t, shutdown.Register(ctx, "resource", func(context.Context) error {
+		closed = true
+		return nil
+	}), "registered via context")
+
+	go func() {
+		time.Sleep(period)
+		syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+	}()
+	err = r.Run(ctx, func(ctx context.Context) error {
+		<-ctx.Done()
+		// second signal while stopping forces exit
+		syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+		select {
+		case code := <-exited:
+			assert.Equal(t, 1, code, "exit code")
+		case <-time.After(10 * period):
+			t.Error("no forced exit")
+		}
+		return nil
+	})
+	assert.NoError(t, err, "run")
+	assert.True(t, closed, "hook executed")
+	assert.False(t, shutdown.Register(context.Background(), "none", nil), "no registry")
+}
@@ -0,0 +1,245 @@
+package sqs
+
+import (
+	"context"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awssqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/requestid"
+)
+
+// ConsumerConfig is a consumer configuration suitable for config.Scan
+type ConsumerConfig struct {
+	QueueURL          string        `yaml:"queue_url" env:"SQS_QUEUE_URL"`
+	MaxMessages       int32         `yaml:"max_messages" default:"10"`
+	WaitTime          time.Duration `yaml:"wait_time" default:"20s"`
+	VisibilityTimeout time.Duration `yaml:"visibility_timeout" default:"30s"`
+}
+
+type Message struct {
+	ID           string
+	Body         string
+	Attributes   map[string]string
+	ReceiveCount int
+}
+
+// Handler processes message, returned error leaves message in queue to be received again
+type Handler = func(ctx context.Context, msg Message) error
+
+type option = func(c *Consumer) error
+
+func withDefaults() option {
+	return func(c *Consumer) error {
+		c.name = "sqs"
+		c.log = l.With().Str("component", "sqs").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(c *Consumer) error {
+		c.name = name
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(c *Consumer) error {
+		c.log = log
+		return nil
+	}
+}
+
+// NewConsumer creates component long-polling queue between Start and Stop.
+// Messages of received batch are handled concurrently, visibility timeout is extended
+// while handler runs and succeeded messages are deleted in batch.
+func NewConsumer(awsCfg aws.Config, cfg ConsumerConfig, handler Handler, options ...option) (*Consumer, error) {
+	if cfg.QueueURL == "" {
+		return nil, errors.New("empty queue url")
+	}
+	if cfg.MaxMessages < 1 || cfg.MaxMessages > 10 {
+		cfg.MaxMessages = 10
+	}
+	if cfg.VisibilityTimeout < time.Second {
+		cfg.VisibilityTimeout = 30 * time.Second
+	}
+
+	c := Consumer{client: awssqs.NewFromConfig(awsCfg), cfg: cfg, handler: handler}
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&c); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	return &c, nil
+}
+
+type Consumer struct {
+	name    string
+	client  *awssqs.Client
+	cfg     ConsumerConfig
+	handler Handler
+	log     zerolog.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (c *Consumer) Start(context.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel, c.done = cancel, make(chan struct{})
+	go func() {
+		defer close(c.done)
+		for ctx.Err() == nil {
+			if err := c.receive(ctx); err != nil && ctx.Err() == nil {
+				c.log.Error().Err(err).Msg("receive")
+				select {
+				case <-ctx.Done():
+				case <-time.After(time.Second):
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops polling and waits for handlers of received batch to complete
+func (c *Consumer) Stop(ctx context.Context) error {
+	if c.cancel == nil {
+		return nil
+	}
+	c.cancel()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.done:
+		return nil
+	}
+}
+
+func (c *Consumer) String() string { return c.name }
+
+func (c *Consumer) receive(ctx context.Context) error {
+	out, err := c.client.ReceiveMessage(ctx, &awssqs.ReceiveMessageInput{
+		QueueUrl:                    aws.String(c.cfg.QueueURL),
+		MaxNumberOfMessages:         c.cfg.MaxMessages,
+		WaitTimeSeconds:             int32(c.cfg.WaitTime / time.Second),
+		VisibilityTimeout:           int32(c.cfg.VisibilityTimeout / time.Second),
+		MessageAttributeNames:       []string{"All"},
+		MessageSystemAttributeNames: []types.MessageSystemAttributeName{types.MessageSystemAttributeNameApproximateReceiveCount},
+	})
+	if err != nil {
+		return errors.Wrap(err, "receive message")
+	}
+	if len(out.Messages) == 0 {
+		return nil
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		entries []types.DeleteMessageBatchRequestEntry
+	)
+	for i, m := range out.Messages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.process(m); err != nil {
+				c.log.Error().Err(err).Str("message_id", aws.ToString(m.MessageId)).Msg("handle")
+				return
+			}
+			mu.Lock()
+			entries = append(entries, types.DeleteMessageBatchRequestEntry{
+				Id:            aws.String(strconv.Itoa(i)),
+				ReceiptHandle: m.ReceiptHandle,
+			})
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if len(entries) == 0 {
+		return nil
+	}
+	// messages are deleted even after Stop so that handled ones are not redelivered
+	res, err := c.client.DeleteMessageBatch(context.WithoutCancel(ctx), &awssqs.DeleteMessageBatchInput{
+		QueueUrl: aws.String(c.cfg.QueueURL),
+		Entries:  entries,
+	})
+	if err != nil {
+		return errors.Wrap(err, "delete message batch")
+	}
+	for _, failed := range res.Failed {
+		c.log.Error().Str("id", aws.ToString(failed.Id)).Str("code", aws.ToString(failed.Code)).Msg("delete message")
+	}
+	return nil
+}
+
+func (c *Consumer) process(m types.Message) error {
+	ctx := context.Background()
+	if attr, ok := m.MessageAttributes[requestid.Header]; ok && aws.ToString(attr.StringValue) != "" {
+		ctx = requestid.NewContext(ctx, aws.ToString(attr.StringValue))
+	}
+
+	stop := c.extend(m.ReceiptHandle)
+	defer stop()
+
+	msg := Message{
+		ID:         aws.ToString(m.MessageId),
+		Body:       aws.ToString(m.Body),
+		Attributes: map[string]string{},
+	}
+	for key, attr := range m.MessageAttributes {
+		msg.Attributes[key] = aws.ToString(attr.StringValue)
+	}
+	msg.ReceiveCount, _ = strconv.Atoi(m.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)])
+	return c.call(ctx, msg)
+}
+
+// extend keeps message invisible while it is handled
+func (c *Consumer) extend(receipt *string) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(c.cfg.VisibilityTimeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if _, err := c.client.ChangeMessageVisibility(ctx, &awssqs.ChangeMessageVisibilityInput{
+				QueueUrl:          aws.String(c.cfg.QueueURL),
+				ReceiptHandle:     receipt,
+				VisibilityTimeout: int32(c.cfg.VisibilityTimeout / time.Second),
+			}); err != nil && ctx.Err() == nil {
+				c.log.Warn().Err(err).Msg("extend visibility")
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func (c *Consumer) call(ctx context.Context, msg Message) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			c.log.Error().Interface("panic", v).Bytes("stack", debug.Stack()).Msg("recovered")
+			err = errors.Errorf("panic: %v", v)
+		}
+	}()
+	return c.handler(ctx, msg)
+}
@@ -0,0 +1,83 @@
+package sqs
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awssns "github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	awssqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/pkg/errors"
+
+	"github.com/242617/core/requestid"
+)
+
+// PublisherConfig sets destination, messages are published to topic if TopicARN is set
+type PublisherConfig struct {
+	QueueURL string `yaml:"queue_url"`
+	TopicARN string `yaml:"topic_arn"`
+}
+
+// NewPublisher creates publisher sending messages to SQS queue or SNS topic
+func NewPublisher(awsCfg aws.Config, cfg PublisherConfig) (*Publisher, error) {
+	p := Publisher{cfg: cfg}
+	switch {
+	case cfg.TopicARN != "":
+		p.sns = awssns.NewFromConfig(awsCfg)
+	case cfg.QueueURL != "":
+		p.sqs = awssqs.NewFromConfig(awsCfg)
+	default:
+		return nil, errors.New("empty queue url and topic arn")
+	}
+	return &p, nil
+}
+
+type Publisher struct {
+	cfg PublisherConfig
+	sqs *awssqs.Client
+	sns *awssns.Client
+}
+
+// Publish sends message with string attributes propagating request ID from ctx, returns message ID
+func (p *Publisher) Publish(ctx context.Context, body string, attributes map[string]string) (string, error) {
+	attrs := map[string]string{}
+	for key, value := range attributes {
+		attrs[key] = value
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		if _, ok := attrs[requestid.Header]; !ok {
+			attrs[requestid.Header] = id
+		}
+	}
+
+	if p.sns != nil {
+		values := map[string]snstypes.MessageAttributeValue{}
+		for key, value := range attrs {
+			values[key] = snstypes.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+		}
+		out, err := p.sns.Publish(ctx, &awssns.PublishInput{
+			TopicArn:          aws.String(p.cfg.TopicARN),
+			Message:           aws.String(body),
+			MessageAttributes: values,
+		})
+		if err != nil {
+			return "", errors.Wrap(err, "publish")
+		}
+		return aws.ToString(out.MessageId), nil
+	}
+
+	values := map[string]types.MessageAttributeValue{}
+	for key, value := range attrs {
+		values[key] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+	}
+	out, err := p.sqs.SendMessage(ctx, &awssqs.SendMessageInput{
+		QueueUrl:          aws.String(p.cfg.QueueURL),
+		MessageBody:       aws.String(body),
+		MessageAttributes: values,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "send message")
+	}
+	return aws.ToString(out.MessageId), nil
+}
@@ -0,0 +1,158 @@
+package sqs_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/requestid"
+	"github.com/242617/core/sqs"
+)
+
+var period = 10 * time.Millisecond
+
+type attribute struct {
+	DataType    string
+	StringValue string
+}
+
+type message struct {
+	MessageId         string
+	ReceiptHandle     string
+	Body              string
+	Attributes        map[string]string
+	MessageAttributes map[string]attribute
+}
+
+// fakeSQS implements subset of SQS JSON protocol for single queue
+type fakeSQS struct {
+	mu       sync.Mutex
+	queue    []message
+	deleted  []string
+	extended int
+}
+
+func (f *fakeSQS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var in map[string]json.RawMessage
+	_ = json.NewDecoder(r.Body).Decode(&in)
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+
+	switch strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "AmazonSQS.") {
+	case "SendMessage":
+		var req struct {
+			MessageBody       string
+			MessageAttributes map[string]attribute
+		}
+		_ = json.Unmarshal(mustMarshal(in), &req)
+		id := fmt.Sprint(len(f.queue) + len(f.deleted) + 1)
+		f.queue = append(f.queue, message{
+			MessageId:         id,
+			ReceiptHandle:     "receipt-" + id,
+			Body:              req.MessageBody,
+			Attributes:        map[string]string{"ApproximateReceiveCount": "1"},
+			MessageAttributes: req.MessageAttributes,
+		})
+		json.NewEncoder(w).Encode(map[string]string{"MessageId": id})
+	case "ReceiveMessage":
+		messages := f.queue
+		f.queue = nil
+		if len(messages) == 0 {
+			time.Sleep(period)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"Messages": messages})
+	case "ChangeMessageVisibility":
+		f.extended++
+		w.Write([]byte("{}"))
+	case "DeleteMessageBatch":
+		var req struct {
+			Entries []struct{ Id, ReceiptHandle string }
+		}
+		_ = json.Unmarshal(mustMarshal(in), &req)
+		var successful []map[string]string
+		for _, e := range req.Entries {
+			f.deleted = append(f.deleted, e.ReceiptHandle)
+			successful = append(successful, map[string]string{"Id": e.Id})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"Successful": successful, "Failed": []any{}})
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func mustMarshal(v any) []byte {
+	b, _ := json.Marshal(v)
+	return b
+}
+
+func TestSQS(t *testing.T) {
+	fake := fakeSQS{}
+	srv := httptest.NewServer(&fake)
+	defer srv.Close()
+
+	awsCfg := aws.Config{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "access", SecretAccessKey: "secret"}, nil
+		}),
+	}
+	queueURL := srv.URL + "/000000000000/orders"
+
+	p, err := sqs.NewPublisher(awsCfg, sqs.PublisherConfig{QueueURL: queueURL})
+	require.NoError(t, err, "new publisher")
+	ctx := requestid.NewContext(context.Background(), "request")
+	for _, body := range []string{"ok", "fail", "slow"} {
+		_, err := p.Publish(ctx, body, map[string]string{"kind": "order"})
+		require.NoError(t, err, "publish")
+	}
+
+	var (
+		mu      sync.Mutex
+		handled []string
+	)
+	c, err := sqs.NewConsumer(awsCfg, sqs.ConsumerConfig{
+		QueueURL:          queueURL,
+		VisibilityTimeout: time.Second,
+	}, func(ctx context.Context, msg sqs.Message) error {
+		mu.Lock()
+		handled = append(handled, msg.Body+" "+requestid.FromContext(ctx)+" "+msg.Attributes["kind"])
+		mu.Unlock()
+		switch msg.Body {
+		case "fail":
+			return errors.New("sample")
+		case "slow":
+			time.Sleep(60 * period)
+		}
+		return nil
+	})
+	require.NoError(t, err, "new consumer")
+	require.NoError(t, c.Start(context.Background()), "start")
+
+	assert.Eventually(t, func() bool {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		return len(fake.deleted) == 2
+	}, 200*period, period, "handled messages deleted")
+	require.NoError(t, c.Stop(context.Background()), "stop")
+
+	assert.ElementsMatch(t, []string{"ok request order", "fail request order", "slow request order"}, handled, "handled")
+	assert.ElementsMatch(t, []string{"receipt-1", "receipt-3"}, fake.deleted, "failed message kept")
+	assert.Positive(t, fake.extended, "visibility extended for slow handler")
+
+	_, err = sqs.NewPublisher(awsCfg, sqs.PublisherConfig{})
+	assert.Error(t, err, "no destination")
+}
@@ -0,0 +1,60 @@
+package streams
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/242617/core/amqp"
+	"github.com/242617/core/nats"
+)
+
+// Processor is implemented by Stream of any type
+type Processor interface {
+	Process(ctx context.Context, msg Message) error
+}
+
+// AMQPHandler feeds stream with deliveries of amqp consumer, routing key becomes message key
+func AMQPHandler(p Processor) amqp.Handler {
+	return func(ctx context.Context, msg amqp.Message) error {
+		headers := make(map[string]string, len(msg.Headers))
+		for k, v := range msg.Headers {
+			headers[k] = fmt.Sprint(v)
+		}
+		return p.Process(ctx, Message{Key: msg.RoutingKey, Value: msg.Body, Headers: headers})
+	}
+}
+
+// AMQPSink publishes messages of content type using key as routing key
+func AMQPSink(publisher *amqp.Publisher, contentType string) Sink {
+	return SinkFunc(func(ctx context.Context, msg Message) error {
+		headers := make(map[string]any, len(msg.Headers))
+		for k, v := range msg.Headers {
+			headers[k] = v
+		}
+		return publisher.Publish(ctx, amqp.Message{RoutingKey: msg.Key, ContentType: contentType, Body: msg.Value, Headers: headers})
+	})
+}
+
+// NATSHandler feeds stream with messages of nats consumer, subject becomes message key
+func NATSHandler(p Processor) nats.Handler {
+	return func(ctx context.Context, msg nats.Message) error {
+		headers := make(map[string]string, len(msg.Header))
+		for k, v := range msg.Header {
+			if len(v) > 0 {
+				headers[k] = v[0]
+			}
+		}
+		return p.Process(ctx, Message{Key: msg.Subject, Value: msg.Data, Headers: headers})
+	}
+}
+
+// NATSSink publishes messages to subject, message key is ignored
+func NATSSink(conn *nats.Conn, subject string) Sink {
+	return SinkFunc(func(ctx context.Context, msg Message) error {
+		header := make(map[string][]string, len(msg.Headers))
+		for k, v := range msg.Headers {
+			header[k] = []string{v}
+		}
+		return conn.Publish(ctx, nats.Message{Subject: subject, Data: msg.Value, Header: header})
+	})
+}
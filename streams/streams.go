@@ -0,0 +1,345 @@
+package streams
+
+import (
+	"context"
+	"encoding/json"
+	"runtime/debug"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/conc"
+	"github.com/242617/core/protocol"
+	"github.com/242617/core/retry"
+)
+
+const (
+	MetricMessagesTotal = "streams_messages_total"
+	MetricStageDuration = "streams_stage_duration_seconds"
+	MetricStageErrors   = "streams_stage_errors_total"
+)
+
+const (
+	StatusProduced = "produced"
+	StatusFiltered = "filtered"
+	StatusDead     = "dead"
+	StatusFailed   = "failed"
+)
+
+// Headers set on messages sent to dead letter sink
+const (
+	HeaderError = "x-stream-error"
+	HeaderStage = "x-stream-stage"
+)
+
+// Message is a transport independent message, use adapters to connect consumers and producers
+type Message struct {
+	Key     string
+	Value   []byte
+	Headers map[string]string
+}
+
+// Sink sends messages, e.g. producer or dead letter queue
+type Sink interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type SinkFunc func(ctx context.Context, msg Message) error
+
+func (f SinkFunc) Send(ctx context.Context, msg Message) error { return f(ctx, msg) }
+
+type (
+	DecodeFunc[T any] func(msg Message) (T, error)
+	EncodeFunc[T any] func(value T) (Message, error)
+)
+
+// JSON decodes message value from json
+func JSON[T any]() DecodeFunc[T] {
+	return func(msg Message) (T, error) {
+		var value T
+		err := json.Unmarshal(msg.Value, &value)
+		return value, err
+	}
+}
+
+// ToJSON encodes value to json using key func for message key, key may be nil
+func ToJSON[T any](key func(T) string) EncodeFunc[T] {
+	return func(value T) (Message, error) {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return Message{}, err
+		}
+		msg := Message{Value: b}
+		if key != nil {
+			msg.Key = key(value)
+		}
+		return msg, nil
+	}
+}
+
+type stage[T any] struct {
+	name  string
+	fn    func(ctx context.Context, value T) (T, bool, error)
+	sem   *conc.Semaphore
+	retry []retry.Option
+}
+
+type StageOption = func(s *stageConfig) error
+
+type stageConfig struct {
+	concurrency int
+	retry       []retry.Option
+}
+
+// Concurrency limits number of messages processed by stage at once, stage is unlimited by default
+func Concurrency(n int) StageOption {
+	return func(s *stageConfig) error {
+		if n < 1 {
+			return errors.New("concurrency must be positive")
+		}
+		s.concurrency = n
+		return nil
+	}
+}
+
+// Retry makes stage retried with options, stages are called once by default
+func Retry(options ...retry.Option) StageOption {
+	return func(s *stageConfig) error {
+		s.retry = append([]retry.Option{retry.WithMaxAttempts(3)}, options...)
+		return nil
+	}
+}
+
+type Option[T any] func(s *Stream[T]) error
+
+// Map adds stage transforming value
+func Map[T any](name string, f func(ctx context.Context, value T) (T, error), options ...StageOption) Option[T] {
+	return withStage(name, func(ctx context.Context, value T) (T, bool, error) {
+		value, err := f(ctx, value)
+		return value, true, err
+	}, options)
+}
+
+// Filter adds stage dropping values f returns false for, dropped messages are acknowledged
+func Filter[T any](name string, f func(ctx context.Context, value T) (bool, error), options ...StageOption) Option[T] {
+	return withStage(name, func(ctx context.Context, value T) (T, bool, error) {
+		keep, err := f(ctx, value)
+		return value, keep, err
+	}, options)
+}
+
+// Tap adds stage with side effect only, e.g. storing value
+func Tap[T any](name string, f func(ctx context.Context, value T) error, options ...StageOption) Option[T] {
+	return withStage(name, func(ctx context.Context, value T) (T, bool, error) {
+		return value, true, f(ctx, value)
+	}, options)
+}
+
+func withStage[T any](name string, fn func(ctx context.Context, value T) (T, bool, error), options []StageOption) Option[T] {
+	return func(s *Stream[T]) error {
+		if name == "" {
+			return errors.New("empty stage name")
+		}
+		var c stageConfig
+		for _, option := range options {
+			if err := option(&c); err != nil {
+				return errors.Wrapf(err, "stage %q", name)
+			}
+		}
+		st := stage[T]{name: name, fn: fn, retry: c.retry}
+		if c.concurrency > 0 {
+			st.sem = conc.NewSemaphore(int64(c.concurrency))
+		}
+		s.stages = append(s.stages, st)
+		return nil
+	}
+}
+
+// To sends processed values encoded by encode to sink, without sink values are dropped after last stage
+func To[T any](sink Sink, encode EncodeFunc[T]) Option[T] {
+	return func(s *Stream[T]) error {
+		if sink == nil || encode == nil {
+			return errors.New("empty sink or encoder")
+		}
+		s.sink, s.encode = sink, encode
+		return nil
+	}
+}
+
+// DeadLetter sends messages failed to decode or process to sink with error headers.
+// Such messages are acknowledged, without dead letter sink error is returned to consumer.
+func DeadLetter[T any](sink Sink) Option[T] {
+	return func(s *Stream[T]) error {
+		s.dlq = sink
+		return nil
+	}
+}
+
+func WithMetrics[T any](recorder protocol.MetricsRecorder) Option[T] {
+	return func(s *Stream[T]) error {
+		s.metrics = recorder
+		return nil
+	}
+}
+
+func WithLogger[T any](log zerolog.Logger) Option[T] {
+	return func(s *Stream[T]) error {
+		s.log = log
+		return nil
+	}
+}
+
+// New creates stream processing consumed messages: they are decoded, passed through stages in order
+// and sent to sink. Stream is fed by consumer through Process or transport adapters.
+//
+// Example:
+//
+//	s, err := streams.New("orders", streams.JSON[Order](),
+//		streams.Filter("paid", isPaid),
+//		streams.Map("enrich", enrich, streams.Concurrency(8), streams.Retry()),
+//		streams.To(streams.NATSSink(conn, "orders.enriched"), streams.ToJSON(orderID)),
+//		streams.DeadLetter[Order](streams.NATSSink(conn, "orders.dlq")),
+//	)
+//	consumer, err := nats.NewConsumer(conn, cfg, streams.NATSHandler(s))
+func New[T any](name string, decode DecodeFunc[T], options ...Option[T]) (*Stream[T], error) {
+	if name == "" || decode == nil {
+		return nil, errors.New("empty name or decoder")
+	}
+	s := Stream[T]{
+		name:   name,
+		decode: decode,
+		log:    l.With().Str("component", "streams").Str("stream", name).Logger(),
+	}
+	for _, option := range options {
+		if err := option(&s); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	return &s, nil
+}
+
+type Stream[T any] struct {
+	name    string
+	decode  DecodeFunc[T]
+	stages  []stage[T]
+	sink    Sink
+	encode  EncodeFunc[T]
+	dlq     Sink
+	metrics protocol.MetricsRecorder
+	log     zerolog.Logger
+}
+
+func (s *Stream[T]) String() string { return s.name }
+
+// Process handles consumed message, returned error means message should be redelivered
+func (s *Stream[T]) Process(ctx context.Context, msg Message) error {
+	value, err := s.decode(msg)
+	if err != nil {
+		return s.fail(ctx, msg, "decode", errors.Wrap(err, "decode"))
+	}
+
+	for _, st := range s.stages {
+		var keep bool
+		value, keep, err = s.run(ctx, st, value)
+		if err != nil && canceled(ctx, err) {
+			// message in flight at shutdown is redelivered rather than dead-lettered
+			s.record(StatusFailed)
+			return errors.Wrapf(err, "stage %s", st.name)
+		}
+		if err != nil {
+			return s.fail(ctx, msg, st.name, errors.Wrapf(err, "stage %s", st.name))
+		}
+		if !keep {
+			s.record(StatusFiltered)
+			return nil
+		}
+	}
+
+	if s.sink == nil {
+		s.record(StatusProduced)
+		return nil
+	}
+	out, err := s.encode(value)
+	if err != nil {
+		return s.fail(ctx, msg, "encode", errors.Wrap(err, "encode"))
+	}
+	if err := s.sink.Send(ctx, out); err != nil {
+		// sink is likely unavailable, so message is redelivered rather than dead-lettered
+		s.record(StatusFailed)
+		return errors.Wrap(err, "send")
+	}
+	s.record(StatusProduced)
+	return nil
+}
+
+func (s *Stream[T]) run(ctx context.Context, st stage[T], value T) (T, bool, error) {
+	if st.sem != nil {
+		if err := st.sem.Acquire(ctx, 1); err != nil {
+			return value, false, err
+		}
+		defer st.sem.Release(1)
+	}
+
+	start := time.Now()
+	var (
+		result T
+		keep   bool
+	)
+	call := func(ctx context.Context) (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				s.log.Error().Interface("panic", v).Bytes("stack", debug.Stack()).Msg("recovered")
+				err = retry.Permanent(errors.Errorf("panic: %v", v))
+			}
+		}()
+		result, keep, err = st.fn(ctx, value)
+		if err != nil && s.metrics != nil {
+			s.metrics.Add(MetricStageErrors, 1, "stream", s.name, "stage", st.name)
+		}
+		return err
+	}
+
+	var err error
+	if st.retry != nil {
+		err = retry.Do(ctx, call, st.retry...)
+	} else {
+		err = call(ctx)
+	}
+	if s.metrics != nil {
+		s.metrics.Observe(MetricStageDuration, time.Since(start).Seconds(), "stream", s.name, "stage", st.name)
+	}
+	return result, keep, err
+}
+
+func (s *Stream[T]) fail(ctx context.Context, msg Message, stage string, err error) error {
+	if s.dlq == nil {
+		s.record(StatusFailed)
+		return err
+	}
+	s.log.Warn().Err(err).Str("stage", stage).Str("key", msg.Key).Msg("dead letter")
+
+	headers := make(map[string]string, len(msg.Headers)+2)
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	headers[HeaderError], headers[HeaderStage] = err.Error(), stage
+	if dlqErr := s.dlq.Send(ctx, Message{Key: msg.Key, Value: msg.Value, Headers: headers}); dlqErr != nil {
+		s.record(StatusFailed)
+		return errors.Wrap(dlqErr, "send to dead letter")
+	}
+	s.record(StatusDead)
+	return nil
+}
+
+// canceled tells error caused by cancellation of ctx or deadline
+func canceled(ctx context.Context, err error) bool {
+	return ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func (s *Stream[T]) record(status string) {
+	if s.metrics != nil {
+		s.metrics.Add(MetricMessagesTotal, 1, "stream", s.name, "status", status)
+	}
+}
@@ -0,0 +1,159 @@
+package streams_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/retry"
+	"github.com/242617/core/streams"
+)
+
+var period = 10 * time.Millisecond
+
+type order struct {
+	ID    int  `json:"id"`
+	Paid  bool `json:"paid"`
+	Total int  `json:"total"`
+}
+
+type withSink struct {
+	mu   sync.Mutex
+	msgs []streams.Message
+}
+
+func (s *withSink) Send(_ context.Context, msg streams.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.msgs = append(s.msgs, msg)
+	return nil
+}
+
+func (s *withSink) Messages() []streams.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]streams.Message{}, s.msgs...)
+}
+
+func newStream(t *testing.T, out, dlq *withSink, options ...streams.Option[order]) *streams.Stream[order] {
+	options = append([]streams.Option[order]{
+		streams.Filter("paid", func(_ context.Context, o order) (bool, error) { return o.Paid, nil }),
+		streams.Map("tax", func(_ context.Context, o order) (order, error) {
+			if o.Total < 0 {
+				return o, errors.New("negative total")
+			}
+			o.Total = o.Total * 120 / 100
+			return o, nil
+		}),
+		streams.To(out, streams.ToJSON(func(o order) string { return strconv.Itoa(o.ID) })),
+	}, options...)
+	if dlq != nil {
+		options = append(options, streams.DeadLetter[order](dlq))
+	}
+	s, err := streams.New("orders", streams.JSON[order](), options...)
+	require.NoError(t, err, "new")
+	return s
+}
+
+func TestProcess(t *testing.T) {
+	var out, dlq withSink
+	s := newStream(t, &out, &dlq)
+	ctx := context.Background()
+
+	require.NoError(t, s.Process(ctx, streams.Message{Value: []byte(`{"id":1,"paid":true,"total":100}`)}), "produced")
+	require.NoError(t, s.Process(ctx, streams.Message{Value: []byte(`{"id":2,"paid":false,"total":100}`)}), "filtered")
+	require.NoError(t, s.Process(ctx, streams.Message{Key: "3", Value: []byte(`{"id":3,"paid":true,"total":-1}`)}), "failed stage")
+	require.NoError(t, s.Process(ctx, streams.Message{Key: "4", Value: []byte(`{`)}), "poison")
+
+	assert.Equal(t, []streams.Message{{Key: "1", Value: []byte(`{"id":1,"paid":true,"total":120}`)}}, out.Messages(), "output")
+	dead := dlq.Messages()
+	require.Len(t, dead, 2, "dead letters")
+	assert.Equal(t, "tax", dead[0].Headers[streams.HeaderStage], "failed stage")
+	assert.Equal(t, "stage tax: negative total", dead[0].Headers[streams.HeaderError], "error")
+	assert.Equal(t, "decode", dead[1].Headers[streams.HeaderStage], "decode stage")
+}
+
+func TestNoDeadLetter(t *testing.T) {
+	var out withSink
+	s := newStream(t, &out, nil)
+	err := s.Process(context.Background(), streams.Message{Value: []byte(`{"id":3,"paid":true,"total":-1}`)})
+	assert.Error(t, err, "redelivered")
+}
+
+func TestRetry(t *testing.T) {
+	var (
+		out   withSink
+		calls atomic.Int32
+	)
+	s := newStream(t, &out, nil, streams.Tap("store", func(context.Context, order) error {
+		if calls.Add(1) < 3 {
+			return errors.New("temporary")
+		}
+		return nil
+	}, streams.Retry(retry.WithConstantBackoff(period))))
+
+	require.NoError(t, s.Process(context.Background(), streams.Message{Value: []byte(`{"id":1,"paid":true}`)}), "process")
+	assert.Equal(t, int32(3), calls.Load(), "retried")
+}
+
+func TestConcurrency(t *testing.T) {
+	var (
+		out             withSink
+		current, maxRun atomic.Int32
+	)
+	s := newStream(t, &out, nil, streams.Tap("slow", func(context.Context, order) error {
+		n := current.Add(1)
+		defer current.Add(-1)
+		for {
+			m := maxRun.Load()
+			if n <= m || maxRun.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(period)
+		return nil
+	}, streams.Concurrency(2)))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, s.Process(context.Background(), streams.Message{Value: []byte(`{"id":1,"paid":true}`)}), "process")
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), maxRun.Load(), "limited")
+	assert.Len(t, out.Messages(), 6, "all produced")
+}
+
+func TestCanceled(t *testing.T) {
+	var out, dlq withSink
+	started := make(chan struct{})
+	s := newStream(t, &out, &dlq, streams.Tap("slow", func(ctx context.Context, _ order) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}, streams.Concurrency(1)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 2)
+	go func() { errCh <- s.Process(ctx, streams.Message{Value: []byte(`{"id":1,"paid":true}`)}) }()
+	<-started
+	go func() { errCh <- s.Process(ctx, streams.Message{Value: []byte(`{"id":2,"paid":true}`)}) }()
+	time.Sleep(period)
+	cancel()
+
+	for i := 0; i < 2; i++ {
+		assert.ErrorIs(t, <-errCh, context.Canceled, "redelivered")
+	}
+	assert.Empty(t, dlq.Messages(), "not dead-lettered")
+	assert.Empty(t, out.Messages(), "not produced")
+}
@@ -0,0 +1,410 @@
+package taskqueue
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/conc"
+	"github.com/242617/core/protocol"
+	"github.com/242617/core/retry"
+)
+
+const (
+	MetricTasksTotal   = "taskqueue_tasks_total"
+	MetricTaskDuration = "taskqueue_task_duration_seconds"
+	MetricErrorsTotal  = "taskqueue_errors_total"
+)
+
+const (
+	StatePending = "pending"
+	StateRunning = "running"
+	StateDead    = "dead"
+)
+
+// Schema creates table with default name expected by queue
+const Schema = `CREATE TABLE IF NOT EXISTS tasks (
+	id BIGSERIAL PRIMARY KEY,
+	queue TEXT NOT NULL,
+	payload BYTEA NOT NULL,
+	state TEXT NOT NULL DEFAULT 'pending',
+	attempts INT NOT NULL DEFAULT 0,
+	max_attempts INT NOT NULL,
+	run_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS tasks_queue_run_at_idx ON tasks (queue, run_at) WHERE state <> 'dead'`
+
+type Task struct {
+	ID          int64
+	Queue       string
+	Payload     []byte
+	Attempt     int
+	MaxAttempts int
+	CreatedAt   time.Time
+}
+
+// Handler processes task, returning retry.Permanent error moves task to dead state immediately
+type Handler = func(ctx context.Context, task Task) error
+
+type Config struct {
+	Table        string        `yaml:"table"`
+	Workers      int           `yaml:"workers"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// Lease is time task is hidden from other workers, it is extended while handler runs
+	Lease       time.Duration `yaml:"lease"`
+	MaxAttempts int           `yaml:"max_attempts"`
+	MinBackoff  time.Duration `yaml:"min_backoff"`
+	MaxBackoff  time.Duration `yaml:"max_backoff"`
+}
+
+type option = func(q *Queue) error
+
+func withDefaults() option {
+	return func(q *Queue) error {
+		q.name = "taskqueue"
+		q.cfg = Config{
+			Table:        "tasks",
+			Workers:      4,
+			PollInterval: time.Second,
+			Lease:        time.Minute,
+			MaxAttempts:  5,
+			MinBackoff:   time.Second,
+			MaxBackoff:   time.Hour,
+		}
+		q.log = l.With().Str("component", "taskqueue").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(q *Queue) error {
+		q.name = name
+		return nil
+	}
+}
+
+func WithConfig(cfg Config) option {
+	return func(q *Queue) error {
+		if cfg.Table != "" {
+			q.cfg.Table = cfg.Table
+		}
+		if cfg.Workers > 0 {
+			q.cfg.Workers = cfg.Workers
+		}
+		if cfg.PollInterval > 0 {
+			q.cfg.PollInterval = cfg.PollInterval
+		}
+		if cfg.Lease > 0 {
+			q.cfg.Lease = cfg.Lease
+		}
+		if cfg.MaxAttempts > 0 {
+			q.cfg.MaxAttempts = cfg.MaxAttempts
+		}
+		if cfg.MinBackoff > 0 {
+			q.cfg.MinBackoff = cfg.MinBackoff
+		}
+		if cfg.MaxBackoff > 0 {
+			q.cfg.MaxBackoff = cfg.MaxBackoff
+		}
+		if q.cfg.MaxBackoff < q.cfg.MinBackoff {
+			return errors.New("invalid backoff bounds")
+		}
+		return nil
+	}
+}
+
+func WithMetrics(recorder protocol.MetricsRecorder) option {
+	return func(q *Queue) error {
+		q.metrics = recorder
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(q *Queue) error {
+		q.log = log
+		return nil
+	}
+}
+
+// New creates queue storing tasks in db, tasks of queue are processed by handler between Start and Stop
+func New(db *sql.DB, queue string, handler Handler, options ...option) (*Queue, error) {
+	q := Queue{db: db, queue: queue, handler: handler}
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&q); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	q.sem = conc.NewSemaphore(int64(q.cfg.Workers))
+	return &q, nil
+}
+
+type Queue struct {
+	name    string
+	db      *sql.DB
+	queue   string
+	handler Handler
+	cfg     Config
+	metrics protocol.MetricsRecorder
+	log     zerolog.Logger
+
+	sem    *conc.Semaphore
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   context.CancelFunc
+	done   chan struct{}
+}
+
+// Querier is implemented by *sql.DB and *sql.Tx
+type Querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type enqueueOption = func(e *enqueue)
+
+type enqueue struct {
+	runAt       time.Time
+	maxAttempts int
+}
+
+// RunAt postpones task until t
+func RunAt(t time.Time) enqueueOption {
+	return func(e *enqueue) { e.runAt = t }
+}
+
+// Delay postpones task for d
+func Delay(d time.Duration) enqueueOption {
+	return func(e *enqueue) { e.runAt = time.Now().Add(d) }
+}
+
+// MaxAttempts overrides configured number of attempts for task
+func MaxAttempts(n int) enqueueOption {
+	return func(e *enqueue) { e.maxAttempts = n }
+}
+
+// Enqueue stores task returning its id, pass *sql.Tx to enqueue task transactionally
+func (q *Queue) Enqueue(ctx context.Context, db Querier, payload []byte, options ...enqueueOption) (int64, error) {
+	e := enqueue{runAt: time.Now(), maxAttempts: q.cfg.MaxAttempts}
+	for _, option := range options {
+		option(&e)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (queue, payload, max_attempts, run_at) VALUES ($1, $2, $3, $4) RETURNING id", q.cfg.Table)
+	var id int64
+	if err := db.QueryRowContext(ctx, query, q.queue, payload, e.maxAttempts, e.runAt).Scan(&id); err != nil {
+		return 0, errors.Wrap(err, "insert")
+	}
+	return id, nil
+}
+
+func (q *Queue) Start(context.Context) error {
+	if q.stop != nil {
+		return errors.New("already started")
+	}
+	// handlers keep running until Stop deadline
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+	var ctx context.Context
+	ctx, q.stop = context.WithCancel(context.Background())
+	q.done = make(chan struct{})
+	go q.poll(ctx)
+	return nil
+}
+
+// Stop stops polling and waits for running tasks. Context passed to handlers is cancelled when ctx is done,
+// results of interrupted tasks are stored right away, so they are retried with backoff like any other failure.
+func (q *Queue) Stop(ctx context.Context) error {
+	if q.stop == nil {
+		return nil
+	}
+	q.stop()
+	<-q.done
+	defer q.cancel()
+	if err := conc.WaitContext(ctx, &q.wg); err != nil {
+		q.cancel()
+		q.wg.Wait()
+		return errors.Wrap(err, "drain")
+	}
+	return nil
+}
+
+func (q *Queue) String() string { return q.name }
+
+func (q *Queue) poll(ctx context.Context) {
+	defer close(q.done)
+	for {
+		if err := q.sem.Acquire(ctx, 1); err != nil {
+			return
+		}
+		free := 1
+		for free < q.cfg.Workers && q.sem.TryAcquire(1) {
+			free++
+		}
+
+		tasks, err := q.claim(ctx, free)
+		if err != nil && ctx.Err() == nil {
+			q.log.Error().Err(err).Msg("claim")
+			q.record(MetricErrorsTotal, 1)
+		}
+		q.sem.Release(int64(free - len(tasks)))
+		for _, task := range tasks {
+			q.wg.Add(1)
+			go func() {
+				defer q.wg.Done()
+				defer q.sem.Release(1)
+				q.process(q.ctx, task)
+			}()
+		}
+
+		if len(tasks) == 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(q.cfg.PollInterval):
+			}
+		}
+	}
+}
+
+// Work claims available tasks up to number of workers and processes them, it returns number of processed tasks
+func (q *Queue) Work(ctx context.Context) (int, error) {
+	tasks, err := q.claim(ctx, q.cfg.Workers)
+	if err != nil {
+		return 0, err
+	}
+	var wg sync.WaitGroup
+	for _, task := range tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.process(ctx, task)
+		}()
+	}
+	wg.Wait()
+	return len(tasks), nil
+}
+
+func (q *Queue) claim(ctx context.Context, n int) ([]Task, error) {
+	query := fmt.Sprintf(`UPDATE %[1]s SET state = '%[2]s', attempts = attempts + 1, run_at = $1
+WHERE id IN (SELECT id FROM %[1]s WHERE queue = $2 AND state <> '%[3]s' AND run_at <= now() ORDER BY run_at LIMIT $3 FOR UPDATE SKIP LOCKED)
+RETURNING id, queue, payload, attempts, max_attempts, created_at`, q.cfg.Table, StateRunning, StateDead)
+	rows, err := q.db.QueryContext(ctx, query, time.Now().Add(q.cfg.Lease), q.queue, n)
+	if err != nil {
+		return nil, errors.Wrap(err, "update")
+	}
+	defer rows.Close()
+
+	var tasks []Task
+	for rows.Next() {
+		var t Task
+		if err := rows.Scan(&t.ID, &t.Queue, &t.Payload, &t.Attempt, &t.MaxAttempts, &t.CreatedAt); err != nil {
+			return nil, errors.Wrap(err, "scan")
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, errors.Wrap(rows.Err(), "rows")
+}
+
+func (q *Queue) process(ctx context.Context, task Task) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go q.extend(ctx, task)
+
+	start := time.Now()
+	err := q.handle(ctx, task)
+	if q.metrics != nil {
+		q.metrics.Observe(MetricTaskDuration, time.Since(start).Seconds(), "queue", q.queue)
+	}
+
+	// results are stored even if handlers are interrupted, only while task is still claimed by this attempt
+	ctx = context.WithoutCancel(ctx)
+	claimed := fmt.Sprintf("id = $1 AND state = '%s' AND attempts = $2", StateRunning)
+	var (
+		status string
+		res    sql.Result
+	)
+	switch {
+	case err == nil:
+		status = "done"
+		res, err = q.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s", q.cfg.Table, claimed), task.ID, task.Attempt)
+	case task.Attempt >= task.MaxAttempts || retry.IsPermanent(err):
+		status = "dead"
+		q.log.Error().Err(err).Int64("task_id", task.ID).Int("attempt", task.Attempt).Msg("task is dead")
+		res, err = q.db.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET state = '%s', last_error = $3 WHERE %s", q.cfg.Table, StateDead, claimed),
+			task.ID, task.Attempt, err.Error())
+	default:
+		status = "retry"
+		q.log.Warn().Err(err).Int64("task_id", task.ID).Int("attempt", task.Attempt).Msg("task failed")
+		res, err = q.db.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET state = '%s', run_at = $3, last_error = $4 WHERE %s", q.cfg.Table, StatePending, claimed),
+			task.ID, task.Attempt, time.Now().Add(q.backoff(task.Attempt)), err.Error())
+	}
+	if err == nil {
+		var n int64
+		if n, err = res.RowsAffected(); err == nil && n == 0 {
+			// lease expired and task was claimed again, result belongs to the newer attempt
+			q.log.Warn().Int64("task_id", task.ID).Int("attempt", task.Attempt).Str("status", status).Msg("lease lost")
+		}
+	}
+	if err != nil {
+		q.log.Error().Err(err).Int64("task_id", task.ID).Str("status", status).Msg("store result")
+		q.record(MetricErrorsTotal, 1)
+	}
+	if q.metrics != nil {
+		q.metrics.Add(MetricTasksTotal, 1, "queue", q.queue, "status", status)
+	}
+}
+
+func (q *Queue) handle(ctx context.Context, task Task) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			q.log.Error().Interface("panic", v).Bytes("stack", debug.Stack()).Msg("recovered")
+			err = errors.Errorf("panic: %v", v)
+		}
+	}()
+	return q.handler(ctx, task)
+}
+
+// extend keeps task hidden from other workers while it is handled
+func (q *Queue) extend(ctx context.Context, task Task) {
+	ticker := time.NewTicker(q.cfg.Lease / 2)
+	defer ticker.Stop()
+	query := fmt.Sprintf("UPDATE %s SET run_at = $3 WHERE id = $1 AND state = '%s' AND attempts = $2", q.cfg.Table, StateRunning)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := q.db.ExecContext(ctx, query, task.ID, task.Attempt, time.Now().Add(q.cfg.Lease)); err != nil && ctx.Err() == nil {
+			q.log.Warn().Err(err).Int64("task_id", task.ID).Msg("extend lease")
+		}
+	}
+}
+
+func (q *Queue) backoff(attempt int) time.Duration {
+	d := q.cfg.MinBackoff
+	for i := 1; i < attempt && d < q.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > q.cfg.MaxBackoff {
+		d = q.cfg.MaxBackoff
+	}
+	// jitter spreads retries of tasks failed together
+	return d - time.Duration(rand.Int63n(int64(d)/4+1))
+}
+
+func (q *Queue) record(name string, delta float64) {
+	if q.metrics != nil {
+		q.metrics.Add(name, delta, "queue", q.queue)
+	}
+}
@@ -0,0 +1,140 @@
+package taskqueue_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/retry"
+	"github.com/242617/core/taskqueue"
+)
+
+var period = 10 * time.Millisecond
+
+var columns = []string{"id", "queue", "payload", "attempts", "max_attempts", "created_at"}
+
+func TestEnqueue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "new mock")
+	defer db.Close()
+
+	q, err := taskqueue.New(db, "emails", nil)
+	require.NoError(t, err, "new")
+
+	runAt := time.Now().Add(time.Hour)
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO tasks (queue, payload, max_attempts, run_at) VALUES ($1, $2, $3, $4) RETURNING id")).
+		WithArgs("emails", []byte("hello"), 2, runAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	id, err := q.Enqueue(context.Background(), db, []byte("hello"), taskqueue.RunAt(runAt), taskqueue.MaxAttempts(2))
+	require.NoError(t, err, "enqueue")
+	assert.Equal(t, int64(7), id, "id")
+	assert.NoError(t, mock.ExpectationsWereMet(), "expectations")
+}
+
+func TestWork(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "new mock")
+	defer db.Close()
+	mock.MatchExpectationsInOrder(false)
+
+	q, err := taskqueue.New(db, "emails", func(_ context.Context, task taskqueue.Task) error {
+		switch string(task.Payload) {
+		case "fail":
+			return errors.New("temporary")
+		case "invalid":
+			return retry.Permanent(errors.New("invalid payload"))
+		case "panic":
+			panic("boom")
+		}
+		return nil
+	})
+	require.NoError(t, err, "new")
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE tasks SET state = 'running', attempts = attempts + 1, run_at = $1")).
+		WithArgs(sqlmock.AnyArg(), "emails", 4).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, "emails", []byte("ok"), 1, 5, now).
+			AddRow(2, "emails", []byte("fail"), 1, 5, now).
+			AddRow(3, "emails", []byte("panic"), 5, 5, now).
+			AddRow(4, "emails", []byte("invalid"), 1, 5, now))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM tasks WHERE id = $1 AND state = 'running' AND attempts = $2")).
+		WithArgs(1, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE tasks SET state = 'pending', run_at = $3, last_error = $4 WHERE id = $1 AND state = 'running' AND attempts = $2")).
+		WithArgs(2, 1, sqlmock.AnyArg(), "temporary").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE tasks SET state = 'dead', last_error = $3 WHERE id = $1 AND state = 'running' AND attempts = $2")).
+		WithArgs(3, 5, "panic: boom").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE tasks SET state = 'dead', last_error = $3 WHERE id = $1 AND state = 'running' AND attempts = $2")).
+		WithArgs(4, 1, "invalid payload").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	n, err := q.Work(context.Background())
+	require.NoError(t, err, "work")
+	assert.Equal(t, 4, n, "processed")
+	assert.NoError(t, mock.ExpectationsWereMet(), "expectations")
+}
+
+func TestLeaseLost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "new mock")
+	defer db.Close()
+
+	q, err := taskqueue.New(db, "emails", func(context.Context, taskqueue.Task) error {
+		return errors.New("temporary")
+	})
+	require.NoError(t, err, "new")
+
+	// task was claimed again by other worker, so stale result matches no rows
+	mock.ExpectQuery("UPDATE tasks SET state = 'running'").
+		WithArgs(sqlmock.AnyArg(), "emails", 4).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "emails", []byte("slow"), 2, 5, time.Now()))
+	mock.ExpectExec(regexp.QuoteMeta("WHERE id = $1 AND state = 'running' AND attempts = $2")).
+		WithArgs(1, 2, sqlmock.AnyArg(), "temporary").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	n, err := q.Work(context.Background())
+	require.NoError(t, err, "work")
+	assert.Equal(t, 1, n, "processed")
+	assert.NoError(t, mock.ExpectationsWereMet(), "expectations")
+}
+
+func TestStartStop(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err, "new mock")
+	defer db.Close()
+
+	done := make(chan struct{})
+	q, err := taskqueue.New(db, "emails", func(context.Context, taskqueue.Task) error {
+		close(done)
+		return nil
+	}, taskqueue.WithConfig(taskqueue.Config{Workers: 1, PollInterval: 10 * period}))
+	require.NoError(t, err, "new")
+
+	mock.ExpectQuery("UPDATE tasks SET state = 'running'").
+		WithArgs(sqlmock.AnyArg(), "emails", 1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "emails", []byte("ok"), 1, 5, time.Now()))
+	mock.ExpectExec("DELETE FROM tasks").WithArgs(1, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("UPDATE tasks SET state = 'running'").
+		WithArgs(sqlmock.AnyArg(), "emails", 1).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	require.NoError(t, q.Start(context.Background()), "start")
+	select {
+	case <-done:
+	case <-time.After(10 * period):
+		t.Fatal("task is not handled")
+	}
+	time.Sleep(period / 2)
+	require.NoError(t, q.Stop(context.Background()), "stop")
+	assert.NoError(t, mock.ExpectationsWereMet(), "expectations")
+}
@@ -0,0 +1,224 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// PolicyDefault uses cipher suites chosen by Go
+	PolicyDefault = "default"
+	// PolicyIntermediate allows only ECDHE AEAD cipher suites for TLS 1.2
+	PolicyIntermediate = "intermediate"
+	// PolicyModern requires TLS 1.3
+	PolicyModern = "modern"
+)
+
+const (
+	ClientAuthNone    = "none"
+	ClientAuthRequest = "request"
+	ClientAuthRequire = "require"
+	ClientAuthVerify  = "verify"
+)
+
+// Config is a TLS configuration suitable for config.Scan.
+// Certificates and CA are read either from files or from inline PEM.
+type Config struct {
+	CAFile   string `yaml:"ca_file"`
+	CA       string `yaml:"ca"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	Cert     string `yaml:"cert"`
+	Key      string `yaml:"key"`
+	// MinVersion is one of 1.0, 1.1, 1.2 and 1.3
+	MinVersion   string `yaml:"min_version" default:"1.2"`
+	CipherPolicy string `yaml:"cipher_policy" default:"default"`
+	// ClientAuth is server policy for client certificates: none, request, require or verify
+	ClientAuth         string `yaml:"client_auth" default:"none"`
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	// ReloadInterval enables checking certificate files for changes on handshakes
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+}
+
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var clientAuths = map[string]tls.ClientAuthType{
+	ClientAuthNone:    tls.NoClientCert,
+	ClientAuthRequest: tls.RequestClientCert,
+	ClientAuthRequire: tls.RequireAnyClientCert,
+	ClientAuthVerify:  tls.RequireAndVerifyClientCert,
+}
+
+var intermediateSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// Server builds config for servers, certificate is required
+func (c Config) Server() (*tls.Config, error) {
+	cfg, err := c.base()
+	if err != nil {
+		return nil, err
+	}
+	certs, err := c.certificates()
+	if err != nil {
+		return nil, err
+	}
+	if certs == nil {
+		return nil, errors.New("certificate is required")
+	}
+	cfg.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return certs.get() }
+
+	auth, ok := clientAuths[strings.ToLower(c.ClientAuth)]
+	if c.ClientAuth != "" && !ok {
+		return nil, errors.Errorf("unknown client auth %q", c.ClientAuth)
+	}
+	cfg.ClientAuth = auth
+	if cfg.RootCAs != nil {
+		cfg.ClientCAs, cfg.RootCAs = cfg.RootCAs, nil
+	}
+	return cfg, nil
+}
+
+// Client builds config for clients, certificate is optional and used for mutual TLS
+func (c Config) Client() (*tls.Config, error) {
+	cfg, err := c.base()
+	if err != nil {
+		return nil, err
+	}
+	certs, err := c.certificates()
+	if err != nil {
+		return nil, err
+	}
+	if certs != nil {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) { return certs.get() }
+	}
+	cfg.ServerName = c.ServerName
+	cfg.InsecureSkipVerify = c.InsecureSkipVerify
+	return cfg, nil
+}
+
+func (c Config) base() (*tls.Config, error) {
+	cfg := tls.Config{MinVersion: tls.VersionTLS12}
+	if c.MinVersion != "" {
+		v, ok := versions[c.MinVersion]
+		if !ok {
+			return nil, errors.Errorf("unknown min version %q", c.MinVersion)
+		}
+		cfg.MinVersion = v
+	}
+
+	switch strings.ToLower(c.CipherPolicy) {
+	case "", PolicyDefault:
+	case PolicyIntermediate:
+		cfg.CipherSuites = intermediateSuites
+	case PolicyModern:
+		cfg.MinVersion = tls.VersionTLS13
+	default:
+		return nil, errors.Errorf("unknown cipher policy %q", c.CipherPolicy)
+	}
+
+	ca := []byte(c.CA)
+	if c.CAFile != "" {
+		var err error
+		if ca, err = os.ReadFile(c.CAFile); err != nil {
+			return nil, errors.Wrap(err, "read ca")
+		}
+	}
+	if len(ca) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("no certificates in ca")
+		}
+		cfg.RootCAs = pool
+	}
+	return &cfg, nil
+}
+
+func (c Config) certificates() (*certificate, error) {
+	switch {
+	case c.CertFile != "" || c.KeyFile != "":
+		certs := certificate{certFile: c.CertFile, keyFile: c.KeyFile, interval: c.ReloadInterval}
+		if err := certs.load(); err != nil {
+			return nil, err
+		}
+		return &certs, nil
+	case c.Cert != "" || c.Key != "":
+		cert, err := tls.X509KeyPair([]byte(c.Cert), []byte(c.Key))
+		if err != nil {
+			return nil, errors.Wrap(err, "parse key pair")
+		}
+		return &certificate{cert: &cert}, nil
+	}
+	return nil, nil
+}
+
+// certificate reloads key pair once files are modified
+type certificate struct {
+	certFile, keyFile string
+	interval          time.Duration
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+func (c *certificate) get() (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.interval > 0 && time.Since(c.checked) >= c.interval {
+		c.checked = time.Now()
+		if modTime, err := c.stat(); err == nil && modTime.After(c.modTime) {
+			// previous certificate is served until files are consistent
+			if cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile); err == nil {
+				c.cert, c.modTime = &cert, modTime
+			}
+		}
+	}
+	return c.cert, nil
+}
+
+func (c *certificate) load() error {
+	modTime, err := c.stat()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "load key pair")
+	}
+	c.cert, c.modTime, c.checked = &cert, modTime, time.Now()
+	return nil
+}
+
+func (c *certificate) stat() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, errors.Wrap(err, "stat")
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
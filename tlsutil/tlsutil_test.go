@@ -0,0 +1,161 @@
+package tlsutil_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/tlsutil"
+)
+
+var period = 10 * time.Millisecond
+
+type pair struct {
+	cert, key []byte
+	x509      *x509.Certificate
+}
+
+// issue creates certificate signed by parent or self-signed one if parent is nil
+func issue(t *testing.T, name string, parent *pair, ca bool) *pair {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "generate key")
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err, "serial")
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  ca,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, any(key)
+	if parent != nil {
+		block, _ := pem.Decode(parent.key)
+		parentKey, err := x509.ParseECPrivateKey(block.Bytes)
+		require.NoError(t, err, "parse parent key")
+		signer, signerKey = parent.x509, parentKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err, "create certificate")
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err, "parse certificate")
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err, "marshal key")
+	return &pair{
+		cert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		key:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		x509: cert,
+	}
+}
+
+// handshake accepts single connection and returns peer certificate seen by client
+func handshake(t *testing.T, server, client *tls.Config) (*x509.Certificate, error) {
+	l, err := tls.Listen("tcp", "127.0.0.1:0", server)
+	require.NoError(t, err, "listen")
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.(*tls.Conn).Handshake()
+	}()
+
+	conn, err := tls.Dial("tcp", l.Addr().String(), client)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0], nil
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := issue(t, "ca", nil, true)
+	server := issue(t, "localhost", ca, false)
+	client := issue(t, "client", ca, false)
+
+	serverCfg, err := tlsutil.Config{
+		CA:         string(ca.cert),
+		Cert:       string(server.cert),
+		Key:        string(server.key),
+		ClientAuth: tlsutil.ClientAuthVerify,
+	}.Server()
+	require.NoError(t, err, "server config")
+
+	clientCfg, err := tlsutil.Config{
+		CA:         string(ca.cert),
+		Cert:       string(client.cert),
+		Key:        string(client.key),
+		ServerName: "localhost",
+	}.Client()
+	require.NoError(t, err, "client config")
+
+	peer, err := handshake(t, serverCfg, clientCfg)
+	require.NoError(t, err, "handshake")
+	assert.Equal(t, "localhost", peer.Subject.CommonName, "server certificate")
+
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	write := func(p *pair, modTime time.Time) {
+		require.NoError(t, os.WriteFile(certFile, p.cert, 0o600), "write cert")
+		require.NoError(t, os.WriteFile(keyFile, p.key, 0o600), "write key")
+		require.NoError(t, os.Chtimes(certFile, modTime, modTime), "touch cert")
+		require.NoError(t, os.Chtimes(keyFile, modTime, modTime), "touch key")
+	}
+	first, second := issue(t, "first", nil, false), issue(t, "second", nil, false)
+	write(first, time.Now().Add(-time.Minute))
+
+	serverCfg, err := tlsutil.Config{CertFile: certFile, KeyFile: keyFile, ReloadInterval: period}.Server()
+	require.NoError(t, err, "server config")
+	clientCfg, err := tlsutil.Config{InsecureSkipVerify: true}.Client()
+	require.NoError(t, err, "client config")
+
+	peer, err := handshake(t, serverCfg, clientCfg)
+	require.NoError(t, err, "handshake")
+	assert.Equal(t, "first", peer.Subject.CommonName, "initial certificate")
+
+	write(second, time.Now())
+	time.Sleep(2 * period)
+	peer, err = handshake(t, serverCfg, clientCfg)
+	require.NoError(t, err, "handshake")
+	assert.Equal(t, "second", peer.Subject.CommonName, "reloaded certificate")
+}
+
+func TestValidation(t *testing.T) {
+	for name, cfg := range map[string]tlsutil.Config{
+		"min version":   {MinVersion: "2.0"},
+		"cipher policy": {CipherPolicy: "weak"},
+		"ca":            {CA: "not a pem"},
+		"ca file":       {CAFile: "/nonexistent"},
+		"key pair":      {Cert: "x", Key: "y"},
+	} {
+		_, err := cfg.Client()
+		assert.Error(t, err, name)
+	}
+	_, err := tlsutil.Config{}.Server()
+	assert.Error(t, err, "server requires certificate")
+
+	cfg, err := tlsutil.Config{CipherPolicy: tlsutil.PolicyModern}.Client()
+	require.NoError(t, err, "modern policy")
+	assert.Equal(t, uint16(tls.VersionTLS13), cfg.MinVersion, "tls 1.3 required")
+}
@@ -0,0 +1,40 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/242617/core/protocol"
+)
+
+type tracer struct{ tracer trace.Tracer }
+
+func (t *tracer) Start(ctx context.Context, name string, attrs ...string) (context.Context, protocol.Span) {
+	ctx, s := t.tracer.Start(ctx, name, trace.WithAttributes(keyValues(attrs)...))
+	return ctx, &span{s}
+}
+
+type span struct{ span trace.Span }
+
+func (s *span) SetAttributes(attrs ...string) { s.span.SetAttributes(keyValues(attrs)...) }
+
+func (s *span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s *span) End() { s.span.End() }
+
+func keyValues(attrs []string) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs)/2)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		kvs = append(kvs, attribute.String(attrs[i], attrs[i+1]))
+	}
+	return kvs
+}
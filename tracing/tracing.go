@@ -0,0 +1,175 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+
+	"github.com/242617/core/application"
+	"github.com/242617/core/protocol"
+)
+
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// Config is a tracing configuration suitable for config.Scan
+type Config struct {
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	Protocol    string  `yaml:"protocol" env:"OTEL_EXPORTER_OTLP_PROTOCOL" default:"grpc"`
+	Insecure    bool    `yaml:"insecure" env:"OTEL_EXPORTER_OTLP_INSECURE"`
+	SampleRatio float64 `yaml:"sample_ratio" default:"1"`
+}
+
+type option = func(t *Tracing) error
+
+func withDefaults() option {
+	return func(t *Tracing) error {
+		t.name = "tracing"
+		t.cfg = Config{Protocol: ProtocolGRPC, SampleRatio: 1}
+		t.serviceName = application.Name
+		t.log = l.With().Str("component", "tracing").Logger()
+		return nil
+	}
+}
+
+func WithConfig(cfg Config) option {
+	return func(t *Tracing) error {
+		t.cfg = cfg
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(t *Tracing) error {
+		t.name = name
+		return nil
+	}
+}
+
+// WithService sets service name and version resource attributes, application name is used by default
+func WithService(name, version string) option {
+	return func(t *Tracing) error {
+		t.serviceName, t.serviceVersion = name, version
+		return nil
+	}
+}
+
+// WithExporter replaces OTLP exporter
+func WithExporter(exporter sdktrace.SpanExporter) option {
+	return func(t *Tracing) error {
+		t.exporter = exporter
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(t *Tracing) error {
+		t.log = log
+		return nil
+	}
+}
+
+// New creates tracing component. Tracer provider is installed globally on Start and flushed on Stop.
+func New(options ...option) (*Tracing, error) {
+	var t Tracing
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&t); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+
+	if t.exporter == nil {
+		exporter, err := newExporter(t.cfg)
+		if err != nil {
+			return nil, errors.Wrap(err, "new exporter")
+		}
+		t.exporter = exporter
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(t.serviceName),
+		semconv.ServiceVersion(t.serviceVersion),
+		semconv.HostName(application.Hostname),
+	))
+	if err != nil {
+		return nil, errors.Wrap(err, "new resource")
+	}
+
+	t.provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(t.exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(t.cfg.SampleRatio))),
+	)
+	return &t, nil
+}
+
+type Tracing struct {
+	name                        string
+	cfg                         Config
+	serviceName, serviceVersion string
+	exporter                    sdktrace.SpanExporter
+	provider                    *sdktrace.TracerProvider
+	log                         zerolog.Logger
+}
+
+func newExporter(cfg Config) (sdktrace.SpanExporter, error) {
+	ctx := context.Background()
+	switch cfg.Protocol {
+	case ProtocolGRPC, "":
+		var options []otlptracegrpc.Option
+		if cfg.Endpoint != "" {
+			options = append(options, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, options...)
+	case ProtocolHTTP:
+		var options []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, options...)
+	default:
+		return nil, errors.Errorf("unknown protocol %q", cfg.Protocol)
+	}
+}
+
+// Tracer returns named tracer
+func (t *Tracing) Tracer(name string) protocol.Tracer {
+	return &tracer{t.provider.Tracer(name)}
+}
+
+// Provider returns underlying tracer provider for instrumentation libraries
+func (t *Tracing) Provider() *sdktrace.TracerProvider { return t.provider }
+
+func (t *Tracing) Start(context.Context) error {
+	otel.SetTracerProvider(t.provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	t.log.Info().Msgf("tracing %s", t.serviceName)
+	return nil
+}
+
+func (t *Tracing) Stop(ctx context.Context) error {
+	if err := t.provider.Shutdown(ctx); err != nil {
+		return errors.Wrap(err, "shutdown")
+	}
+	return nil
+}
+
+func (t *Tracing) String() string { return t.name }
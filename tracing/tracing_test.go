@@ -0,0 +1,64 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/242617/core/config"
+	"github.com/242617/core/tracing"
+)
+
+func TestBasic(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tr, err := tracing.New(
+		tracing.WithExporter(exporter),
+		tracing.WithService("sample", "1.0.0"),
+	)
+	require.NoError(t, err, "new tracing")
+	require.NoError(t, tr.Start(context.Background()), "start tracing")
+
+	tracer := tr.Tracer("test")
+	ctx, parent := tracer.Start(context.Background(), "parent", "key", "value")
+	_, child := tracer.Start(ctx, "child")
+	sampleErr := errors.New("sample error")
+	child.RecordError(sampleErr)
+	child.End()
+	parent.End()
+
+	require.NoError(t, tr.Provider().ForceFlush(context.Background()), "flush")
+	spans := exporter.GetSpans()
+	require.NoError(t, tr.Stop(context.Background()), "stop tracing")
+
+	require.Len(t, spans, 2, "two spans")
+	assert.Equal(t, "child", spans[0].Name, "child span")
+	assert.Equal(t, codes.Error, spans[0].Status.Code, "child error")
+	assert.Equal(t, spans[1].SpanContext.TraceID(), spans[0].Parent.TraceID(), "same trace")
+	assert.Equal(t, "parent", spans[1].Name, "parent span")
+	assert.Contains(t, spans[1].Attributes, attribute.String("key", "value"), "parent attributes")
+	assert.Contains(t, spans[1].Resource.Attributes(), attribute.String("service.name", "sample"), "service name")
+	assert.Contains(t, spans[1].Resource.Attributes(), attribute.String("service.version", "1.0.0"), "service version")
+}
+
+func TestConfig(t *testing.T) {
+	var cfg tracing.Config
+	require.NoError(t, config.New().Scan(&cfg), "scan config")
+	assert.Equal(t, tracing.ProtocolGRPC, cfg.Protocol, "default protocol")
+	assert.Equal(t, 1.0, cfg.SampleRatio, "default sample ratio")
+
+	for _, protocol := range []string{tracing.ProtocolGRPC, tracing.ProtocolHTTP} {
+		cfg.Protocol = protocol
+		_, err := tracing.New(tracing.WithConfig(cfg))
+		assert.NoError(t, err, "protocol %s", protocol)
+	}
+
+	cfg.Protocol = "unknown"
+	_, err := tracing.New(tracing.WithConfig(cfg))
+	assert.Error(t, err, "unknown protocol")
+}
@@ -0,0 +1,23 @@
+package validate
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/242617/core/errors"
+)
+
+// MaxBodySize limits size of body decoded by Bind
+var MaxBodySize int64 = 1 << 20
+
+// Bind decodes json body of request into v and validates it.
+// Malformed body and failed validation are reported with errors.Invalid code.
+func Bind(r *http.Request, v any) error {
+	d := json.NewDecoder(io.LimitReader(r.Body, MaxBodySize))
+	d.DisallowUnknownFields()
+	if err := d.Decode(v); err != nil {
+		return errors.Wrap(err, errors.Invalid, "malformed body")
+	}
+	return Struct(v)
+}
@@ -0,0 +1,126 @@
+package validate
+
+import (
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	Register("required", required)
+	Register("min", bound(func(n, limit float64) bool { return n >= limit }, "must be at least %s", "length must be at least %s"))
+	Register("max", bound(func(n, limit float64) bool { return n <= limit }, "must be at most %s", "length must be at most %s"))
+	Register("len", bound(func(n, limit float64) bool { return n == limit }, "must be equal to %s", "length must be %s"))
+	Register("oneof", oneOf)
+	Register("email", stringRule(func(s string) bool {
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	}, "must be valid email"))
+	Register("url", stringRule(func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != "" && u.Host != ""
+	}, "must be valid url"))
+	Register("hostport", stringRule(func(s string) bool {
+		_, port, err := net.SplitHostPort(s)
+		return err == nil && port != ""
+	}, "must be host:port"))
+}
+
+func required(v reflect.Value, _ string) error {
+	if v.IsZero() || ((v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0) {
+		return errors.New("is required")
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// bound compares numbers with param, strings, slices and maps by their length
+func bound(ok func(n, limit float64) bool, valueMsg, lenMsg string) Rule {
+	return func(v reflect.Value, param string) error {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+
+		var (
+			n, limit float64
+			msg      = valueMsg
+			err      error
+		)
+		switch v.Kind() {
+		case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+			n, msg = float64(v.Len()), lenMsg
+			if v.Kind() == reflect.String {
+				n = float64(len([]rune(v.String())))
+			}
+			limit, err = strconv.ParseFloat(param, 64)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = float64(v.Int())
+			if v.Type() == durationType {
+				var d time.Duration
+				d, err = time.ParseDuration(param)
+				limit = float64(d)
+			} else {
+				limit, err = strconv.ParseFloat(param, 64)
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n = float64(v.Uint())
+			limit, err = strconv.ParseFloat(param, 64)
+		case reflect.Float32, reflect.Float64:
+			n = v.Float()
+			limit, err = strconv.ParseFloat(param, 64)
+		default:
+			return errors.Errorf("unsupported kind %q", v.Kind())
+		}
+		if err != nil {
+			return errors.Errorf("invalid parameter %q", param)
+		}
+		if !ok(n, limit) {
+			return errors.Errorf(msg, param)
+		}
+		return nil
+	}
+}
+
+func oneOf(v reflect.Value, param string) error {
+	options := strings.Fields(param)
+	var s string
+	switch v.Kind() {
+	case reflect.String:
+		s = v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = strconv.FormatUint(v.Uint(), 10)
+	default:
+		return errors.Errorf("unsupported kind %q", v.Kind())
+	}
+	for _, option := range options {
+		if s == option {
+			return nil
+		}
+	}
+	return errors.Errorf("must be one of %s", strings.Join(options, ", "))
+}
+
+// stringRule checks non-empty strings, use required to reject empty ones
+func stringRule(ok func(string) bool, msg string) Rule {
+	return func(v reflect.Value, _ string) error {
+		if v.Kind() != reflect.String {
+			return errors.Errorf("unsupported kind %q", v.Kind())
+		}
+		if s := v.String(); s != "" && !ok(s) {
+			return errors.New(msg)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,206 @@
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/242617/core/errors"
+)
+
+// Rule checks value against tag parameter, returned error is used as field error message
+type Rule func(v reflect.Value, param string) error
+
+// Validator is implemented by types with validation logic beyond tags
+type Validator interface {
+	Validate() error
+}
+
+var (
+	mu    sync.RWMutex
+	rules = map[string]Rule{}
+)
+
+// Register adds rule usable in validate tags, it replaces rule with the same name
+func Register(name string, rule Rule) {
+	mu.Lock()
+	defer mu.Unlock()
+	rules[name] = rule
+}
+
+func lookup(name string) (Rule, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	rule, ok := rules[name]
+	return rule, ok
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string { return e.Field + ": " + e.Message }
+
+// Errors aggregates errors of all fields
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Struct validates fields by validate tags, e.g. `validate:"required,max=64"`,
+// and calls Validate of values implementing Validator.
+// Failures are returned as Errors wrapped with errors.Invalid code.
+func Struct(v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return errors.New(errors.Invalid, "nil value")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return errors.Newf(errors.Internal, "unexpected kind: %q", rv.Kind())
+	}
+
+	var errs Errors
+	if err := walk(rv, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errors.Wrap(errs, errors.Invalid, "validation failed")
+	}
+	return nil
+}
+
+func walk(v reflect.Value, path string, errs *Errors) error {
+	if err := custom(v, path, errs); err != nil {
+		return err
+	}
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		tf := t.Field(i)
+		if !tf.IsExported() {
+			continue
+		}
+		field := join(path, name(tf))
+		vf := v.Field(i)
+
+		if tag := tf.Tag.Get("validate"); tag != "" && tag != "-" {
+			if err := check(vf, field, tag, errs); err != nil {
+				return err
+			}
+		}
+		if err := nested(vf, field, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nested descends into structs, pointers to them and their slices
+func nested(v reflect.Value, path string, errs *Errors) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return nested(v.Elem(), path, errs)
+	case reflect.Struct:
+		if v.Type().PkgPath() == "time" {
+			return nil
+		}
+		return walk(v, path, errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := nested(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := nested(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key()), errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func custom(v reflect.Value, path string, errs *Errors) error {
+	var validator Validator
+	if v.CanAddr() {
+		validator, _ = v.Addr().Interface().(Validator)
+	}
+	if validator == nil {
+		validator, _ = v.Interface().(Validator)
+	}
+	if validator == nil {
+		return nil
+	}
+	if err := validator.Validate(); err != nil {
+		var fields Errors
+		if errors.As(err, &fields) {
+			for _, fe := range fields {
+				fe.Field = join(path, fe.Field)
+				*errs = append(*errs, fe)
+			}
+			return nil
+		}
+		field := path
+		if field == "" {
+			field = "."
+		}
+		*errs = append(*errs, FieldError{Field: field, Rule: "custom", Message: err.Error()})
+	}
+	return nil
+}
+
+func check(v reflect.Value, field, tag string, errs *Errors) error {
+	for _, rule := range strings.Split(tag, ",") {
+		ruleName, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if ruleName == "omitempty" {
+			if v.IsZero() {
+				return nil
+			}
+			continue
+		}
+		fn, ok := lookup(ruleName)
+		if !ok {
+			return errors.Newf(errors.Internal, "unknown rule %q of field %s", ruleName, field)
+		}
+		if err := fn(v, param); err != nil {
+			*errs = append(*errs, FieldError{Field: field, Rule: ruleName, Message: err.Error()})
+			// the rest rules are meaningless for missing value
+			if ruleName == "required" {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// name returns field name as seen by clients: json or yaml tag name, otherwise Go name
+func name(f reflect.StructField) string {
+	for _, key := range []string{"json", "yaml"} {
+		if n, _, _ := strings.Cut(f.Tag.Get(key), ","); n != "" && n != "-" {
+			return n
+		}
+	}
+	return f.Name
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
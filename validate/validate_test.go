@@ -0,0 +1,129 @@
+package validate_test
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	coreerrors "github.com/242617/core/errors"
+	"github.com/242617/core/validate"
+)
+
+type Address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type User struct {
+	Name      string        `json:"name" validate:"required,min=2,max=8"`
+	Email     string        `json:"email" validate:"omitempty,email"`
+	Age       int           `json:"age" validate:"min=18"`
+	Role      string        `json:"role" validate:"oneof=admin user"`
+	Timeout   time.Duration `yaml:"timeout" validate:"max=1m"`
+	Addresses []Address     `json:"addresses"`
+	Home      *Address      `json:"home"`
+}
+
+type Range struct {
+	From, To int
+}
+
+func (r Range) Validate() error {
+	if r.From > r.To {
+		return errors.New("from must not exceed to")
+	}
+	return nil
+}
+
+func fields(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var errs validate.Errors
+	require.True(t, errors.As(err, &errs), "field errors")
+	res := map[string]string{}
+	for _, fe := range errs {
+		res[fe.Field] = fe.Rule
+	}
+	return res
+}
+
+func TestStruct(t *testing.T) {
+	valid := User{Name: "ivan", Email: "ivan@example.com", Age: 20, Role: "user", Timeout: time.Second}
+	assert.NoError(t, validate.Struct(&valid), "valid")
+	assert.NoError(t, validate.Struct(valid), "by value")
+
+	err := validate.Struct(&User{
+		Name:      "i",
+		Email:     "ivan",
+		Age:       17,
+		Role:      "root",
+		Timeout:   time.Hour,
+		Addresses: []Address{{City: "Moscow"}, {}},
+		Home:      &Address{},
+	})
+	require.Error(t, err, "invalid")
+	assert.Equal(t, coreerrors.Invalid, coreerrors.CodeOf(err), "code")
+	assert.Equal(t, map[string]string{
+		"name":              "min",
+		"email":             "email",
+		"age":               "min",
+		"role":              "oneof",
+		"timeout":           "max",
+		"addresses[1].city": "required",
+		"home.city":         "required",
+	}, fields(t, err), "fields")
+}
+
+func TestRequired(t *testing.T) {
+	err := validate.Struct(&struct {
+		Name string   `validate:"required,min=2"`
+		Tags []string `validate:"required"`
+	}{Tags: []string{}})
+	assert.Equal(t, map[string]string{"Name": "required", "Tags": "required"}, fields(t, err), "skip rest rules")
+}
+
+func TestValidator(t *testing.T) {
+	assert.NoError(t, validate.Struct(&Range{From: 1, To: 2}), "valid")
+	assert.Equal(t, map[string]string{".": "custom"}, fields(t, validate.Struct(&Range{From: 2, To: 1})), "top level")
+
+	err := validate.Struct(&struct {
+		Range Range `json:"range"`
+	}{Range{From: 2, To: 1}})
+	assert.Equal(t, map[string]string{"range": "custom"}, fields(t, err), "nested")
+}
+
+func TestRegister(t *testing.T) {
+	validate.Register("upper", func(v reflect.Value, _ string) error {
+		if s := v.String(); s != strings.ToUpper(s) {
+			return errors.New("must be upper case")
+		}
+		return nil
+	})
+	type Code struct {
+		Value string `validate:"upper"`
+	}
+	assert.NoError(t, validate.Struct(&Code{"RU"}), "valid")
+	assert.Equal(t, map[string]string{"Value": "upper"}, fields(t, validate.Struct(&Code{"ru"})), "invalid")
+
+	err := validate.Struct(&struct {
+		Value string `validate:"unknown"`
+	}{})
+	assert.Equal(t, coreerrors.Internal, coreerrors.CodeOf(err), "unknown rule")
+}
+
+func TestBind(t *testing.T) {
+	var u User
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"ivan","age":30,"role":"admin"}`))
+	require.NoError(t, validate.Bind(r, &u), "bind")
+	assert.Equal(t, "ivan", u.Name, "decoded")
+
+	r = httptest.NewRequest("POST", "/", strings.NewReader(`{"name":`))
+	assert.Equal(t, coreerrors.Invalid, coreerrors.CodeOf(validate.Bind(r, &u)), "malformed")
+
+	r = httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"ivan","age":1,"role":"admin"}`))
+	assert.Equal(t, map[string]string{"age": "min"}, fields(t, validate.Bind(r, &User{})), "invalid")
+}
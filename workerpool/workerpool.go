@@ -0,0 +1,237 @@
+package workerpool
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/protocol"
+)
+
+const (
+	MetricQueueDepth = "workerpool_queue_depth"
+	MetricTasksTotal = "workerpool_tasks_total"
+)
+
+var (
+	ErrStopped   = errors.New("pool is stopped")
+	ErrQueueFull = errors.New("queue is full")
+)
+
+type Task = func(context.Context)
+
+type option = func(p *Pool) error
+
+func withDefaults() option {
+	return func(p *Pool) error {
+		p.name = "workerpool"
+		p.workers, p.queueSize = 4, 64
+		p.log = l.With().Str("component", "workerpool").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(p *Pool) error {
+		p.name = name
+		return nil
+	}
+}
+
+func WithWorkers(workers int) option {
+	return func(p *Pool) error {
+		if workers < 1 {
+			return errors.New("workers must be positive")
+		}
+		p.workers = workers
+		return nil
+	}
+}
+
+// WithQueueSize sets number of tasks waiting for a free worker
+func WithQueueSize(size int) option {
+	return func(p *Pool) error {
+		if size < 0 {
+			return errors.New("queue size must not be negative")
+		}
+		p.queueSize = size
+		return nil
+	}
+}
+
+func WithMetrics(recorder protocol.MetricsRecorder) option {
+	return func(p *Pool) error {
+		p.metrics = recorder
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(p *Pool) error {
+		p.log = log
+		return nil
+	}
+}
+
+// New creates pool of workers executing submitted tasks. Workers run between Start and Stop.
+func New(options ...option) (*Pool, error) {
+	var p Pool
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&p); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	return &p, nil
+}
+
+type Pool struct {
+	name               string
+	workers, queueSize int
+	metrics            protocol.MetricsRecorder
+	log                zerolog.Logger
+
+	mu      sync.RWMutex
+	queue   chan Task
+	started bool
+	// done is closed by Stop to release Submit waiting for free space in queue
+	done    chan struct{}
+	senders sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func (p *Pool) Start(context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.started {
+		return errors.New("already started")
+	}
+	p.queue, p.done = make(chan Task, p.queueSize), make(chan struct{})
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	p.started = true
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return nil
+}
+
+// Stop stops accepting tasks and waits for queued and running ones to complete.
+// Context passed to tasks is cancelled when ctx is done.
+func (p *Pool) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.started {
+		p.mu.Unlock()
+		return nil
+	}
+	p.started = false
+	close(p.done)
+	p.mu.Unlock()
+
+	// queue is closed once no Submit may send to it
+	p.senders.Wait()
+	close(p.queue)
+
+	doneCh := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(doneCh)
+	}()
+
+	select {
+	case <-ctx.Done():
+		p.cancel()
+		<-doneCh
+		return errors.Wrap(ctx.Err(), "drain")
+	case <-doneCh:
+		p.cancel()
+	}
+	return nil
+}
+
+// Submit queues task waiting for free space in queue until ctx is done or pool is stopped
+func (p *Pool) Submit(ctx context.Context, task Task) error {
+	p.mu.RLock()
+	if !p.started {
+		p.mu.RUnlock()
+		return ErrStopped
+	}
+	queue, done := p.queue, p.done
+	p.senders.Add(1)
+	p.mu.RUnlock()
+	defer p.senders.Done()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return ErrStopped
+	case queue <- task:
+	}
+	p.record()
+	return nil
+}
+
+// TrySubmit queues task if there is free space in queue
+func (p *Pool) TrySubmit(task Task) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if !p.started {
+		return ErrStopped
+	}
+	select {
+	case p.queue <- task:
+	default:
+		return ErrQueueFull
+	}
+	p.record()
+	return nil
+}
+
+// QueueDepth returns number of tasks waiting for a free worker
+func (p *Pool) QueueDepth() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.queue)
+}
+
+func (p *Pool) String() string { return p.name }
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for task := range p.queue {
+		p.record()
+		status := "ok"
+		if !p.execute(task) {
+			status = "panic"
+		}
+		if p.metrics != nil {
+			p.metrics.Add(MetricTasksTotal, 1, "pool", p.name, "status", status)
+		}
+	}
+}
+
+func (p *Pool) execute(task Task) (ok bool) {
+	defer func() {
+		if v := recover(); v != nil {
+			p.log.Error().Interface("panic", v).Bytes("stack", debug.Stack()).Msg("recovered")
+			ok = false
+		}
+	}()
+	task(p.ctx)
+	return true
+}
+
+func (p *Pool) record() {
+	if p.metrics != nil {
+		p.metrics.Set(MetricQueueDepth, float64(len(p.queue)), "pool", p.name)
+	}
+}
@@ -0,0 +1,120 @@
+package workerpool_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/workerpool"
+)
+
+var period = 10 * time.Millisecond
+
+func TestBasic(t *testing.T) {
+	p, err := workerpool.New(workerpool.WithWorkers(2), workerpool.WithQueueSize(10))
+	require.NoError(t, err, "new pool")
+	assert.ErrorIs(t, p.Submit(context.Background(), func(context.Context) {}), workerpool.ErrStopped, "not started")
+	require.NoError(t, p.Start(context.Background()), "start pool")
+
+	var calls int32
+	for i := 0; i < 10; i++ {
+		require.NoError(t, p.Submit(context.Background(), func(context.Context) {
+			time.Sleep(period)
+			atomic.AddInt32(&calls, 1)
+		}), "submit")
+	}
+
+	require.NoError(t, p.Stop(context.Background()), "stop pool")
+	assert.Equal(t, int32(10), atomic.LoadInt32(&calls), "queue drained")
+	assert.ErrorIs(t, p.Submit(context.Background(), func(context.Context) {}), workerpool.ErrStopped, "stopped")
+}
+
+func TestQueueFull(t *testing.T) {
+	p, err := workerpool.New(workerpool.WithWorkers(1), workerpool.WithQueueSize(1))
+	require.NoError(t, err, "new pool")
+	require.NoError(t, p.Start(context.Background()), "start pool")
+
+	releaseCh, startedCh := make(chan struct{}), make(chan struct{})
+	require.NoError(t, p.TrySubmit(func(context.Context) {
+		close(startedCh)
+		<-releaseCh
+	}), "first task")
+	<-startedCh
+	require.NoError(t, p.TrySubmit(func(context.Context) {}), "second task queued")
+	assert.Equal(t, 1, p.QueueDepth(), "queue depth")
+	assert.ErrorIs(t, p.TrySubmit(func(context.Context) {}), workerpool.ErrQueueFull, "queue full")
+
+	ctx, cancel := context.WithTimeout(context.Background(), period)
+	defer cancel()
+	assert.ErrorIs(t, p.Submit(ctx, func(context.Context) {}), context.DeadlineExceeded, "submit timeout")
+
+	close(releaseCh)
+	require.NoError(t, p.Stop(context.Background()), "stop pool")
+}
+
+func TestPanic(t *testing.T) {
+	p, err := workerpool.New(workerpool.WithWorkers(1))
+	require.NoError(t, err, "new pool")
+	require.NoError(t, p.Start(context.Background()), "start pool")
+
+	var calls int32
+	require.NoError(t, p.Submit(context.Background(), func(context.Context) { panic("sample panic") }), "submit")
+	require.NoError(t, p.Submit(context.Background(), func(context.Context) { atomic.AddInt32(&calls, 1) }), "submit")
+	require.NoError(t, p.Stop(context.Background()), "stop pool")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "worker survived panic")
+}
+
+func TestStopTimeout(t *testing.T) {
+	p, err := workerpool.New(workerpool.WithWorkers(1))
+	require.NoError(t, err, "new pool")
+	require.NoError(t, p.Start(context.Background()), "start pool")
+
+	var cancelled int32
+	require.NoError(t, p.Submit(context.Background(), func(ctx context.Context) {
+		<-ctx.Done()
+		atomic.AddInt32(&cancelled, 1)
+	}), "submit")
+
+	ctx, cancel := context.WithTimeout(context.Background(), period)
+	defer cancel()
+	assert.ErrorIs(t, p.Stop(ctx), context.DeadlineExceeded, "stop timeout")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&cancelled), "task context cancelled")
+}
+
+func TestStopBlockedSubmit(t *testing.T) {
+	p, err := workerpool.New(workerpool.WithWorkers(1), workerpool.WithQueueSize(1))
+	require.NoError(t, err, "new pool")
+	require.NoError(t, p.Start(context.Background()), "start pool")
+
+	startedCh := make(chan struct{})
+	require.NoError(t, p.TrySubmit(func(ctx context.Context) {
+		close(startedCh)
+		<-ctx.Done()
+	}), "first task")
+	<-startedCh
+	require.NoError(t, p.TrySubmit(func(context.Context) {}), "second task queued")
+
+	submitCh := make(chan error, 1)
+	go func() { submitCh <- p.Submit(context.Background(), func(context.Context) {}) }()
+	time.Sleep(period)
+	assert.ErrorIs(t, p.TrySubmit(func(context.Context) {}), workerpool.ErrQueueFull, "not blocked by submit")
+	assert.Equal(t, 1, p.QueueDepth(), "queue depth")
+
+	stopCh := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), period)
+		defer cancel()
+		stopCh <- p.Stop(ctx)
+	}()
+	select {
+	case err := <-stopCh:
+		assert.ErrorIs(t, err, context.DeadlineExceeded, "stop timeout")
+	case <-time.After(10 * period):
+		t.Fatal("stop blocked by submit")
+	}
+	assert.ErrorIs(t, <-submitCh, workerpool.ErrStopped, "submit released")
+}
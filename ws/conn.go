@@ -0,0 +1,119 @@
+package ws
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Conn is a registered websocket connection
+type Conn struct {
+	id   string
+	hub  *Hub
+	ws   *websocket.Conn
+	send chan []byte
+
+	once  sync.Once
+	close chan closeMessage
+	done  chan struct{}
+}
+
+type closeMessage struct {
+	code int
+	text string
+}
+
+// ID returns request ID of upgrade request
+func (c *Conn) ID() string { return c.id }
+
+// Send queues text message, connection is closed if its queue is full
+func (c *Conn) Send(data []byte) error {
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+	select {
+	case c.send <- data:
+		return nil
+	default:
+		c.closeWith(websocket.ClosePolicyViolation, "send queue overflow")
+		return ErrQueueFull
+	}
+}
+
+// Close closes connection with normal closure status
+func (c *Conn) Close() { c.closeWith(websocket.CloseNormalClosure, "") }
+
+func (c *Conn) closeWith(code int, text string) {
+	c.once.Do(func() {
+		c.close <- closeMessage{code, text}
+		close(c.done)
+	})
+}
+
+func (c *Conn) readLoop() {
+	defer c.closeWith(websocket.CloseNormalClosure, "")
+
+	pongWait := 2 * c.hub.pingInterval
+	c.ws.SetReadLimit(c.hub.readLimit)
+	_ = c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				c.hub.log.Debug().Err(err).Str("conn", c.id).Msg("read")
+			}
+			return
+		}
+		if c.hub.onMessage != nil {
+			c.hub.onMessage(c, data)
+		}
+	}
+}
+
+// writeLoop owns writes to connection and closes it once done
+func (c *Conn) writeLoop() {
+	ticker := time.NewTicker(c.hub.pingInterval)
+	defer func() {
+		ticker.Stop()
+		c.ws.Close()
+		c.hub.unregister(c)
+	}()
+
+	for {
+		select {
+		case data := <-c.send:
+			if err := c.write(websocket.TextMessage, data); err != nil {
+				c.closeWith(websocket.CloseAbnormalClosure, "")
+			}
+		case <-ticker.C:
+			if err := c.write(websocket.PingMessage, nil); err != nil {
+				c.closeWith(websocket.CloseAbnormalClosure, "")
+			}
+		case msg := <-c.close:
+			// flush queued messages before closing
+			for len(c.send) > 0 && msg.code != websocket.ClosePolicyViolation && msg.code != websocket.CloseAbnormalClosure {
+				if err := c.write(websocket.TextMessage, <-c.send); err != nil {
+					return
+				}
+			}
+			if msg.code != websocket.CloseAbnormalClosure {
+				_ = c.write(websocket.CloseMessage, websocket.FormatCloseMessage(msg.code, msg.text))
+			}
+			return
+		}
+	}
+}
+
+func (c *Conn) write(messageType int, data []byte) error {
+	if c.hub.writeTimeout > 0 {
+		_ = c.ws.SetWriteDeadline(time.Now().Add(c.hub.writeTimeout))
+	}
+	return c.ws.WriteMessage(messageType, data)
+}
@@ -0,0 +1,286 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	l "github.com/rs/zerolog/log"
+
+	"github.com/242617/core/protocol"
+	"github.com/242617/core/requestid"
+)
+
+const MetricConnections = "ws_connections"
+
+var (
+	ErrClosed    = errors.New("connection is closed")
+	ErrQueueFull = errors.New("send queue is full")
+)
+
+type option = func(h *Hub) error
+
+func withDefaults() option {
+	return func(h *Hub) error {
+		h.name = "ws"
+		h.queueSize = 64
+		h.pingInterval, h.writeTimeout = 30*time.Second, 10*time.Second
+		h.readLimit = 1 << 20
+		h.log = l.With().Str("component", "ws").Logger()
+		return nil
+	}
+}
+
+func WithName(name string) option {
+	return func(h *Hub) error {
+		h.name = name
+		return nil
+	}
+}
+
+// WithQueueSize sets number of outgoing messages buffered per connection,
+// connection is closed when its queue overflows
+func WithQueueSize(size int) option {
+	return func(h *Hub) error {
+		if size < 1 {
+			return errors.New("queue size must be positive")
+		}
+		h.queueSize = size
+		return nil
+	}
+}
+
+// WithPingInterval sets keepalive interval, connection is dropped if no pong arrives within two intervals
+func WithPingInterval(interval time.Duration) option {
+	return func(h *Hub) error {
+		if interval <= 0 {
+			return errors.New("interval must be positive")
+		}
+		h.pingInterval = interval
+		return nil
+	}
+}
+
+func WithWriteTimeout(timeout time.Duration) option {
+	return func(h *Hub) error {
+		h.writeTimeout = timeout
+		return nil
+	}
+}
+
+// WithReadLimit sets max size of incoming message
+func WithReadLimit(limit int64) option {
+	return func(h *Hub) error {
+		h.readLimit = limit
+		return nil
+	}
+}
+
+// WithCheckOrigin sets origin check of upgrade requests, same origin is required by default
+func WithCheckOrigin(f func(r *http.Request) bool) option {
+	return func(h *Hub) error {
+		h.upgrader.CheckOrigin = f
+		return nil
+	}
+}
+
+// WithOnMessage sets handler of incoming messages, it is called sequentially per connection
+func WithOnMessage(f func(c *Conn, data []byte)) option {
+	return func(h *Hub) error {
+		h.onMessage = f
+		return nil
+	}
+}
+
+// WithOnConnect sets callback called for every registered connection
+func WithOnConnect(f func(c *Conn)) option {
+	return func(h *Hub) error {
+		h.onConnect = f
+		return nil
+	}
+}
+
+// WithOnDisconnect sets callback called for every unregistered connection
+func WithOnDisconnect(f func(c *Conn)) option {
+	return func(h *Hub) error {
+		h.onDisconnect = f
+		return nil
+	}
+}
+
+func WithMetrics(recorder protocol.MetricsRecorder) option {
+	return func(h *Hub) error {
+		h.metrics = recorder
+		return nil
+	}
+}
+
+func WithLogger(log zerolog.Logger) option {
+	return func(h *Hub) error {
+		h.log = log
+		return nil
+	}
+}
+
+// New creates hub managing websocket connections upgraded by Handler
+func New(options ...option) (*Hub, error) {
+	h := Hub{conns: map[*Conn]struct{}{}}
+	options = append([]option{withDefaults()}, options...)
+	for _, option := range options {
+		if err := option(&h); err != nil {
+			return nil, errors.Wrap(err, "apply option")
+		}
+	}
+	return &h, nil
+}
+
+type Hub struct {
+	name                       string
+	queueSize                  int
+	pingInterval, writeTimeout time.Duration
+	readLimit                  int64
+	upgrader                   websocket.Upgrader
+	onMessage                  func(*Conn, []byte)
+	onConnect, onDisconnect    func(*Conn)
+	metrics                    protocol.MetricsRecorder
+	log                        zerolog.Logger
+
+	mu      sync.RWMutex
+	conns   map[*Conn]struct{}
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+func (h *Hub) Start(context.Context) error { return nil }
+
+// Stop rejects new connections and closes existing ones with going away status
+func (h *Hub) Stop(ctx context.Context) error {
+	h.mu.Lock()
+	h.stopped = true
+	conns := make([]*Conn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	h.mu.Unlock()
+
+	for _, c := range conns {
+		c.closeWith(websocket.CloseGoingAway, "server shutdown")
+	}
+
+	doneCh := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(doneCh)
+	}()
+	select {
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "close connections")
+	case <-doneCh:
+		return nil
+	}
+}
+
+func (h *Hub) String() string { return h.name }
+
+// Len returns number of registered connections
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns)
+}
+
+// Broadcast sends message to all connections, slow ones are closed
+func (h *Hub) Broadcast(data []byte) {
+	h.mu.RLock()
+	conns := make([]*Conn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	h.mu.RUnlock()
+
+	for _, c := range conns {
+		if err := c.Send(data); err != nil && !errors.Is(err, ErrClosed) {
+			h.log.Warn().Err(err).Str("conn", c.id).Msg("broadcast")
+		}
+	}
+}
+
+// Handler upgrades requests to websocket connections registered in hub
+func (h *Hub) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.mu.RLock()
+		stopped := h.stopped
+		h.mu.RUnlock()
+		if stopped {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+
+		ws, err := h.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// upgrader has already responded
+			h.log.Debug().Err(err).Msg("upgrade")
+			return
+		}
+
+		id := requestid.FromContext(r.Context())
+		if id == "" {
+			id = requestid.New()
+		}
+		c := &Conn{
+			id:    id,
+			hub:   h,
+			ws:    ws,
+			send:  make(chan []byte, h.queueSize),
+			close: make(chan closeMessage, 1),
+			done:  make(chan struct{}),
+		}
+		if !h.register(c) {
+			ws.Close()
+			return
+		}
+		go c.writeLoop()
+		go c.readLoop()
+	})
+}
+
+func (h *Hub) register(c *Conn) bool {
+	h.mu.Lock()
+	if h.stopped {
+		h.mu.Unlock()
+		return false
+	}
+	h.conns[c] = struct{}{}
+	n := len(h.conns)
+	h.wg.Add(1)
+	h.mu.Unlock()
+
+	h.record(n)
+	if h.onConnect != nil {
+		h.onConnect(c)
+	}
+	return true
+}
+
+func (h *Hub) unregister(c *Conn) {
+	h.mu.Lock()
+	delete(h.conns, c)
+	n := len(h.conns)
+	h.mu.Unlock()
+
+	h.record(n)
+	if h.onDisconnect != nil {
+		h.onDisconnect(c)
+	}
+	h.wg.Done()
+}
+
+func (h *Hub) record(n int) {
+	if h.metrics != nil {
+		h.metrics.Set(MetricConnections, float64(n), "hub", h.name)
+	}
+}
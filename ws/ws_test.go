@@ -0,0 +1,91 @@
+package ws_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/242617/core/httpserver"
+	"github.com/242617/core/ws"
+)
+
+var period = 10 * time.Millisecond
+
+func dial(t *testing.T, url string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http"), nil)
+	require.NoError(t, err, "dial")
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func read(t *testing.T, conn *websocket.Conn) string {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(100*period)), "set deadline")
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err, "read")
+	return string(data)
+}
+
+func TestHubBehindHTTPServer(t *testing.T) {
+	h, err := ws.New(ws.WithOnMessage(func(c *ws.Conn, data []byte) {
+		assert.NoError(t, c.Send(append([]byte("echo "), data...)), "send")
+	}))
+	require.NoError(t, err, "new")
+	s, err := httpserver.New(httpserver.WithAddr("127.0.0.1:0"), httpserver.WithHandler(h.Handler()))
+	require.NoError(t, err, "new server")
+	require.NoError(t, s.Start(context.Background()), "start server")
+	defer s.Stop(context.Background())
+	defer h.Stop(context.Background())
+
+	conn := dial(t, "http://"+s.Addr())
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("sample")), "write")
+	assert.Equal(t, "echo sample", read(t, conn), "echo through default middlewares")
+}
+
+func TestHub(t *testing.T) {
+	h, err := ws.New(ws.WithOnMessage(func(c *ws.Conn, data []byte) {
+		assert.NoError(t, c.Send(append([]byte("echo "), data...)), "send")
+	}))
+	require.NoError(t, err, "new")
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	first, second := dial(t, srv.URL), dial(t, srv.URL)
+	assert.Eventually(t, func() bool { return h.Len() == 2 }, 100*period, period, "registered")
+
+	require.NoError(t, first.WriteMessage(websocket.TextMessage, []byte("sample")), "write")
+	assert.Equal(t, "echo sample", read(t, first), "echo")
+
+	h.Broadcast([]byte("all"))
+	assert.Equal(t, "all", read(t, first), "first broadcast")
+	assert.Equal(t, "all", read(t, second), "second broadcast")
+
+	require.NoError(t, second.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")), "close")
+	assert.Eventually(t, func() bool { return h.Len() == 1 }, 100*period, period, "unregistered")
+
+	require.NoError(t, h.Stop(context.Background()), "stop")
+	_, _, err = first.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "going away on shutdown: %v", err)
+	assert.Zero(t, h.Len(), "all closed")
+
+	_, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	assert.Error(t, err, "rejected after stop")
+	assert.Equal(t, 503, res.StatusCode, "unavailable")
+}
+
+func TestKeepalive(t *testing.T) {
+	h, err := ws.New(ws.WithPingInterval(period))
+	require.NoError(t, err, "new")
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	// client not reading never answers pings
+	dial(t, srv.URL)
+	assert.Eventually(t, func() bool { return h.Len() == 1 }, 100*period, period, "registered")
+	assert.Eventually(t, func() bool { return h.Len() == 0 }, 100*period, period, "dropped without pongs")
+}